@@ -23,6 +23,7 @@ import (
 	"github.com/ethereum/go-ethereum/cmd/devp2p/internal/v5test"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/urfave/cli/v2"
 )
 
@@ -36,6 +37,8 @@ var (
 			discv5CrawlCommand,
 			discv5TestCommand,
 			discv5ListenCommand,
+			discv5RegisterTopicCommand,
+			discv5SearchTopicCommand,
 		},
 	}
 	discv5PingCommand = &cli.Command{
@@ -77,8 +80,33 @@ var (
 			listenAddrFlag,
 		},
 	}
+	discv5RegisterTopicCommand = &cli.Command{
+		Name:      "register-topic",
+		Usage:     "Runs a node advertising itself for a topic",
+		ArgsUsage: "<topic>",
+		Action:    discv5RegisterTopic,
+		Flags: []cli.Flag{
+			bootnodesFlag,
+			nodekeyFlag,
+			nodedbFlag,
+			listenAddrFlag,
+		},
+	}
+	discv5SearchTopicCommand = &cli.Command{
+		Name:      "search-topic",
+		Usage:     "Finds nodes advertising a topic",
+		ArgsUsage: "<topic>",
+		Action:    discv5SearchTopic,
+		Flags:     []cli.Flag{bootnodesFlag, topicSearchTimeoutFlag},
+	}
 )
 
+var topicSearchTimeoutFlag = &cli.DurationFlag{
+	Name:  "timeout",
+	Usage: "Time limit for the topic search",
+	Value: 1 * time.Minute,
+}
+
 func discv5Ping(ctx *cli.Context) error {
 	n := getNodeArg(ctx)
 	disc := startV5(ctx)
@@ -134,6 +162,43 @@ func discv5Listen(ctx *cli.Context) error {
 	select {}
 }
 
+func discv5RegisterTopic(ctx *cli.Context) error {
+	topic := getTopicArg(ctx)
+	disc := startV5(ctx)
+	defer disc.Close()
+
+	fmt.Println(disc.Self())
+	disc.RegisterTopic(topic, nil)
+	return nil
+}
+
+func discv5SearchTopic(ctx *cli.Context) error {
+	topic := getTopicArg(ctx)
+	disc := startV5(ctx)
+	defer disc.Close()
+
+	it := disc.TopicNodes(topic)
+	time.AfterFunc(ctx.Duration(topicSearchTimeoutFlag.Name), it.Close)
+	defer it.Close()
+
+	seen := make(map[enode.ID]bool)
+	for it.Next() {
+		if n := it.Node(); !seen[n.ID()] {
+			seen[n.ID()] = true
+			fmt.Println(n)
+		}
+	}
+	return nil
+}
+
+// getTopicArg returns the topic given as command-line argument.
+func getTopicArg(ctx *cli.Context) discover.Topic {
+	if ctx.NArg() < 1 {
+		exit("missing topic as command-line argument")
+	}
+	return discover.NewTopic(ctx.Args().First())
+}
+
 // startV5 starts an ephemeral discovery v5 node.
 func startV5(ctx *cli.Context) *discover.UDPv5 {
 	ln, config := makeDiscoveryConfig(ctx)
@@ -46,6 +46,12 @@ var (
 // dial attempts to dial the given node and perform a handshake,
 // returning the created Conn if successful.
 func (s *Suite) dial() (*Conn, error) {
+	return s.dialAs(g.G68)
+}
+
+// dialAs is like dial, but only advertises the g protocol versions up to
+// the given one, so older protocol versions can be negotiated too.
+func (s *Suite) dialAs(version uint) (*Conn, error) {
 	// dial
 	fd, err := net.Dial("tcp", fmt.Sprintf("%v:%d", s.Dest.IP(), s.Dest.TCP()))
 	if err != nil {
@@ -60,11 +66,10 @@ func (s *Suite) dial() (*Conn, error) {
 		return nil, err
 	}
 	// set default p2p capabilities
-	conn.caps = []p2p.Cap{
-		{Name: "g", Version: 66},
-		{Name: "g", Version: 67},
+	for v := uint(g.G66); v <= version; v++ {
+		conn.caps = append(conn.caps, p2p.Cap{Name: "g", Version: v})
 	}
-	conn.ourHighestProtoVersion = 67
+	conn.ourHighestProtoVersion = version
 	return &conn, nil
 }
 
@@ -248,6 +253,18 @@ func (c *Conn) readAndServe(chain *Chain, timeout time.Duration) Message {
 	return errorf("no message received within %v", timeout)
 }
 
+// announcedHashes returns the transaction hashes carried by an announcement of
+// any g protocol version.
+func announcedHashes(msg Message) []common.Hash {
+	switch msg := msg.(type) {
+	case *NewPooledTransactionHashes66:
+		return *msg
+	case *NewPooledTransactionHashes:
+		return msg.Hashes
+	}
+	return nil
+}
+
 // headersRequest executes the given `GetBlockHeaders` request.
 func (c *Conn) headersRequest(request *GetBlockHeaders, chain *Chain, reqID uint64) ([]*types.Header, error) {
 	defer c.SetReadDeadline(time.Time{})
@@ -359,6 +376,8 @@ func (s *Suite) waitAnnounce(conn *Conn, blockAnnouncement *NewBlock) error {
 			return nil
 
 		// ignore tx announcements from previous tests
+		case *NewPooledTransactionHashes66:
+			continue
 		case *NewPooledTransactionHashes:
 			continue
 		case *Transactions:
@@ -20,11 +20,14 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"math"
 	"math/rand"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/g/protocols/snap"
+	"github.com/ethereum/go-ethereum/gdb"
 	"github.com/ethereum/go-ethereum/internal/utesting"
 	"github.com/ethereum/go-ethereum/light"
 	"github.com/ethereum/go-ethereum/trie"
@@ -217,10 +220,10 @@ var (
 	emptyCode = common.HexToHash("c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470")
 )
 
-// TestSnapGetByteCodes various forms of GetByteCodes requests.
-func (s *Suite) TestSnapGetByteCodes(t *utesting.T) {
-	// The halfchain import should yield these bytecodes
-	var hcBytecodes []common.Hash
+// halfchainBytecodes returns the hashes of the contract codes deployed by the
+// halfchain import.
+func halfchainBytecodes() []common.Hash {
+	var hashes []common.Hash
 	for _, s := range []string{
 		"0x200c90460d8b0063210d5f5b9918e053c8f2c024485e0f1b48be8b1fc71b1317",
 		"0x20ba67ed4ac6aff626e0d1d4db623e2fada9593daeefc4a6eb4b70e6cff986f3",
@@ -248,8 +251,14 @@ func (s *Suite) TestSnapGetByteCodes(t *utesting.T) {
 		"0xe85d487abbbc83bf3423cf9731360cf4f5a37220e18e5add54e72ee20861196a",
 		"0xf195ea389a5eea28db0be93660014275b158963dec44af1dfa7d4743019a9a49",
 	} {
-		hcBytecodes = append(hcBytecodes, common.HexToHash(s))
+		hashes = append(hashes, common.HexToHash(s))
 	}
+	return hashes
+}
+
+// TestSnapGetByteCodes various forms of GetByteCodes requests.
+func (s *Suite) TestSnapGetByteCodes(t *utesting.T) {
+	hcBytecodes := halfchainBytecodes()
 
 	for i, tc := range []byteCodesTest{
 		// A few stateroots
@@ -445,6 +454,518 @@ func (s *Suite) TestSnapTrieNodes(t *utesting.T) {
 	}
 }
 
+var (
+	// storageAccounts are accounts of the halfchain state which all have the
+	// same three storage slots.
+	storageAccounts = []common.Hash{
+		common.HexToHash("0x28f0847834712d0d4a56cc9fd09ca7a91cd58d022e0f3790ef365ae82a471419"),
+		common.HexToHash("0x2dd0c3acf9e0a4e68cb041d31702c63c0e41a1ac9f9ae7b2623faa8b203b210c"),
+		common.HexToHash("0x32ccbfc6c88d71e1b45e89c47ae44f4b68a63572ff265913f40fa08d202e23e4"),
+		common.HexToHash("0xf493f79c43bd747129a226ad42529885a4b108aba6046b2d12071695a6627844"),
+	}
+	// storageSlots are the storage slot hashes of storageAccounts.
+	storageSlots = []common.Hash{
+		common.HexToHash("0x405787fa12a823e0f2b7631cc41b3ba8828b3321ca811111fa75cd3aa3bb5ace"),
+		common.HexToHash("0xb10e2d527612073b26eecdfd717e6a320cf44b4afac2b0732d9fcbe2b7fa0cf6"),
+		common.HexToHash("0xc2575a0e9e593c00f959f8c92f12db2869c3395a3b0502d05e2516446f71f85b"),
+	}
+)
+
+type accRangeProofTest struct {
+	nBytes uint64
+	origin common.Hash
+	limit  common.Hash
+
+	expAccounts int
+	expFirst    common.Hash
+	expLast     common.Hash
+	expMore     bool
+}
+
+// TestSnapAccountRangeProofs checks the Merkle proofs of GetAccountRange responses
+// at the boundaries of the requested range and of the state.
+func (s *Suite) TestSnapAccountRangeProofs(t *utesting.T) {
+	var (
+		ffHash        = common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+		zero          = common.Hash{}
+		firstKey      = common.HexToHash("0x00bf49f440a1cd0527e4d06e2765654c0f56452257516d793a9b8d604dcfdf2a")
+		firstKeyPlus1 = common.HexToHash("0x00bf49f440a1cd0527e4d06e2765654c0f56452257516d793a9b8d604dcfdf2b")
+		secondKey     = common.HexToHash("0x09e47cd5056a689e708f22fe1f932709a320518e444f5f7d8d46a3da523d6606")
+		lastKey       = common.HexToHash("0xfdafaf0adbb15d4e95cd94f4d4757f027175b7df467a2207a1b674c8d7134fd0")
+		lastKeyPlus1  = common.HexToHash("0xfdafaf0adbb15d4e95cd94f4d4757f027175b7df467a2207a1b674c8d7134fd1")
+	)
+	conn := s.dialSnapPeer(t)
+	defer conn.Close()
+
+	for i, tc := range []accRangeProofTest{
+		// The whole state fits into the response
+		{1 << 20, zero, ffHash, 102, firstKey, lastKey, false},
+		// Oversized byte limits must be capped by the node, not rejected
+		{math.MaxUint64, zero, ffHash, 102, firstKey, lastKey, false},
+		// A capped response must prove the last account
+		{4000, zero, ffHash, 76, firstKey, common.HexToHash("0xd2669dcf3858e7f1eecb8b5fedbf22fbea3e9433848a75035f79d68422c2dcda"), true},
+		// An origin between two accounts must be proven absent
+		{4000, firstKeyPlus1, ffHash, 76, secondKey, common.HexToHash("0xd28f55d3b994f16389f36944ad685b48e0fc3f8fbe86c3ca92ebecadf16a783f"), true},
+		{1, firstKeyPlus1, ffHash, 1, secondKey, secondKey, true},
+		// A limit matching an account includes the account
+		{4000, firstKeyPlus1, secondKey, 1, secondKey, secondKey, true},
+		{4000, firstKey, firstKey, 1, firstKey, firstKey, true},
+		// The last account of the state, nothing more to the right
+		{4000, lastKey, ffHash, 1, lastKey, lastKey, false},
+		// Empty ranges past the last account must still be proven
+		{4000, lastKeyPlus1, ffHash, 0, zero, zero, false},
+		{4000, ffHash, ffHash, 0, zero, zero, false},
+	} {
+		tc := tc
+		if err := s.snapGetAccountRangeProof(conn, &tc); err != nil {
+			t.Errorf("test %d \n range: %#x - %#x\n bytes: %d\nfailed: %v", i, tc.origin, tc.limit, tc.nBytes, err)
+		}
+	}
+}
+
+// TestSnapResponseByteLimits checks that responses to all request types honor
+// the soft byte limit, i.e. only the last item of a response may exceed it.
+func (s *Suite) TestSnapResponseByteLimits(t *utesting.T) {
+	var (
+		root    = s.chain.RootAt(999)
+		ffHash  = common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+		codes   = halfchainBytecodes()
+		account = storageAccounts[len(storageAccounts)-1]
+		paths   = []snap.TrieNodePathSet{
+			{[]byte{0}},
+			{account[:], []byte{0}, []byte{0x14}, []byte{0x1b}, []byte{0x1c}},
+			{storageAccounts[0][:], []byte{0}, []byte{0x14}, []byte{0x1b}, []byte{0x1c}},
+		}
+	)
+	conn := s.dialSnapPeer(t)
+	defer conn.Close()
+
+	for _, limit := range []uint64{0, 1, 50, 100, 200, 500, 1000, 4000} {
+		accounts, err := conn.snapAccountRange(s.chain, &GetAccountRange{Root: root, Limit: ffHash, Bytes: limit})
+		if err != nil {
+			t.Fatalf("limit %d: %v", limit, err)
+		}
+		sizes := make([]int, len(accounts.Accounts))
+		for i, acc := range accounts.Accounts {
+			sizes[i] = common.HashLength + len(acc.Body)
+		}
+		if err := checkSoftLimit(sizes, limit, 0); err != nil {
+			t.Errorf("limit %d: account range: %v", limit, err)
+		}
+
+		// Storage ranges may overshoot the limit by a small slack in order
+		// to avoid proving a storage range cut short.
+		storage, err := conn.snapStorageRanges(s.chain, &GetStorageRanges{Root: root, Accounts: storageAccounts, Bytes: limit})
+		if err != nil {
+			t.Fatalf("limit %d: %v", limit, err)
+		}
+		sizes = sizes[:0]
+		for _, slots := range storage.Slots {
+			for _, slot := range slots {
+				sizes = append(sizes, common.HashLength+len(slot.Body))
+			}
+		}
+		if limit > 0 || len(sizes) > 0 {
+			if err := checkSoftLimit(sizes, limit, 0.1); err != nil {
+				t.Errorf("limit %d: storage ranges: %v", limit, err)
+			}
+		}
+
+		bytecodes, err := conn.snapByteCodes(s.chain, &GetByteCodes{Hashes: codes, Bytes: limit})
+		if err != nil {
+			t.Fatalf("limit %d: %v", limit, err)
+		}
+		sizes = sizes[:0]
+		for _, code := range bytecodes {
+			sizes = append(sizes, len(code))
+		}
+		if err := checkSoftLimit(sizes, limit, 0); err != nil {
+			t.Errorf("limit %d: bytecodes: %v", limit, err)
+		}
+
+		nodes, err := conn.snapTrieNodes(s.chain, &GetTrieNodes{Root: root, Paths: paths, Bytes: limit})
+		if err != nil {
+			t.Fatalf("limit %d: %v", limit, err)
+		}
+		sizes = sizes[:0]
+		for _, node := range nodes.Nodes {
+			sizes = append(sizes, len(node))
+		}
+		if err := checkSoftLimit(sizes, limit, 0); err != nil {
+			t.Errorf("limit %d: trie nodes: %v", limit, err)
+		}
+	}
+}
+
+// TestSnapStaleRoots checks that the states of the last 128 blocks are served,
+// and that requests for older or unknown state roots don't get the peer
+// disconnected. Older states may still be available, e.g. on archive nodes, so
+// they may either be answered with empty responses or with valid proofs against
+// the requested root. Unknown roots must be answered with empty responses.
+func (s *Suite) TestSnapStaleRoots(t *utesting.T) {
+	var (
+		head    = s.chain.RootAt(999)
+		ffHash  = common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+		account = storageAccounts[len(storageAccounts)-1]
+	)
+	conn := s.dialSnapPeer(t)
+	defer conn.Close()
+
+	for _, tc := range []struct {
+		root     common.Hash
+		served   bool
+		optional bool // whether the root may be served or not
+	}{
+		{root: head, served: true},
+		{root: s.chain.RootAt(999 - 1), served: true},
+		{root: s.chain.RootAt(999 - 64), served: true},
+		{root: s.chain.RootAt(999 - 127), served: true},
+		{root: s.chain.RootAt(500), optional: true},
+		{root: s.chain.RootAt(0), optional: true},
+		{root: common.Hash{0x13, 0x37}, served: false},
+	} {
+		check := func(what string, served bool) {
+			if !tc.optional && served != tc.served {
+				t.Errorf("root %x: %s served: %t, want %t", tc.root, what, served, tc.served)
+			}
+		}
+		accounts, err := conn.snapAccountRange(s.chain, &GetAccountRange{Root: tc.root, Limit: ffHash, Bytes: 500})
+		if err != nil {
+			t.Fatalf("root %x: %v", tc.root, err)
+		}
+		check("account range", len(accounts.Accounts) > 0)
+		storage, err := conn.snapStorageRanges(s.chain, &GetStorageRanges{Root: tc.root, Accounts: []common.Hash{account}, Bytes: 500})
+		if err != nil {
+			t.Fatalf("root %x: %v", tc.root, err)
+		}
+		check("storage ranges", len(storage.Slots) > 0)
+		paths := []snap.TrieNodePathSet{{[]byte{0}}, {account[:], []byte{0}}}
+		nodes, err := conn.snapTrieNodes(s.chain, &GetTrieNodes{Root: tc.root, Paths: paths, Bytes: 500})
+		if err != nil {
+			t.Fatalf("root %x: %v", tc.root, err)
+		}
+		check("trie nodes", len(nodes.Nodes) > 0)
+
+		// Whatever is served for an old root has to be proven against it.
+		if tc.optional {
+			if err := verifyStaleState(conn, s.chain, tc.root, account, accounts, storage, nodes); err != nil {
+				t.Errorf("root %x: %v", tc.root, err)
+			}
+		}
+		// The peer must still be connected and serve the current state.
+		res, err := conn.snapAccountRange(s.chain, &GetAccountRange{Root: head, Limit: ffHash, Bytes: 1})
+		if err != nil {
+			t.Fatalf("root %x: peer lost after request: %v", tc.root, err)
+		}
+		if len(res.Accounts) == 0 {
+			t.Fatalf("root %x: head state not served after request", tc.root)
+		}
+	}
+}
+
+// verifyStaleState checks that the responses to requests for an old state root
+// are either empty or valid proofs against that root.
+func verifyStaleState(conn *Conn, chain *Chain, root, account common.Hash, accounts *snap.AccountRangePacket, storage *snap.StorageRangesPacket, nodes *snap.TrieNodesPacket) error {
+	if len(accounts.Accounts) > 0 || len(accounts.Proof) > 0 {
+		hashes, values, err := accounts.Unpack()
+		if err != nil {
+			return err
+		}
+		keys := make([][]byte, len(hashes))
+		for i, hash := range hashes {
+			keys[i] = common.CopyBytes(hash[:])
+		}
+		var end []byte
+		if len(keys) > 0 {
+			end = keys[len(keys)-1]
+		}
+		var proofdb gdb.KeyValueReader
+		if len(accounts.Proof) > 0 {
+			proofdb = proofSet(accounts.Proof)
+		}
+		if _, err := trie.VerifyRangeProof(root, common.Hash{}.Bytes(), end, keys, values, proofdb); err != nil {
+			return fmt.Errorf("invalid account range: %v", err)
+		}
+	}
+	var stRoot common.Hash
+	if len(storage.Slots) > 0 || len(nodes.Nodes) > 1 {
+		var err error
+		if stRoot, err = conn.snapStorageRoot(chain, root, account); err != nil {
+			return fmt.Errorf("storage served without its account: %v", err)
+		}
+	}
+	if len(storage.Slots) > 1 {
+		return fmt.Errorf("storage of %d accounts served, requested 1", len(storage.Slots))
+	}
+	if len(storage.Slots) > 0 && len(storage.Slots[0]) > 0 {
+		slots := storage.Slots[0]
+		keys := make([][]byte, len(slots))
+		values := make([][]byte, len(slots))
+		for i, slot := range slots {
+			keys[i] = common.CopyBytes(slot.Hash[:])
+			values[i] = slot.Body
+		}
+		var proofdb gdb.KeyValueReader
+		if len(storage.Proof) > 0 {
+			proofdb = proofSet(storage.Proof)
+		}
+		if _, err := trie.VerifyRangeProof(stRoot, common.Hash{}.Bytes(), keys[len(keys)-1], keys, values, proofdb); err != nil {
+			return fmt.Errorf("invalid storage range: %v", err)
+		}
+	}
+	// The requested paths are the account trie root and the storage trie root.
+	for i, node := range nodes.Nodes {
+		want := root
+		switch {
+		case i == 1:
+			want = stRoot
+		case i > 1:
+			return fmt.Errorf("%d trie nodes served, requested 2", len(nodes.Nodes))
+		}
+		if got := crypto.Keccak256Hash(node); got != want {
+			return fmt.Errorf("trie node #%d has hash %x, want %x", i, got, want)
+		}
+	}
+	return nil
+}
+
+type stRangesProofTest struct {
+	accounts []common.Hash
+	origin   []byte
+	limit    []byte
+	nBytes   uint64
+
+	expSlots [][]common.Hash
+}
+
+// TestSnapStorageRangesMultiAccount checks GetStorageRanges requests spanning
+// multiple accounts, and the proofs of ranges cut short.
+func (s *Suite) TestSnapStorageRangesMultiAccount(t *utesting.T) {
+	var (
+		zero     = common.Hash{}
+		ffHash   = common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+		a, b     = storageAccounts[0], storageAccounts[1]
+		c, d     = storageAccounts[2], storageAccounts[3]
+		all      = storageSlots
+		slot1    = storageSlots[0]
+		slot1Inc = common.HexToHash("0x405787fa12a823e0f2b7631cc41b3ba8828b3321ca811111fa75cd3aa3bb5acf")
+	)
+	conn := s.dialSnapPeer(t)
+	defer conn.Close()
+
+	for i, tc := range []stRangesProofTest{
+		// Complete storage of multiple accounts, no proofs needed
+		{
+			accounts: []common.Hash{a, b, c},
+			nBytes:   4000,
+			expSlots: [][]common.Hash{all, all, all},
+		},
+		{
+			accounts: []common.Hash{a, b},
+			origin:   zero[:],
+			limit:    ffHash[:],
+			nBytes:   4000,
+			expSlots: [][]common.Hash{all, all},
+		},
+		{
+			accounts: []common.Hash{d, d},
+			nBytes:   4000,
+			expSlots: [][]common.Hash{all, all},
+		},
+		// Byte limit reached in the second account, which must be proven
+		{
+			accounts: []common.Hash{a, b, c, d},
+			nBytes:   100,
+			expSlots: [][]common.Hash{all, {slot1}},
+		},
+		// Byte limit reached in the first account
+		{
+			accounts: []common.Hash{a, b, c, d},
+			nBytes:   1,
+			expSlots: [][]common.Hash{{slot1}},
+		},
+		// The origin only applies to the first account, and the proof of
+		// its range ends the response
+		{
+			accounts: []common.Hash{a, b},
+			origin:   slot1[:],
+			nBytes:   4000,
+			expSlots: [][]common.Hash{all},
+		},
+		{
+			accounts: []common.Hash{a, b},
+			origin:   slot1Inc[:],
+			nBytes:   4000,
+			expSlots: [][]common.Hash{all[1:]},
+		},
+	} {
+		tc := tc
+		if err := s.snapGetStorageRangesProof(conn, &tc); err != nil {
+			t.Errorf("test %d \n range: %#x - %#x\n bytes: %d\n #accounts: %d\nfailed: %v",
+				i, tc.origin, tc.limit, tc.nBytes, len(tc.accounts), err)
+		}
+	}
+}
+
+// TestSnapByteCodesBatchLimits checks GetByteCodes requests with oversized
+// batches and hashes unknown to the node.
+func (s *Suite) TestSnapByteCodesBatchLimits(t *utesting.T) {
+	var (
+		hcBytecodes = halfchainBytecodes()
+		unknown     = common.HexToHash("0x1337133713371337133713371337133713371337133713371337133713371337")
+		large       []common.Hash
+	)
+	for len(large) < 4096 {
+		large = append(large, hcBytecodes...)
+	}
+	for i, tc := range []byteCodesTest{
+		// Unknown hashes are left out of the response
+		{
+			nBytes: 10000, hashes: []common.Hash{unknown, hcBytecodes[0], unknown, hcBytecodes[1], unknown},
+			expHashes: 2,
+		},
+		{
+			nBytes: 10000, hashes: []common.Hash{unknown, unknown},
+			expHashes: 0,
+		},
+		{
+			nBytes: 10000, hashes: nil,
+			expHashes: 0,
+		},
+		// Oversized byte limits must be capped by the node, not rejected
+		{
+			nBytes: math.MaxUint64, hashes: hcBytecodes,
+			expHashes: len(hcBytecodes),
+		},
+	} {
+		tc := tc
+		if err := s.snapGetByteCodes(t, &tc); err != nil {
+			t.Errorf("test %d \n bytes: %d\n #hashes: %d\nfailed: %v", i, tc.nBytes, len(tc.hashes), err)
+		}
+	}
+
+	// Oversized batches may be truncated by the node, but must be served
+	// without dropping the peer.
+	conn := s.dialSnapPeer(t)
+	defer conn.Close()
+	codes, err := conn.snapByteCodes(s.chain, &GetByteCodes{Hashes: large, Bytes: 10 * 1024 * 1024})
+	if err != nil {
+		t.Fatalf("large batch: %v", err)
+	}
+	if len(codes) == 0 || len(codes) > len(large) {
+		t.Errorf("large batch: got %d bytecodes for %d hashes", len(codes), len(large))
+	}
+	codes, err = conn.snapByteCodes(s.chain, &GetByteCodes{Hashes: hcBytecodes[:1], Bytes: 10000})
+	if err != nil {
+		t.Fatalf("request after large batch: %v", err)
+	}
+	if len(codes) != 1 {
+		t.Errorf("request after large batch: got %d bytecodes, want 1", len(codes))
+	}
+}
+
+// TestSnapTrieNodesHealing checks GetTrieNodes requests for storage trie nodes,
+// as used by the healing phase of snap sync.
+func (s *Suite) TestSnapTrieNodesHealing(t *utesting.T) {
+	var (
+		root      = s.chain.RootAt(999)
+		account   = storageAccounts[len(storageAccounts)-1]
+		other     = storageAccounts[0]
+		stRoot    = common.HexToHash("0xbe3d75a1729be157e79c3b77f00206db4d54e3ea14375a015451c88ec067c790")
+		noStorage = common.HexToHash("0x19aeaa8ca7759ecc3309af9b1a64b5dc6a819cee6b4ad90fb121eca46b69b783")
+		unknown   = common.HexToHash("0x0100000000000000000000000000000000000000000000000000000000000000")
+		leaf1     = common.HexToHash("0x9ddd70915eb71e1c868c88a5e19e1b60b8f7c12727c5db3829b5e38d770661ab")
+		leaf2     = common.HexToHash("0xf4984a11f61a2921456141df88de6e1a710d28681b91af794c5a721e47839cd7")
+		leaf3     = common.HexToHash("0xb92bbcfcacad3b833b4d2a4993069af365b8ae1fb94abe5cd3f89d97ee911462")
+		empty     = emptyCode
+	)
+	for i, tc := range []trieNodesTest{
+		{ // storage trie root
+			root:      root,
+			paths:     []snap.TrieNodePathSet{{account[:], []byte{0}}},
+			nBytes:    5000,
+			expHashes: []common.Hash{stRoot},
+		},
+		{ // storage trie root and its children in one pathset
+			root: root,
+			paths: []snap.TrieNodePathSet{
+				{account[:], []byte{0}, []byte{0x14}, []byte{0x1b}, []byte{0x1c}},
+			},
+			nBytes:    5000,
+			expHashes: []common.Hash{stRoot, leaf1, leaf2, leaf3},
+		},
+		{ // storage trie nodes of multiple accounts
+			root: root,
+			paths: []snap.TrieNodePathSet{
+				{account[:], []byte{0}},
+				{other[:], []byte{0x1c}, []byte{0x14}},
+			},
+			nBytes:    5000,
+			expHashes: []common.Hash{stRoot, leaf3, leaf1},
+		},
+		{ // account and storage trie nodes mixed
+			root: root,
+			paths: []snap.TrieNodePathSet{
+				{[]byte{0}},
+				{account[:], []byte{0}},
+				{[]byte{0}},
+			},
+			nBytes:    5000,
+			expHashes: []common.Hash{root, stRoot, root},
+		},
+		{ // missing storage trie node
+			root:      root,
+			paths:     []snap.TrieNodePathSet{{account[:], []byte{0x10}}},
+			nBytes:    5000,
+			expHashes: []common.Hash{empty},
+		},
+		{ // account without storage
+			root:      root,
+			paths:     []snap.TrieNodePathSet{{noStorage[:], []byte{0}}},
+			nBytes:    5000,
+			expHashes: []common.Hash{empty},
+		},
+		{ // unknown accounts are skipped
+			root: root,
+			paths: []snap.TrieNodePathSet{
+				{unknown[:], []byte{0}},
+				{[]byte{0}},
+			},
+			nBytes:    5000,
+			expHashes: []common.Hash{root},
+		},
+		{ // zero-length pathset after storage paths
+			root: root,
+			paths: []snap.TrieNodePathSet{
+				{account[:], []byte{0}},
+				{},
+			},
+			nBytes:    5000,
+			expReject: true,
+		},
+		{ // byte limit reached within a pathset
+			root: root,
+			paths: []snap.TrieNodePathSet{
+				{account[:], []byte{0}, []byte{0x14}, []byte{0x1b}, []byte{0x1c}},
+				{[]byte{0}},
+			},
+			nBytes:    120,
+			expHashes: []common.Hash{stRoot, leaf1},
+		},
+		{ // unknown root, old roots may still be served (see TestSnapStaleRoots)
+			root:      common.Hash{0x13, 0x37},
+			paths:     []snap.TrieNodePathSet{{account[:], []byte{0}}},
+			nBytes:    5000,
+			expHashes: []common.Hash{},
+		},
+	} {
+		tc := tc
+		if err := s.snapGetTrieNodes(t, &tc); err != nil {
+			t.Errorf("test %d \n #hashes %x\n root: %#x\n bytes: %d\nfailed: %v", i, len(tc.expHashes), tc.root, tc.nBytes, err)
+		}
+	}
+}
+
 func (s *Suite) snapGetAccountRange(t *utesting.T, tc *accRangeTest) error {
 	conn, err := s.dialSnap()
 	if err != nil {
@@ -597,32 +1118,7 @@ func (s *Suite) snapGetByteCodes(t *utesting.T, tc *byteCodesTest) error {
 	}
 	// Cross reference the requested bytecodes with the response to find gaps
 	// that the serving node is missing
-	var (
-		bytecodes = res.Codes
-		hasher    = sha3.NewLegacyKeccak256().(crypto.KeccakState)
-		hash      = make([]byte, 32)
-		codes     = make([][]byte, len(req.Hashes))
-	)
-
-	for i, j := 0, 0; i < len(bytecodes); i++ {
-		// Find the next hash that we've been served, leaving misses with nils
-		hasher.Reset()
-		hasher.Write(bytecodes[i])
-		hasher.Read(hash)
-
-		for j < len(req.Hashes) && !bytes.Equal(hash, req.Hashes[j][:]) {
-			j++
-		}
-		if j < len(req.Hashes) {
-			codes[j] = bytecodes[i]
-			j++
-			continue
-		}
-		// We've either ran out of hashes, or got unrequested data
-		return errors.New("unexpected bytecode")
-	}
-
-	return nil
+	return matchByteCodes(req.Hashes, res.Codes)
 }
 
 func (s *Suite) snapGetTrieNodes(t *utesting.T, tc *trieNodesTest) error {
@@ -676,3 +1172,261 @@ func (s *Suite) snapGetTrieNodes(t *utesting.T, tc *trieNodesTest) error {
 	}
 	return err
 }
+
+// dialSnapPeer dials the node and performs the handshake for a snap connection.
+func (s *Suite) dialSnapPeer(t *utesting.T) *Conn {
+	conn, err := s.dialSnap()
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	if err := conn.peer(s.chain, nil); err != nil {
+		conn.Close()
+		t.Fatalf("peering failed: %v", err)
+	}
+	return conn
+}
+
+func (s *Suite) snapGetAccountRangeProof(conn *Conn, tc *accRangeProofTest) error {
+	root := s.chain.RootAt(999)
+	res, err := conn.snapAccountRange(s.chain, &GetAccountRange{
+		Root:   root,
+		Origin: tc.origin,
+		Limit:  tc.limit,
+		Bytes:  tc.nBytes,
+	})
+	if err != nil {
+		return err
+	}
+	if exp, got := tc.expAccounts, len(res.Accounts); exp != got {
+		return fmt.Errorf("expected %d accounts, got %d", exp, got)
+	}
+	if len(res.Accounts) > 0 {
+		if exp, got := tc.expFirst, res.Accounts[0].Hash; exp != got {
+			return fmt.Errorf("expected first account %#x, got %#x", exp, got)
+		}
+		if exp, got := tc.expLast, res.Accounts[len(res.Accounts)-1].Hash; exp != got {
+			return fmt.Errorf("expected last account %#x, got %#x", exp, got)
+		}
+	}
+	hashes, accounts, err := res.Unpack()
+	if err != nil {
+		return err
+	}
+	// The proof may only be left out if the response contains the whole state.
+	var proofdb gdb.KeyValueReader
+	if len(res.Proof) > 0 {
+		proofdb = proofSet(res.Proof)
+	} else if tc.origin != (common.Hash{}) || len(hashes) == 0 {
+		return errors.New("missing range proof")
+	}
+	keys := make([][]byte, len(hashes))
+	for i, key := range hashes {
+		keys[i] = common.CopyBytes(key[:])
+	}
+	var end []byte
+	if len(keys) > 0 {
+		end = keys[len(keys)-1]
+	}
+	more, err := trie.VerifyRangeProof(root, tc.origin[:], end, keys, accounts, proofdb)
+	if err != nil {
+		return fmt.Errorf("invalid range proof: %v", err)
+	}
+	if more != tc.expMore {
+		return fmt.Errorf("proof shows more accounts: %t, expected %t", more, tc.expMore)
+	}
+	return nil
+}
+
+func (s *Suite) snapGetStorageRangesProof(conn *Conn, tc *stRangesProofTest) error {
+	root := s.chain.RootAt(999)
+	res, err := conn.snapStorageRanges(s.chain, &GetStorageRanges{
+		Root:     root,
+		Accounts: tc.accounts,
+		Origin:   tc.origin,
+		Limit:    tc.limit,
+		Bytes:    tc.nBytes,
+	})
+	if err != nil {
+		return err
+	}
+	if exp, got := len(tc.expSlots), len(res.Slots); exp != got {
+		return fmt.Errorf("expected slots of %d accounts, got %d", exp, got)
+	}
+	for i, slots := range res.Slots {
+		if exp, got := len(tc.expSlots[i]), len(slots); exp != got {
+			return fmt.Errorf("expected %d slots for account #%d, got %d", exp, i, got)
+		}
+		for j, slot := range slots {
+			if exp, got := tc.expSlots[i][j], slot.Hash; exp != got {
+				return fmt.Errorf("expected slot #%d of account #%d to be %#x, got %#x", j, i, exp, got)
+			}
+		}
+	}
+	// Verify the slots against the storage roots. Only the last range may be
+	// incomplete, which then has to be proven.
+	for i, slots := range res.Slots {
+		stRoot, err := conn.snapStorageRoot(s.chain, root, tc.accounts[i])
+		if err != nil {
+			return err
+		}
+		var (
+			keys   = make([][]byte, len(slots))
+			values = make([][]byte, len(slots))
+			origin = common.Hash{}
+		)
+		for j, slot := range slots {
+			keys[j] = common.CopyBytes(slot.Hash[:])
+			values[j] = slot.Body
+		}
+		if i == 0 && len(tc.origin) > 0 {
+			origin = common.BytesToHash(tc.origin)
+		}
+		var proofdb gdb.KeyValueReader
+		if i == len(res.Slots)-1 && len(res.Proof) > 0 {
+			proofdb = proofSet(res.Proof)
+		}
+		if _, err := trie.VerifyRangeProof(stRoot, origin[:], keys[len(keys)-1], keys, values, proofdb); err != nil {
+			return fmt.Errorf("invalid storage range of account #%d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// proofSet creates a database of the nodes of a Merkle proof.
+func proofSet(proof [][]byte) *light.NodeSet {
+	nodes := make(light.NodeList, len(proof))
+	for i, node := range proof {
+		nodes[i] = node
+	}
+	return nodes.NodeSet()
+}
+
+// checkSoftLimit checks that only the last of the response items with the given
+// sizes exceeds the byte limit, allowing the given ratio of slack.
+func checkSoftLimit(sizes []int, limit uint64, slack float64) error {
+	if len(sizes) == 0 {
+		return errors.New("empty response")
+	}
+	var total uint64
+	for _, size := range sizes[:len(sizes)-1] {
+		total += uint64(size)
+	}
+	if max := uint64(float64(limit) * (1 + slack)); total > max {
+		return fmt.Errorf("%d items of %d bytes exceed the limit", len(sizes), total+uint64(sizes[len(sizes)-1]))
+	}
+	return nil
+}
+
+// matchByteCodes cross references the requested bytecodes with the response,
+// which may skip bytecodes the node doesn't have but must keep their order.
+func matchByteCodes(hashes []common.Hash, bytecodes [][]byte) error {
+	var (
+		hasher = sha3.NewLegacyKeccak256().(crypto.KeccakState)
+		hash   = make([]byte, 32)
+	)
+	for i, j := 0, 0; i < len(bytecodes); i++ {
+		// Find the next hash that we've been served, leaving misses with nils
+		hasher.Reset()
+		hasher.Write(bytecodes[i])
+		hasher.Read(hash)
+
+		for j < len(hashes) && !bytes.Equal(hash, hashes[j][:]) {
+			j++
+		}
+		if j < len(hashes) {
+			j++
+			continue
+		}
+		// We've either ran out of hashes, or got unrequested data
+		return errors.New("unexpected bytecode")
+	}
+	return nil
+}
+
+// snapAccountRange performs a GetAccountRange request on the connection.
+func (c *Conn) snapAccountRange(chain *Chain, req *GetAccountRange) (*snap.AccountRangePacket, error) {
+	req.ID = uint64(rand.Int63())
+	resp, err := c.snapRequest(req, req.ID, chain)
+	if err != nil {
+		return nil, fmt.Errorf("account range request failed: %v", err)
+	}
+	res, ok := resp.(*AccountRange)
+	if !ok {
+		return nil, fmt.Errorf("account range response wrong: %T %v", resp, resp)
+	}
+	if res.ID != req.ID {
+		return nil, fmt.Errorf("account range response has request ID %d, want %d", res.ID, req.ID)
+	}
+	return (*snap.AccountRangePacket)(res), nil
+}
+
+// snapStorageRanges performs a GetStorageRanges request on the connection.
+func (c *Conn) snapStorageRanges(chain *Chain, req *GetStorageRanges) (*snap.StorageRangesPacket, error) {
+	req.ID = uint64(rand.Int63())
+	resp, err := c.snapRequest(req, req.ID, chain)
+	if err != nil {
+		return nil, fmt.Errorf("storage ranges request failed: %v", err)
+	}
+	res, ok := resp.(*StorageRanges)
+	if !ok {
+		return nil, fmt.Errorf("storage ranges response wrong: %T %v", resp, resp)
+	}
+	if res.ID != req.ID {
+		return nil, fmt.Errorf("storage ranges response has request ID %d, want %d", res.ID, req.ID)
+	}
+	return (*snap.StorageRangesPacket)(res), nil
+}
+
+// snapByteCodes performs a GetByteCodes request on the connection and checks
+// that the response matches the requested hashes.
+func (c *Conn) snapByteCodes(chain *Chain, req *GetByteCodes) ([][]byte, error) {
+	req.ID = uint64(rand.Int63())
+	resp, err := c.snapRequest(req, req.ID, chain)
+	if err != nil {
+		return nil, fmt.Errorf("getBytecodes request failed: %v", err)
+	}
+	res, ok := resp.(*ByteCodes)
+	if !ok {
+		return nil, fmt.Errorf("bytecodes response wrong: %T %v", resp, resp)
+	}
+	if res.ID != req.ID {
+		return nil, fmt.Errorf("bytecodes response has request ID %d, want %d", res.ID, req.ID)
+	}
+	if err := matchByteCodes(req.Hashes, res.Codes); err != nil {
+		return nil, err
+	}
+	return res.Codes, nil
+}
+
+// snapTrieNodes performs a GetTrieNodes request on the connection.
+func (c *Conn) snapTrieNodes(chain *Chain, req *GetTrieNodes) (*snap.TrieNodesPacket, error) {
+	req.ID = uint64(rand.Int63())
+	resp, err := c.snapRequest(req, req.ID, chain)
+	if err != nil {
+		return nil, fmt.Errorf("trienodes request failed: %v", err)
+	}
+	res, ok := resp.(*TrieNodes)
+	if !ok {
+		return nil, fmt.Errorf("trienodes response wrong: %T %v", resp, resp)
+	}
+	if res.ID != req.ID {
+		return nil, fmt.Errorf("trienodes response has request ID %d, want %d", res.ID, req.ID)
+	}
+	return (*snap.TrieNodesPacket)(res), nil
+}
+
+// snapStorageRoot retrieves the storage root of an account.
+func (c *Conn) snapStorageRoot(chain *Chain, root, account common.Hash) (common.Hash, error) {
+	res, err := c.snapAccountRange(chain, &GetAccountRange{Root: root, Origin: account, Limit: account, Bytes: 1})
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if len(res.Accounts) == 0 || res.Accounts[0].Hash != account {
+		return common.Hash{}, fmt.Errorf("account %#x not served", account)
+	}
+	acc, err := snapshot.FullAccount(res.Accounts[0].Body)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return common.BytesToHash(acc.Root), nil
+}
@@ -17,9 +17,11 @@
 package gtest
 
 import (
+	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/g/protocols/g"
 	"github.com/ethereum/go-ethereum/internal/utesting"
 	"github.com/ethereum/go-ethereum/p2p/enode"
@@ -72,7 +74,12 @@ func (s *Suite) GTests() []utesting.Test {
 		{Name: "TestTransaction", Fn: s.TestTransaction},
 		{Name: "TestMaliciousTx", Fn: s.TestMaliciousTx},
 		{Name: "TestLargeTxRequest", Fn: s.TestLargeTxRequest},
+		{Name: "TestTxAnnounce66", Fn: s.TestTxAnnounce66},
+		{Name: "TestTxAnnounce67", Fn: s.TestTxAnnounce67},
 		{Name: "TestNewPooledTxs", Fn: s.TestNewPooledTxs},
+		{Name: "TestNewPooledTxs66", Fn: s.TestNewPooledTxs66},
+		{Name: "TestNewPooledTxs67", Fn: s.TestNewPooledTxs67},
+		{Name: "TestWrongAnnounceMetadata", Fn: s.TestWrongAnnounceMetadata},
 	}
 }
 
@@ -83,6 +90,12 @@ func (s *Suite) SnapTests() []utesting.Test {
 		{Name: "TestSnapGetByteCodes", Fn: s.TestSnapGetByteCodes},
 		{Name: "TestSnapGetTrieNodes", Fn: s.TestSnapTrieNodes},
 		{Name: "TestSnapGetStorageRanges", Fn: s.TestSnapGetStorageRanges},
+		{Name: "TestSnapAccountRangeProofs", Fn: s.TestSnapAccountRangeProofs},
+		{Name: "TestSnapResponseByteLimits", Fn: s.TestSnapResponseByteLimits},
+		{Name: "TestSnapStaleRoots", Fn: s.TestSnapStaleRoots},
+		{Name: "TestSnapStorageRangesMultiAccount", Fn: s.TestSnapStorageRangesMultiAccount},
+		{Name: "TestSnapByteCodesBatchLimits", Fn: s.TestSnapByteCodesBatchLimits},
+		{Name: "TestSnapTrieNodesHealing", Fn: s.TestSnapTrieNodesHealing},
 	}
 }
 
@@ -500,9 +513,98 @@ func (s *Suite) TestLargeTxRequest(t *utesting.T) {
 	}
 }
 
+// TestTxAnnounce66 tests whether a node propagates transactions to a peer
+// which negotiated g/66, using the announcement format of that version.
+func (s *Suite) TestTxAnnounce66(t *utesting.T) {
+	s.testTxAnnounce(t, g.G66)
+}
+
+// TestTxAnnounce67 tests whether a node propagates transactions to a peer
+// which negotiated g/67, using the announcement format of that version.
+func (s *Suite) TestTxAnnounce67(t *utesting.T) {
+	s.testTxAnnounce(t, g.G67)
+}
+
+// testTxAnnounce sends a new transaction to the node and waits for it to be
+// propagated to another peer connected with the given protocol version.
+func (s *Suite) testTxAnnounce(t *utesting.T, version uint) {
+	_, txs, err := generateTxs(s, 1)
+	if err != nil {
+		t.Fatalf("failed to generate transactions: %v", err)
+	}
+	tx := txs[0]
+
+	sendConn, err := s.dial()
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer sendConn.Close()
+	recvConn, err := s.dialAs(version)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer recvConn.Close()
+
+	if err = sendConn.peer(s.chain, nil); err != nil {
+		t.Fatalf("peering failed: %v", err)
+	}
+	if err = sendConn.Write(&Transactions{tx}); err != nil {
+		t.Fatalf("failed to write to connection: %v", err)
+	}
+	if err = recvConn.peer(s.chain, nil); err != nil {
+		t.Fatalf("peering failed: %v", err)
+	}
+	// update last nonce seen
+	nonce = tx.Nonce()
+
+	// wait for the transaction to be propagated in any of the valid formats
+	for {
+		var hashes []common.Hash
+		switch msg := recvConn.readAndServe(s.chain, timeout).(type) {
+		case *Transactions:
+			for _, tx := range *msg {
+				hashes = append(hashes, tx.Hash())
+			}
+		case *NewPooledTransactionHashes66:
+			hashes = *msg
+
+		// ignore block announcements from previous tests
+		case *NewBlockHashes:
+			continue
+		case *NewBlock:
+			continue
+		default:
+			t.Fatalf("unexpected %s", pretty.Sdump(msg))
+		}
+		for _, hash := range hashes {
+			if hash == tx.Hash() {
+				return
+			}
+		}
+	}
+}
+
 // TestNewPooledTxs tests whether a node will do a GetPooledTransactions
 // request upon receiving a NewPooledTransactionHashes announcement.
 func (s *Suite) TestNewPooledTxs(t *utesting.T) {
+	s.testNewPooledTxs(t, g.G68)
+}
+
+// TestNewPooledTxs66 is like TestNewPooledTxs, but announces the transactions
+// in the g/66 format, without types and sizes.
+func (s *Suite) TestNewPooledTxs66(t *utesting.T) {
+	s.testNewPooledTxs(t, g.G66)
+}
+
+// TestNewPooledTxs67 is like TestNewPooledTxs, but announces the transactions
+// in the g/67 format, without types and sizes.
+func (s *Suite) TestNewPooledTxs67(t *utesting.T) {
+	s.testNewPooledTxs(t, g.G67)
+}
+
+// testNewPooledTxs announces a batch of transactions to the node over the given
+// protocol version and waits for the node to request them.
+func (s *Suite) testNewPooledTxs(t *utesting.T, version uint) {
 	// send the next block to ensure the node is no longer syncing and
 	// is able to accept txs
 	if err := s.sendNextBlock(); err != nil {
@@ -510,20 +612,27 @@ func (s *Suite) TestNewPooledTxs(t *utesting.T) {
 	}
 
 	// generate 50 txs
-	hashMap, _, err := generateTxs(s, 50)
+	_, txs, err := generateTxs(s, 50)
 	if err != nil {
 		t.Fatalf("failed to generate transactions: %v", err)
 	}
 
 	// create new pooled tx hashes announcement
-	hashes := make([]common.Hash, 0)
-	for _, hash := range hashMap {
-		hashes = append(hashes, hash)
+	var (
+		hashes = make([]common.Hash, len(txs))
+		kinds  = make([]byte, len(txs))
+		sizes  = make([]uint32, len(txs))
+	)
+	for i, tx := range txs {
+		hashes[i], kinds[i], sizes[i] = tx.Hash(), tx.Type(), uint32(tx.Size())
+	}
+	var announce Message = NewPooledTransactionHashes{Types: kinds, Sizes: sizes, Hashes: hashes}
+	if version < g.G68 {
+		announce = NewPooledTransactionHashes66(hashes)
 	}
-	announce := NewPooledTransactionHashes(hashes)
 
 	// send announcement
-	conn, err := s.dial()
+	conn, err := s.dialAs(version)
 	if err != nil {
 		t.Fatalf("dial failed: %v", err)
 	}
@@ -546,6 +655,8 @@ func (s *Suite) TestNewPooledTxs(t *utesting.T) {
 			return
 
 		// ignore propagated txs from previous tests
+		case *NewPooledTransactionHashes66:
+			continue
 		case *NewPooledTransactionHashes:
 			continue
 		case *Transactions:
@@ -561,3 +672,63 @@ func (s *Suite) TestNewPooledTxs(t *utesting.T) {
 		}
 	}
 }
+
+// TestWrongAnnounceMetadata tests whether a node disconnects a peer which lied
+// about the type of a transaction in its g/68 announcement, once it delivers
+// the transaction.
+func (s *Suite) TestWrongAnnounceMetadata(t *utesting.T) {
+	// send the next block to ensure the node is no longer syncing and
+	// is able to accept txs
+	if err := s.sendNextBlock(); err != nil {
+		t.Fatalf("failed to send next block: %v", err)
+	}
+	_, txs, err := generateTxs(s, 1)
+	if err != nil {
+		t.Fatalf("failed to generate transactions: %v", err)
+	}
+	tx := txs[0]
+
+	// announce the legacy transaction as a dynamic fee one
+	announce := NewPooledTransactionHashes{
+		Types:  []byte{types.DynamicFeeTxType},
+		Sizes:  []uint32{uint32(tx.Size())},
+		Hashes: []common.Hash{tx.Hash()},
+	}
+	conn, err := s.dial()
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+	if err = conn.peer(s.chain, nil); err != nil {
+		t.Fatalf("peering failed: %v", err)
+	}
+	if err = conn.Write(announce); err != nil {
+		t.Fatalf("failed to write to connection: %v", err)
+	}
+	// serve the transaction when requested and wait for the disconnect
+	for {
+		switch msg := conn.readAndServe(s.chain, timeout).(type) {
+		case *GetPooledTransactions:
+			resp := &PooledTransactions{
+				RequestId:                msg.RequestId,
+				PooledTransactionsPacket: g.PooledTransactionsPacket{tx},
+			}
+			if err := conn.Write(resp); err != nil {
+				t.Fatalf("failed to write to connection: %v", err)
+			}
+		case *Disconnect:
+			return
+		case *Error:
+			if strings.Contains(msg.String(), "i/o timeout") {
+				t.Fatalf("peer not disconnected after delivering mismatching transaction")
+			}
+			return
+
+		// ignore propagated txs and blocks from previous tests
+		case *NewPooledTransactionHashes66, *NewPooledTransactionHashes, *Transactions, *NewBlockHashes, *NewBlock:
+			continue
+		default:
+			t.Fatalf("unexpected %s", pretty.Sdump(msg))
+		}
+	}
+}
@@ -95,8 +95,8 @@ func sendSuccessfulTx(s *Suite, tx *types.Transaction, prevTx *types.Transaction
 				}
 			}
 			return fmt.Errorf("missing transaction: got %v missing %v", recTxs, tx.Hash())
-		case *NewPooledTransactionHashes:
-			txHashes := *msg
+		case *NewPooledTransactionHashes66, *NewPooledTransactionHashes:
+			txHashes := announcedHashes(msg)
 			// if you receive an old tx propagation, read from connection again
 			if len(txHashes) == 1 && prevTx != nil {
 				if txHashes[0] == prevTx.Hash() {
@@ -201,8 +201,8 @@ func sendMultipleSuccessfulTxs(t *utesting.T, s *Suite, txs []*types.Transaction
 			for _, tx := range *msg {
 				recvHashes = append(recvHashes, tx.Hash())
 			}
-		case *NewPooledTransactionHashes:
-			recvHashes = append(recvHashes, *msg...)
+		case *NewPooledTransactionHashes66, *NewPooledTransactionHashes:
+			recvHashes = append(recvHashes, announcedHashes(msg)...)
 		default:
 			if !strings.Contains(pretty.Sdump(msg), "i/o timeout") {
 				return fmt.Errorf("unexpected message while waiting to receive txs: %s", pretty.Sdump(msg))
@@ -246,8 +246,8 @@ func checkMaliciousTxPropagation(s *Suite, txs []*types.Transaction, conn *Conn)
 		if len(badTxs) > 0 {
 			return fmt.Errorf("received %d bad txs: \n%v", len(badTxs), badTxs)
 		}
-	case *NewPooledTransactionHashes:
-		badTxs, _ := compareReceivedTxs(*msg, txs)
+	case *NewPooledTransactionHashes66, *NewPooledTransactionHashes:
+		badTxs, _ := compareReceivedTxs(announcedHashes(msg), txs)
 		if len(badTxs) > 0 {
 			return fmt.Errorf("received %d bad txs: \n%v", len(badTxs), badTxs)
 		}
@@ -126,8 +126,16 @@ type NewBlock g.NewBlockPacket
 func (msg NewBlock) Code() int     { return 23 }
 func (msg NewBlock) ReqID() uint64 { return 0 }
 
-// NewPooledTransactionHashes is the network packet for the tx hash propagation message.
-type NewPooledTransactionHashes g.NewPooledTransactionHashesPacket
+// NewPooledTransactionHashes66 is the network packet for the tx hash propagation message
+// on g/66 and g/67.
+type NewPooledTransactionHashes66 g.NewPooledTransactionHashesPacket66
+
+func (msg NewPooledTransactionHashes66) Code() int     { return 24 }
+func (msg NewPooledTransactionHashes66) ReqID() uint64 { return 0 }
+
+// NewPooledTransactionHashes is the network packet for the tx hash propagation message,
+// carrying the types and sizes of the transactions since g/68.
+type NewPooledTransactionHashes g.NewPooledTransactionHashesPacket68
 
 func (msg NewPooledTransactionHashes) Code() int     { return 24 }
 func (msg NewPooledTransactionHashes) ReqID() uint64 { return 0 }
@@ -203,7 +211,11 @@ func (c *Conn) Read() Message {
 	case (Transactions{}).Code():
 		msg = new(Transactions)
 	case (NewPooledTransactionHashes{}).Code():
-		msg = new(NewPooledTransactionHashes)
+		if c.negotiatedProtoVersion < g.G68 {
+			msg = new(NewPooledTransactionHashes66)
+		} else {
+			msg = new(NewPooledTransactionHashes)
+		}
 	case (GetPooledTransactions{}.Code()):
 		gMsg := new(g.GetPooledTransactionsPacket66)
 		if err := rlp.DecodeBytes(rawData, gMsg); err != nil {
@@ -53,6 +53,7 @@ func init() {
 		dnsCommand,
 		nodesetCommand,
 		rlpxCommand,
+		replayCommand,
 	}
 }
 
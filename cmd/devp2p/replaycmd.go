@@ -0,0 +1,389 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/devp2p/internal/gtest"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/rlpx"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	replayCommand = &cli.Command{
+		Name:      "replay",
+		Usage:     "Plays a traffic recording against a node",
+		ArgsUsage: "<node> <recording>",
+		Description: `
+The replay command connects to a node and sends it the messages which the remote peer
+sent in the given recording, reproducing their original timing. Messages sent by the
+node are printed as they arrive. Recordings are created by running a node with the
+--p2p.record flag.`,
+		Action: replay,
+		Flags: []cli.Flag{
+			replayFastFlag,
+			replayWaitFlag,
+		},
+	}
+	replayFastFlag = &cli.BoolFlag{
+		Name:  "fast",
+		Usage: "Sends messages as fast as possible instead of reproducing the recorded timing",
+	}
+	replayWaitFlag = &cli.DurationFlag{
+		Name:  "wait",
+		Usage: "Time to wait for messages from the node after the last message was sent",
+		Value: 5 * time.Second,
+	}
+)
+
+// Message codes of the base protocol.
+const (
+	replayBaseLength = 16
+	replayHelloMsg   = 0x00
+	replayDiscMsg    = 0x01
+	replayPingMsg    = 0x02
+	replayPongMsg    = 0x03
+)
+
+func replay(ctx *cli.Context) error {
+	if ctx.NArg() < 2 {
+		exit("missing path to recording as command-line argument")
+	}
+	n := getNodeArg(ctx)
+	entries, err := loadRecording(ctx.Args().Get(1))
+	if err != nil {
+		return err
+	}
+	r, err := newReplayer(n, entries[0])
+	if err != nil {
+		return err
+	}
+	defer r.close()
+
+	fmt.Printf("Connected to %s (%s), running %v\n", n.ID().TerminalString(), r.name, r.protocolNames())
+	go r.readLoop()
+	r.send(entries[1:], !ctx.Bool(replayFastFlag.Name))
+
+	select {
+	case <-time.After(ctx.Duration(replayWaitFlag.Name)):
+	case <-r.closed:
+	}
+	r.printSummary(entries)
+	return nil
+}
+
+// loadRecording reads a recording file.
+func loadRecording(file string) ([]p2p.RecordEntry, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	entries, err := p2p.ReadRecording(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recording: %v", err)
+	}
+	if len(entries) == 0 || entries[0].Type != p2p.RecordConnect {
+		return nil, errors.New("invalid recording: missing connect entry")
+	}
+	return entries, nil
+}
+
+// replayer plays a recording over an RLPx connection.
+type replayer struct {
+	conn      *rlpx.Conn
+	name      string
+	protocols []p2p.RecordProtocol
+	offsets   map[string]uint64
+	start     time.Time
+
+	wmu    sync.Mutex // serializes writes
+	closed chan struct{}
+
+	mu       sync.Mutex
+	received map[replayKey]int
+	sent     int
+	err      error
+}
+
+// replayKey identifies a message type.
+type replayKey struct {
+	proto string
+	code  uint64
+}
+
+func (k replayKey) String() string {
+	return fmt.Sprintf("%s/%d", k.proto, k.code)
+}
+
+// newReplayer connects to the node and negotiates the protocols of the recording.
+func newReplayer(n *enode.Node, connect p2p.RecordEntry) (*replayer, error) {
+	fd, err := net.Dial("tcp", fmt.Sprintf("%v:%d", n.IP(), n.TCP()))
+	if err != nil {
+		return nil, err
+	}
+	conn := rlpx.NewConn(fd, n.Pubkey())
+	key, _ := crypto.GenerateKey()
+	if _, err := conn.Handshake(key); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	r := &replayer{
+		conn:     conn,
+		offsets:  make(map[string]uint64),
+		closed:   make(chan struct{}),
+		received: make(map[replayKey]int),
+	}
+	if err := r.handshake(key, connect); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// handshake exchanges the devp2p hello with the node, announcing the protocols
+// which ran on the recorded connection.
+func (r *replayer) handshake(key *ecdsa.PrivateKey, connect p2p.RecordEntry) error {
+	ours := &gtest.Hello{
+		Version: 5,
+		Name:    connect.Name,
+		ID:      crypto.FromECDSAPub(&key.PublicKey)[1:],
+	}
+	for _, p := range connect.Protocols {
+		ours.Caps = append(ours.Caps, p2p.Cap{Name: p.Name, Version: p.Version})
+	}
+	enc, err := rlp.EncodeToBytes(ours)
+	if err != nil {
+		return err
+	}
+	if _, err := r.conn.Write(replayHelloMsg, enc); err != nil {
+		return err
+	}
+	code, data, _, err := r.conn.Read()
+	if err != nil {
+		return err
+	}
+	switch code {
+	case replayHelloMsg:
+	case replayDiscMsg:
+		return fmt.Errorf("disconnected: %v", decodeDisconnect(data))
+	default:
+		return fmt.Errorf("invalid message code %d, expected handshake (code zero)", code)
+	}
+	var theirs gtest.Hello
+	if err := rlp.DecodeBytes(data, &theirs); err != nil {
+		return fmt.Errorf("invalid handshake: %v", err)
+	}
+	r.name = theirs.Name
+	if theirs.Version >= 5 {
+		r.conn.SetSnappy(true)
+	}
+
+	// Assign message code offsets to the shared protocols in the same way as
+	// the node does, i.e. in alphabetical order.
+	offset := uint64(replayBaseLength)
+	for _, p := range connect.Protocols {
+		for _, cap := range theirs.Caps {
+			if cap.Name == p.Name && cap.Version == p.Version {
+				r.protocols = append(r.protocols, p)
+				r.offsets[p.Name] = offset
+				offset += p.Length
+				break
+			}
+		}
+	}
+	if len(r.protocols) == 0 {
+		return errors.New("no protocols of the recording are supported by the node")
+	}
+	r.start = time.Now()
+	return nil
+}
+
+// send plays the messages which the remote peer sent in the recording.
+func (r *replayer) send(entries []p2p.RecordEntry, timed bool) {
+	var first time.Time
+	for _, e := range entries {
+		if e.Type != p2p.RecordRecv {
+			continue
+		}
+		offset, ok := r.offsets[e.Protocol]
+		if !ok {
+			fmt.Printf("Skipping %s message, protocol not supported by the node\n", replayKey{e.Protocol, e.Code})
+			continue
+		}
+		if first.IsZero() {
+			first = e.Time
+		}
+		if timed {
+			if wait := time.Until(r.start.Add(e.Time.Sub(first))); wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-r.closed:
+				}
+			}
+		}
+		select {
+		case <-r.closed:
+			return
+		default:
+		}
+		if err := r.write(offset+e.Code, e.Payload); err != nil {
+			r.fail(err)
+			return
+		}
+		r.mu.Lock()
+		r.sent++
+		r.mu.Unlock()
+		r.print("->", replayKey{e.Protocol, e.Code}, len(e.Payload))
+	}
+}
+
+// readLoop prints the messages sent by the node.
+func (r *replayer) readLoop() {
+	for {
+		code, data, _, err := r.conn.Read()
+		if err != nil {
+			r.fail(err)
+			return
+		}
+		switch {
+		case code == replayPingMsg:
+			if err := r.write(replayPongMsg, []byte{0xC0}); err != nil {
+				r.fail(err)
+				return
+			}
+		case code == replayDiscMsg:
+			r.fail(fmt.Errorf("disconnected: %v", decodeDisconnect(data)))
+			return
+		case code < replayBaseLength:
+			// Ignore other base protocol messages.
+		default:
+			key := r.messageKey(code)
+			r.mu.Lock()
+			r.received[key]++
+			r.mu.Unlock()
+			r.print("<-", key, len(data))
+		}
+	}
+}
+
+// messageKey resolves the protocol of a message code.
+func (r *replayer) messageKey(code uint64) replayKey {
+	for _, p := range r.protocols {
+		if offset := r.offsets[p.Name]; code >= offset && code < offset+p.Length {
+			return replayKey{p.Name, code - offset}
+		}
+	}
+	return replayKey{"unknown", code}
+}
+
+func (r *replayer) write(code uint64, data []byte) error {
+	r.wmu.Lock()
+	defer r.wmu.Unlock()
+	_, err := r.conn.Write(code, data)
+	return err
+}
+
+func (r *replayer) print(dir string, key replayKey, size int) {
+	fmt.Printf("%10v %s %-12v %d bytes\n", time.Since(r.start).Round(time.Millisecond), dir, key, size)
+}
+
+// fail ends the replay with the given error.
+func (r *replayer) fail(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == nil {
+		r.err = err
+		close(r.closed)
+	}
+}
+
+func (r *replayer) close() {
+	disc, _ := rlp.EncodeToBytes([]p2p.DiscReason{p2p.DiscRequested})
+	r.write(replayDiscMsg, disc)
+	r.conn.Close()
+}
+
+func (r *replayer) protocolNames() []string {
+	names := make([]string, len(r.protocols))
+	for i, p := range r.protocols {
+		names[i] = fmt.Sprintf("%s/%d", p.Name, p.Version)
+	}
+	return names
+}
+
+// printSummary compares the messages sent by the node with the ones sent by the
+// recorded node.
+func (r *replayer) printSummary(entries []p2p.RecordEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	recorded := make(map[replayKey]int)
+	for _, e := range entries {
+		if e.Type == p2p.RecordSend {
+			recorded[replayKey{e.Protocol, e.Code}]++
+		}
+	}
+	keys := make([]replayKey, 0, len(recorded))
+	for key := range recorded {
+		keys = append(keys, key)
+	}
+	for key := range r.received {
+		if _, ok := recorded[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].proto != keys[j].proto {
+			return keys[i].proto < keys[j].proto
+		}
+		return keys[i].code < keys[j].code
+	})
+
+	fmt.Printf("\nSent %d messages", r.sent)
+	if r.err != nil {
+		fmt.Printf(", connection ended: %v", r.err)
+	}
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 1, 2, 2, ' ', 0)
+	fmt.Fprintf(w, "MESSAGE\tRECORDED\tREPLAYED\n")
+	for _, key := range keys {
+		fmt.Fprintf(w, "%v\t%d\t%d\n", key, recorded[key], r.received[key])
+	}
+	w.Flush()
+}
+
+func decodeDisconnect(data []byte) p2p.DiscReason {
+	var reason []p2p.DiscReason
+	if err := rlp.DecodeBytes(data, &reason); err != nil || len(reason) == 0 {
+		return p2p.DiscRequested
+	}
+	return reason[0]
+}
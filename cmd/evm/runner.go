@@ -233,6 +233,11 @@ func runCmd(ctx *cli.Context) error {
 
 	if chainConfig != nil {
 		runtimeConfig.ChainConfig = chainConfig
+	} else if vm.HasEOFMagic(code) {
+		// EOF code is only valid from Prague onwards, activate it from genesis
+		config := *params.AllGashProtocolChanges
+		config.PragueBlock = new(big.Int)
+		runtimeConfig.ChainConfig = &config
 	} else {
 		runtimeConfig.ChainConfig = params.AllGashProtocolChanges
 	}
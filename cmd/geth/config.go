@@ -32,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/usbwallet"
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/g/catalyst"
 	"github.com/ethereum/go-ethereum/g/gconfig"
 	"github.com/ethereum/go-ethereum/internal/flags"
 	"github.com/ethereum/go-ethereum/internal/gapi"
@@ -166,7 +167,7 @@ func makeFullNode(ctx *cli.Context) (*node.Node, gapi.Backend) {
 		cfg.G.OverrideTerminalTotalDifficultyPassed = &override
 	}
 
-	backend, g := utils.RegisterEthService(stack, &cfg.G)
+	backend, g := utils.RegisterEthService(ctx, stack, &cfg.G)
 
 	// Warn users to migrate if they have a legacy freezer format.
 	if g != nil && !ctx.IsSet(utils.IgnoreLegacyReceiptsFlag.Name) {
@@ -187,6 +188,16 @@ func makeFullNode(ctx *cli.Context) (*node.Node, gapi.Backend) {
 		}
 	}
 
+	// Drive the chain with a simulated beacon client in developer mode.
+	if g != nil && ctx.IsSet(utils.DeveloperFlag.Name) {
+		simBeacon, err := catalyst.NewSimulatedBeacon(ctx.Uint64(utils.DeveloperPeriodFlag.Name), g)
+		if err != nil {
+			utils.Fatalf("Failed to create the simulated beacon: %v", err)
+		}
+		catalyst.RegisterSimulatedBeaconAPIs(stack, simBeacon)
+		stack.RegisterLifecycle(simBeacon)
+	}
+
 	// Configure log filter RPC API.
 	filterSystem := utils.RegisterFilterAPI(stack, backend, &cfg.G)
 
@@ -33,6 +33,11 @@
 //
 //	$ p2psim node connect node01 node02
 //	Connected node01 to node02
+//
+// Scenarios describing a network with faulty links and a timeline of events
+// can be run with:
+//
+//	$ p2psim scenario partition.yaml
 package main
 
 import (
@@ -134,6 +139,12 @@ func main() {
 			Usage:  "load a network snapshot from stdin",
 			Action: loadSnapshot,
 		},
+		{
+			Name:      "scenario",
+			ArgsUsage: "<file>",
+			Usage:     "run a scenario from a YAML or JSON file",
+			Action:    runScenario,
+		},
 		{
 			Name:   "node",
 			Usage:  "manage simulation nodes",
@@ -265,6 +276,38 @@ func loadSnapshot(ctx *cli.Context) error {
 	return client.LoadSnapshot(snap)
 }
 
+func runScenario(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return cli.ShowCommandHelp(ctx, ctx.Command.Name)
+	}
+	data, err := os.ReadFile(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	sc, err := simulations.LoadScenario(data)
+	if err != nil {
+		return err
+	}
+	result, err := client.RunScenario(sc)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(ctx.App.Writer, 1, 2, 2, ' ', 0)
+	fmt.Fprintf(w, "AT\tASSERTION\tNODES\tRESULT\n")
+	for _, a := range result.Assertions {
+		status := "ok"
+		if !a.Passed {
+			status = "FAIL: " + a.Error
+		}
+		fmt.Fprintf(w, "%v\t%s\t%s\t%s\n", a.At, a.Type, strings.Join(append(a.Nodes, a.Peers...), ","), status)
+	}
+	w.Flush()
+	if !result.Passed() {
+		return fmt.Errorf("scenario %q failed", result.Name)
+	}
+	return nil
+}
+
 func listNodes(ctx *cli.Context) error {
 	if ctx.NArg() != 0 {
 		return cli.ShowCommandHelp(ctx, ctx.Command.Name)
@@ -159,12 +159,12 @@ var (
 	// Dev mode
 	DeveloperFlag = &cli.BoolFlag{
 		Name:     "dev",
-		Usage:    "Ephemeral proof-of-authority network with a pre-funded developer account, mining enabled",
+		Usage:    "Ephemeral proof-of-stake network with a pre-funded developer account, driven by a simulated beacon client",
 		Category: flags.DevCategory,
 	}
 	DeveloperPeriodFlag = &cli.IntFlag{
 		Name:     "dev.period",
-		Usage:    "Block period to use in developer mode (0 = mine only if transaction pending)",
+		Usage:    "Block period of the simulated beacon client in developer mode (0 = seal a block only when transactions are pending)",
 		Category: flags.DevCategory,
 	}
 	DeveloperGasLimitFlag = &cli.Uint64Flag{
@@ -399,6 +399,17 @@ var (
 		Value:    core.DefaultTxPoolConfig.Rejournal,
 		Category: flags.TxPoolCategory,
 	}
+	TxPoolPoolJournalFlag = &cli.StringFlag{
+		Name:     "txpool.pooljournal",
+		Usage:    "Disk journal for all pooled transactions, remotes included, to survive node restarts (disabled if empty)",
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolPoolJournalCapFlag = &cli.Uint64Flag{
+		Name:     "txpool.pooljournalcap",
+		Usage:    "Maximum size in bytes of the full pool journal",
+		Value:    core.DefaultTxPoolConfig.PoolJournalCap,
+		Category: flags.TxPoolCategory,
+	}
 	TxPoolPriceLimitFlag = &cli.Uint64Flag{
 		Name:     "txpool.pricelimit",
 		Usage:    "Minimum gas price limit to enforce for acceptance into the pool",
@@ -435,12 +446,24 @@ var (
 		Value:    gconfig.Defaults.TxPool.GlobalQueue,
 		Category: flags.TxPoolCategory,
 	}
+	TxPoolGlobalBlobsFlag = &cli.Uint64Flag{
+		Name:     "txpool.globalblobs",
+		Usage:    "Maximum total size in bytes of blob sidecars for all accounts",
+		Value:    gconfig.Defaults.TxPool.GlobalBlobs,
+		Category: flags.TxPoolCategory,
+	}
 	TxPoolLifetimeFlag = &cli.DurationFlag{
 		Name:     "txpool.lifetime",
 		Usage:    "Maximum amount of time non-executable transaction are queued",
 		Value:    gconfig.Defaults.TxPool.Lifetime,
 		Category: flags.TxPoolCategory,
 	}
+	TxPoolPrivateLifetimeFlag = &cli.Uint64Flag{
+		Name:     "txpool.privatelifetime",
+		Usage:    "Number of blocks after which unincluded private transactions are dropped",
+		Value:    gconfig.Defaults.TxPool.PrivateLifetime,
+		Category: flags.TxPoolCategory,
+	}
 
 	// Performance tuning settings
 	CacheFlag = &cli.IntFlag{
@@ -563,6 +586,11 @@ var (
 		Usage:    "Disable remote sealing verification",
 		Category: flags.MinerCategory,
 	}
+	MinerPayloadRebuildFlag = &cli.BoolFlag{
+		Name:     "miner.payloadrebuild",
+		Usage:    "Keep rebuilding requested payloads every recommit interval until retrieved by the consensus client",
+		Category: flags.MinerCategory,
+	}
 
 	// Account settings
 	UnlockedAccountFlag = &cli.StringFlag{
@@ -850,6 +878,41 @@ var (
 		Value:    30303,
 		Category: flags.NetworkingCategory,
 	}
+	QUICPortFlag = &cli.IntFlag{
+		Name:     "quic.port",
+		Usage:    "UDP port for accepting P2P connections over QUIC (disabled if unset)",
+		Category: flags.NetworkingCategory,
+	}
+	HolePunchFlag = &cli.BoolFlag{
+		Name:     "nat.holepunch",
+		Usage:    "Reach peers behind NATs by UDP hole punching through discovery v5 relays (requires --v5disc and --quic.port)",
+		Category: flags.NetworkingCategory,
+	}
+	P2PRecordDirFlag = &flags.DirectoryFlag{
+		Name:     "p2p.record",
+		Usage:    "Directory for recording the messages exchanged with each peer (disabled if unset)",
+		Category: flags.NetworkingCategory,
+	}
+	DialNetrestrictFlag = &cli.StringFlag{
+		Name:     "p2p.dial.netrestrict",
+		Usage:    "Restricts dialing discovered nodes to the given IP networks (CIDR masks)",
+		Category: flags.NetworkingCategory,
+	}
+	DialRequireFlag = &cli.StringFlag{
+		Name:     "p2p.dial.require",
+		Usage:    "Comma separated node record keys which discovered nodes must advertise to be dialed (e.g. snap)",
+		Category: flags.NetworkingCategory,
+	}
+	DialClientsFlag = &cli.StringFlag{
+		Name:     "p2p.dial.clients",
+		Usage:    "Comma separated client names which discovered nodes must advertise to be dialed, if they advertise any",
+		Category: flags.NetworkingCategory,
+	}
+	AdvertiseClientFlag = &cli.BoolFlag{
+		Name:     "p2p.advertise.client",
+		Usage:    "Advertises the client name and version in the local node record",
+		Category: flags.NetworkingCategory,
+	}
 
 	// Console
 	JSpathFlag = &flags.DirectoryFlag{
@@ -884,6 +947,18 @@ var (
 		Value:    gconfig.Defaults.GPO.IgnorePrice.Int64(),
 		Category: flags.GasPriceCategory,
 	}
+	GpoModeFlag = &cli.StringFlag{
+		Name:     "gpo.mode",
+		Usage:    `Strategy used to suggest gas prices ("blocks" samples recent blocks, "pending" inspects the transaction pool)`,
+		Value:    gconfig.Defaults.GPO.Mode,
+		Category: flags.GasPriceCategory,
+	}
+	GpoPendingBlocksFlag = &cli.IntFlag{
+		Name:     "gpo.pendingblocks",
+		Usage:    "Number of blocks within which transactions should be included in pending mode",
+		Value:    gconfig.Defaults.GPO.PendingBlocks,
+		Category: flags.GasPriceCategory,
+	}
 
 	// Metrics flags
 	MetricsEnabledFlag = &cli.BoolFlag{
@@ -1140,6 +1215,9 @@ func setListenAddress(ctx *cli.Context, cfg *p2p.Config) {
 	if ctx.IsSet(DiscoveryPortFlag.Name) {
 		cfg.DiscAddr = fmt.Sprintf(":%d", ctx.Int(DiscoveryPortFlag.Name))
 	}
+	if ctx.IsSet(QUICPortFlag.Name) {
+		cfg.QUICAddr = fmt.Sprintf(":%d", ctx.Int(QUICPortFlag.Name))
+	}
 }
 
 // setNAT creates a port mapper from command line flags.
@@ -1449,6 +1527,28 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 		}
 		cfg.NetRestrict = list
 	}
+	if ctx.IsSet(HolePunchFlag.Name) {
+		cfg.HolePunching = ctx.Bool(HolePunchFlag.Name)
+	}
+	if ctx.IsSet(P2PRecordDirFlag.Name) {
+		cfg.RecordDir = ctx.String(P2PRecordDirFlag.Name)
+	}
+	if netrestrict := ctx.String(DialNetrestrictFlag.Name); netrestrict != "" {
+		list, err := netutil.ParseNetlist(netrestrict)
+		if err != nil {
+			Fatalf("Option %q: %v", DialNetrestrictFlag.Name, err)
+		}
+		cfg.DialNetRestrict = list
+	}
+	if ctx.IsSet(DialRequireFlag.Name) {
+		cfg.DialRequire = SplitAndTrim(ctx.String(DialRequireFlag.Name))
+	}
+	if ctx.IsSet(DialClientsFlag.Name) {
+		cfg.DialClients = SplitAndTrim(ctx.String(DialClientsFlag.Name))
+	}
+	if ctx.IsSet(AdvertiseClientFlag.Name) {
+		cfg.AdvertiseClient = ctx.Bool(AdvertiseClientFlag.Name)
+	}
 
 	if ctx.Bool(DeveloperFlag.Name) {
 		// --dev mode can't use p2p networking.
@@ -1566,6 +1666,12 @@ func setGPO(ctx *cli.Context, cfg *gasprice.Config, light bool) {
 	if ctx.IsSet(GpoIgnoreGasPriceFlag.Name) {
 		cfg.IgnorePrice = big.NewInt(ctx.Int64(GpoIgnoreGasPriceFlag.Name))
 	}
+	if ctx.IsSet(GpoModeFlag.Name) {
+		cfg.Mode = ctx.String(GpoModeFlag.Name)
+	}
+	if ctx.IsSet(GpoPendingBlocksFlag.Name) {
+		cfg.PendingBlocks = ctx.Int(GpoPendingBlocksFlag.Name)
+	}
 }
 
 func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
@@ -1588,6 +1694,12 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.IsSet(TxPoolRejournalFlag.Name) {
 		cfg.Rejournal = ctx.Duration(TxPoolRejournalFlag.Name)
 	}
+	if ctx.IsSet(TxPoolPoolJournalFlag.Name) {
+		cfg.PoolJournal = ctx.String(TxPoolPoolJournalFlag.Name)
+	}
+	if ctx.IsSet(TxPoolPoolJournalCapFlag.Name) {
+		cfg.PoolJournalCap = ctx.Uint64(TxPoolPoolJournalCapFlag.Name)
+	}
 	if ctx.IsSet(TxPoolPriceLimitFlag.Name) {
 		cfg.PriceLimit = ctx.Uint64(TxPoolPriceLimitFlag.Name)
 	}
@@ -1606,9 +1718,15 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.IsSet(TxPoolGlobalQueueFlag.Name) {
 		cfg.GlobalQueue = ctx.Uint64(TxPoolGlobalQueueFlag.Name)
 	}
+	if ctx.IsSet(TxPoolGlobalBlobsFlag.Name) {
+		cfg.GlobalBlobs = ctx.Uint64(TxPoolGlobalBlobsFlag.Name)
+	}
 	if ctx.IsSet(TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = ctx.Duration(TxPoolLifetimeFlag.Name)
 	}
+	if ctx.IsSet(TxPoolPrivateLifetimeFlag.Name) {
+		cfg.PrivateLifetime = ctx.Uint64(TxPoolPrivateLifetimeFlag.Name)
+	}
 }
 
 func setGash(ctx *cli.Context, cfg *gconfig.Config) {
@@ -1658,6 +1776,9 @@ func setMiner(ctx *cli.Context, cfg *miner.Config) {
 	if ctx.IsSet(MinerNoVerifyFlag.Name) {
 		cfg.Noverify = ctx.Bool(MinerNoVerifyFlag.Name)
 	}
+	if ctx.IsSet(MinerPayloadRebuildFlag.Name) {
+		cfg.PayloadRebuild = ctx.Bool(MinerPayloadRebuildFlag.Name)
+	}
 }
 
 func setRequiredBlocks(ctx *cli.Context, cfg *gconfig.Config) {
@@ -1947,7 +2068,7 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *gconfig.Config) {
 		log.Info("Using developer account", "address", developer.Address)
 
 		// Create a new developer genesis block or reuse existing one
-		cfg.Genesis = core.DeveloperGenesisBlock(uint64(ctx.Int(DeveloperPeriodFlag.Name)), ctx.Uint64(DeveloperGasLimitFlag.Name), developer.Address)
+		cfg.Genesis = core.DeveloperGenesisBlock(ctx.Uint64(DeveloperGasLimitFlag.Name), developer.Address)
 		if ctx.IsSet(DataDirFlag.Name) {
 			// If datadir doesn't exist we need to open db in write-mode
 			// so leveldb can create files.
@@ -1992,7 +2113,7 @@ func SetDNSDiscoveryDefaults(cfg *gconfig.Config, genesis common.Hash) {
 // RegisterEthService adds an Ethereum client to the stack.
 // The second return value is the full node instance, which may be nil if the
 // node is running as a light client.
-func RegisterEthService(stack *node.Node, cfg *gconfig.Config) (gapi.Backend, *g.Ethereum) {
+func RegisterEthService(ctx *cli.Context, stack *node.Node, cfg *gconfig.Config) (gapi.Backend, *g.Ethereum) {
 	if cfg.SyncMode == downloader.LightSync {
 		backend, err := les.New(stack, cfg)
 		if err != nil {
@@ -2014,8 +2135,12 @@ func RegisterEthService(stack *node.Node, cfg *gconfig.Config) (gapi.Backend, *g
 			Fatalf("Failed to create the LES server: %v", err)
 		}
 	}
-	if err := gcatalyst.Register(stack, backend); err != nil {
-		Fatalf("Failed to register the Engine API service: %v", err)
+	// In developer mode the chain is driven by a simulated beacon client
+	// instead of an external one using the engine API.
+	if !ctx.IsSet(DeveloperFlag.Name) {
+		if err := gcatalyst.Register(stack, backend); err != nil {
+			Fatalf("Failed to register the Engine API service: %v", err)
+		}
 	}
 	stack.RegisterAPIs(tracers.APIs(backend.APIBackend))
 	return backend.APIBackend, backend
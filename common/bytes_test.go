@@ -67,6 +67,15 @@ func TestFromHex(t *testing.T) {
 	}
 }
 
+func TestFromHexUpperPrefix(t *testing.T) {
+	input := "0X01"
+	expected := []byte{1}
+	result := FromHex(input)
+	if !bytes.Equal(expected, result) {
+		t.Errorf("Expected %x got %x", expected, result)
+	}
+}
+
 func TestIsHex(t *testing.T) {
 	tests := []struct {
 		input string
@@ -97,6 +106,15 @@ func TestFromHexOddLength(t *testing.T) {
 	}
 }
 
+func TestFromHexUpperPrefixOddLength(t *testing.T) {
+	input := "0X1"
+	expected := []byte{1}
+	result := FromHex(input)
+	if !bytes.Equal(expected, result) {
+		t.Errorf("Expected %x got %x", expected, result)
+	}
+}
+
 func TestNoPrefixShortHexOddLength(t *testing.T) {
 	input := "1"
 	expected := []byte{1}
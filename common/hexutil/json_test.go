@@ -124,6 +124,29 @@ func TestMarshalBytes(t *testing.T) {
 	}
 }
 
+func TestMarshalBytesText(t *testing.T) {
+	for _, test := range encodeBytesTests {
+		in := test.input.([]byte)
+		out, err := Bytes(in).MarshalText()
+		if err != nil {
+			t.Errorf("%x: %v", in, err)
+			continue
+		}
+		if string(out) != test.want {
+			t.Errorf("%x: MarshalText output mismatch: got %q, want %q", in, out, test.want)
+			continue
+		}
+		var dec Bytes
+		if err := dec.UnmarshalText(out); err != nil {
+			t.Errorf("%x: UnmarshalText error: %v", in, err)
+			continue
+		}
+		if !bytes.Equal(dec, in) {
+			t.Errorf("%x: round trip mismatch: got %x", in, dec)
+		}
+	}
+}
+
 var unmarshalBigTests = []unmarshalTest{
 	// invalid encoding
 	{input: "", wantErr: errJSONEOF},
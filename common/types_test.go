@@ -44,15 +44,15 @@ func TestIsHexAddress(t *testing.T) {
 		str string
 		exp bool
 	}{
-		{"G5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", true},
+		{"0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", true},
 		{"5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", true},
-		{"G5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", true},
-		{"GAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA", true},
-		{"GAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA", true},
-		{"G5aaeb6053f3e94c9b9a09f33669435e7ef1beaed1", false},
-		{"G5aaeb6053f3e94c9b9a09f33669435e7ef1beae", false},
+		{"0X5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", true},
+		{"0XAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA", true},
+		{"0xAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA", true},
+		{"0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed1", false},
+		{"0x5aaeb6053f3e94c9b9a09f33669435e7ef1beae", false},
 		{"5aaeb6053f3e94c9b9a09f33669435e7ef1beaed11", false},
-		{"Gxaaeb6053f3e94c9b9a09f33669435e7ef1beaed", false},
+		{"0xxaaeb6053f3e94c9b9a09f33669435e7ef1beaed", false},
 	}
 
 	for _, test := range tests {
@@ -129,15 +129,15 @@ func TestAddressHexChecksum(t *testing.T) {
 		Output string
 	}{
 		// Test cases from https://github.com/ethereum/EIPs/blob/master/EIPS/eip-55.md#specification
-		{"G5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", "G5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"},
-		{"Gfb6916095ca1df60bb79ce92ce3ea74c37c5d359", "GfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359"},
-		{"Gdbf03b407c01e7cd3cbea99509d93f8dddc8c6fb", "GdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB"},
-		{"Gd1220a0cf47c7b9be7a2e6ba89f429762e7b9adb", "GD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb"},
+		{"0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"},
+		{"0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359", "0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359"},
+		{"0xdbf03b407c01e7cd3cbea99509d93f8dddc8c6fb", "0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB"},
+		{"0xd1220a0cf47c7b9be7a2e6ba89f429762e7b9adb", "0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb"},
 		// Ensure that non-standard length input values are handled correctly
-		{"0xa", "G000000000000000000000000000000000000000A"},
-		{"0x0a", "G000000000000000000000000000000000000000A"},
-		{"0x00a", "G000000000000000000000000000000000000000A"},
-		{"0x000000000000000000000000000000000000000a", "G000000000000000000000000000000000000000A"},
+		{"0xa", "0x000000000000000000000000000000000000000A"},
+		{"0x0a", "0x000000000000000000000000000000000000000A"},
+		{"0x00a", "0x000000000000000000000000000000000000000A"},
+		{"0x000000000000000000000000000000000000000a", "0x000000000000000000000000000000000000000A"},
 	}
 	for i, test := range tests {
 		output := HexToAddress(test.Input).Hex()
@@ -7,6 +7,8 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/params"
 )
@@ -135,3 +137,36 @@ func TestVerifyTerminalBlock(t *testing.T) {
 		}
 	}
 }
+
+func TestFinalizeWithdrawals(t *testing.T) {
+	chain := newMockChain()
+	chain.config.ShanghaiBlock = big.NewInt(0)
+
+	var (
+		engine = New(nil)
+		a      = common.Address{0xaa}
+		b      = common.Address{0xbb}
+		header = &types.Header{Number: big.NewInt(1), Difficulty: common.Big0}
+	)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.AddBalance(a, big.NewInt(1))
+
+	withdrawals := []*types.Withdrawal{
+		{Index: 0, Validator: 1, Address: a, Amount: 2},
+		{Index: 1, Validator: 2, Address: b, Amount: 3},
+		{Index: 2, Validator: 3, Address: a, Amount: 4},
+	}
+	engine.Finalize(chain, header, statedb, nil, nil, withdrawals)
+
+	// Amounts are in Gwei and added on top of the existing balance
+	gwei := big.NewInt(params.GWei)
+	if have, want := statedb.GetBalance(a), new(big.Int).Add(big.NewInt(1), new(big.Int).Mul(big.NewInt(6), gwei)); have.Cmp(want) != 0 {
+		t.Errorf("balance mismatch for %x: have %v, want %v", a, have, want)
+	}
+	if have, want := statedb.GetBalance(b), new(big.Int).Mul(big.NewInt(3), gwei); have.Cmp(want) != 0 {
+		t.Errorf("balance mismatch for %x: have %v, want %v", b, have, want)
+	}
+	if header.Root != statedb.IntermediateRoot(true) {
+		t.Error("header root not updated")
+	}
+}
@@ -310,6 +310,17 @@ func (gash *Gash) verifyHeader(chain consensus.ChainHeaderReader, header, parent
 	if diff := new(big.Int).Sub(header.Number, parent.Number); diff.Cmp(big.NewInt(1)) != 0 {
 		return consensus.ErrInvalidNumber
 	}
+	// Verify the non-existence of withdrawalsHash, withdrawals are a beacon feature
+	if header.WithdrawalsHash != nil {
+		return fmt.Errorf("invalid withdrawalsHash: have %x, expected nil", header.WithdrawalsHash)
+	}
+	// Verify the non-existence of the data gas fields, blobs are a beacon feature
+	switch {
+	case header.ExcessDataGas != nil:
+		return fmt.Errorf("invalid excessDataGas: have %d, expected nil", *header.ExcessDataGas)
+	case header.DataGasUsed != nil:
+		return fmt.Errorf("invalid dataGasUsed: have %d, expected nil", *header.DataGasUsed)
+	}
 	// Verify the engine specific seal securing the block
 	if seal {
 		if err := gash.verifySeal(chain, header, false); err != nil {
@@ -597,7 +608,7 @@ func (gash *Gash) Prepare(chain consensus.ChainHeaderReader, header *types.Heade
 
 // Finalize implements consensus.Engine, accumulating the block and uncle rewards,
 // setting the final state on the header
-func (gash *Gash) Finalize(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header) {
+func (gash *Gash) Finalize(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, withdrawals []*types.Withdrawal) {
 	// Accumulate any block and uncle rewards and commit the final state root
 	accumulateRewards(chain.Config(), state, header, uncles)
 	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
@@ -605,9 +616,12 @@ func (gash *Gash) Finalize(chain consensus.ChainHeaderReader, header *types.Head
 
 // FinalizeAndAssemble implements consensus.Engine, accumulating the block and
 // uncle rewards, setting the final state and assembling the block.
-func (gash *Gash) FinalizeAndAssemble(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt) (*types.Block, error) {
+func (gash *Gash) FinalizeAndAssemble(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt, withdrawals []*types.Withdrawal) (*types.Block, error) {
+	if len(withdrawals) > 0 {
+		return nil, errors.New("gash does not support withdrawals")
+	}
 	// Finalize block
-	gash.Finalize(chain, header, state, txs, uncles)
+	gash.Finalize(chain, header, state, txs, uncles, nil)
 
 	// Header seems complete, assemble into a block and return
 	return types.NewBlock(header, txs, uncles, receipts, trie.NewStackTrie(nil)), nil
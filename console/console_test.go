@@ -95,7 +95,7 @@ func newTester(t *testing.T, confOverride func(*gconfig.Config)) *tester {
 		t.Fatalf("failed to create node: %v", err)
 	}
 	gConf := &gconfig.Config{
-		Genesis: core.DeveloperGenesisBlock(15, 11_500_000, common.Address{}),
+		Genesis: core.DeveloperGenesisBlock(11_500_000, common.Address{}),
 		Miner: miner.Config{
 			ACbase: common.HexToAddress(testAddress),
 		},
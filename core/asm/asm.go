@@ -32,6 +32,7 @@ type instructionIterator struct {
 	op      vm.OpCode
 	error   error
 	started bool
+	eof     bool
 }
 
 // NewInstructionIterator create a new instruction iterator.
@@ -41,6 +42,15 @@ func NewInstructionIterator(code []byte) *instructionIterator {
 	return it
 }
 
+// NewEOFInstructionIterator creates a new instruction iterator for a code
+// section of an EOF container. It additionally decodes the immediate arguments
+// of the EOF-only instructions.
+func NewEOFInstructionIterator(code []byte) *instructionIterator {
+	it := NewInstructionIterator(code)
+	it.eof = true
+	return it
+}
+
 // Next returns true if there is a next instruction and moves on.
 func (it *instructionIterator) Next() bool {
 	if it.error != nil || uint64(len(it.code)) <= it.pc {
@@ -73,12 +83,38 @@ func (it *instructionIterator) Next() bool {
 			return false
 		}
 		it.arg = it.code[it.pc+1 : u]
+	} else if it.eof {
+		it.arg, it.error = eofImmediate(it.code, it.pc, it.op)
+		if it.error != nil {
+			return false
+		}
 	} else {
 		it.arg = nil
 	}
 	return true
 }
 
+// eofImmediate returns the immediate argument of the EOF instruction at pc, or
+// nil if the instruction has none.
+func eofImmediate(code []byte, pc uint64, op vm.OpCode) ([]byte, error) {
+	var size uint64
+	switch op {
+	case vm.RJUMP, vm.RJUMPI, vm.CALLF:
+		size = 2
+	case vm.RJUMPV:
+		if uint64(len(code)) <= pc+1 {
+			return nil, fmt.Errorf("incomplete %v instruction at %v", op, pc)
+		}
+		size = 1 + 2*uint64(code[pc+1])
+	default:
+		return nil, nil
+	}
+	if uint64(len(code)) < pc+1+size {
+		return nil, fmt.Errorf("incomplete %v instruction at %v", op, pc)
+	}
+	return code[pc+1 : pc+1+size], nil
+}
+
 // Error returns any error that may have been encountered.
 func (it *instructionIterator) Error() error {
 	return it.error
@@ -105,8 +141,27 @@ func PrintDisassembled(code string) error {
 	if err != nil {
 		return err
 	}
+	if vm.HasEOFMagic(script) {
+		var c vm.Container
+		if err := c.UnmarshalBinary(script); err != nil {
+			return err
+		}
+		for i, section := range c.Code {
+			fmt.Printf("# code section %d (inputs %d, outputs %d, max stack %d)\n", i, c.Types[i].Input, c.Types[i].Output, c.Types[i].MaxStackHeight)
+			if err := printInstructions(NewEOFInstructionIterator(section)); err != nil {
+				return err
+			}
+		}
+		if len(c.Data) > 0 {
+			fmt.Printf("# data section\n%#x\n", c.Data)
+		}
+		return nil
+	}
+	return printInstructions(NewInstructionIterator(script))
+}
 
-	it := NewInstructionIterator(script)
+// printInstructions pretty-prints all the instructions of the iterator to stdout.
+func printInstructions(it *instructionIterator) error {
 	for it.Next() {
 		if it.Arg() != nil && 0 < len(it.Arg()) {
 			fmt.Printf("%05x: %v %#x\n", it.PC(), it.Op(), it.Arg())
@@ -72,3 +72,34 @@ func TestInstructionIteratorEmpty(t *testing.T) {
 		t.Errorf("Expected 0, but got %v instead.", cnt)
 	}
 }
+
+// Tests disassembling the instructions of an EOF code section
+func TestEOFInstructionIterator(t *testing.T) {
+	// PUSH1 0x01, RJUMPI 0x0001, INVALID, RJUMPV [0x0000], CALLF 0x0001, STOP
+	script, _ := hex.DecodeString("60015d0001fe5e010000b0000100")
+
+	var ops []string
+	it := NewEOFInstructionIterator(script)
+	for it.Next() {
+		ops = append(ops, it.Op().String())
+	}
+	if err := it.Error(); err != nil {
+		t.Fatalf("Expected no error, but encountered %v instead.", err)
+	}
+	want := []string{"PUSH1", "RJUMPI", "INVALID", "RJUMPV", "CALLF", "STOP"}
+	if len(ops) != len(want) {
+		t.Fatalf("Expected %v, but got %v instead.", want, ops)
+	}
+	for i := range ops {
+		if ops[i] != want[i] {
+			t.Errorf("Expected %v, but got %v instead.", want, ops)
+		}
+	}
+	// Truncated jump table
+	it = NewEOFInstructionIterator([]byte{0x5e, 0x02, 0x00, 0x00})
+	for it.Next() {
+	}
+	if it.Error() == nil {
+		t.Errorf("Expected an error for truncated RJUMPV, got none.")
+	}
+}
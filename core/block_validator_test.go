@@ -392,3 +392,50 @@ func TestCalcGasLimit(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the withdrawals of a block body are validated against the header
+// and the Shanghai fork.
+func TestValidateBodyWithdrawals(t *testing.T) {
+	var (
+		config = *params.AllGashProtocolChanges
+		gspec  = &Genesis{Config: &config}
+		engine = beacon.New(gash.NewFaker())
+	)
+	config.TerminalTotalDifficulty = common.Big0
+	config.ShanghaiBlock = big.NewInt(2)
+
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 2, func(i int, b *BlockGen) {
+		b.SetDifficulty(common.Big0) // Post-merge block
+		if i == 1 {
+			b.AddWithdrawal(&types.Withdrawal{Validator: 1, Address: common.Address{0xaa}, Amount: 100})
+		}
+	})
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	validator := chain.Validator()
+	pre, post := blocks[0], blocks[1]
+	if err := validator.ValidateBody(pre); err != nil {
+		t.Fatalf("pre-shanghai block rejected: %v", err)
+	}
+	if err := validator.ValidateBody(pre.WithWithdrawals([]*types.Withdrawal{})); err == nil {
+		t.Error("pre-shanghai block with withdrawals accepted")
+	}
+	if _, err := chain.InsertChain(blocks[:1]); err != nil {
+		t.Fatalf("failed to insert pre-shanghai block: %v", err)
+	}
+	if err := validator.ValidateBody(post); err != nil {
+		t.Fatalf("shanghai block rejected: %v", err)
+	}
+	if err := validator.ValidateBody(post.WithWithdrawals(nil)); err == nil {
+		t.Error("shanghai block without withdrawals accepted")
+	}
+	tampered := *post.Withdrawals()[0]
+	tampered.Amount++
+	if err := validator.ValidateBody(post.WithWithdrawals([]*types.Withdrawal{&tampered})); err == nil {
+		t.Error("shanghai block with mismatching withdrawals accepted")
+	}
+}
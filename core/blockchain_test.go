@@ -3965,3 +3965,122 @@ func TestTxIndexer(t *testing.T) {
 		os.RemoveAll(frdir)
 	}
 }
+
+// Tests that the coinbase is warm from the start of the transaction after the
+// Shanghai fork (EIP-3651).
+func TestEIP3651(t *testing.T) {
+	var (
+		aa     = common.HexToAddress("0x000000000000000000000000000000000000aaaa")
+		engine = gash.NewFaker()
+		config = *params.AllGashProtocolChanges
+
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		funds  = new(big.Int).Mul(common.Big1, big.NewInt(params.AC))
+		gspec  = &Genesis{
+			Config: &config,
+			Alloc: GenesisAlloc{
+				addr: {Balance: funds},
+				// The address 0xAAAA reads the balance of the coinbase
+				aa: {
+					Code: []byte{
+						byte(vm.COINBASE),
+						byte(vm.BALANCE),
+						byte(vm.POP),
+					},
+					Balance: big.NewInt(0),
+				},
+			},
+		}
+	)
+	gspec.Config.ShanghaiBlock = big.NewInt(2)
+	signer := types.LatestSigner(gspec.Config)
+
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 2, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{1})
+		tx, _ := types.SignTx(types.NewTx(&types.DynamicFeeTx{
+			ChainID:   gspec.Config.ChainID,
+			Nonce:     uint64(i),
+			To:        &aa,
+			Gas:       30000,
+			GasFeeCap: newGwei(5),
+			GasTipCap: big.NewInt(2),
+		}), signer, key)
+		b.AddTx(tx)
+	})
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+	// The coinbase is cold before the fork and warm afterwards
+	for number, access := range map[uint64]uint64{
+		1: params.ColdAccountAccessCostEIP2929,
+		2: params.WarmStorageReadCostEIP2929,
+	} {
+		block := chain.GetBlockByNumber(number)
+		if want := params.TxGas + 2*vm.GasQuickStep + access; block.GasUsed() != want {
+			t.Errorf("block %d: gas used mismatch: have %d, want %d", number, block.GasUsed(), want)
+		}
+	}
+}
+
+// Tests that init code is metered and limited in size after the Shanghai fork
+// (EIP-3860).
+func TestEIP3860(t *testing.T) {
+	var (
+		engine = gash.NewFaker()
+		config = *params.AllGashProtocolChanges
+
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		funds  = new(big.Int).Mul(common.Big1, big.NewInt(params.AC))
+		gspec  = &Genesis{
+			Config: &config,
+			Alloc:  GenesisAlloc{addr: {Balance: funds}},
+		}
+	)
+	gspec.Config.ShanghaiBlock = big.NewInt(2)
+	signer := types.LatestSigner(gspec.Config)
+
+	create := func(nonce uint64, size int) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTx(&types.DynamicFeeTx{
+			ChainID:   gspec.Config.ChainID,
+			Nonce:     nonce,
+			Gas:       500000,
+			GasFeeCap: newGwei(5),
+			GasTipCap: big.NewInt(2),
+			Data:      make([]byte, size), // STOP only, deploys empty code
+		}), signer, key)
+		return tx
+	}
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 2, func(i int, b *BlockGen) {
+		b.AddTx(create(uint64(i), params.MaxInitCodeSize))
+	})
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+	// Init code words are only charged for after the fork
+	base := params.TxGasContractCreation + uint64(params.MaxInitCodeSize)*params.TxDataZeroGas
+	for number, want := range map[uint64]uint64{
+		1: base,
+		2: base + uint64(params.MaxInitCodeSize)/32*params.InitCodeWordGas,
+	} {
+		if block := chain.GetBlockByNumber(number); block.GasUsed() != want {
+			t.Errorf("block %d: gas used mismatch: have %d, want %d", number, block.GasUsed(), want)
+		}
+	}
+	// Init code above the limit is rejected after the fork
+	bad := GenerateBadBlock(blocks[1], engine, types.Transactions{create(2, params.MaxInitCodeSize+1)}, gspec.Config)
+	if _, err := chain.InsertChain(types.Blocks{bad}); !errors.Is(err, ErrMaxInitCodeSizeExceeded) {
+		t.Fatalf("oversized init code error mismatch: have %v, want %v", err, ErrMaxInitCodeSizeExceeded)
+	}
+}
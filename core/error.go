@@ -96,4 +96,24 @@ var (
 
 	// ErrSenderNoEOA is returned if the sender of a transaction is a contract.
 	ErrSenderNoEOA = errors.New("sender not an eoa")
+
+	// ErrMaxInitCodeSizeExceeded is returned if creation transaction provides the init code bigger
+	// than init code size limit.
+	ErrMaxInitCodeSizeExceeded = errors.New("max initcode size exceeded")
+
+	// ErrDataFeeCapTooLow is returned if the transaction fee cap for data gas is
+	// less than the data gas fee of the block.
+	ErrDataFeeCapTooLow = errors.New("max fee per data gas less than block data gas fee")
+
+	// ErrMissingBlobHashes is returned if a blob transaction carries no blob
+	// versioned hashes.
+	ErrMissingBlobHashes = errors.New("blob transaction missing blob hashes")
+
+	// ErrInvalidVersionedHash is returned if a blob versioned hash has an
+	// unsupported version.
+	ErrInvalidVersionedHash = errors.New("invalid blob versioned hash")
+
+	// ErrDataGasLimitReached is returned if the data gas consumed by a blob
+	// transaction exceeds the per-block data gas limit.
+	ErrDataGasLimitReached = errors.New("data gas limit reached")
 )
@@ -23,6 +23,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	cmath "github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -80,6 +81,9 @@ type Message interface {
 	IsFake() bool
 	Data() []byte
 	AccessList() types.AccessList
+
+	DataGasFeeCap() *big.Int
+	DataHashes() []common.Hash
 }
 
 // ExecutionResult includes all output after executing given evm
@@ -118,7 +122,7 @@ func (result *ExecutionResult) Revert() []byte {
 }
 
 // IntrinsicGas computes the 'intrinsic gas' for a message with the given data.
-func IntrinsicGas(data []byte, accessList types.AccessList, isContractCreation bool, isHomestead, isEIP2028 bool) (uint64, error) {
+func IntrinsicGas(data []byte, accessList types.AccessList, isContractCreation bool, isHomestead, isEIP2028, isEIP3860 bool) (uint64, error) {
 	// Set the starting gas for the raw transaction
 	var gas uint64
 	if isContractCreation && isHomestead {
@@ -150,6 +154,14 @@ func IntrinsicGas(data []byte, accessList types.AccessList, isContractCreation b
 			return 0, ErrGasUintOverflow
 		}
 		gas += z * params.TxDataZeroGas
+
+		if isContractCreation && isEIP3860 {
+			lenWords := toWordSize(uint64(len(data)))
+			if (math.MaxUint64-gas)/params.InitCodeWordGas < lenWords {
+				return 0, ErrGasUintOverflow
+			}
+			gas += lenWords * params.InitCodeWordGas
+		}
 	}
 	if accessList != nil {
 		gas += uint64(len(accessList)) * params.TxAccessListAddressGas
@@ -158,6 +170,14 @@ func IntrinsicGas(data []byte, accessList types.AccessList, isContractCreation b
 	return gas, nil
 }
 
+// toWordSize returns the ceiled word size required for init code payment calculation.
+func toWordSize(size uint64) uint64 {
+	if size > math.MaxUint64-31 {
+		return math.MaxUint64/32 + 1
+	}
+	return (size + 31) / 32
+}
+
 // NewStateTransition initialises and returns a new state transition object.
 func NewStateTransition(evm *vm.EVM, msg Message, gp *GasPool) *StateTransition {
 	return &StateTransition{
@@ -192,6 +212,11 @@ func (st *StateTransition) to() common.Address {
 	return *st.msg.To()
 }
 
+// dataGasUsed returns the amount of data gas consumed by the blobs of the message.
+func (st *StateTransition) dataGasUsed() uint64 {
+	return uint64(len(st.msg.DataHashes())) * params.BlobTxDataGasPerBlob
+}
+
 func (st *StateTransition) buyGas() error {
 	mgval := new(big.Int).SetUint64(st.msg.Gas())
 	mgval = mgval.Mul(mgval, st.gasPrice)
@@ -201,6 +226,22 @@ func (st *StateTransition) buyGas() error {
 		balanceCheck = balanceCheck.Mul(balanceCheck, st.gasFeeCap)
 		balanceCheck.Add(balanceCheck, st.value)
 	}
+	if st.evm.ChainConfig().IsCancun(st.evm.Context.BlockNumber) {
+		if dataGas := st.dataGasUsed(); dataGas > 0 {
+			// Check that the user has enough funds to cover the data gas cap
+			dataBalanceCheck := new(big.Int).SetUint64(dataGas)
+			dataBalanceCheck.Mul(dataBalanceCheck, st.msg.DataGasFeeCap())
+			if balanceCheck == mgval {
+				balanceCheck = new(big.Int).Set(mgval)
+			}
+			balanceCheck.Add(balanceCheck, dataBalanceCheck)
+
+			// Pay for dataGasUsed * actual data gas fee
+			dataFee := new(big.Int).SetUint64(dataGas)
+			dataFee.Mul(dataFee, misc.CalcBlobFee(st.excessDataGas()))
+			mgval.Add(mgval, dataFee)
+		}
+	}
 	if have, want := st.state.GetBalance(st.msg.From()), balanceCheck; have.Cmp(want) < 0 {
 		return fmt.Errorf("%w: address %v have %v want %v", ErrInsufficientFunds, st.msg.From().Hex(), have, want)
 	}
@@ -235,6 +276,22 @@ func (st *StateTransition) preCheck() error {
 				st.msg.From().Hex(), codeHash)
 		}
 	}
+	// Check the blob version validity
+	if st.msg.DataHashes() != nil {
+		if len(st.msg.DataHashes()) == 0 {
+			return ErrMissingBlobHashes
+		}
+		if st.dataGasUsed() > params.MaxDataGasPerBlock {
+			return fmt.Errorf("%w: address %v, blobs: %d", ErrDataGasLimitReached,
+				st.msg.From().Hex(), len(st.msg.DataHashes()))
+		}
+		for i, hash := range st.msg.DataHashes() {
+			if hash[0] != params.BlobTxHashVersion {
+				return fmt.Errorf("%w: blob %d version mismatch (have %d, supported %d)", ErrInvalidVersionedHash,
+					i, hash[0], params.BlobTxHashVersion)
+			}
+		}
+	}
 	// Make sure that transaction gasFeeCap is greater than the baseFee (post london)
 	if st.evm.ChainConfig().IsLondon(st.evm.Context.BlockNumber) {
 		// Skip the checks if gas fields are zero and baseFee was explicitly disabled (g_call)
@@ -259,9 +316,28 @@ func (st *StateTransition) preCheck() error {
 			}
 		}
 	}
+	// Make sure that the data gas fee cap covers the data gas fee (post cancun)
+	if st.evm.ChainConfig().IsCancun(st.evm.Context.BlockNumber) && st.dataGasUsed() > 0 {
+		// Skip the checks if gas fields are zero and baseFee was explicitly disabled (g_call)
+		if !st.evm.Config.NoBaseFee || st.msg.DataGasFeeCap().BitLen() > 0 {
+			if dataFee := misc.CalcBlobFee(st.excessDataGas()); st.msg.DataGasFeeCap().Cmp(dataFee) < 0 {
+				return fmt.Errorf("%w: address %v, maxFeePerDataGas: %v dataGasFee: %v", ErrDataFeeCapTooLow,
+					st.msg.From().Hex(), st.msg.DataGasFeeCap(), dataFee)
+			}
+		}
+	}
 	return st.buyGas()
 }
 
+// excessDataGas returns the excess data gas of the block being processed, zero
+// if the block predates EIP-4844.
+func (st *StateTransition) excessDataGas() uint64 {
+	if st.evm.Context.ExcessDataGas == nil {
+		return 0
+	}
+	return *st.evm.Context.ExcessDataGas
+}
+
 // TransitionDb will transition the state by applying the current message and
 // returning the evm execution result with following fields.
 //
@@ -282,6 +358,7 @@ func (st *StateTransition) TransitionDb() (*ExecutionResult, error) {
 	// 4. the purchased gas is enough to cover intrinsic usage
 	// 5. there is no overflow when calculating intrinsic gas
 	// 6. caller has enough balance to cover asset transfer for **topmost** call
+	// 7. the init code of a contract creation does not exceed the size limit
 
 	// Check clauses 1-3, buy gas if everything is correct
 	if err := st.preCheck(); err != nil {
@@ -303,7 +380,7 @@ func (st *StateTransition) TransitionDb() (*ExecutionResult, error) {
 	)
 
 	// Check clauses 4-5, subtract intrinsic gas if everything is correct
-	gas, err := IntrinsicGas(st.data, st.msg.AccessList(), contractCreation, rules.IsHomestead, rules.IsIstanbul)
+	gas, err := IntrinsicGas(st.data, st.msg.AccessList(), contractCreation, rules.IsHomestead, rules.IsIstanbul, rules.IsShanghai)
 	if err != nil {
 		return nil, err
 	}
@@ -317,10 +394,19 @@ func (st *StateTransition) TransitionDb() (*ExecutionResult, error) {
 		return nil, fmt.Errorf("%w: address %v", ErrInsufficientFundsForTransfer, msg.From().Hex())
 	}
 
+	// Check clause 7
+	if contractCreation && rules.IsShanghai && len(st.data) > params.MaxInitCodeSize {
+		return nil, fmt.Errorf("%w: code size %v limit %v", ErrMaxInitCodeSizeExceeded, len(st.data), params.MaxInitCodeSize)
+	}
+
 	// Set up the initial access list.
 	if rules.IsBerlin {
 		st.state.PrepareAccessList(msg.From(), msg.To(), vm.ActivePrecompiles(rules), msg.AccessList())
 	}
+	// The coinbase is warm from the start of the transaction (EIP-3651)
+	if rules.IsShanghai {
+		st.state.AddAddressToAccessList(st.evm.Context.Coinbase)
+	}
 	var (
 		ret   []byte
 		vmerr error // vm errors do not effect consensus and are therefore not assigned to err
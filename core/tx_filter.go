@@ -0,0 +1,184 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// ErrTxFiltered is returned if a transaction is rejected by one of the admission
+// filters configured for the transaction pool.
+var ErrTxFiltered = errors.New("transaction rejected by admission policy")
+
+// filteredMeter counts the transactions rejected by any admission filter. Every
+// filter also has its own meter under txpool/filtered/<name>.
+var filteredMeter = metrics.NewRegisteredMeter("txpool/filtered", nil)
+
+// TxFilter is an admission policy deciding whether a transaction may enter the
+// transaction pool. Filters are evaluated in order after the consensus validity
+// checks, the first one returning an error rejecting the transaction.
+type TxFilter interface {
+	// Name returns a short identifier of the filter, used in errors and metrics.
+	Name() string
+
+	// Filter returns an error describing the reason if the transaction, signed
+	// by the given sender, should not be admitted into the pool.
+	Filter(tx *types.Transaction, from common.Address, local bool) error
+}
+
+// SenderMinTip is a minimum priority fee required from a specific sender.
+type SenderMinTip struct {
+	Sender common.Address // Sender to enforce the minimum tip on
+	MinTip *big.Int       // Minimum priority fee (or gas price for legacy transactions)
+}
+
+// TxAdmissionConfig are the admission policies of the transaction pool on top
+// of the consensus and resource limit rules.
+type TxAdmissionConfig struct {
+	AllowSenders   []common.Address // If non-empty, only transactions from these senders are admitted
+	DenySenders    []common.Address // Senders whose transactions are always rejected
+	DenyRecipients []common.Address // Recipients (accounts or contracts) whose transactions are always rejected
+	MaxCalldata    uint64           // Maximum size of the transaction calldata in bytes (0 = unlimited)
+	SenderMinTips  []SenderMinTip   // Minimum priority fees required from specific senders
+
+	Hooks []TxFilter `toml:"-"` // Custom filters evaluated after the configured ones
+}
+
+// filters assembles the chain of admission filters described by the config.
+func (config *TxAdmissionConfig) filters() []TxFilter {
+	var filters []TxFilter
+	if len(config.AllowSenders) > 0 || len(config.DenySenders) > 0 || len(config.DenyRecipients) > 0 {
+		filters = append(filters, newAddressFilter(config.AllowSenders, config.DenySenders, config.DenyRecipients))
+	}
+	if config.MaxCalldata > 0 {
+		filters = append(filters, &calldataFilter{limit: config.MaxCalldata})
+	}
+	if len(config.SenderMinTips) > 0 {
+		filters = append(filters, newSenderTipFilter(config.SenderMinTips))
+	}
+	return append(filters, config.Hooks...)
+}
+
+// txFilterHook is a custom admission filter backed by a plain function.
+type txFilterHook struct {
+	name string
+	fn   func(tx *types.Transaction, from common.Address, local bool) error
+}
+
+// NewTxFilterHook creates an admission filter from a plain function, to be used
+// as a custom hook in the transaction pool's admission config.
+func NewTxFilterHook(name string, fn func(tx *types.Transaction, from common.Address, local bool) error) TxFilter {
+	return &txFilterHook{name: name, fn: fn}
+}
+
+func (h *txFilterHook) Name() string { return h.name }
+
+func (h *txFilterHook) Filter(tx *types.Transaction, from common.Address, local bool) error {
+	return h.fn(tx, from, local)
+}
+
+// addressFilter rejects transactions based on sender and recipient lists.
+type addressFilter struct {
+	allow map[common.Address]struct{}
+	deny  map[common.Address]struct{}
+	dests map[common.Address]struct{}
+}
+
+func newAddressFilter(allow, deny, dests []common.Address) *addressFilter {
+	set := func(addrs []common.Address) map[common.Address]struct{} {
+		s := make(map[common.Address]struct{}, len(addrs))
+		for _, addr := range addrs {
+			s[addr] = struct{}{}
+		}
+		return s
+	}
+	return &addressFilter{allow: set(allow), deny: set(deny), dests: set(dests)}
+}
+
+func (f *addressFilter) Name() string { return "address" }
+
+func (f *addressFilter) Filter(tx *types.Transaction, from common.Address, local bool) error {
+	if _, ok := f.allow[from]; len(f.allow) > 0 && !ok {
+		return fmt.Errorf("sender %v not allowed", from)
+	}
+	if _, ok := f.deny[from]; ok {
+		return fmt.Errorf("sender %v denied", from)
+	}
+	if to := tx.To(); to != nil {
+		if _, ok := f.dests[*to]; ok {
+			return fmt.Errorf("recipient %v denied", *to)
+		}
+	}
+	return nil
+}
+
+// calldataFilter rejects transactions with calldata above a size limit.
+type calldataFilter struct {
+	limit uint64
+}
+
+func (f *calldataFilter) Name() string { return "calldata" }
+
+func (f *calldataFilter) Filter(tx *types.Transaction, from common.Address, local bool) error {
+	if size := uint64(len(tx.Data())); size > f.limit {
+		return fmt.Errorf("calldata size %d above limit %d", size, f.limit)
+	}
+	return nil
+}
+
+// senderTipFilter rejects transactions of specific senders below a minimum tip.
+type senderTipFilter struct {
+	tips map[common.Address]*big.Int
+}
+
+func newSenderTipFilter(tips []SenderMinTip) *senderTipFilter {
+	f := &senderTipFilter{tips: make(map[common.Address]*big.Int, len(tips))}
+	for _, tip := range tips {
+		if tip.MinTip != nil {
+			f.tips[tip.Sender] = tip.MinTip
+		}
+	}
+	return f
+}
+
+func (f *senderTipFilter) Name() string { return "sendertip" }
+
+func (f *senderTipFilter) Filter(tx *types.Transaction, from common.Address, local bool) error {
+	if tip, ok := f.tips[from]; ok && tx.GasTipCapIntCmp(tip) < 0 {
+		return fmt.Errorf("tip %v below sender minimum %v", tx.GasTipCap(), tip)
+	}
+	return nil
+}
+
+// filterTx runs a transaction through the given admission filters, returning
+// the first rejection wrapped into ErrTxFiltered.
+func filterTx(filters []TxFilter, tx *types.Transaction, from common.Address, local bool) error {
+	for _, filter := range filters {
+		if err := filter.Filter(tx, from, local); err != nil {
+			filteredMeter.Mark(1)
+			metrics.GetOrRegisterMeter("txpool/filtered/"+filter.Name(), nil).Mark(1)
+			return fmt.Errorf("%w: %s: %v", ErrTxFiltered, filter.Name(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// TxLifecycle is the kind of a transaction pool lifecycle event.
+type TxLifecycle string
+
+const (
+	TxLifecycleAdded    TxLifecycle = "added"    // Transaction accepted into the pool
+	TxLifecyclePromoted TxLifecycle = "promoted" // Transaction moved from the queue to pending
+	TxLifecycleReplaced TxLifecycle = "replaced" // Transaction replaced by another with the same nonce
+	TxLifecycleDropped  TxLifecycle = "dropped"  // Transaction removed without being included
+	TxLifecycleIncluded TxLifecycle = "included" // Transaction included in the chain
+)
+
+// Reasons for which the pool drops transactions.
+const (
+	TxDropUnderpriced        = "underpriced"         // Evicted by better priced transactions or a raised price limit
+	TxDropReplaceUnderpriced = "replace-underpriced" // Lost against an already pending transaction with the same nonce
+	TxDropNonceTooLow        = "nonce-too-low"       // Nonce used by a transaction included in the chain
+	TxDropUnpayable          = "unpayable"           // Sender balance or block gas limit too low
+	TxDropLifetime           = "lifetime"            // Queued for longer than the configured lifetime
+	TxDropQueueLimit         = "queue-limit"         // Evicted to respect the queue limits
+	TxDropPendingLimit       = "pending-limit"       // Evicted to respect the pending limits
+	TxDropPrivateExpired     = "private-expired"     // Private transaction not included within its lifetime
+)
+
+// TxHistoryEvent is a single lifecycle event of a transaction in the pool.
+type TxHistoryEvent struct {
+	Hash        common.Hash // Hash of the transaction the event belongs to
+	Kind        TxLifecycle // Kind of the lifecycle event
+	Reason      string      // Reason of the drop, only set for dropped transactions
+	ReplacedBy  common.Hash // Hash of the replacement, only set for replaced transactions
+	BlockNumber uint64      // Number of the including block, only set for included transactions
+	Time        time.Time   // Time the event was recorded at
+}
+
+// txHistory is a bounded ring buffer of transaction lifecycle events. Once full,
+// the oldest events are overwritten.
+type txHistory struct {
+	events []TxHistoryEvent
+	next   int  // Index to write the next event at
+	full   bool // Whether the buffer has wrapped around
+	lock   sync.RWMutex
+}
+
+// newTxHistory creates a lifecycle event ring buffer of the given size.
+func newTxHistory(size uint64) *txHistory {
+	return &txHistory{
+		events: make([]TxHistoryEvent, size),
+	}
+}
+
+// record inserts a new event into the history, evicting the oldest one if full.
+func (h *txHistory) record(event TxHistoryEvent) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.events[h.next] = event
+	if h.next++; h.next == len(h.events) {
+		h.next, h.full = 0, true
+	}
+}
+
+// get retrieves all the retained events of a transaction, oldest first.
+func (h *txHistory) get(hash common.Hash) []TxHistoryEvent {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	var (
+		events []TxHistoryEvent
+		start  = 0
+		count  = h.next
+	)
+	if h.full {
+		start, count = h.next, len(h.events)
+	}
+	for i := 0; i < count; i++ {
+		if event := h.events[(start+i)%len(h.events)]; event.Hash == hash {
+			events = append(events, event)
+		}
+	}
+	return events
+}
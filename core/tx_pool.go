@@ -18,6 +18,7 @@ package core
 
 import (
 	"errors"
+	"fmt"
 	"math"
 	"math/big"
 	"sort"
@@ -51,6 +52,12 @@ const (
 	// more expensive to propagate; larger transactions also take more resources
 	// to validate whether they fit into the pool or not.
 	txMaxSize = 4 * txSlotSize // 128KB
+
+	// txMaxBlobSize is the maximum size the blob sidecar of a single transaction
+	// can have. It is enforced separately from txMaxSize, which only covers the
+	// transaction without its blobs. Each blob is allowed its data plus some
+	// headroom for the commitment, proof and encoding overhead.
+	txMaxBlobSize = params.BlobTxMaxBlobsPerTx * (params.BlobTxFieldElementsPerBlob*params.BlobTxBytesPerFieldElement + 1024)
 )
 
 var (
@@ -106,6 +113,9 @@ var (
 	queuedNofundsMeter   = metrics.NewRegisteredMeter("txpool/queued/nofunds", nil)   // Dropped due to out-of-funds
 	queuedEvictionMeter  = metrics.NewRegisteredMeter("txpool/queued/eviction", nil)  // Dropped due to lifetime
 
+	// Metrics for private transactions
+	privateExpiredMeter = metrics.NewRegisteredMeter("txpool/private/expired", nil) // Dropped due to block lifetime
+
 	// General tx metrics
 	knownTxMeter       = metrics.NewRegisteredMeter("txpool/known", nil)
 	validTxMeter       = metrics.NewRegisteredMeter("txpool/valid", nil)
@@ -125,6 +135,7 @@ var (
 	queuedGauge  = metrics.NewRegisteredGauge("txpool/queued", nil)
 	localGauge   = metrics.NewRegisteredGauge("txpool/local", nil)
 	slotsGauge   = metrics.NewRegisteredGauge("txpool/slots", nil)
+	blobsGauge   = metrics.NewRegisteredGauge("txpool/blobs", nil)
 
 	reheapTimer = metrics.NewRegisteredTimer("txpool/reheap", nil)
 )
@@ -156,6 +167,9 @@ type TxPoolConfig struct {
 	Journal   string           // Journal of local transactions to survive node restarts
 	Rejournal time.Duration    // Time interval to regenerate the local transaction journal
 
+	PoolJournal    string // Journal of all pooled transactions (remotes included) to survive node restarts
+	PoolJournalCap uint64 // Maximum size in bytes of the full pool journal
+
 	PriceLimit uint64 // Minimum gas price to enforce for acceptance into the pool
 	PriceBump  uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
 
@@ -163,8 +177,14 @@ type TxPoolConfig struct {
 	GlobalSlots  uint64 // Maximum number of executable transaction slots for all accounts
 	AccountQueue uint64 // Maximum number of non-executable transaction slots permitted per account
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
+	GlobalBlobs  uint64 // Maximum total size in bytes of blob sidecars for all accounts
 
 	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
+
+	PrivateLifetime uint64 // Number of blocks after which unincluded private transactions are dropped
+	HistorySize     uint64 // Number of transaction lifecycle events to retain
+
+	Admission TxAdmissionConfig // Admission policies on top of the consensus rules
 }
 
 // DefaultTxPoolConfig contains the default configurations for the transaction
@@ -173,6 +193,8 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	Journal:   "transactions.rlp",
 	Rejournal: time.Hour,
 
+	PoolJournalCap: 64 * 1024 * 1024,
+
 	PriceLimit: 1,
 	PriceBump:  10,
 
@@ -180,8 +202,12 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	GlobalSlots:  4096 + 1024, // urgent + floating queue capacity with 4:1 ratio
 	AccountQueue: 64,
 	GlobalQueue:  1024,
+	GlobalBlobs:  256 * 1024 * 1024,
 
 	Lifetime: 3 * time.Hour,
+
+	PrivateLifetime: 25,
+	HistorySize:     16384,
 }
 
 // sanitize checks the provided user configurations and changes anything that's
@@ -192,6 +218,10 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 		log.Warn("Sanitizing invalid txpool journal time", "provided", conf.Rejournal, "updated", time.Second)
 		conf.Rejournal = time.Second
 	}
+	if conf.PoolJournal != "" && conf.PoolJournalCap < 1 {
+		log.Warn("Sanitizing invalid txpool pool journal cap", "provided", conf.PoolJournalCap, "updated", DefaultTxPoolConfig.PoolJournalCap)
+		conf.PoolJournalCap = DefaultTxPoolConfig.PoolJournalCap
+	}
 	if conf.PriceLimit < 1 {
 		log.Warn("Sanitizing invalid txpool price limit", "provided", conf.PriceLimit, "updated", DefaultTxPoolConfig.PriceLimit)
 		conf.PriceLimit = DefaultTxPoolConfig.PriceLimit
@@ -216,10 +246,22 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 		log.Warn("Sanitizing invalid txpool global queue", "provided", conf.GlobalQueue, "updated", DefaultTxPoolConfig.GlobalQueue)
 		conf.GlobalQueue = DefaultTxPoolConfig.GlobalQueue
 	}
+	if conf.GlobalBlobs < txMaxBlobSize {
+		log.Warn("Sanitizing invalid txpool global blobs", "provided", conf.GlobalBlobs, "updated", DefaultTxPoolConfig.GlobalBlobs)
+		conf.GlobalBlobs = DefaultTxPoolConfig.GlobalBlobs
+	}
 	if conf.Lifetime < 1 {
 		log.Warn("Sanitizing invalid txpool lifetime", "provided", conf.Lifetime, "updated", DefaultTxPoolConfig.Lifetime)
 		conf.Lifetime = DefaultTxPoolConfig.Lifetime
 	}
+	if conf.PrivateLifetime < 1 {
+		log.Warn("Sanitizing invalid txpool private lifetime", "provided", conf.PrivateLifetime, "updated", DefaultTxPoolConfig.PrivateLifetime)
+		conf.PrivateLifetime = DefaultTxPoolConfig.PrivateLifetime
+	}
+	if conf.HistorySize < 1 {
+		log.Warn("Sanitizing invalid txpool history size", "provided", conf.HistorySize, "updated", DefaultTxPoolConfig.HistorySize)
+		conf.HistorySize = DefaultTxPoolConfig.HistorySize
+	}
 	return conf
 }
 
@@ -236,6 +278,7 @@ type TxPool struct {
 	chain       blockChain
 	gasPrice    *big.Int
 	txFeed      event.Feed
+	dropFeed    event.Feed
 	scope       event.SubscriptionScope
 	signer      types.Signer
 	mu          sync.RWMutex
@@ -243,7 +286,10 @@ type TxPool struct {
 	istanbul bool // Fork indicator whether we are in the istanbul stage.
 	eip2718  bool // Fork indicator whether we are using EIP-2718 type transactions.
 	eip1559  bool // Fork indicator whether we are using EIP-1559 type transactions.
+	shanghai bool // Fork indicator whether we are in the Shanghai stage.
+	cancun   bool // Fork indicator whether we are in the Cancun stage.
 
+	currentHead   *types.Header  // Current head of the blockchain
 	currentState  *state.StateDB // Current state in the blockchain head
 	pendingNonces *txNoncer      // Pending state tracking virtual nonces
 	currentMaxGas uint64         // Current gas limit for transaction caps
@@ -251,11 +297,19 @@ type TxPool struct {
 	locals  *accountSet // Set of local transaction to exempt from eviction rules
 	journal *txJournal  // Journal of local transaction to back up to disk
 
+	poolJournal *txPoolJournal // Journal of all pooled transactions to back up to disk
+
 	pending map[common.Address]*txList   // All currently processable transactions
 	queue   map[common.Address]*txList   // Queued but non-processable transactions
 	beats   map[common.Address]time.Time // Last heartbeat from each known account
 	all     *txLookup                    // All transactions to allow lookups
 	priced  *txPricedList                // All transactions sorted by price
+	filters []TxFilter                   // Admission filters to run transactions through
+	private map[common.Hash]uint64       // Private transactions mapped to the block number they expire at
+
+	history  *txHistory             // Ring buffer of recent transaction lifecycle events
+	drops    []TxHistoryEvent       // Drop events recorded since the last feed notification
+	included map[common.Hash]uint64 // Transactions included by the last reset, mapped to their block
 
 	chainHeadCh     chan ChainHeadEvent
 	chainHeadSub    event.Subscription
@@ -290,6 +344,7 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		queue:           make(map[common.Address]*txList),
 		beats:           make(map[common.Address]time.Time),
 		all:             newTxLookup(),
+		private:         make(map[common.Hash]uint64),
 		chainHeadCh:     make(chan ChainHeadEvent, chainHeadChanSize),
 		reqResetCh:      make(chan *txpoolResetRequest),
 		reqPromoteCh:    make(chan *accountSet),
@@ -298,6 +353,8 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		reorgShutdownCh: make(chan struct{}),
 		initDoneCh:      make(chan struct{}),
 		gasPrice:        new(big.Int).SetUint64(config.PriceLimit),
+		filters:         config.Admission.filters(),
+		history:         newTxHistory(config.HistorySize),
 	}
 	pool.locals = newAccountSet(pool.signer)
 	for _, addr := range config.Locals {
@@ -322,6 +379,17 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 			log.Warn("Failed to rotate transaction journal", "err", err)
 		}
 	}
+	// If full pool journaling is enabled, load from disk, revalidating everything
+	if config.PoolJournal != "" {
+		pool.poolJournal = newTxPoolJournal(config.PoolJournal, config.PoolJournalCap)
+
+		add := func(txs []*types.Transaction, local bool) []error {
+			return pool.Add(txs, local, true)
+		}
+		if err := pool.poolJournal.load(add); err != nil {
+			log.Warn("Failed to load transaction pool journal", "err", err)
+		}
+	}
 
 	// Subscribe events from blockchain and start the main event loop.
 	pool.chainHeadSub = pool.chain.SubscribeChainHeadEvent(pool.chainHeadCh)
@@ -391,11 +459,13 @@ func (pool *TxPool) loop() {
 					list := pool.queue[addr].Flatten()
 					for _, tx := range list {
 						pool.removeTx(tx.Hash(), true)
+						pool.recordDrop(tx.Hash(), TxDropLifetime)
 					}
 					queuedEvictionMeter.Mark(int64(len(list)))
 				}
 			}
 			pool.mu.Unlock()
+			pool.sendDrops()
 
 		// Handle local transaction journal rotation
 		case <-journal.C:
@@ -406,6 +476,9 @@ func (pool *TxPool) loop() {
 				}
 				pool.mu.Unlock()
 			}
+			if pool.poolJournal != nil {
+				pool.writePoolJournal()
+			}
 		}
 	}
 }
@@ -422,15 +495,113 @@ func (pool *TxPool) Stop() {
 	if pool.journal != nil {
 		pool.journal.close()
 	}
+	if pool.poolJournal != nil {
+		pool.writePoolJournal()
+	}
 	log.Info("Transaction pool stopped")
 }
 
+// writePoolJournal dumps the entire content of the pool into the full pool
+// journal. Executable transactions are written first, so that they are the ones
+// retained if the journal size cap is reached.
+func (pool *TxPool) writePoolJournal() {
+	pool.mu.RLock()
+	var txs []*types.Transaction
+	for _, list := range pool.pending {
+		txs = append(txs, pool.public(list.Flatten())...)
+	}
+	for _, list := range pool.queue {
+		txs = append(txs, pool.public(list.Flatten())...)
+	}
+	pool.mu.RUnlock()
+
+	isLocal := func(hash common.Hash) bool {
+		return pool.all.GetLocal(hash) != nil
+	}
+	if err := pool.poolJournal.write(txs, isLocal); err != nil {
+		log.Warn("Failed to write transaction pool journal", "err", err)
+	}
+}
+
 // SubscribeNewTxsEvent registers a subscription of NewTxsEvent and
 // starts sending event to the given channel.
 func (pool *TxPool) SubscribeNewTxsEvent(ch chan<- NewTxsEvent) event.Subscription {
 	return pool.scope.Track(pool.txFeed.Subscribe(ch))
 }
 
+// SubscribeDropTxsEvent registers a subscription of DropTxsEvent and starts
+// sending event to the given channel.
+func (pool *TxPool) SubscribeDropTxsEvent(ch chan<- DropTxsEvent) event.Subscription {
+	return pool.scope.Track(pool.dropFeed.Subscribe(ch))
+}
+
+// History retrieves the retained lifecycle events of a transaction, oldest first.
+func (pool *TxPool) History(hash common.Hash) []TxHistoryEvent {
+	return pool.history.get(hash)
+}
+
+// record inserts a lifecycle event of a transaction into the pool history.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) record(hash common.Hash, kind TxLifecycle) {
+	pool.history.record(TxHistoryEvent{Hash: hash, Kind: kind, Time: time.Now()})
+}
+
+// recordReplace inserts a replacement event of a transaction into the pool history.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) recordReplace(hash common.Hash, by common.Hash) {
+	pool.history.record(TxHistoryEvent{Hash: hash, Kind: TxLifecycleReplaced, ReplacedBy: by, Time: time.Now()})
+}
+
+// recordDrop inserts a drop event of a transaction into the pool history and
+// schedules it for the drop event feed.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) recordDrop(hash common.Hash, reason string) {
+	event := TxHistoryEvent{Hash: hash, Kind: TxLifecycleDropped, Reason: reason, Time: time.Now()}
+	pool.history.record(event)
+	pool.drops = append(pool.drops, event)
+}
+
+// recordStale inserts the event of a transaction removed due to its nonce being
+// used on chain, which is an inclusion if it was seen in the last reset.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) recordStale(hash common.Hash) {
+	if number, ok := pool.included[hash]; ok {
+		pool.history.record(TxHistoryEvent{Hash: hash, Kind: TxLifecycleIncluded, BlockNumber: number, Time: time.Now()})
+		return
+	}
+	pool.recordDrop(hash, TxDropNonceTooLow)
+}
+
+// markIncluded tracks the transactions of a block that was added to the chain,
+// to report them as included instead of dropped when removed from the pool.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) markIncluded(block *types.Block) {
+	if pool.included == nil {
+		pool.included = make(map[common.Hash]uint64)
+	}
+	for _, tx := range block.Transactions() {
+		pool.included[tx.Hash()] = block.NumberU64()
+	}
+}
+
+// sendDrops notifies the subscribers of the drop events recorded since the last
+// notification.
+func (pool *TxPool) sendDrops() {
+	pool.mu.Lock()
+	drops := pool.drops
+	pool.drops = nil
+	pool.mu.Unlock()
+
+	if len(drops) > 0 {
+		pool.dropFeed.Send(DropTxsEvent{Events: drops})
+	}
+}
+
 // GasPrice returns the current gas price enforced by the transaction pool.
 func (pool *TxPool) GasPrice() *big.Int {
 	pool.mu.RLock()
@@ -443,6 +614,8 @@ func (pool *TxPool) GasPrice() *big.Int {
 // SetGasPrice updates the minimum price required by the transaction pool for a
 // new transaction, and drops all transactions below this threshold.
 func (pool *TxPool) SetGasPrice(price *big.Int) {
+	defer pool.sendDrops()
+
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
@@ -454,6 +627,7 @@ func (pool *TxPool) SetGasPrice(price *big.Int) {
 		drop := pool.all.RemotesBelowTip(price)
 		for _, tx := range drop {
 			pool.removeTx(tx.Hash(), false)
+			pool.recordDrop(tx.Hash(), TxDropUnderpriced)
 		}
 		pool.priced.Removed(len(drop))
 	}
@@ -566,25 +740,90 @@ func (pool *TxPool) Locals() []common.Address {
 	return pool.locals.flatten()
 }
 
-// local retrieves all currently known local transactions, grouped by origin
-// account and sorted by nonce. The returned transaction set is a copy and can be
-// freely modified by calling code.
+// local retrieves all currently known local public transactions, grouped by
+// origin account and sorted by nonce. The returned transaction set is a copy and
+// can be freely modified by calling code.
 func (pool *TxPool) local() map[common.Address]types.Transactions {
 	txs := make(map[common.Address]types.Transactions)
 	for addr := range pool.locals.accounts {
 		if pending := pool.pending[addr]; pending != nil {
-			txs[addr] = append(txs[addr], pending.Flatten()...)
+			txs[addr] = append(txs[addr], pool.public(pending.Flatten())...)
 		}
 		if queued := pool.queue[addr]; queued != nil {
-			txs[addr] = append(txs[addr], queued.Flatten()...)
+			txs[addr] = append(txs[addr], pool.public(queued.Flatten())...)
 		}
 	}
 	return txs
 }
 
-// validateTx checks whether a transaction is valid according to the consensus
-// rules and adheres to some heuristic limits of the local node (price and size).
-func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
+// public filters out the private transactions from the given list, in place.
+func (pool *TxPool) public(txs types.Transactions) types.Transactions {
+	if len(pool.private) == 0 {
+		return txs
+	}
+	filtered := txs[:0]
+	for _, tx := range txs {
+		if _, ok := pool.private[tx.Hash()]; !ok {
+			filtered = append(filtered, tx)
+		}
+	}
+	return filtered
+}
+
+// expirePrivate forgets about private transactions that reached the end of
+// their lifetime, dropping the ones still not included.
+//
+// The private marker is retained until expiry even if the transaction leaves
+// the pool, since a mined transaction may be reorged back in and must not be
+// propagated to the network then either.
+func (pool *TxPool) expirePrivate() {
+	head := pool.currentHead.Number.Uint64()
+	for hash, expiry := range pool.private {
+		if head < expiry {
+			continue
+		}
+		if pool.all.Get(hash) != nil {
+			log.Debug("Dropping expired private transaction", "hash", hash, "expiry", expiry)
+			pool.removeTx(hash, true)
+			pool.recordDrop(hash, TxDropPrivateExpired)
+			privateExpiredMeter.Mark(1)
+		}
+		delete(pool.private, hash)
+	}
+}
+
+// validateBlobTx checks the blob specific fields of a blob transaction: the
+// versioned hashes must be present, within the per-block limit and backed by
+// a sidecar whose blobs match them.
+func validateBlobTx(tx *types.Transaction) error {
+	hashes := tx.BlobHashes()
+	if len(hashes) == 0 {
+		return ErrMissingBlobHashes
+	}
+	if len(hashes) > params.BlobTxMaxBlobsPerTx {
+		return fmt.Errorf("too many blobs in transaction: have %d, permitted %d", len(hashes), params.BlobTxMaxBlobsPerTx)
+	}
+	sidecar := tx.BlobTxSidecar()
+	if sidecar == nil {
+		return errors.New("missing blob sidecar in transaction")
+	}
+	return sidecar.ValidateBlobs(hashes)
+}
+
+// ValidateTxBasics checks whether a transaction is valid according to the
+// consensus rules and the size limits of the pool, without considering the
+// current state of its sender. It's used to screen transactions which are not
+// admitted into the pool itself, such as the ones of a bundle.
+func (pool *TxPool) ValidateTxBasics(tx *types.Transaction) error {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	return pool.validateTxBasics(tx)
+}
+
+// validateTxBasics checks whether a transaction is valid according to the
+// consensus rules and the size limits of the pool, irrelevant of the state.
+func (pool *TxPool) validateTxBasics(tx *types.Transaction) error {
 	// Accept only legacy transactions until EIP-2718/2930 activates.
 	if !pool.eip2718 && tx.Type() != types.LegacyTxType {
 		return ErrTxTypeNotSupported
@@ -593,10 +832,27 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if !pool.eip1559 && tx.Type() == types.DynamicFeeTxType {
 		return ErrTxTypeNotSupported
 	}
-	// Reject transactions over defined size to prevent DOS attacks
-	if uint64(tx.Size()) > txMaxSize {
+	// Reject blob transactions until EIP-4844 activates.
+	if !pool.cancun && tx.Type() == types.BlobTxType {
+		return ErrTxTypeNotSupported
+	}
+	// Reject transactions over defined size to prevent DOS attacks. The blobs
+	// of a blob transaction are limited on their own.
+	if uint64(tx.WithoutBlobTxSidecar().Size()) > txMaxSize {
 		return ErrOversizedData
 	}
+	if blobSize(tx) > txMaxBlobSize {
+		return ErrOversizedData
+	}
+	if tx.Type() == types.BlobTxType {
+		if err := validateBlobTx(tx); err != nil {
+			return err
+		}
+	}
+	// Check whether the init code size has been exceeded.
+	if pool.shanghai && tx.To() == nil && len(tx.Data()) > params.MaxInitCodeSize {
+		return fmt.Errorf("%w: code size %v limit %v", ErrMaxInitCodeSizeExceeded, len(tx.Data()), params.MaxInitCodeSize)
+	}
 	// Transactions can't be negative. This may never happen using RLP decoded
 	// transactions but may occur if you create a transaction using the RPC.
 	if tx.Value().Sign() < 0 {
@@ -618,10 +874,32 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 		return ErrTipAboveFeeCap
 	}
 	// Make sure the transaction is signed properly.
-	from, err := types.Sender(pool.signer, tx)
-	if err != nil {
+	if _, err := types.Sender(pool.signer, tx); err != nil {
 		return ErrInvalidSender
 	}
+	// Ensure the transaction has more gas than the basic tx fee.
+	intrGas, err := IntrinsicGas(tx.Data(), tx.AccessList(), tx.To() == nil, true, pool.istanbul, pool.shanghai)
+	if err != nil {
+		return err
+	}
+	if tx.Gas() < intrGas {
+		return ErrIntrinsicGas
+	}
+	return nil
+}
+
+// validateTx checks whether a transaction is valid according to the consensus
+// rules and adheres to some heuristic limits of the local node (price and size).
+func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
+	if err := pool.validateTxBasics(tx); err != nil {
+		return err
+	}
+	from, _ := types.Sender(pool.signer, tx) // already validated
+
+	// Run the transaction through the configured admission policies
+	if err := filterTx(pool.filters, tx, from, local); err != nil {
+		return err
+	}
 	// Drop non-local transactions under our own minimal accepted gas price or tip
 	if !local && tx.GasTipCapIntCmp(pool.gasPrice) < 0 {
 		return ErrUnderpriced
@@ -635,14 +913,6 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if pool.currentState.GetBalance(from).Cmp(tx.Cost()) < 0 {
 		return ErrInsufficientFunds
 	}
-	// Ensure the transaction has more gas than the basic tx fee.
-	intrGas, err := IntrinsicGas(tx.Data(), tx.AccessList(), tx.To() == nil, true, pool.istanbul)
-	if err != nil {
-		return err
-	}
-	if tx.Gas() < intrGas {
-		return ErrIntrinsicGas
-	}
 	return nil
 }
 
@@ -706,10 +976,24 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 			log.Trace("Discarding freshly underpriced transaction", "hash", tx.Hash(), "gasTipCap", tx.GasTipCap(), "gasFeeCap", tx.GasFeeCap())
 			underpricedTxMeter.Mark(1)
 			pool.removeTx(tx.Hash(), false)
+			pool.recordDrop(tx.Hash(), TxDropUnderpriced)
 		}
 	}
-	// Try to replace an existing transaction in the pending pool
+	// If the blob space of the pool is exhausted, discard the blob transaction.
+	// Blobs dwarf the rest of the pool, so they are capped on their own. A
+	// replacement only needs room beyond the blobs of the transaction it evicts.
 	from, _ := types.Sender(pool.signer, tx) // already validated
+	if size := blobSize(tx); size > 0 {
+		if old := pool.overlapping(from, tx.Nonce()); old != nil {
+			size -= blobSize(old)
+		}
+		if uint64(pool.all.Blobs()+size) > pool.config.GlobalBlobs {
+			log.Trace("Discarding blob transaction over pool capacity", "hash", hash, "size", size)
+			overflowedTxMeter.Mark(1)
+			return false, ErrTxPoolOverflow
+		}
+	}
+	// Try to replace an existing transaction in the pending pool
 	if list := pool.pending[from]; list != nil && list.Overlaps(tx) {
 		// Nonce already pending, check if required price bump is met
 		inserted, old := list.Add(tx, pool.config.PriceBump)
@@ -722,9 +1006,11 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 			pool.all.Remove(old.Hash())
 			pool.priced.Removed(1)
 			pendingReplaceMeter.Mark(1)
+			pool.recordReplace(old.Hash(), hash)
 		}
 		pool.all.Add(tx, isLocal)
 		pool.priced.Put(tx, isLocal)
+		pool.record(hash, TxLifecycleAdded)
 		pool.journalTx(from, tx)
 		pool.queueTxEvent(tx)
 		log.Trace("Pooled new executable transaction", "hash", hash, "from", from, "to", tx.To())
@@ -747,12 +1033,29 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 	if isLocal {
 		localGauge.Inc(1)
 	}
+	pool.record(hash, TxLifecycleAdded)
 	pool.journalTx(from, tx)
 
 	log.Trace("Pooled new future transaction", "hash", hash, "from", from, "to", tx.To())
 	return replaced, nil
 }
 
+// overlapping returns the pending or queued transaction of an account with the
+// given nonce, which a new transaction with the same nonce would replace.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) overlapping(from common.Address, nonce uint64) *types.Transaction {
+	if list := pool.pending[from]; list != nil {
+		if tx := list.txs.Get(nonce); tx != nil {
+			return tx
+		}
+	}
+	if list := pool.queue[from]; list != nil {
+		return list.txs.Get(nonce)
+	}
+	return nil
+}
+
 // enqueueTx inserts a new transaction into the non-executable transaction queue.
 //
 // Note, this method assumes the pool lock is held!
@@ -773,6 +1076,7 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction, local boo
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed(1)
 		queuedReplaceMeter.Mark(1)
+		pool.recordReplace(old.Hash(), hash)
 	} else {
 		// Nothing was replaced, bump the queued counter
 		queuedGauge.Inc(1)
@@ -796,10 +1100,13 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction, local boo
 // journalTx adds the specified transaction to the local disk journal if it is
 // deemed to have been sent from a local account.
 func (pool *TxPool) journalTx(from common.Address, tx *types.Transaction) {
-	// Only journal if it's enabled and the transaction is local
+	// Only journal if it's enabled and the transaction is local and public
 	if pool.journal == nil || !pool.locals.contains(from) {
 		return
 	}
+	if _, ok := pool.private[tx.Hash()]; ok {
+		return
+	}
 	if err := pool.journal.insert(tx); err != nil {
 		log.Warn("Failed to journal local transaction", "err", err)
 	}
@@ -822,6 +1129,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		pool.all.Remove(hash)
 		pool.priced.Removed(1)
 		pendingDiscardMeter.Mark(1)
+		pool.recordDrop(hash, TxDropReplaceUnderpriced)
 		return false
 	}
 	// Otherwise discard any previous transaction and mark this
@@ -829,18 +1137,90 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed(1)
 		pendingReplaceMeter.Mark(1)
+		pool.recordReplace(old.Hash(), hash)
 	} else {
 		// Nothing was replaced, bump the pending counter
 		pendingGauge.Inc(1)
 	}
 	// Set the potentially new pending nonce and notify any subsystems of the new tx
 	pool.pendingNonces.set(addr, tx.Nonce()+1)
+	pool.record(hash, TxLifecyclePromoted)
 
 	// Successful promotion, bump the heartbeat
 	pool.beats[addr] = time.Now()
 	return true
 }
 
+// Filter returns whether the given transaction can be consumed by the pool,
+// which is the case for every transaction type it knows how to validate.
+func (pool *TxPool) Filter(tx *types.Transaction) bool {
+	switch tx.Type() {
+	case types.LegacyTxType, types.AccessListTxType, types.DynamicFeeTxType, types.BlobTxType:
+		return true
+	default:
+		return false
+	}
+}
+
+// Add enqueues a batch of transactions into the pool if they are valid. Local
+// transactions are only treated as such if local tracking is enabled. This is
+// the generic entrypoint used when the pool is run as a subpool.
+func (pool *TxPool) Add(txs []*types.Transaction, local bool, sync bool) []error {
+	return pool.addTxs(txs, local && !pool.config.NoLocals, sync)
+}
+
+// AddPrivate enqueues a single local transaction into the pool if it is valid,
+// marking it private. Private transactions are never journaled, nor propagated
+// to the network, and are dropped if not included within the configured number
+// of blocks.
+func (pool *TxPool) AddPrivate(tx *types.Transaction) error {
+	// Cache the sender before obtaining the lock, same as for public transactions
+	if _, err := types.Sender(pool.signer, tx); err != nil {
+		invalidTxMeter.Mark(1)
+		return ErrInvalidSender
+	}
+	hash := tx.Hash()
+
+	// Mark the transaction private and insert it within a single critical section,
+	// so it's never announced and neither a public copy nor a concurrent private
+	// submission can slip in between the known check and the insertion.
+	pool.mu.Lock()
+	if pool.all.Get(hash) != nil {
+		pool.mu.Unlock()
+		knownTxMeter.Mark(1)
+		return ErrAlreadyKnown
+	}
+	expiry, known := pool.private[hash]
+	pool.private[hash] = pool.currentHead.Number.Uint64() + pool.config.PrivateLifetime
+
+	errs, dirty := pool.addTxsLocked([]*types.Transaction{tx}, !pool.config.NoLocals)
+	if errs[0] != nil {
+		// Restore the marker of a previously included private transaction
+		if known {
+			pool.private[hash] = expiry
+		} else {
+			delete(pool.private, hash)
+		}
+	}
+	pool.mu.Unlock()
+
+	if errs[0] != nil {
+		return errs[0]
+	}
+	<-pool.requestPromoteExecutables(dirty)
+	return nil
+}
+
+// IsPrivate returns whether the transaction with the given hash was submitted
+// privately and must not be propagated to the network.
+func (pool *TxPool) IsPrivate(hash common.Hash) bool {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	_, ok := pool.private[hash]
+	return ok
+}
+
 // AddLocals enqueues a batch of transactions into the pool if they are valid, marking the
 // senders as a local ones, ensuring they go around the local pricing constraints.
 //
@@ -1158,6 +1538,9 @@ func (pool *TxPool) runReorg(done chan struct{}, reset *txpoolResetRequest, dirt
 		// Reset from the old head to the new, rescheduling any reorged transactions
 		pool.reset(reset.oldHead, reset.newHead)
 
+		// Drop any private transactions that were not included in time
+		pool.expirePrivate()
+
 		// Nonces were reset, discard any events that became stale
 		for addr := range events {
 			events[addr].Forward(pool.pendingNonces.get(addr))
@@ -1197,8 +1580,12 @@ func (pool *TxPool) runReorg(done chan struct{}, reset *txpoolResetRequest, dirt
 
 	dropBetweenReorgHistogram.Update(int64(pool.changesSinceReorg))
 	pool.changesSinceReorg = 0 // Reset change counter
+	pool.included = nil        // Inclusions were all reported by now
 	pool.mu.Unlock()
 
+	// Notify subsystems for dropped transactions
+	pool.sendDrops()
+
 	// Notify subsystems for newly added transactions
 	for _, tx := range promoted {
 		addr, _ := types.Sender(pool.signer, tx)
@@ -1261,6 +1648,7 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 				}
 				for add.NumberU64() > rem.NumberU64() {
 					included = append(included, add.Transactions()...)
+					pool.markIncluded(add)
 					if add = pool.chain.GetBlock(add.ParentHash(), add.NumberU64()-1); add == nil {
 						log.Error("Unrooted new chain seen by tx pool", "block", newHead.Number, "hash", newHead.Hash())
 						return
@@ -1273,6 +1661,7 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 						return
 					}
 					included = append(included, add.Transactions()...)
+					pool.markIncluded(add)
 					if add = pool.chain.GetBlock(add.ParentHash(), add.NumberU64()-1); add == nil {
 						log.Error("Unrooted new chain seen by tx pool", "block", newHead.Number, "hash", newHead.Hash())
 						return
@@ -1281,6 +1670,11 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 				reinject = types.TxDifference(discarded, included)
 			}
 		}
+	} else if oldHead != nil {
+		// Simple chain extension, track the transactions included by the new head
+		if block := pool.chain.GetBlock(newHead.Hash(), newHead.Number.Uint64()); block != nil {
+			pool.markIncluded(block)
+		}
 	}
 	// Initialize the internal state to the current head
 	if newHead == nil {
@@ -1291,6 +1685,7 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 		log.Error("Failed to reset txpool state", "err", err)
 		return
 	}
+	pool.currentHead = newHead
 	pool.currentState = statedb
 	pool.pendingNonces = newTxNoncer(statedb)
 	pool.currentMaxGas = newHead.GasLimit
@@ -1305,6 +1700,8 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	pool.istanbul = pool.chainconfig.IsIstanbul(next)
 	pool.eip2718 = pool.chainconfig.IsBerlin(next)
 	pool.eip1559 = pool.chainconfig.IsLondon(next)
+	pool.shanghai = pool.chainconfig.IsShanghai(next)
+	pool.cancun = pool.chainconfig.IsCancun(next)
 }
 
 // promoteExecutables moves transactions that have become processable from the
@@ -1325,6 +1722,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 		for _, tx := range forwards {
 			hash := tx.Hash()
 			pool.all.Remove(hash)
+			pool.recordStale(hash)
 		}
 		log.Trace("Removed old queued transactions", "count", len(forwards))
 		// Drop all transactions that are too costly (low balance or out of gas)
@@ -1332,6 +1730,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 		for _, tx := range drops {
 			hash := tx.Hash()
 			pool.all.Remove(hash)
+			pool.recordDrop(hash, TxDropUnpayable)
 		}
 		log.Trace("Removed unpayable queued transactions", "count", len(drops))
 		queuedNofundsMeter.Mark(int64(len(drops)))
@@ -1354,6 +1753,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 			for _, tx := range caps {
 				hash := tx.Hash()
 				pool.all.Remove(hash)
+				pool.recordDrop(hash, TxDropQueueLimit)
 				log.Trace("Removed cap-exceeding queued transaction", "hash", hash)
 			}
 			queuedRateLimitMeter.Mark(int64(len(caps)))
@@ -1416,6 +1816,7 @@ func (pool *TxPool) truncatePending() {
 						// Drop the transaction from the global pools too
 						hash := tx.Hash()
 						pool.all.Remove(hash)
+						pool.recordDrop(hash, TxDropPendingLimit)
 
 						// Update the account nonce to the dropped transaction
 						pool.pendingNonces.setIfLower(offenders[i], tx.Nonce())
@@ -1443,6 +1844,7 @@ func (pool *TxPool) truncatePending() {
 					// Drop the transaction from the global pools too
 					hash := tx.Hash()
 					pool.all.Remove(hash)
+					pool.recordDrop(hash, TxDropPendingLimit)
 
 					// Update the account nonce to the dropped transaction
 					pool.pendingNonces.setIfLower(addr, tx.Nonce())
@@ -1490,6 +1892,7 @@ func (pool *TxPool) truncateQueue() {
 		if size := uint64(list.Len()); size <= drop {
 			for _, tx := range list.Flatten() {
 				pool.removeTx(tx.Hash(), true)
+				pool.recordDrop(tx.Hash(), TxDropQueueLimit)
 			}
 			drop -= size
 			queuedRateLimitMeter.Mark(int64(size))
@@ -1499,6 +1902,7 @@ func (pool *TxPool) truncateQueue() {
 		txs := list.Flatten()
 		for i := len(txs) - 1; i >= 0 && drop > 0; i-- {
 			pool.removeTx(txs[i].Hash(), true)
+			pool.recordDrop(txs[i].Hash(), TxDropQueueLimit)
 			drop--
 			queuedRateLimitMeter.Mark(1)
 		}
@@ -1522,6 +1926,7 @@ func (pool *TxPool) demoteUnexecutables() {
 		for _, tx := range olds {
 			hash := tx.Hash()
 			pool.all.Remove(hash)
+			pool.recordStale(hash)
 			log.Trace("Removed old pending transaction", "hash", hash)
 		}
 		// Drop all transactions that are too costly (low balance or out of gas), and queue any invalids back for later
@@ -1530,6 +1935,7 @@ func (pool *TxPool) demoteUnexecutables() {
 			hash := tx.Hash()
 			log.Trace("Removed unpayable pending transaction", "hash", hash)
 			pool.all.Remove(hash)
+			pool.recordDrop(hash, TxDropUnpayable)
 		}
 		pendingNofundsMeter.Mark(int64(len(drops)))
 
@@ -1661,6 +2067,7 @@ func (as *accountSet) merge(other *accountSet) {
 // to build upper-level structure.
 type txLookup struct {
 	slots   int
+	blobs   int
 	lock    sync.RWMutex
 	locals  map[common.Hash]*types.Transaction
 	remotes map[common.Hash]*types.Transaction
@@ -1756,6 +2163,14 @@ func (t *txLookup) Slots() int {
 	return t.slots
 }
 
+// Blobs returns the current number of blob sidecar bytes held in the lookup.
+func (t *txLookup) Blobs() int {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.blobs
+}
+
 // Add adds a transaction to the lookup.
 func (t *txLookup) Add(tx *types.Transaction, local bool) {
 	t.lock.Lock()
@@ -1764,6 +2179,9 @@ func (t *txLookup) Add(tx *types.Transaction, local bool) {
 	t.slots += numSlots(tx)
 	slotsGauge.Update(int64(t.slots))
 
+	t.blobs += blobSize(tx)
+	blobsGauge.Update(int64(t.blobs))
+
 	if local {
 		t.locals[tx.Hash()] = tx
 	} else {
@@ -1787,6 +2205,9 @@ func (t *txLookup) Remove(hash common.Hash) {
 	t.slots -= numSlots(tx)
 	slotsGauge.Update(int64(t.slots))
 
+	t.blobs -= blobSize(tx)
+	blobsGauge.Update(int64(t.blobs))
+
 	delete(t.locals, hash)
 	delete(t.remotes, hash)
 }
@@ -1820,7 +2241,17 @@ func (t *txLookup) RemotesBelowTip(threshold *big.Int) types.Transactions {
 	return found
 }
 
-// numSlots calculates the number of slots needed for a single transaction.
+// numSlots calculates the number of slots needed for a single transaction. The
+// blob sidecar is not counted, blobs are accounted for separately.
 func numSlots(tx *types.Transaction) int {
-	return int((tx.Size() + txSlotSize - 1) / txSlotSize)
+	return int((tx.WithoutBlobTxSidecar().Size() + txSlotSize - 1) / txSlotSize)
+}
+
+// blobSize calculates the number of bytes taken up by the blob sidecar of a
+// transaction, zero for anything without blobs attached.
+func blobSize(tx *types.Transaction) int {
+	if tx.BlobTxSidecar() == nil {
+		return 0
+	}
+	return int(tx.Size() - tx.WithoutBlobTxSidecar().Size())
 }
@@ -0,0 +1,214 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// poolJournalMagic is the header identifying a full pool journal file, with the
+// last byte being the format version.
+var poolJournalMagic = []byte("gtxpool\x01")
+
+// poolJournalChecksums is the CRC32 table used to checksum journal entries.
+var poolJournalChecksums = crc32.MakeTable(crc32.Castagnoli)
+
+// errPoolJournalHeader is returned if the pool journal does not start with the
+// expected magic header, either due to corruption or a format change.
+var errPoolJournalHeader = errors.New("invalid pool journal header")
+
+// poolJournalEntry is a single transaction stored in the full pool journal.
+type poolJournalEntry struct {
+	Local    bool   // Whether the transaction was tracked as local
+	Tx       []byte // Binary encoding of the transaction (including blob sidecars)
+	Checksum uint32 // CRC32 (Castagnoli) checksum of the transaction encoding
+}
+
+// txPoolJournal is a periodically regenerated snapshot of all the transactions
+// in the pool, remote ones included, with the aim of allowing the pool contents
+// to survive node restarts. As opposed to txJournal, it is never appended to,
+// rather the entire snapshot is atomically replaced on every write.
+type txPoolJournal struct {
+	path  string // Filesystem path to store the transactions at
+	limit uint64 // Maximum number of bytes to store in the journal
+}
+
+// newTxPoolJournal creates a new full pool journal at the given path, capped at
+// the given size.
+func newTxPoolJournal(path string, limit uint64) *txPoolJournal {
+	return &txPoolJournal{
+		path:  path,
+		limit: limit,
+	}
+}
+
+// load parses a pool journal dump from disk, loading its contents into the pool
+// via the given add method. Entries failing their checksum are skipped, whilst
+// any decoding error aborts the load, keeping whatever was loaded until then.
+func (journal *txPoolJournal) load(add func(txs []*types.Transaction, local bool) []error) error {
+	input, err := os.Open(journal.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Skip the parsing if the journal file doesn't exist at all
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	reader := bufio.NewReader(input)
+	magic := make([]byte, len(poolJournalMagic))
+	if _, err := io.ReadFull(reader, magic); err != nil || !bytes.Equal(magic, poolJournalMagic) {
+		return errPoolJournalHeader
+	}
+	var (
+		stream = rlp.NewStream(reader, 0)
+
+		total, dropped, corrupt int
+		failure                 error
+
+		locals, remotes types.Transactions
+	)
+	// Create a method to load a limited batch of transactions and bump the
+	// appropriate progress counters. Then use this method to load all the
+	// journaled transactions in small-ish batches.
+	loadBatch := func(txs types.Transactions, local bool) {
+		for _, err := range add(txs, local) {
+			// Transactions already loaded from the local journal are fine
+			if err != nil && !errors.Is(err, ErrAlreadyKnown) {
+				log.Debug("Failed to add pool journal transaction", "err", err)
+				dropped++
+			}
+		}
+	}
+	for {
+		// Parse the next entry and terminate on error
+		var entry poolJournalEntry
+		if err = stream.Decode(&entry); err != nil {
+			if err != io.EOF {
+				failure = err
+			}
+			break
+		}
+		total++
+
+		// Skip any entry that was corrupted on disk
+		if crc32.Checksum(entry.Tx, poolJournalChecksums) != entry.Checksum {
+			corrupt++
+			continue
+		}
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(entry.Tx); err != nil {
+			corrupt++
+			continue
+		}
+		// Entry valid, queue up for later, import if threshold is reached
+		if entry.Local {
+			if locals = append(locals, tx); locals.Len() > 1024 {
+				loadBatch(locals, true)
+				locals = locals[:0]
+			}
+		} else {
+			if remotes = append(remotes, tx); remotes.Len() > 1024 {
+				loadBatch(remotes, false)
+				remotes = remotes[:0]
+			}
+		}
+	}
+	if locals.Len() > 0 {
+		loadBatch(locals, true)
+	}
+	if remotes.Len() > 0 {
+		loadBatch(remotes, false)
+	}
+	log.Info("Loaded transaction pool journal", "transactions", total, "dropped", dropped, "corrupt", corrupt)
+
+	return failure
+}
+
+// write regenerates the pool journal from the given transactions, in order, up
+// to the configured size limit. The journal is written to a temporary file and
+// moved in place only when complete, so a crash never leaves a truncated file.
+func (journal *txPoolJournal) write(txs []*types.Transaction, isLocal func(common.Hash) bool) error {
+	replacement, err := os.OpenFile(journal.path+".new", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	var (
+		output  = bufio.NewWriter(replacement)
+		size    = uint64(len(poolJournalMagic))
+		written int
+	)
+	if _, err := output.Write(poolJournalMagic); err != nil {
+		replacement.Close()
+		return err
+	}
+	for _, tx := range txs {
+		blob, err := tx.MarshalBinary()
+		if err != nil {
+			replacement.Close()
+			return err
+		}
+		entry, err := rlp.EncodeToBytes(&poolJournalEntry{
+			Local:    isLocal(tx.Hash()),
+			Tx:       blob,
+			Checksum: crc32.Checksum(blob, poolJournalChecksums),
+		})
+		if err != nil {
+			replacement.Close()
+			return err
+		}
+		if size+uint64(len(entry)) > journal.limit {
+			break
+		}
+		if _, err := output.Write(entry); err != nil {
+			replacement.Close()
+			return err
+		}
+		size += uint64(len(entry))
+		written++
+	}
+	if err := output.Flush(); err != nil {
+		replacement.Close()
+		return err
+	}
+	if err := replacement.Sync(); err != nil {
+		replacement.Close()
+		return err
+	}
+	if err := replacement.Close(); err != nil {
+		return err
+	}
+	// Replace the live journal with the newly generated one
+	if err := os.Rename(journal.path+".new", journal.path); err != nil {
+		return fmt.Errorf("failed to replace pool journal: %w", err)
+	}
+	log.Info("Regenerated transaction pool journal", "transactions", written, "skipped", len(txs)-written, "size", common.StorageSize(size))
+	return nil
+}
@@ -23,6 +23,8 @@ import (
 	"math/big"
 	"math/rand"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -32,6 +34,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/trie"
@@ -2345,6 +2348,347 @@ func testTransactionJournaling(t *testing.T, nolocals bool) {
 	pool.Stop()
 }
 
+// Tests that the full pool journal persists remote transactions across restarts,
+// revalidates them on load, skips corrupted entries and respects its size cap.
+func TestTransactionPoolJournaling(t *testing.T) {
+	t.Parallel()
+
+	// Create the original pool to inject transactions into the journal
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{1000000, statedb, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.PoolJournal = filepath.Join(t.TempDir(), "txpool.rlp")
+	config.PoolJournalCap = DefaultTxPoolConfig.PoolJournalCap
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+
+	keyA, _ := crypto.GenerateKey()
+	keyB, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(keyA.PublicKey), big.NewInt(1000000000))
+	testAddBalance(pool, crypto.PubkeyToAddress(keyB.PublicKey), big.NewInt(1000000000))
+
+	txs := []*types.Transaction{
+		pricedTransaction(0, 100000, big.NewInt(1), keyA),
+		pricedTransaction(1, 100000, big.NewInt(1), keyA),
+		pricedTransaction(3, 100000, big.NewInt(1), keyA),
+		pricedTransaction(0, 100000, big.NewInt(1), keyB),
+	}
+	for i, err := range pool.AddRemotesSync(txs) {
+		if err != nil {
+			t.Fatalf("failed to add remote transaction %d: %v", i, err)
+		}
+	}
+	if pending, queued := pool.Stats(); pending != 3 || queued != 1 {
+		t.Fatalf("pool stats mismatch: have %d/%d, want %d/%d", pending, queued, 3, 1)
+	}
+	// Terminate the old pool, bump a nonce, restart and ensure the valid ones survive
+	pool.Stop()
+	statedb.SetNonce(crypto.PubkeyToAddress(keyB.PublicKey), 1)
+	blockchain = &testBlockChain{1000000, statedb, new(event.Feed)}
+
+	pool = NewTxPool(config, params.TestChainConfig, blockchain)
+	if pending, queued := pool.Stats(); pending != 2 || queued != 1 {
+		t.Fatalf("pool stats mismatch after restart: have %d/%d, want %d/%d", pending, queued, 2, 1)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	pool.Stop()
+
+	// Corrupt the checksum of the last entry and ensure only that one is lost
+	blob, err := os.ReadFile(config.PoolJournal)
+	if err != nil {
+		t.Fatalf("failed to read pool journal: %v", err)
+	}
+	blob[len(blob)-1] ^= 0x01
+	if err := os.WriteFile(config.PoolJournal, blob, 0644); err != nil {
+		t.Fatalf("failed to corrupt pool journal: %v", err)
+	}
+	pool = NewTxPool(config, params.TestChainConfig, blockchain)
+	if pending, queued := pool.Stats(); pending+queued != 2 {
+		t.Fatalf("pool size mismatch after corruption: have %d, want %d", pending+queued, 2)
+	}
+	// Shrink the cap to fit a single entry and ensure the executable one is kept
+	pool.Stop()
+
+	config.PoolJournalCap = uint64(len(poolJournalMagic)) + 150
+	pool = NewTxPool(config, params.TestChainConfig, blockchain)
+	pool.Stop()
+
+	pool = NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+	if pending, queued := pool.Stats(); pending != 1 || queued != 0 {
+		t.Fatalf("pool stats mismatch after capping: have %d/%d, want %d/%d", pending, queued, 1, 0)
+	}
+}
+
+// Tests that private transactions are tracked as such, are never journaled and
+// are dropped if not included within their lifetime.
+func TestTransactionPrivate(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{1000000, statedb, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.PoolJournal = filepath.Join(t.TempDir(), "txpool.rlp")
+	config.PoolJournalCap = DefaultTxPoolConfig.PoolJournalCap
+	config.PrivateLifetime = 2
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+
+	key, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	public := pricedTransaction(0, 100000, big.NewInt(1), key)
+	private := pricedTransaction(1, 100000, big.NewInt(1), key)
+
+	if err := pool.AddLocal(public); err != nil {
+		t.Fatalf("failed to add public transaction: %v", err)
+	}
+	if err := pool.AddPrivate(private); err != nil {
+		t.Fatalf("failed to add private transaction: %v", err)
+	}
+	if err := pool.AddPrivate(private); err != ErrAlreadyKnown {
+		t.Fatalf("duplicate private transaction error mismatch: have %v, want %v", err, ErrAlreadyKnown)
+	}
+	if pool.IsPrivate(public.Hash()) || !pool.IsPrivate(private.Hash()) {
+		t.Fatalf("private flag mismatch")
+	}
+	if pending, queued := pool.Stats(); pending != 2 || queued != 0 {
+		t.Fatalf("pool stats mismatch: have %d/%d, want %d/%d", pending, queued, 2, 0)
+	}
+	// Private transactions must not be rejournaled
+	pool.mu.RLock()
+	locals := pool.local()
+	pool.mu.RUnlock()
+	if txs := locals[crypto.PubkeyToAddress(key.PublicKey)]; len(txs) != 1 || txs[0].Hash() != public.Hash() {
+		t.Fatalf("local journal set mismatch: have %v, want only %x", txs, public.Hash())
+	}
+	// Ensure the transaction survives until its lifetime is reached
+	setHead := func(number int64) {
+		pool.mu.Lock()
+		pool.currentHead = &types.Header{Number: big.NewInt(number)}
+		pool.expirePrivate()
+		pool.mu.Unlock()
+	}
+	setHead(1)
+	if !pool.Has(private.Hash()) {
+		t.Fatalf("private transaction dropped before expiry")
+	}
+	setHead(2)
+	if pool.Has(private.Hash()) || pool.IsPrivate(private.Hash()) {
+		t.Fatalf("private transaction not dropped after expiry")
+	}
+	if !pool.Has(public.Hash()) {
+		t.Fatalf("public transaction dropped")
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	// Add another private transaction and ensure it's not persisted in the pool journal
+	if err := pool.AddPrivate(private); err != nil {
+		t.Fatalf("failed to re-add private transaction: %v", err)
+	}
+	pool.Stop()
+
+	pool = NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	if pool.Has(private.Hash()) {
+		t.Fatalf("private transaction persisted across restart")
+	}
+	if !pool.Has(public.Hash()) {
+		t.Fatalf("public transaction lost across restart")
+	}
+}
+
+// Tests that the configured admission filters reject transactions with errors
+// identifying the filter, and that custom hooks are evaluated.
+func TestTransactionAdmissionFilters(t *testing.T) {
+	t.Parallel()
+
+	var (
+		allowed, _ = crypto.GenerateKey()
+		denied, _  = crypto.GenerateKey()
+		tipped, _  = crypto.GenerateKey()
+		sanctioned = common.Address{0xde, 0xad}
+		hooked     = uint64(1000)
+	)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{1000000, statedb, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.Admission = TxAdmissionConfig{
+		DenySenders:    []common.Address{crypto.PubkeyToAddress(denied.PublicKey)},
+		DenyRecipients: []common.Address{sanctioned},
+		MaxCalldata:    32,
+		SenderMinTips:  []SenderMinTip{{Sender: crypto.PubkeyToAddress(tipped.PublicKey), MinTip: big.NewInt(10)}},
+		Hooks: []TxFilter{NewTxFilterHook("nonce", func(tx *types.Transaction, from common.Address, local bool) error {
+			if tx.Nonce() == hooked {
+				return errors.New("hooked nonce")
+			}
+			return nil
+		})},
+	}
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	for _, key := range []*ecdsa.PrivateKey{allowed, denied, tipped} {
+		testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+	}
+	sign := func(key *ecdsa.PrivateKey, nonce uint64, to common.Address, price int64, data []byte) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, to, big.NewInt(100), 100000, big.NewInt(price), data), types.HomesteadSigner{}, key)
+		return tx
+	}
+	tests := []struct {
+		tx     *types.Transaction
+		filter string
+	}{
+		{sign(allowed, 0, common.Address{}, 1, nil), ""},
+		{sign(denied, 0, common.Address{}, 1, nil), "address"},
+		{sign(allowed, 1, sanctioned, 1, nil), "address"},
+		{sign(allowed, 1, common.Address{}, 1, make([]byte, 33)), "calldata"},
+		{sign(tipped, 0, common.Address{}, 9, nil), "sendertip"},
+		{sign(tipped, 0, common.Address{}, 10, nil), ""},
+		{sign(allowed, hooked, common.Address{}, 1, nil), "nonce"},
+	}
+	for i, tt := range tests {
+		err := pool.AddRemotesSync([]*types.Transaction{tt.tx})[0]
+		if tt.filter == "" {
+			if err != nil {
+				t.Errorf("test %d: unexpected rejection: %v", i, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrTxFiltered) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, ErrTxFiltered)
+			continue
+		}
+		if !strings.Contains(err.Error(), ": "+tt.filter+": ") {
+			t.Errorf("test %d: rejecting filter mismatch: have %v, want %s", i, err, tt.filter)
+		}
+	}
+}
+
+// historyBlockChain is a test blockchain additionally serving a set of blocks,
+// letting pool resets see the transactions included by a new head.
+type historyBlockChain struct {
+	*testBlockChain
+	blocks map[common.Hash]*types.Block
+}
+
+func (bc *historyBlockChain) GetBlock(hash common.Hash, number uint64) *types.Block {
+	if block, ok := bc.blocks[hash]; ok {
+		return block
+	}
+	return bc.testBlockChain.GetBlock(hash, number)
+}
+
+// Tests that the lifecycle events of transactions are recorded in the history,
+// that drops are reported on the feed and that the history is bounded.
+func TestTransactionHistory(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &historyBlockChain{
+		testBlockChain: &testBlockChain{1000000, statedb, new(event.Feed)},
+		blocks:         make(map[common.Hash]*types.Block),
+	}
+
+	config := testTxPoolConfig
+	config.HistorySize = 5
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	drops := make(chan DropTxsEvent, 1)
+	sub := pool.SubscribeDropTxsEvent(drops)
+	defer sub.Unsubscribe()
+
+	keyA, _ := crypto.GenerateKey()
+	keyB, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(keyA.PublicKey), big.NewInt(1000000000))
+	testAddBalance(pool, crypto.PubkeyToAddress(keyB.PublicKey), big.NewInt(1000000000))
+
+	// Add a transaction and replace it, both should be added and promoted
+	original := pricedTransaction(0, 100000, big.NewInt(1), keyA)
+	replacement := pricedTransaction(0, 100000, big.NewInt(2), keyA)
+	if err := pool.AddRemotesSync([]*types.Transaction{original})[0]; err != nil {
+		t.Fatalf("failed to add original transaction: %v", err)
+	}
+	if err := pool.AddRemotesSync([]*types.Transaction{replacement})[0]; err != nil {
+		t.Fatalf("failed to add replacement transaction: %v", err)
+	}
+	checkKinds := func(hash common.Hash, kinds ...TxLifecycle) []TxHistoryEvent {
+		t.Helper()
+
+		events := pool.History(hash)
+		if len(events) != len(kinds) {
+			t.Fatalf("event count mismatch for %x: have %d, want %d", hash, len(events), len(kinds))
+		}
+		for i, kind := range kinds {
+			if events[i].Kind != kind {
+				t.Errorf("event %d kind mismatch for %x: have %s, want %s", i, hash, events[i].Kind, kind)
+			}
+		}
+		return events
+	}
+	events := checkKinds(original.Hash(), TxLifecycleAdded, TxLifecyclePromoted, TxLifecycleReplaced)
+	if events[2].ReplacedBy != replacement.Hash() {
+		t.Errorf("replacement mismatch: have %x, want %x", events[2].ReplacedBy, replacement.Hash())
+	}
+	checkKinds(replacement.Hash(), TxLifecycleAdded)
+
+	// Raise the price limit and ensure the cheap transaction is dropped and reported
+	cheap := pricedTransaction(0, 100000, big.NewInt(1), keyB)
+	if err := pool.AddRemotesSync([]*types.Transaction{cheap})[0]; err != nil {
+		t.Fatalf("failed to add cheap transaction: %v", err)
+	}
+	pool.SetGasPrice(big.NewInt(2))
+
+	events = checkKinds(cheap.Hash(), TxLifecycleAdded, TxLifecyclePromoted, TxLifecycleDropped)
+	if events[2].Reason != TxDropUnderpriced {
+		t.Errorf("drop reason mismatch: have %s, want %s", events[2].Reason, TxDropUnderpriced)
+	}
+	select {
+	case ev := <-drops:
+		if len(ev.Events) != 1 || ev.Events[0].Hash != cheap.Hash() {
+			t.Errorf("drop event mismatch: have %v", ev.Events)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("drop event not delivered")
+	}
+	// Include the replacement in a block on top of the current head and ensure
+	// the reset reports it as included instead of dropped
+	parent := blockchain.CurrentBlock().Header()
+	block := types.NewBlock(&types.Header{
+		ParentHash: parent.Hash(),
+		Number:     big.NewInt(1),
+		GasLimit:   parent.GasLimit,
+		BaseFee:    big.NewInt(1),
+	}, types.Transactions{replacement}, nil, nil, trie.NewStackTrie(nil))
+	blockchain.blocks[block.Hash()] = block
+
+	statedb.SetNonce(crypto.PubkeyToAddress(keyA.PublicKey), 1)
+	<-pool.requestReset(parent, block.Header())
+
+	events = pool.History(replacement.Hash())
+	if len(events) == 0 || events[len(events)-1].Kind != TxLifecycleIncluded || events[len(events)-1].BlockNumber != 1 {
+		t.Errorf("inclusion not recorded: have %v", events)
+	}
+	select {
+	case ev := <-drops:
+		t.Errorf("included transaction reported as dropped: %v", ev.Events)
+	case <-time.After(100 * time.Millisecond):
+	}
+	// The history is bounded, so the oldest events must have been evicted
+	if events := pool.History(original.Hash()); len(events) != 0 {
+		t.Errorf("stale events retained: have %d", len(events))
+	}
+}
+
 // TestTransactionStatusCheck tests that the pool can correctly retrieve the
 // pending status of individual transactions.
 func TestTransactionStatusCheck(t *testing.T) {
@@ -2559,3 +2903,163 @@ func BenchmarkPoolMultiAccountBatchInsert(b *testing.B) {
 		pool.AddRemotesSync([]*types.Transaction{tx})
 	}
 }
+
+// Tests that private transactions retain their private marker after leaving the
+// pool through inclusion, so they are not propagated if reorged back in.
+func TestTransactionPrivateReorg(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{1000000, statedb, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.PrivateLifetime = 3
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+	<-pool.initDoneCh
+
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	testAddBalance(pool, addr, big.NewInt(1000000000))
+
+	private := transaction(0, 100000, key)
+	if err := pool.AddPrivate(private); err != nil {
+		t.Fatalf("failed to add private transaction: %v", err)
+	}
+	setHead := func(number int64, nonce uint64) {
+		pool.mu.Lock()
+		pool.currentHead = &types.Header{Number: big.NewInt(number)}
+		pool.currentState.SetNonce(addr, nonce)
+		pool.demoteUnexecutables()
+		pool.expirePrivate()
+		pool.mu.Unlock()
+	}
+	// Include the transaction and ensure the marker is retained
+	setHead(1, 1)
+	if pool.Has(private.Hash()) {
+		t.Fatalf("included private transaction still pooled")
+	}
+	if !pool.IsPrivate(private.Hash()) {
+		t.Fatalf("private marker dropped on inclusion")
+	}
+	// Reorg the transaction back in the same way a pool reset reinjects it
+	pool.mu.Lock()
+	pool.currentHead = &types.Header{Number: big.NewInt(0)}
+	pool.currentState.SetNonce(addr, 0)
+	pool.addTxsLocked([]*types.Transaction{private}, false)
+	pool.mu.Unlock()
+
+	if !pool.Has(private.Hash()) {
+		t.Fatalf("reorged private transaction not reinjected")
+	}
+	if !pool.IsPrivate(private.Hash()) {
+		t.Fatalf("reorged private transaction lost its private marker")
+	}
+	if txs := pool.public(types.Transactions{private}); len(txs) != 0 {
+		t.Fatalf("reorged private transaction considered public")
+	}
+	// Once the lifetime is over, the marker must be dropped along with the transaction
+	setHead(3, 0)
+	if pool.Has(private.Hash()) || pool.IsPrivate(private.Hash()) {
+		t.Fatalf("private transaction not dropped after expiry")
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// blobTransaction creates a signed blob transaction carrying the given number
+// of empty blobs in its sidecar.
+func blobTransaction(nonce uint64, blobs int, key *ecdsa.PrivateKey) *types.Transaction {
+	return pricedBlobTransaction(nonce, blobs, big.NewInt(1), key)
+}
+
+// pricedBlobTransaction creates a signed blob transaction carrying the given
+// number of empty blobs in its sidecar, paying the given gas price.
+func pricedBlobTransaction(nonce uint64, blobs int, gasprice *big.Int, key *ecdsa.PrivateKey) *types.Transaction {
+	sidecar := new(types.BlobTxSidecar)
+	for i := 0; i < blobs; i++ {
+		commit, _ := kzg4844.BlobToCommitment(kzg4844.Blob{})
+		proof, _ := kzg4844.ComputeBlobProof(kzg4844.Blob{}, commit)
+
+		sidecar.Blobs = append(sidecar.Blobs, kzg4844.Blob{})
+		sidecar.Commitments = append(sidecar.Commitments, commit)
+		sidecar.Proofs = append(sidecar.Proofs, proof)
+	}
+	tx, _ := types.SignNewTx(key, types.NewCancunSigner(params.TestChainConfig.ChainID), &types.BlobTx{
+		ChainID:       params.TestChainConfig.ChainID,
+		Nonce:         nonce,
+		GasTipCap:     gasprice,
+		GasFeeCap:     gasprice,
+		Gas:           100000,
+		Value:         big.NewInt(100),
+		DataGasFeeCap: big.NewInt(1),
+		BlobHashes:    sidecar.BlobHashes(),
+		Sidecar:       sidecar,
+	})
+	return tx
+}
+
+// Tests that the blobs of a blob transaction are limited separately from the
+// rest of the transaction, both per transaction and across the entire pool.
+func TestTransactionBlobLimits(t *testing.T) {
+	t.Parallel()
+
+	config := *params.TestChainConfig
+	config.CancunBlock = big.NewInt(0)
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{10000000, statedb, new(event.Feed)}
+
+	pool := NewTxPool(testTxPoolConfig, &config, blockchain)
+	defer pool.Stop()
+	<-pool.initDoneCh
+
+	key, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	// Force the pool-wide limit below the sanitized minimum to keep the test small
+	single := blobTransaction(0, 1, key)
+
+	pool.mu.Lock()
+	pool.config.GlobalBlobs = uint64(2 * blobSize(single))
+	pool.mu.Unlock()
+
+	// A transaction with more blobs than permitted must be rejected on size
+	if err := pool.addRemoteSync(blobTransaction(0, params.BlobTxMaxBlobsPerTx+1, key)); !errors.Is(err, ErrOversizedData) {
+		t.Fatalf("oversized blob transaction error mismatch: have %v, want %v", err, ErrOversizedData)
+	}
+	// Fill up the blob space of the pool and ensure it's tracked
+	for i := uint64(0); i < 2; i++ {
+		if err := pool.addRemoteSync(blobTransaction(i, 1, key)); err != nil {
+			t.Fatalf("failed to add blob transaction %d: %v", i, err)
+		}
+	}
+	if have, want := pool.all.Blobs(), 2*blobSize(single); have != want {
+		t.Fatalf("blob size mismatch: have %d, want %d", have, want)
+	}
+	// Any further blobs must be rejected, irrelevant of the free slots
+	if err := pool.addRemoteSync(blobTransaction(2, 1, key)); !errors.Is(err, ErrTxPoolOverflow) {
+		t.Fatalf("blob overflow error mismatch: have %v, want %v", err, ErrTxPoolOverflow)
+	}
+	// Replacing a blob transaction must be allowed at capacity, since it does not
+	// add any blobs to the pool
+	replacement := pricedBlobTransaction(1, 1, big.NewInt(2), key)
+	if err := pool.addRemoteSync(replacement); err != nil {
+		t.Fatalf("failed to replace blob transaction at capacity: %v", err)
+	}
+	if pool.all.Get(replacement.Hash()) == nil {
+		t.Fatalf("replacement blob transaction missing from pool")
+	}
+	if have, want := pool.all.Blobs(), 2*blobSize(single); have != want {
+		t.Fatalf("blob size mismatch after replacement: have %d, want %d", have, want)
+	}
+	// Transaction slots must not account for the blob sidecar
+	if have, want := numSlots(single), numSlots(single.WithoutBlobTxSidecar()); have != want {
+		t.Fatalf("blob transaction slot mismatch: have %d, want %d", have, want)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
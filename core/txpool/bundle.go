@@ -0,0 +1,223 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// maxBundles is the maximum number of bundles tracked by the pool. Bundles
+	// arriving above this limit are rejected until older ones expire.
+	maxBundles = 1024
+
+	// maxBundleFutureBlocks is the maximum number of blocks ahead of the current
+	// head a bundle may target. Bundles are only evicted once their target block
+	// is passed, so they must not be allowed to pin pool slots indefinitely.
+	maxBundleFutureBlocks = 25
+
+	// maxBundleTxs is the maximum number of transactions in a single bundle.
+	maxBundleTxs = 64
+
+	// maxBundleSize is the maximum total size of the transactions in a single
+	// bundle, including any blob sidecars.
+	maxBundleSize = 4 * 1024 * 1024
+)
+
+var (
+	// ErrBundleEmpty is returned if a bundle without transactions is submitted.
+	ErrBundleEmpty = errors.New("bundle has no transactions")
+
+	// ErrBundleExpired is returned if a bundle targets a block that's already
+	// been sealed.
+	ErrBundleExpired = errors.New("bundle targets past block")
+
+	// ErrBundleTooFar is returned if a bundle targets a block too far ahead of
+	// the current head.
+	ErrBundleTooFar = errors.New("bundle targets block too far in the future")
+
+	// ErrBundleTooLarge is returned if a bundle contains too many transactions or
+	// its transactions are too large in total.
+	ErrBundleTooLarge = errors.New("bundle too large")
+
+	// ErrBundleTimestamp is returned if a bundle's minimum timestamp is above
+	// its maximum one.
+	ErrBundleTimestamp = errors.New("bundle minimum timestamp above maximum")
+
+	// ErrBundlePoolFull is returned if the bundle pool cannot accept any more
+	// bundles until some are included or expire.
+	ErrBundlePoolFull = errors.New("bundle pool is full")
+)
+
+var (
+	bundleAddMeter    = metrics.NewRegisteredMeter("txpool/bundles/added", nil)
+	bundleExpireMeter = metrics.NewRegisteredMeter("txpool/bundles/expired", nil)
+)
+
+// Bundle is an ordered group of transactions that must all be included in the
+// target block, back to back, or not at all.
+type Bundle struct {
+	Txs          types.Transactions // Transactions to include, in order
+	BlockNumber  uint64             // Block number the bundle is valid for
+	MinTimestamp uint64             // Minimum block timestamp to include at (0 = unbounded)
+	MaxTimestamp uint64             // Maximum block timestamp to include at (0 = unbounded)
+
+	RevertingTxHashes []common.Hash // Transactions allowed to revert without invalidating the bundle
+}
+
+// Hash returns the unique identifier of the bundle, which is the hash of the
+// concatenated transaction hashes.
+func (b *Bundle) Hash() common.Hash {
+	hashes := make([]byte, 0, len(b.Txs)*common.HashLength)
+	for _, tx := range b.Txs {
+		hashes = append(hashes, tx.Hash().Bytes()...)
+	}
+	return crypto.Keccak256Hash(hashes)
+}
+
+// AllowsRevert returns whether the transaction with the given hash is allowed to
+// revert without invalidating the entire bundle.
+func (b *Bundle) AllowsRevert(hash common.Hash) bool {
+	for _, allowed := range b.RevertingTxHashes {
+		if allowed == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// Valid returns whether the bundle may be included in a block with the given
+// number and timestamp.
+func (b *Bundle) Valid(number uint64, timestamp uint64) bool {
+	if b.BlockNumber != number {
+		return false
+	}
+	if b.MinTimestamp != 0 && timestamp < b.MinTimestamp {
+		return false
+	}
+	if b.MaxTimestamp != 0 && timestamp > b.MaxTimestamp {
+		return false
+	}
+	return true
+}
+
+// bundlePool tracks the bundles submitted for future blocks. Bundles are only
+// dropped once a block past their target number is requested, since they may
+// be needed for building multiple competing payloads of the same height.
+type bundlePool struct {
+	bundles map[common.Hash]*Bundle
+	arrival map[common.Hash]uint64 // Sequence number of each bundle's arrival
+	counter uint64                 // Sequence number of the next arriving bundle
+	lock    sync.RWMutex
+}
+
+// newBundlePool creates an empty bundle pool.
+func newBundlePool() *bundlePool {
+	return &bundlePool{
+		bundles: make(map[common.Hash]*Bundle),
+		arrival: make(map[common.Hash]uint64),
+	}
+}
+
+// add inserts a bundle into the pool, deduplicating identical ones. The bundle
+// is assumed to have been checked already.
+func (p *bundlePool) add(bundle *Bundle) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	hash := bundle.Hash()
+	if _, ok := p.bundles[hash]; ok {
+		return nil
+	}
+	if len(p.bundles) >= maxBundles {
+		return ErrBundlePoolFull
+	}
+	p.bundles[hash] = bundle
+	p.arrival[hash] = p.counter
+	p.counter++
+	bundleAddMeter.Mark(1)
+
+	log.Debug("Added transaction bundle", "hash", hash, "txs", len(bundle.Txs), "block", bundle.BlockNumber)
+	return nil
+}
+
+// checkBundle runs the sanity checks of a bundle which don't depend on the
+// transactions it carries being valid. The head is the number of the current
+// chain head, which the bundle must target past, but not too far.
+func checkBundle(bundle *Bundle, head uint64) error {
+	if len(bundle.Txs) == 0 {
+		return ErrBundleEmpty
+	}
+	if len(bundle.Txs) > maxBundleTxs {
+		return fmt.Errorf("%w: %d transactions, permitted %d", ErrBundleTooLarge, len(bundle.Txs), maxBundleTxs)
+	}
+	var size common.StorageSize
+	for _, tx := range bundle.Txs {
+		size += tx.Size()
+	}
+	if size > maxBundleSize {
+		return fmt.Errorf("%w: %v, permitted %v", ErrBundleTooLarge, size, common.StorageSize(maxBundleSize))
+	}
+	if bundle.BlockNumber <= head {
+		return ErrBundleExpired
+	}
+	if bundle.BlockNumber > head+maxBundleFutureBlocks {
+		return fmt.Errorf("%w: block %d, head %d", ErrBundleTooFar, bundle.BlockNumber, head)
+	}
+	if bundle.MaxTimestamp != 0 && bundle.MinTimestamp > bundle.MaxTimestamp {
+		return ErrBundleTimestamp
+	}
+	return nil
+}
+
+// valid retrieves all the bundles includable in a block with the given number
+// and timestamp in order of arrival, dropping any bundle targeting earlier
+// blocks.
+func (p *bundlePool) valid(number uint64, timestamp uint64) []*Bundle {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var hashes []common.Hash
+	for hash, bundle := range p.bundles {
+		if bundle.BlockNumber < number {
+			delete(p.bundles, hash)
+			delete(p.arrival, hash)
+			bundleExpireMeter.Mark(1)
+			continue
+		}
+		if bundle.Valid(number, timestamp) {
+			hashes = append(hashes, hash)
+		}
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		return p.arrival[hashes[i]] < p.arrival[hashes[j]]
+	})
+	bundles := make([]*Bundle, len(hashes))
+	for i, hash := range hashes {
+		bundles[i] = p.bundles[hash]
+	}
+	return bundles
+}
@@ -0,0 +1,138 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// SubPool represents a specialized transaction pool that lives on its own (e.g.
+// blob pool). Since independent of how many specialized pools we have, they do
+// need to be updated in lockstep and assemble into one coherent view for block
+// production, this interface defines the common methods that allow the primary
+// transaction pool to manage the subpools.
+//
+// Feature specific capabilities that not every subpool can sensibly support are
+// not part of this interface. They are defined as separate optional interfaces,
+// which the primary transaction pool detects via type assertions, skipping any
+// subpool that does not implement them.
+type SubPool interface {
+	// Filter is a selector used to decide whether a transaction would be added
+	// to this particular subpool.
+	Filter(tx *types.Transaction) bool
+
+	// Has returns an indicator whether subpool has a transaction cached with the
+	// given hash.
+	Has(hash common.Hash) bool
+
+	// Get returns a transaction if it is contained in the pool, or nil otherwise.
+	Get(hash common.Hash) *types.Transaction
+
+	// Add enqueues a batch of transactions into the pool if they are valid. Due
+	// to the large transaction churn, add may postpone fully integrating the tx
+	// to a later point to batch multiple ones together, unless sync is set.
+	Add(txs []*types.Transaction, local bool, sync bool) []error
+
+	// Pending retrieves all currently processable transactions, grouped by origin
+	// account and sorted by nonce.
+	Pending(enforceTips bool) map[common.Address]types.Transactions
+
+	// SubscribeNewTxsEvent subscribes to new transaction events.
+	SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription
+
+	// Nonce returns the next nonce of an account, with all transactions executable
+	// by the pool already applied on top.
+	Nonce(addr common.Address) uint64
+
+	// Stats retrieves the current pool stats, namely the number of pending and the
+	// number of queued (non-executable) transactions.
+	Stats() (int, int)
+
+	// Content retrieves the data content of the transaction pool, returning all the
+	// pending as well as queued transactions, grouped by account and sorted by nonce.
+	Content() (map[common.Address]types.Transactions, map[common.Address]types.Transactions)
+
+	// ContentFrom retrieves the data content of the transaction pool, returning the
+	// pending as well as queued transactions of this address, grouped by nonce.
+	ContentFrom(addr common.Address) (types.Transactions, types.Transactions)
+
+	// Locals retrieves the accounts currently considered local by the pool.
+	Locals() []common.Address
+
+	// Status returns the known status (unknown/pending/queued) of a batch of
+	// transactions identified by their hashes.
+	Status(hashes []common.Hash) []core.TxStatus
+
+	// SetGasPrice updates the minimum price required by the subpool for a new
+	// transaction, and drops all transactions below this threshold.
+	SetGasPrice(price *big.Int)
+
+	// Stop terminates any background processing threads and releases any held
+	// resources.
+	Stop()
+}
+
+// privateSubPool is an optional extension of SubPool, implemented by subpools
+// which accept transactions that must never be propagated to the network.
+type privateSubPool interface {
+	SubPool
+
+	// AddPrivate enqueues a single local transaction into the pool, marking it
+	// private so it's never propagated to the network.
+	AddPrivate(tx *types.Transaction) error
+
+	// IsPrivate returns whether the transaction with the given hash was submitted
+	// privately and must not be propagated to the network.
+	IsPrivate(hash common.Hash) bool
+}
+
+// historySubPool is an optional extension of SubPool, implemented by subpools
+// which track the lifecycle of their transactions.
+type historySubPool interface {
+	SubPool
+
+	// SubscribeDropTxsEvent subscribes to dropped transaction events.
+	SubscribeDropTxsEvent(ch chan<- core.DropTxsEvent) event.Subscription
+
+	// History retrieves the retained lifecycle events of a transaction, oldest
+	// first.
+	History(hash common.Hash) []core.TxHistoryEvent
+}
+
+// validatingSubPool is an optional extension of SubPool, implemented by subpools
+// able to check transactions they are not going to admit, such as the ones of a
+// bundle, against their stateless validity rules.
+type validatingSubPool interface {
+	SubPool
+
+	// ValidateTxBasics checks whether a transaction is valid according to the
+	// consensus rules and the limits of the pool, irrelevant of any state.
+	ValidateTxBasics(tx *types.Transaction) error
+}
+
+// Ensure the legacy transaction pool can be used as a subpool.
+var (
+	_ SubPool           = (*core.TxPool)(nil)
+	_ privateSubPool    = (*core.TxPool)(nil)
+	_ historySubPool    = (*core.TxPool)(nil)
+	_ validatingSubPool = (*core.TxPool)(nil)
+)
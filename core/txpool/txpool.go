@@ -0,0 +1,415 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package txpool implements a transaction pool coordinator, multiplexing a set
+// of specialized subpools behind a single transaction pool interface.
+package txpool
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// ErrPrivateNotSupported is returned if a private transaction is routed to a
+// subpool which cannot keep transactions private.
+var ErrPrivateNotSupported = errors.New("private transactions not supported")
+
+// TxPool is an aggregator for various transaction specific pools, collectively
+// tracking all the transactions deemed interesting by the node. Transactions
+// enter the pool when they are received from the network or submitted locally.
+// They exit the pool when they are included in the blockchain or evicted due to
+// resource constraints.
+type TxPool struct {
+	subpools []SubPool               // List of subpools for specialized transaction handling
+	bundles  *bundlePool             // Transaction bundles submitted for atomic inclusion
+	subs     event.SubscriptionScope // Subscription scope to unsubscribe all on shutdown
+}
+
+// New creates a new transaction pool to gather, sort and filter inbound
+// transactions from the network. Every transaction is routed to the first
+// subpool whose filter accepts it.
+func New(subpools ...SubPool) *TxPool {
+	return &TxPool{
+		subpools: subpools,
+		bundles:  newBundlePool(),
+	}
+}
+
+// Stop terminates the transaction pool and all its subpools.
+func (p *TxPool) Stop() {
+	// Unsubscribe all subscriptions registered from txpool
+	p.subs.Close()
+
+	for _, subpool := range p.subpools {
+		subpool.Stop()
+	}
+}
+
+// SetGasPrice updates the minimum price required by the subpools for a new
+// transaction, and drops all transactions below this threshold.
+func (p *TxPool) SetGasPrice(price *big.Int) {
+	for _, subpool := range p.subpools {
+		subpool.SetGasPrice(price)
+	}
+}
+
+// Has returns an indicator whether the pool has a transaction cached with the
+// given hash.
+func (p *TxPool) Has(hash common.Hash) bool {
+	for _, subpool := range p.subpools {
+		if subpool.Has(hash) {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns a transaction if it is contained in the pool, or nil otherwise.
+func (p *TxPool) Get(hash common.Hash) *types.Transaction {
+	for _, subpool := range p.subpools {
+		if tx := subpool.Get(hash); tx != nil {
+			return tx
+		}
+	}
+	return nil
+}
+
+// Add enqueues a batch of transactions into the pool if they are valid. Due
+// to the large transaction churn, add may postpone fully integrating the tx
+// to a later point to batch multiple ones together, unless sync is set.
+func (p *TxPool) Add(txs []*types.Transaction, local bool, sync bool) []error {
+	// Split the input transactions between the subpools. It shouldn't really
+	// happen that we receive merged batches, but better graceful than strange
+	// errors.
+	//
+	// We also need to track how the transactions were split across the subpools,
+	// so we can piece back the returned errors into the original order.
+	txsets := make([][]*types.Transaction, len(p.subpools))
+	splits := make([]int, len(txs))
+
+	for i, tx := range txs {
+		// Mark this transaction belonging to no-subpool
+		splits[i] = -1
+
+		// Try to find a subpool that accepts the transaction
+		for j, subpool := range p.subpools {
+			if subpool.Filter(tx) {
+				txsets[j] = append(txsets[j], tx)
+				splits[i] = j
+				break
+			}
+		}
+	}
+	// Add the transactions split apart to the individual subpools and piece
+	// back the errors into the original sort order.
+	errsets := make([][]error, len(p.subpools))
+	for i := 0; i < len(p.subpools); i++ {
+		if len(txsets[i]) > 0 {
+			errsets[i] = p.subpools[i].Add(txsets[i], local, sync)
+		}
+	}
+	errs := make([]error, len(txs))
+	for i, split := range splits {
+		// If the transaction was rejected by all subpools, mark it unsupported
+		if split == -1 {
+			errs[i] = core.ErrTxTypeNotSupported
+			continue
+		}
+		// Find which subpool handled it and pull in the corresponding error
+		errs[i] = errsets[split][0]
+		errsets[split] = errsets[split][1:]
+	}
+	return errs
+}
+
+// AddLocals enqueues a batch of transactions into the pool if they are valid,
+// marking the senders as local ones, ensuring they go around the local pricing
+// constraints.
+//
+// This method is used to add transactions from the RPC API and performs
+// synchronous pool reorganization and event propagation.
+func (p *TxPool) AddLocals(txs []*types.Transaction) []error {
+	return p.Add(txs, true, true)
+}
+
+// AddLocal enqueues a single local transaction into the pool if it is valid.
+// This is a convenience wrapper around AddLocals.
+func (p *TxPool) AddLocal(tx *types.Transaction) error {
+	return p.AddLocals([]*types.Transaction{tx})[0]
+}
+
+// AddRemotes enqueues a batch of transactions into the pool if they are valid.
+// If the senders are not among the locally tracked ones, full pricing constraints
+// will apply.
+//
+// This method is used to add transactions from the p2p network and does not
+// wait for pool reorganization and internal event propagation.
+func (p *TxPool) AddRemotes(txs []*types.Transaction) []error {
+	return p.Add(txs, false, false)
+}
+
+// AddRemotesSync is like AddRemotes, but waits for pool reorganization.
+func (p *TxPool) AddRemotesSync(txs []*types.Transaction) []error {
+	return p.Add(txs, false, true)
+}
+
+// AddPrivate enqueues a single local transaction into the subpool accepting it,
+// marking it private. Private transactions are never propagated to the network
+// and are only included in blocks produced by the local miner.
+func (p *TxPool) AddPrivate(tx *types.Transaction) error {
+	for _, subpool := range p.subpools {
+		if subpool.Filter(tx) {
+			if private, ok := subpool.(privateSubPool); ok {
+				return private.AddPrivate(tx)
+			}
+			return ErrPrivateNotSupported
+		}
+	}
+	return core.ErrTxTypeNotSupported
+}
+
+// IsPrivate returns whether the transaction with the given hash was submitted
+// privately and must not be propagated to the network.
+func (p *TxPool) IsPrivate(hash common.Hash) bool {
+	for _, subpool := range p.subpools {
+		if private, ok := subpool.(privateSubPool); ok && private.IsPrivate(hash) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddBundle enqueues a bundle of transactions to be included atomically in the
+// block it targets, which must be at most a few blocks past the given head. The
+// transactions are checked against the stateless rules of the subpool accepting
+// them, their execution is only validated by the miner when simulating the bundle.
+func (p *TxPool) AddBundle(bundle *Bundle, head uint64) error {
+	if err := checkBundle(bundle, head); err != nil {
+		return err
+	}
+	for i, tx := range bundle.Txs {
+		if err := p.validateTxBasics(tx); err != nil {
+			return fmt.Errorf("transaction %d: %w", i, err)
+		}
+	}
+	return p.bundles.add(bundle)
+}
+
+// validateTxBasics checks a transaction against the stateless validity rules of
+// the subpool accepting it, if the subpool supports such checks.
+func (p *TxPool) validateTxBasics(tx *types.Transaction) error {
+	for _, subpool := range p.subpools {
+		if subpool.Filter(tx) {
+			if validator, ok := subpool.(validatingSubPool); ok {
+				return validator.ValidateTxBasics(tx)
+			}
+			return nil
+		}
+	}
+	return core.ErrTxTypeNotSupported
+}
+
+// Bundles retrieves all the bundles includable in a block with the given number
+// and timestamp. Bundles targeting earlier blocks are dropped.
+func (p *TxPool) Bundles(number uint64, timestamp uint64) []*Bundle {
+	return p.bundles.valid(number, timestamp)
+}
+
+// Pending retrieves all currently processable transactions, grouped by origin
+// account and sorted by nonce.
+func (p *TxPool) Pending(enforceTips bool) map[common.Address]types.Transactions {
+	txs := make(map[common.Address]types.Transactions)
+	for _, subpool := range p.subpools {
+		mergeTransactions(txs, subpool.Pending(enforceTips))
+	}
+	return txs
+}
+
+// SubscribeNewTxsEvent registers a subscription of NewTxsEvent and starts sending
+// events to the given channel.
+func (p *TxPool) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
+	subs := make([]event.Subscription, len(p.subpools))
+	for i, subpool := range p.subpools {
+		subs[i] = subpool.SubscribeNewTxsEvent(ch)
+	}
+	return p.subs.Track(joinSubscriptions(subs...))
+}
+
+// SubscribeDropTxsEvent registers a subscription of DropTxsEvent and starts
+// sending events to the given channel.
+func (p *TxPool) SubscribeDropTxsEvent(ch chan<- core.DropTxsEvent) event.Subscription {
+	var subs []event.Subscription
+	for _, subpool := range p.subpools {
+		if history, ok := subpool.(historySubPool); ok {
+			subs = append(subs, history.SubscribeDropTxsEvent(ch))
+		}
+	}
+	return p.subs.Track(joinSubscriptions(subs...))
+}
+
+// History retrieves the retained lifecycle events of a transaction across all
+// the subpools, oldest first.
+func (p *TxPool) History(hash common.Hash) []core.TxHistoryEvent {
+	var events []core.TxHistoryEvent
+	for _, subpool := range p.subpools {
+		if history, ok := subpool.(historySubPool); ok {
+			events = append(events, history.History(hash)...)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+	return events
+}
+
+// Nonce returns the next nonce of an account, with all transactions executable
+// by the pool already applied on top.
+func (p *TxPool) Nonce(addr common.Address) uint64 {
+	// Since (for now) accounts are unique to subpools, only one pool will have
+	// (at max) a non-state nonce. To avoid stateful lookups, just return the
+	// highest nonce for now.
+	var nonce uint64
+	for _, subpool := range p.subpools {
+		if next := subpool.Nonce(addr); nonce < next {
+			nonce = next
+		}
+	}
+	return nonce
+}
+
+// Stats retrieves the current pool stats, namely the number of pending and the
+// number of queued (non-executable) transactions.
+func (p *TxPool) Stats() (int, int) {
+	var runnable, blocked int
+	for _, subpool := range p.subpools {
+		run, block := subpool.Stats()
+
+		runnable += run
+		blocked += block
+	}
+	return runnable, blocked
+}
+
+// Content retrieves the data content of the transaction pool, returning all the
+// pending as well as queued transactions, grouped by account and sorted by nonce.
+func (p *TxPool) Content() (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
+	var (
+		runnable = make(map[common.Address]types.Transactions)
+		blocked  = make(map[common.Address]types.Transactions)
+	)
+	for _, subpool := range p.subpools {
+		run, block := subpool.Content()
+
+		mergeTransactions(runnable, run)
+		mergeTransactions(blocked, block)
+	}
+	return runnable, blocked
+}
+
+// ContentFrom retrieves the data content of the transaction pool, returning the
+// pending as well as queued transactions of this address, grouped by nonce.
+func (p *TxPool) ContentFrom(addr common.Address) (types.Transactions, types.Transactions) {
+	var runnable, blocked types.Transactions
+	for _, subpool := range p.subpools {
+		run, block := subpool.ContentFrom(addr)
+
+		runnable = append(runnable, run...)
+		blocked = append(blocked, block...)
+	}
+	sort.Sort(types.TxByNonce(runnable))
+	sort.Sort(types.TxByNonce(blocked))
+	return runnable, blocked
+}
+
+// Locals retrieves the accounts currently considered local by the pool.
+func (p *TxPool) Locals() []common.Address {
+	// Retrieve the locals from each subpool and deduplicate them
+	locals := make(map[common.Address]struct{})
+	for _, subpool := range p.subpools {
+		for _, local := range subpool.Locals() {
+			locals[local] = struct{}{}
+		}
+	}
+	// Flatten and return the deduplicated local set
+	flat := make([]common.Address, 0, len(locals))
+	for local := range locals {
+		flat = append(flat, local)
+	}
+	return flat
+}
+
+// Status returns the known status (unknown/pending/queued) of a batch of
+// transactions identified by their hashes.
+func (p *TxPool) Status(hashes []common.Hash) []core.TxStatus {
+	status := make([]core.TxStatus, len(hashes))
+	for _, subpool := range p.subpools {
+		for i, stat := range subpool.Status(hashes) {
+			if stat != core.TxStatusUnknown {
+				status[i] = stat
+			}
+		}
+	}
+	return status
+}
+
+// mergeTransactions appends the per-account transaction lists of src into dst,
+// keeping the lists of accounts present in multiple subpools sorted by nonce.
+func mergeTransactions(dst, src map[common.Address]types.Transactions) {
+	for addr, txs := range src {
+		if have, ok := dst[addr]; ok {
+			merged := append(have, txs...)
+			sort.Sort(types.TxByNonce(merged))
+			dst[addr] = merged
+			continue
+		}
+		dst[addr] = txs
+	}
+}
+
+// joinSubscriptions joins multiple subscriptions to be able to track them as
+// one entity and collectively cancel them or consume any errors from them.
+func joinSubscriptions(subs ...event.Subscription) event.Subscription {
+	return event.NewSubscription(func(unsubbed <-chan struct{}) error {
+		// Unsubscribe all subscriptions before returning
+		defer func() {
+			for _, sub := range subs {
+				sub.Unsubscribe()
+			}
+		}()
+		// Wait for an error on any of the subscriptions and propagate up
+		errc := make(chan error, len(subs))
+		for i := range subs {
+			go func(sub event.Subscription) {
+				if err, ok := <-sub.Err(); ok {
+					errc <- err
+				}
+			}(subs[i])
+		}
+		select {
+		case err := <-errc:
+			return err
+		case <-unsubbed:
+			return nil
+		}
+	})
+}
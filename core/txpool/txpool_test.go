@@ -0,0 +1,379 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+var errRejected = errors.New("rejected")
+
+// testSubPool is a minimal subpool accepting a single transaction type, used to
+// test the routing and aggregation logic of the coordinator.
+type testSubPool struct {
+	txType  byte
+	txs     map[common.Hash]*types.Transaction
+	pending map[common.Address]types.Transactions
+	private map[common.Hash]bool
+	feed    event.Feed
+	drops   event.Feed
+	stopped bool
+}
+
+func newTestSubPool(txType byte) *testSubPool {
+	return &testSubPool{
+		txType:  txType,
+		txs:     make(map[common.Hash]*types.Transaction),
+		pending: make(map[common.Address]types.Transactions),
+		private: make(map[common.Hash]bool),
+	}
+}
+
+func (p *testSubPool) Filter(tx *types.Transaction) bool       { return tx.Type() == p.txType }
+func (p *testSubPool) Has(hash common.Hash) bool               { return p.txs[hash] != nil }
+func (p *testSubPool) Get(hash common.Hash) *types.Transaction { return p.txs[hash] }
+
+func (p *testSubPool) Add(txs []*types.Transaction, local bool, sync bool) []error {
+	errs := make([]error, len(txs))
+	for i, tx := range txs {
+		if tx.Gas() == 0 {
+			errs[i] = errRejected
+			continue
+		}
+		p.txs[tx.Hash()] = tx
+		p.pending[common.Address{}] = append(p.pending[common.Address{}], tx)
+	}
+	p.feed.Send(core.NewTxsEvent{Txs: txs})
+	return errs
+}
+
+func (p *testSubPool) AddPrivate(tx *types.Transaction) error {
+	if err := p.Add([]*types.Transaction{tx}, true, true)[0]; err != nil {
+		return err
+	}
+	p.private[tx.Hash()] = true
+	return nil
+}
+
+func (p *testSubPool) IsPrivate(hash common.Hash) bool { return p.private[hash] }
+
+func (p *testSubPool) Pending(enforceTips bool) map[common.Address]types.Transactions {
+	return p.pending
+}
+
+func (p *testSubPool) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
+	return p.feed.Subscribe(ch)
+}
+
+func (p *testSubPool) SubscribeDropTxsEvent(ch chan<- core.DropTxsEvent) event.Subscription {
+	return p.drops.Subscribe(ch)
+}
+
+func (p *testSubPool) History(hash common.Hash) []core.TxHistoryEvent {
+	if p.txs[hash] == nil {
+		return nil
+	}
+	return []core.TxHistoryEvent{{Hash: hash, Kind: core.TxLifecycleAdded}}
+}
+
+func (p *testSubPool) Nonce(addr common.Address) uint64 { return uint64(len(p.txs)) }
+func (p *testSubPool) Stats() (int, int)                { return len(p.txs), 0 }
+
+func (p *testSubPool) Content() (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
+	return p.pending, nil
+}
+
+func (p *testSubPool) ContentFrom(addr common.Address) (types.Transactions, types.Transactions) {
+	return p.pending[addr], nil
+}
+
+func (p *testSubPool) Locals() []common.Address { return nil }
+
+func (p *testSubPool) Status(hashes []common.Hash) []core.TxStatus {
+	status := make([]core.TxStatus, len(hashes))
+	for i, hash := range hashes {
+		if p.txs[hash] != nil {
+			status[i] = core.TxStatusPending
+		}
+	}
+	return status
+}
+
+func (p *testSubPool) SetGasPrice(price *big.Int) {}
+func (p *testSubPool) Stop()                      { p.stopped = true }
+
+// Tests that transactions are routed to the subpool accepting them and that the
+// returned errors are pieced back together in the original order.
+func TestAddRouting(t *testing.T) {
+	var (
+		legacy  = newTestSubPool(types.LegacyTxType)
+		dynamic = newTestSubPool(types.DynamicFeeTxType)
+		pool    = New(legacy, dynamic)
+	)
+	txs := []*types.Transaction{
+		types.NewTx(&types.DynamicFeeTx{Nonce: 0, Gas: 21000}),
+		types.NewTx(&types.LegacyTx{Nonce: 1, Gas: 21000}),
+		types.NewTx(&types.AccessListTx{Nonce: 2, Gas: 21000}),
+		types.NewTx(&types.DynamicFeeTx{Nonce: 3}),
+		types.NewTx(&types.LegacyTx{Nonce: 4, Gas: 21000}),
+	}
+	errs := pool.AddRemotes(txs)
+	want := []error{nil, nil, core.ErrTxTypeNotSupported, errRejected, nil}
+	for i := range want {
+		if errs[i] != want[i] {
+			t.Errorf("tx %d: error mismatch: have %v, want %v", i, errs[i], want[i])
+		}
+	}
+	if !legacy.Has(txs[1].Hash()) || !legacy.Has(txs[4].Hash()) {
+		t.Errorf("legacy transactions not routed to legacy subpool")
+	}
+	if !dynamic.Has(txs[0].Hash()) || dynamic.Has(txs[3].Hash()) {
+		t.Errorf("dynamic fee transactions misrouted")
+	}
+	if !pool.Has(txs[0].Hash()) || pool.Get(txs[1].Hash()) == nil || pool.Has(txs[2].Hash()) {
+		t.Errorf("transaction lookup mismatch")
+	}
+	if pending, queued := pool.Stats(); pending != 3 || queued != 0 {
+		t.Errorf("stats mismatch: have %d/%d, want %d/%d", pending, queued, 3, 0)
+	}
+	// Transactions of the same account across subpools must be merged in nonce order
+	merged := pool.Pending(false)[common.Address{}]
+	if len(merged) != 3 {
+		t.Fatalf("pending count mismatch: have %d, want %d", len(merged), 3)
+	}
+	for i := 1; i < len(merged); i++ {
+		if merged[i-1].Nonce() > merged[i].Nonce() {
+			t.Errorf("pending not nonce sorted: %d before %d", merged[i-1].Nonce(), merged[i].Nonce())
+		}
+	}
+	status := pool.Status([]common.Hash{txs[0].Hash(), txs[1].Hash(), txs[2].Hash()})
+	if status[0] != core.TxStatusPending || status[1] != core.TxStatusPending || status[2] != core.TxStatusUnknown {
+		t.Errorf("status mismatch: have %v", status)
+	}
+	pool.Stop()
+	if !legacy.stopped || !dynamic.stopped {
+		t.Errorf("subpools not stopped")
+	}
+}
+
+// Tests that private transactions are routed to the subpool accepting them and
+// reported as private by the coordinator.
+func TestAddPrivateRouting(t *testing.T) {
+	var (
+		legacy  = newTestSubPool(types.LegacyTxType)
+		dynamic = newTestSubPool(types.DynamicFeeTxType)
+		pool    = New(legacy, dynamic)
+	)
+	private := types.NewTx(&types.DynamicFeeTx{Nonce: 0, Gas: 21000})
+	if err := pool.AddPrivate(private); err != nil {
+		t.Fatalf("failed to add private transaction: %v", err)
+	}
+	if !dynamic.IsPrivate(private.Hash()) || legacy.Has(private.Hash()) {
+		t.Errorf("private transaction misrouted")
+	}
+	if !pool.IsPrivate(private.Hash()) {
+		t.Errorf("private transaction not reported private")
+	}
+	public := types.NewTx(&types.LegacyTx{Nonce: 1, Gas: 21000})
+	pool.AddRemotes([]*types.Transaction{public})
+	if pool.IsPrivate(public.Hash()) {
+		t.Errorf("public transaction reported private")
+	}
+	if err := pool.AddPrivate(types.NewTx(&types.AccessListTx{Gas: 21000})); err != core.ErrTxTypeNotSupported {
+		t.Errorf("unsupported private transaction error mismatch: have %v, want %v", err, core.ErrTxTypeNotSupported)
+	}
+}
+
+// Tests that subpools not implementing the private extension are skipped by the
+// coordinator and refuse private transactions routed to them.
+func TestAddPrivateUnsupported(t *testing.T) {
+	var (
+		legacy = newTestSubPool(types.LegacyTxType)
+		pool   = New(struct{ SubPool }{legacy})
+	)
+	tx := types.NewTx(&types.LegacyTx{Gas: 21000})
+	if err := pool.AddPrivate(tx); err != ErrPrivateNotSupported {
+		t.Errorf("private transaction error mismatch: have %v, want %v", err, ErrPrivateNotSupported)
+	}
+	if legacy.Has(tx.Hash()) {
+		t.Errorf("private transaction added to unsupported subpool")
+	}
+	legacy.private[tx.Hash()] = true
+	if pool.IsPrivate(tx.Hash()) {
+		t.Errorf("private status queried from unsupported subpool")
+	}
+}
+
+// Tests that a single subscription receives the events of all the subpools and
+// that unsubscribing detaches it from all of them.
+func TestSubscribeJoined(t *testing.T) {
+	var (
+		legacy  = newTestSubPool(types.LegacyTxType)
+		dynamic = newTestSubPool(types.DynamicFeeTxType)
+		pool    = New(legacy, dynamic)
+	)
+	ch := make(chan core.NewTxsEvent, 2)
+	sub := pool.SubscribeNewTxsEvent(ch)
+
+	pool.AddRemotes([]*types.Transaction{
+		types.NewTx(&types.LegacyTx{Gas: 21000}),
+		types.NewTx(&types.DynamicFeeTx{Gas: 21000}),
+	})
+	for i := 0; i < 2; i++ {
+		select {
+		case <-ch:
+		case <-time.After(time.Second):
+			t.Fatalf("event %d not delivered", i)
+		}
+	}
+	sub.Unsubscribe()
+
+	if n := legacy.feed.Send(core.NewTxsEvent{}); n != 0 {
+		t.Errorf("legacy subpool still subscribed")
+	}
+	if n := dynamic.feed.Send(core.NewTxsEvent{}); n != 0 {
+		t.Errorf("dynamic subpool still subscribed")
+	}
+}
+
+// Tests that bundles are only returned for the block and timestamp range they
+// target in order of arrival, and that bundles targeting past blocks are dropped.
+func TestBundleFiltering(t *testing.T) {
+	pool := New(newTestSubPool(types.LegacyTxType))
+
+	if err := pool.AddBundle(&Bundle{BlockNumber: 1}, 0); err != ErrBundleEmpty {
+		t.Errorf("empty bundle error mismatch: have %v, want %v", err, ErrBundleEmpty)
+	}
+	if err := pool.AddBundle(&Bundle{Txs: types.Transactions{types.NewTx(&types.LegacyTx{})}, BlockNumber: 1, MinTimestamp: 2, MaxTimestamp: 1}, 0); err != ErrBundleTimestamp {
+		t.Errorf("inverted timestamp bundle error mismatch: have %v, want %v", err, ErrBundleTimestamp)
+	}
+	var (
+		first  = &Bundle{Txs: types.Transactions{types.NewTx(&types.LegacyTx{Nonce: 0})}, BlockNumber: 1}
+		window = &Bundle{Txs: types.Transactions{types.NewTx(&types.LegacyTx{Nonce: 1})}, BlockNumber: 1, MinTimestamp: 10, MaxTimestamp: 20}
+		second = &Bundle{Txs: types.Transactions{types.NewTx(&types.LegacyTx{Nonce: 2})}, BlockNumber: 2}
+	)
+	for _, bundle := range []*Bundle{first, window, second, first} {
+		if err := pool.AddBundle(bundle, 0); err != nil {
+			t.Fatalf("failed to add bundle: %v", err)
+		}
+	}
+	if bundles := pool.Bundles(1, 5); len(bundles) != 1 || bundles[0] != first {
+		t.Errorf("bundles before window mismatch: have %v", bundles)
+	}
+	if bundles := pool.Bundles(1, 15); len(bundles) != 2 || bundles[0] != first || bundles[1] != window {
+		t.Errorf("bundles within window mismatch: have %v, want arrival order", bundles)
+	}
+	if bundles := pool.Bundles(2, 15); len(bundles) != 1 || bundles[0] != second {
+		t.Errorf("next block bundles mismatch: have %v", bundles)
+	}
+	if bundles := pool.Bundles(1, 15); len(bundles) != 0 {
+		t.Errorf("expired bundles not dropped: have %d", len(bundles))
+	}
+}
+
+// validatingTestSubPool is a test subpool which also checks the stateless
+// validity of transactions, rejecting the ones without gas.
+type validatingTestSubPool struct {
+	*testSubPool
+}
+
+func (p *validatingTestSubPool) ValidateTxBasics(tx *types.Transaction) error {
+	if tx.Gas() == 0 {
+		return errRejected
+	}
+	return nil
+}
+
+// Tests that bundles are bounded in their target block, number of transactions
+// and size, and that their transactions are validated by the accepting subpool.
+func TestBundleLimits(t *testing.T) {
+	pool := New(&validatingTestSubPool{newTestSubPool(types.LegacyTxType)})
+
+	valid := types.Transactions{types.NewTx(&types.LegacyTx{Gas: 21000})}
+
+	if err := pool.AddBundle(&Bundle{Txs: valid, BlockNumber: 10}, 10); err != ErrBundleExpired {
+		t.Errorf("past bundle error mismatch: have %v, want %v", err, ErrBundleExpired)
+	}
+	if err := pool.AddBundle(&Bundle{Txs: valid, BlockNumber: 10 + maxBundleFutureBlocks + 1}, 10); !errors.Is(err, ErrBundleTooFar) {
+		t.Errorf("far future bundle error mismatch: have %v, want %v", err, ErrBundleTooFar)
+	}
+	if err := pool.AddBundle(&Bundle{Txs: valid, BlockNumber: 10 + maxBundleFutureBlocks}, 10); err != nil {
+		t.Errorf("failed to add bundle at the future limit: %v", err)
+	}
+	many := make(types.Transactions, maxBundleTxs+1)
+	for i := range many {
+		many[i] = types.NewTx(&types.LegacyTx{Nonce: uint64(i), Gas: 21000})
+	}
+	if err := pool.AddBundle(&Bundle{Txs: many, BlockNumber: 11}, 10); !errors.Is(err, ErrBundleTooLarge) {
+		t.Errorf("crowded bundle error mismatch: have %v, want %v", err, ErrBundleTooLarge)
+	}
+	large := types.Transactions{types.NewTx(&types.LegacyTx{Gas: 21000, Data: make([]byte, maxBundleSize)})}
+	if err := pool.AddBundle(&Bundle{Txs: large, BlockNumber: 11}, 10); !errors.Is(err, ErrBundleTooLarge) {
+		t.Errorf("oversized bundle error mismatch: have %v, want %v", err, ErrBundleTooLarge)
+	}
+	invalid := types.Transactions{valid[0], types.NewTx(&types.LegacyTx{Nonce: 1})}
+	if err := pool.AddBundle(&Bundle{Txs: invalid, BlockNumber: 11}, 10); !errors.Is(err, errRejected) {
+		t.Errorf("invalid transaction bundle error mismatch: have %v, want %v", err, errRejected)
+	}
+	unsupported := types.Transactions{types.NewTx(&types.DynamicFeeTx{Gas: 21000})}
+	if err := pool.AddBundle(&Bundle{Txs: unsupported, BlockNumber: 11}, 10); !errors.Is(err, core.ErrTxTypeNotSupported) {
+		t.Errorf("unsupported transaction bundle error mismatch: have %v, want %v", err, core.ErrTxTypeNotSupported)
+	}
+	if bundles := pool.Bundles(10+maxBundleFutureBlocks, 0); len(bundles) != 1 {
+		t.Errorf("bundle count mismatch: have %d, want %d", len(bundles), 1)
+	}
+}
+
+// Tests that lifecycle history is only gathered from subpools implementing the
+// history extension.
+func TestHistoryUnsupported(t *testing.T) {
+	var (
+		legacy  = newTestSubPool(types.LegacyTxType)
+		dynamic = newTestSubPool(types.DynamicFeeTxType)
+		pool    = New(struct{ SubPool }{legacy}, dynamic)
+	)
+	var (
+		public  = types.NewTx(&types.LegacyTx{Gas: 21000})
+		tracked = types.NewTx(&types.DynamicFeeTx{Gas: 21000})
+	)
+	pool.AddRemotes([]*types.Transaction{public, tracked})
+
+	if events := pool.History(public.Hash()); len(events) != 0 {
+		t.Errorf("history gathered from unsupported subpool: %v", events)
+	}
+	if events := pool.History(tracked.Hash()); len(events) != 1 {
+		t.Errorf("history event count mismatch: have %d, want %d", len(events), 1)
+	}
+	ch := make(chan core.DropTxsEvent, 1)
+	sub := pool.SubscribeDropTxsEvent(ch)
+	defer sub.Unsubscribe()
+
+	if n := legacy.drops.Send(core.DropTxsEvent{}); n != 0 {
+		t.Errorf("unsupported subpool subscribed to drop events")
+	}
+	if n := dynamic.drops.Send(core.DropTxsEvent{}); n != 1 {
+		t.Errorf("history subpool not subscribed to drop events")
+	}
+}
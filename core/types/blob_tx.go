@@ -0,0 +1,255 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// ErrSidecarMismatch is returned if the blobs, commitments and proofs of a blob
+// transaction sidecar do not match its versioned hashes.
+var ErrSidecarMismatch = errors.New("blob sidecar does not match versioned hashes")
+
+// BlobTx represents an EIP-4844 transaction.
+type BlobTx struct {
+	ChainID       *big.Int
+	Nonce         uint64
+	GasTipCap     *big.Int // a.k.a. maxPriorityFeePerGas
+	GasFeeCap     *big.Int // a.k.a. maxFeePerGas
+	Gas           uint64
+	To            common.Address
+	Value         *big.Int
+	Data          []byte
+	AccessList    AccessList
+	DataGasFeeCap *big.Int // a.k.a. maxFeePerDataGas
+	BlobHashes    []common.Hash
+
+	// A blob transaction can optionally contain blobs. This field must be set
+	// when BlobTx is used to create a transaction for signing. It is not part
+	// of the consensus encoding and only travels in the network encoding.
+	Sidecar *BlobTxSidecar `rlp:"-"`
+
+	// Signature values
+	V *big.Int `json:"v" gencodec:"required"`
+	R *big.Int `json:"r" gencodec:"required"`
+	S *big.Int `json:"s" gencodec:"required"`
+}
+
+// BlobTxSidecar contains the blobs of a blob transaction.
+type BlobTxSidecar struct {
+	Blobs       []kzg4844.Blob       // Blobs needed by the blob pool
+	Commitments []kzg4844.Commitment // Commitments needed by the blob pool
+	Proofs      []kzg4844.Proof      // Proofs needed by the blob pool
+}
+
+// BlobHashes computes the blob hashes of the given blobs.
+func (sc *BlobTxSidecar) BlobHashes() []common.Hash {
+	h := make([]common.Hash, len(sc.Commitments))
+	for i := range sc.Commitments {
+		h[i] = kzg4844.CalcBlobHashV1(&sc.Commitments[i])
+	}
+	return h
+}
+
+// encodedSize computes the RLP size of the sidecar elements. This does NOT return the
+// encoded size of the BlobTxSidecar, it's just a helper for tx.Size().
+func (sc *BlobTxSidecar) encodedSize() uint64 {
+	var blobs, commitments, proofs uint64
+	for i := range sc.Blobs {
+		blobs += rlp.BytesSize(sc.Blobs[i][:])
+	}
+	for i := range sc.Commitments {
+		commitments += rlp.BytesSize(sc.Commitments[i][:])
+	}
+	for i := range sc.Proofs {
+		proofs += rlp.BytesSize(sc.Proofs[i][:])
+	}
+	return rlp.ListSize(blobs) + rlp.ListSize(commitments) + rlp.ListSize(proofs)
+}
+
+// ValidateBlobs checks that the sidecar is well formed with respect to the given
+// versioned hashes: every blob must have a commitment and a proof, the commitments
+// must hash to the versioned hashes and the proofs must verify the blobs against
+// their commitments.
+func (sc *BlobTxSidecar) ValidateBlobs(hashes []common.Hash) error {
+	if len(sc.Blobs) != len(hashes) {
+		return fmt.Errorf("%w: invalid number of %d blobs compared to %d blob hashes", ErrSidecarMismatch, len(sc.Blobs), len(hashes))
+	}
+	if len(sc.Commitments) != len(hashes) {
+		return fmt.Errorf("%w: invalid number of %d blob commitments compared to %d blob hashes", ErrSidecarMismatch, len(sc.Commitments), len(hashes))
+	}
+	if len(sc.Proofs) != len(hashes) {
+		return fmt.Errorf("%w: invalid number of %d blob proofs compared to %d blob hashes", ErrSidecarMismatch, len(sc.Proofs), len(hashes))
+	}
+	for i, vhash := range sc.BlobHashes() {
+		if vhash != hashes[i] {
+			return fmt.Errorf("%w: blob %d: computed hash %#x mismatches transaction one %#x", ErrSidecarMismatch, i, vhash, hashes[i])
+		}
+	}
+	for i := range sc.Blobs {
+		if err := kzg4844.VerifyBlobProof(sc.Blobs[i], sc.Commitments[i], sc.Proofs[i]); err != nil {
+			return fmt.Errorf("invalid blob %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// blobTxWithBlobs is used for encoding of transactions when blobs are present.
+type blobTxWithBlobs struct {
+	BlobTx      *BlobTx
+	Blobs       []kzg4844.Blob
+	Commitments []kzg4844.Commitment
+	Proofs      []kzg4844.Proof
+}
+
+// copy creates a deep copy of the transaction data and initializes all fields.
+func (tx *BlobTx) copy() TxData {
+	cpy := &BlobTx{
+		Nonce: tx.Nonce,
+		To:    tx.To,
+		Data:  common.CopyBytes(tx.Data),
+		Gas:   tx.Gas,
+		// These are copied below.
+		AccessList:    make(AccessList, len(tx.AccessList)),
+		BlobHashes:    make([]common.Hash, len(tx.BlobHashes)),
+		Value:         new(big.Int),
+		ChainID:       new(big.Int),
+		GasTipCap:     new(big.Int),
+		GasFeeCap:     new(big.Int),
+		DataGasFeeCap: new(big.Int),
+		V:             new(big.Int),
+		R:             new(big.Int),
+		S:             new(big.Int),
+	}
+	copy(cpy.AccessList, tx.AccessList)
+	copy(cpy.BlobHashes, tx.BlobHashes)
+
+	if tx.Value != nil {
+		cpy.Value.Set(tx.Value)
+	}
+	if tx.ChainID != nil {
+		cpy.ChainID.Set(tx.ChainID)
+	}
+	if tx.GasTipCap != nil {
+		cpy.GasTipCap.Set(tx.GasTipCap)
+	}
+	if tx.GasFeeCap != nil {
+		cpy.GasFeeCap.Set(tx.GasFeeCap)
+	}
+	if tx.DataGasFeeCap != nil {
+		cpy.DataGasFeeCap.Set(tx.DataGasFeeCap)
+	}
+	if tx.V != nil {
+		cpy.V.Set(tx.V)
+	}
+	if tx.R != nil {
+		cpy.R.Set(tx.R)
+	}
+	if tx.S != nil {
+		cpy.S.Set(tx.S)
+	}
+	if tx.Sidecar != nil {
+		cpy.Sidecar = &BlobTxSidecar{
+			Blobs:       append([]kzg4844.Blob(nil), tx.Sidecar.Blobs...),
+			Commitments: append([]kzg4844.Commitment(nil), tx.Sidecar.Commitments...),
+			Proofs:      append([]kzg4844.Proof(nil), tx.Sidecar.Proofs...),
+		}
+	}
+	return cpy
+}
+
+// accessors for innerTx.
+func (tx *BlobTx) txType() byte           { return BlobTxType }
+func (tx *BlobTx) chainID() *big.Int      { return tx.ChainID }
+func (tx *BlobTx) accessList() AccessList { return tx.AccessList }
+func (tx *BlobTx) data() []byte           { return tx.Data }
+func (tx *BlobTx) gas() uint64            { return tx.Gas }
+func (tx *BlobTx) gasFeeCap() *big.Int    { return tx.GasFeeCap }
+func (tx *BlobTx) gasTipCap() *big.Int    { return tx.GasTipCap }
+func (tx *BlobTx) gasPrice() *big.Int     { return tx.GasFeeCap }
+func (tx *BlobTx) value() *big.Int        { return tx.Value }
+func (tx *BlobTx) nonce() uint64          { return tx.Nonce }
+func (tx *BlobTx) to() *common.Address    { tmp := tx.To; return &tmp }
+func (tx *BlobTx) dataGas() uint64        { return params.BlobTxDataGasPerBlob * uint64(len(tx.BlobHashes)) }
+
+func (tx *BlobTx) rawSignatureValues() (v, r, s *big.Int) {
+	return tx.V, tx.R, tx.S
+}
+
+func (tx *BlobTx) setSignatureValues(chainID, v, r, s *big.Int) {
+	tx.ChainID, tx.V, tx.R, tx.S = chainID, v, r, s
+}
+
+// withoutSidecar returns a shallow copy of the transaction data with the
+// sidecar stripped off.
+func (tx *BlobTx) withoutSidecar() *BlobTx {
+	cpy := *tx
+	cpy.Sidecar = nil
+	return &cpy
+}
+
+// encode writes the network encoding of the transaction: the canonical encoding
+// if the sidecar is missing, or the transaction wrapped together with its blobs
+// otherwise.
+func (tx *BlobTx) encode(w *bytes.Buffer) error {
+	if tx.Sidecar == nil {
+		return rlp.Encode(w, tx)
+	}
+	inner := &blobTxWithBlobs{
+		BlobTx:      tx,
+		Blobs:       tx.Sidecar.Blobs,
+		Commitments: tx.Sidecar.Commitments,
+		Proofs:      tx.Sidecar.Proofs,
+	}
+	return rlp.Encode(w, inner)
+}
+
+// decode parses either the canonical or the network encoding of the transaction.
+// The two formats are distinguished by whether the first element of the outer
+// list is itself a list.
+func (tx *BlobTx) decode(input []byte) error {
+	outer, _, err := rlp.SplitList(input)
+	if err != nil {
+		return err
+	}
+	kind, _, _, err := rlp.Split(outer)
+	if err != nil {
+		return err
+	}
+	if kind != rlp.List {
+		return rlp.DecodeBytes(input, tx)
+	}
+	var inner blobTxWithBlobs
+	if err := rlp.DecodeBytes(input, &inner); err != nil {
+		return err
+	}
+	*tx = *inner.BlobTx
+	tx.Sidecar = &BlobTxSidecar{
+		Blobs:       inner.Blobs,
+		Commitments: inner.Commitments,
+		Proofs:      inner.Proofs,
+	}
+	return nil
+}
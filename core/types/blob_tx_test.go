@@ -0,0 +1,129 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+)
+
+var (
+	emptyBlob          = kzg4844.Blob{}
+	emptyBlobCommit, _ = kzg4844.BlobToCommitment(emptyBlob)
+	emptyBlobProof, _  = kzg4844.ComputeBlobProof(emptyBlob, emptyBlobCommit)
+)
+
+func createBlobTx(t *testing.T, withSidecar bool) *Transaction {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+	sidecar := &BlobTxSidecar{
+		Blobs:       []kzg4844.Blob{emptyBlob},
+		Commitments: []kzg4844.Commitment{emptyBlobCommit},
+		Proofs:      []kzg4844.Proof{emptyBlobProof},
+	}
+	blobtx := &BlobTx{
+		ChainID:       big.NewInt(1),
+		Nonce:         5,
+		GasTipCap:     big.NewInt(22),
+		GasFeeCap:     big.NewInt(5),
+		Gas:           25000,
+		To:            common.Address{0x03, 0x04, 0x05},
+		Value:         big.NewInt(99),
+		Data:          make([]byte, 50),
+		DataGasFeeCap: big.NewInt(15),
+		BlobHashes:    sidecar.BlobHashes(),
+	}
+	if withSidecar {
+		blobtx.Sidecar = sidecar
+	}
+	tx, err := SignNewTx(key, NewCancunSigner(common.Big1), blobtx)
+	if err != nil {
+		t.Fatalf("could not sign transaction: %v", err)
+	}
+	return tx
+}
+
+// TestBlobTxCoding tests that blob transactions round-trip through both their
+// network (with sidecar) and canonical (without sidecar) binary encodings.
+func TestBlobTxCoding(t *testing.T) {
+	tx := createBlobTx(t, true)
+
+	parsed, err := encodeDecodeBinary(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := assertEqual(parsed, tx); err != nil {
+		t.Fatal(err)
+	}
+	if parsed.BlobTxSidecar() == nil {
+		t.Fatal("sidecar lost in network encoding")
+	}
+	if err := parsed.BlobTxSidecar().ValidateBlobs(parsed.BlobHashes()); err != nil {
+		t.Fatalf("decoded sidecar invalid: %v", err)
+	}
+	if have, want := parsed.DataGas(), uint64(131072); have != want {
+		t.Fatalf("data gas mismatch: have %d, want %d", have, want)
+	}
+	// Stripping the sidecar must retain the hash and yield the canonical form
+	stripped := tx.WithoutBlobTxSidecar()
+	if stripped.BlobTxSidecar() != nil {
+		t.Fatal("sidecar not stripped")
+	}
+	if tx.BlobTxSidecar() == nil {
+		t.Fatal("stripping mutated the original transaction")
+	}
+	if stripped.Hash() != tx.Hash() {
+		t.Fatalf("hash mismatch after stripping: have %x, want %x", stripped.Hash(), tx.Hash())
+	}
+	if stripped.Size() >= tx.Size() {
+		t.Fatalf("stripped size %v not smaller than full size %v", stripped.Size(), tx.Size())
+	}
+	parsed, err = encodeDecodeBinary(stripped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := assertEqual(parsed, tx); err != nil {
+		t.Fatal(err)
+	}
+	if parsed.BlobTxSidecar() != nil {
+		t.Fatal("sidecar present in canonical encoding")
+	}
+}
+
+// TestBlobTxSidecarValidation tests that sidecars not matching the versioned
+// hashes of the transaction are rejected.
+func TestBlobTxSidecarValidation(t *testing.T) {
+	tx := createBlobTx(t, true)
+	sidecar := tx.BlobTxSidecar()
+
+	if err := sidecar.ValidateBlobs(tx.BlobHashes()); err != nil {
+		t.Fatalf("valid sidecar rejected: %v", err)
+	}
+	if err := sidecar.ValidateBlobs(nil); !errors.Is(err, ErrSidecarMismatch) {
+		t.Fatalf("blob count mismatch: have %v, want %v", err, ErrSidecarMismatch)
+	}
+	if err := sidecar.ValidateBlobs([]common.Hash{{0x01}}); !errors.Is(err, ErrSidecarMismatch) {
+		t.Fatalf("blob hash mismatch: have %v, want %v", err, ErrSidecarMismatch)
+	}
+}
@@ -194,6 +194,48 @@ func TestEIP2718BlockEncoding(t *testing.T) {
 	}
 }
 
+func TestWithdrawalsBlockEncoding(t *testing.T) {
+	header := &Header{
+		Difficulty: big.NewInt(0),
+		Number:     big.NewInt(1),
+		GasLimit:   30000000,
+		BaseFee:    big.NewInt(params.InitialBaseFee),
+	}
+	withdrawals := []*Withdrawal{
+		{Index: 0, Validator: 1, Address: common.HexToAddress("0x1000"), Amount: 32},
+		{Index: 1, Validator: 2, Address: common.HexToAddress("0x2000"), Amount: 1},
+	}
+	check := func(f string, got, want interface{}) {
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s mismatch: got %v, want %v", f, got, want)
+		}
+	}
+	// Blocks without withdrawals must keep the legacy encoding
+	legacy := NewBlockWithWithdrawals(header, nil, nil, nil, nil, newHasher())
+	check("WithdrawalsHash", legacy.Header().WithdrawalsHash, (*common.Hash)(nil))
+
+	empty := NewBlockWithWithdrawals(header, nil, nil, nil, []*Withdrawal{}, newHasher())
+	check("WithdrawalsHash", *empty.Header().WithdrawalsHash, EmptyRootHash)
+
+	block := NewBlockWithWithdrawals(header, nil, nil, nil, withdrawals, newHasher())
+	check("WithdrawalsHash", *block.Header().WithdrawalsHash, DeriveSha(Withdrawals(withdrawals), newHasher()))
+
+	enc, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		t.Fatal("encode error: ", err)
+	}
+	var dec Block
+	if err := rlp.DecodeBytes(enc, &dec); err != nil {
+		t.Fatal("decode error: ", err)
+	}
+	check("Hash", dec.Hash(), block.Hash())
+	check("len(Withdrawals)", len(dec.Withdrawals()), len(withdrawals))
+	for i, w := range dec.Withdrawals() {
+		check("Withdrawal", w, withdrawals[i])
+	}
+	check("Size", dec.Size(), common.StorageSize(len(enc)))
+}
+
 func TestUncleHash(t *testing.T) {
 	uncles := make([]*Header, 0)
 	h := CalcUncleHash(uncles)
@@ -116,3 +116,50 @@ func codeBitmapInternal(code, bits bitvec) bitvec {
 	}
 	return bits
 }
+
+// eofCodeBitmap collects data locations in code of an EOF code section, which
+// includes the immediate arguments of the relative jump and function call
+// instructions in addition to PUSH data.
+func eofCodeBitmap(code []byte) bitvec {
+	// The bitmap is 4 bytes longer than necessary, in case the code
+	// ends with a PUSH32, the algorithm will push zeroes onto the
+	// bitvector outside the bounds of the actual code.
+	bits := make(bitvec, len(code)/8+1+4)
+	return eofCodeBitmapInternal(code, bits)
+}
+
+// eofCodeBitmapInternal is the internal implementation of eofCodeBitmap.
+func eofCodeBitmapInternal(code, bits bitvec) bitvec {
+	for pc := uint64(0); pc < uint64(len(code)); {
+		var (
+			op      = OpCode(code[pc])
+			numbits uint16
+		)
+		pc++
+
+		switch {
+		case op >= PUSH1 && op <= PUSH32:
+			numbits = uint16(op - PUSH1 + 1)
+		case op == RJUMP || op == RJUMPI || op == CALLF:
+			numbits = 2
+		case op == RJUMPV:
+			// RJUMPV is unique as it has a variable sized operand. The total
+			// size is determined by the count byte which immediately follows
+			// RJUMPV.
+			if pc < uint64(len(code)) {
+				numbits = 1 + uint16(code[pc])*2
+			}
+		}
+		// Mark the immediate bytes one by one, as RJUMPV operands may be
+		// larger than the bitmap's fast paths support.
+		for ; numbits >= 8; numbits -= 8 {
+			bits.set8(pc)
+			pc += 8
+		}
+		for ; numbits > 0; numbits-- {
+			bits.set1(pc)
+			pc++
+		}
+	}
+	return bits
+}
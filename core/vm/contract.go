@@ -53,10 +53,11 @@ type Contract struct {
 	jumpdests map[common.Hash]bitvec // Aggregated result of JUMPDEST analysis.
 	analysis  bitvec                 // Locally cached result of JUMPDEST analysis
 
-	Code     []byte
-	CodeHash common.Hash
-	CodeAddr *common.Address
-	Input    []byte
+	Code      []byte
+	Container *Container // Parsed EOF container, nil for legacy code
+	CodeHash  common.Hash
+	CodeAddr  *common.Address
+	Input     []byte
 
 	Gas   uint64
 	value *big.Int
@@ -150,6 +151,29 @@ func (c *Contract) GetOp(n uint64) OpCode {
 	return STOP
 }
 
+// getOp returns the n'th element in the given code section of an EOF contract,
+// or in the contract's byte array for legacy code.
+func (c *Contract) getOp(section uint64, n uint64) OpCode {
+	if code := c.CodeAt(section); n < uint64(len(code)) {
+		return OpCode(code[n])
+	}
+	return STOP
+}
+
+// IsEOF returns whether the contract's code is an EOF container.
+func (c *Contract) IsEOF() bool {
+	return c.Container != nil
+}
+
+// CodeAt returns the given code section of an EOF contract, or the entire code
+// of a legacy contract.
+func (c *Contract) CodeAt(section uint64) []byte {
+	if c.Container == nil {
+		return c.Code
+	}
+	return c.Container.Code[section]
+}
+
 // Caller returns the caller of the contract.
 //
 // Caller will recursively call caller when the contract is a delegate
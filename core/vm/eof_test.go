@@ -0,0 +1,180 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"errors"
+	"io"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestEOFMarshaling(t *testing.T) {
+	for i, test := range []struct {
+		want Container
+		err  error
+	}{
+		{
+			want: Container{
+				Types: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 1}},
+				Code:  [][]byte{{byte(PUSH1), 0x00, byte(STOP)}},
+				Data:  []byte{},
+			},
+		},
+		{
+			want: Container{
+				Types: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 1}},
+				Code:  [][]byte{{byte(PUSH1), 0x00, byte(STOP)}},
+				Data:  []byte{0x01, 0x02, 0x03},
+			},
+		},
+		{
+			want: Container{
+				Types: []*FunctionMetadata{
+					{Input: 0, Output: 0, MaxStackHeight: 1},
+					{Input: 2, Output: 3, MaxStackHeight: 4},
+					{Input: 1, Output: 1, MaxStackHeight: 1},
+				},
+				Code: [][]byte{
+					{0x7f, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+					{byte(CALLF)},
+					{byte(RETF)},
+				},
+				Data: []byte{},
+			},
+		},
+	} {
+		var (
+			b   = test.want.MarshalBinary()
+			got Container
+		)
+		if err := got.UnmarshalBinary(b); err != nil && err != test.err {
+			t.Fatalf("test %d: got error \"%v\", want \"%v\"", i, err, test.err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Fatalf("test %d: got %+v, want %+v", i, got, test.want)
+		}
+	}
+}
+
+func TestEOFUnmarshalErrors(t *testing.T) {
+	valid := (&Container{
+		Types: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 0}},
+		Code:  [][]byte{{byte(STOP)}},
+		Data:  []byte{},
+	}).MarshalBinary()
+
+	mutate := func(fn func(b []byte) []byte) []byte {
+		return fn(append([]byte{}, valid...))
+	}
+	for i, test := range []struct {
+		code []byte
+		err  error
+	}{
+		{[]byte{0xef, 0x01}, ErrInvalidMagic},
+		{valid[:10], io.ErrUnexpectedEOF},
+		{mutate(func(b []byte) []byte { b[2] = 2; return b }), ErrInvalidVersion},
+		{mutate(func(b []byte) []byte { b[3] = kindCode; return b }), ErrMissingTypeHeader},
+		{mutate(func(b []byte) []byte { b[5] = 3; return b }), ErrInvalidTypeSize},
+		{mutate(func(b []byte) []byte { b[6] = kindData; return b }), ErrMissingCodeHeader},
+		{mutate(func(b []byte) []byte { b[11] = kindCode; return b }), ErrMissingDataHeader},
+		{mutate(func(b []byte) []byte { b[14] = 1; return b }), ErrMissingTerminator},
+		{mutate(func(b []byte) []byte { return append(b, 0x00) }), ErrInvalidContainerSize},
+		{mutate(func(b []byte) []byte { b[15] = 1; return b }), ErrInvalidSection0Type},
+		{mutate(func(b []byte) []byte { b[17] = 0x04; return b }), ErrTooLargeMaxStackHeight},
+	} {
+		var c Container
+		if err := c.UnmarshalBinary(test.code); !errors.Is(err, test.err) {
+			t.Errorf("test %d: got error \"%v\", want \"%v\"", i, err, test.err)
+		}
+	}
+}
+
+func TestEOFExecution(t *testing.T) {
+	var (
+		address = common.BytesToAddress([]byte("contract"))
+		config  = *params.AllGashProtocolChanges
+		vmctx   = BlockContext{
+			CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+			Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+			BlockNumber: new(big.Int),
+		}
+	)
+	config.PragueBlock = new(big.Int)
+
+	container := &Container{
+		Types: []*FunctionMetadata{
+			{Input: 0, Output: 0, MaxStackHeight: 2},
+			{Input: 0, Output: 1, MaxStackHeight: 1},
+		},
+		Code: [][]byte{
+			{
+				byte(PUSH1), 0x01,
+				byte(RJUMPI), 0x00, 0x01,
+				byte(INVALID),
+				byte(CALLF), 0x00, 0x01, // jumps to here
+				byte(PUSH1), 0x00,
+				byte(MSTORE),
+				byte(PUSH1), 0x20,
+				byte(PUSH1), 0x00,
+				byte(RETURN),
+			},
+			{
+				byte(PUSH1), 0x2a,
+				byte(RETF),
+			},
+		},
+		Data: []byte{},
+	}
+	code := container.MarshalBinary()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.CreateAccount(address)
+	statedb.SetCode(address, code)
+	statedb.Finalise(true)
+
+	evm := NewEVM(vmctx, TxContext{}, statedb, &config, Config{})
+	ret, _, err := evm.Call(AccountRef(common.Address{}), address, nil, 100000, new(big.Int))
+	if err != nil {
+		t.Fatalf("failed to execute eof code: %v", err)
+	}
+	if want := common.LeftPadBytes([]byte{0x2a}, 32); !reflect.DeepEqual(ret, want) {
+		t.Fatalf("return data mismatch: have %x, want %x", ret, want)
+	}
+	// The validated container must be cached by code hash
+	if c, ok := evm.containers[statedb.GetCodeHash(address)]; !ok || c == nil {
+		t.Fatal("validated container not cached")
+	}
+	// Deploying invalid EOF initcode must fail
+	container.Types[0].MaxStackHeight = 3
+	if _, _, _, err := evm.Create(AccountRef(common.Address{}), container.MarshalBinary(), 100000, new(big.Int)); !errors.Is(err, ErrInvalidEOFInitcode) {
+		t.Fatalf("invalid initcode error mismatch: have %v, want %v", err, ErrInvalidEOFInitcode)
+	}
+	// Invalid EOF code placed into the state directly must not be executed
+	container.Types[0].MaxStackHeight = 2
+	container.Code[0][8] = 0x05 // CALLF to a missing section
+	statedb.SetCode(address, container.MarshalBinary())
+	if _, _, err := evm.Call(AccountRef(common.Address{}), address, nil, 100000, new(big.Int)); err == nil {
+		t.Fatal("invalid eof code executed")
+	}
+}
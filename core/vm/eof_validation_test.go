@@ -0,0 +1,159 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateCode(t *testing.T) {
+	for i, test := range []struct {
+		code     []byte
+		section  int
+		metadata []*FunctionMetadata
+		err      error
+	}{
+		{
+			code:     []byte{byte(CALLER), byte(POP), byte(STOP)},
+			metadata: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 1}},
+		},
+		{
+			code:     []byte{byte(CALLF), 0x00, 0x00, byte(STOP)},
+			metadata: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 0}},
+		},
+		{
+			code:     []byte{byte(ADDRESS), byte(CALLF), 0x00, 0x00, byte(STOP)},
+			metadata: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 1}},
+		},
+		{
+			code:     []byte{byte(CALLER), byte(POP)},
+			metadata: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 1}},
+			err:      ErrInvalidCodeTermination,
+		},
+		{
+			code: []byte{
+				byte(RJUMP), 0x00, 0x01,
+				byte(CALLER),
+				byte(STOP),
+			},
+			metadata: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 0}},
+			err:      ErrUnreachableCode,
+		},
+		{
+			code: []byte{
+				byte(PUSH1), 0x42, byte(ADD),
+				byte(STOP),
+			},
+			metadata: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 1}},
+			err:      ErrEOFStackUnderflow,
+		},
+		{
+			code: []byte{
+				byte(PUSH1), 0x42, byte(POP),
+				byte(STOP),
+			},
+			metadata: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 2}},
+			err:      ErrInvalidMaxStackHeight,
+		},
+		{
+			code: []byte{
+				0x0c, // unassigned
+				byte(RJUMPI), 0x00, 0x01,
+				byte(PUSH1), 0x42, // jumps to here
+				byte(POP),
+				byte(STOP),
+			},
+			metadata: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 1}},
+			err:      ErrUndefinedInstruction,
+		},
+		{
+			code: []byte{
+				byte(ADDRESS),
+				byte(RJUMPI), 0x00, 0x01,
+				byte(PUSH1), 0x42, // jumps to here
+				byte(POP),
+				byte(STOP),
+			},
+			metadata: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 1}},
+			err:      ErrInvalidJumpDest,
+		},
+		{
+			code: []byte{
+				byte(ADDRESS),
+				byte(RJUMPV),
+				0x02,
+				0x00,
+				0x01,
+				0x00,
+				0x02,
+				byte(PUSH1), 0x42, // jumps to here
+				byte(POP), // and here
+				byte(STOP),
+			},
+			metadata: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 1}},
+			err:      ErrInvalidJumpDest,
+		},
+		{
+			code: []byte{
+				byte(ADDRESS),
+				byte(RJUMPV),
+				0x00,
+				byte(STOP),
+			},
+			metadata: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 1}},
+			err:      ErrInvalidBranchCount,
+		},
+		{
+			code: []byte{
+				byte(RETF),
+			},
+			section:  1,
+			metadata: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 0}, {Input: 1, Output: 1, MaxStackHeight: 1}},
+		},
+		{
+			code: []byte{
+				byte(CALLF), 0x00, 0x01,
+				byte(POP),
+				byte(STOP),
+			},
+			metadata: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 1}, {Input: 0, Output: 1, MaxStackHeight: 1}},
+		},
+		{
+			code: []byte{
+				byte(CALLF), 0x00, 0x02,
+				byte(STOP),
+			},
+			metadata: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 0}},
+			err:      ErrInvalidSectionArgument,
+		},
+		{
+			code: []byte{
+				byte(JUMPDEST),
+				byte(PC),
+				byte(STOP),
+			},
+			metadata: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 1}},
+			err:      ErrUndefinedInstruction,
+		},
+	} {
+		err := validateCode(test.code, test.section, test.metadata, &pragueEOFInstructionSet)
+		if !errors.Is(err, test.err) {
+			t.Errorf("test %d (%x): unexpected error (want: %v, got: %v)", i, test.code, test.err, err)
+		}
+	}
+}
@@ -17,6 +17,7 @@
 package vm
 
 import (
+	"fmt"
 	"math/big"
 	"sync/atomic"
 	"time"
@@ -44,6 +45,8 @@ type (
 func (evm *EVM) precompile(addr common.Address) (PrecompiledContract, bool) {
 	var precompiles map[common.Address]PrecompiledContract
 	switch {
+	case evm.chainRules.IsCancun:
+		precompiles = PrecompiledContractsCancun
 	case evm.chainRules.IsBerlin:
 		precompiles = PrecompiledContractsBerlin
 	case evm.chainRules.IsIstanbul:
@@ -76,14 +79,17 @@ type BlockContext struct {
 	Difficulty  *big.Int       // Provides information for DIFFICULTY
 	BaseFee     *big.Int       // Provides information for BASEFEE
 	Random      *common.Hash   // Provides information for PREVRANDAO
+
+	ExcessDataGas *uint64 // Provides information for the data gas fee (post cancun)
 }
 
 // TxContext provides the EVM with information about a transaction.
 // All fields can change between transactions.
 type TxContext struct {
 	// Message information
-	Origin   common.Address // Provides information for ORIGIN
-	GasPrice *big.Int       // Provides information for GASPRICE
+	Origin     common.Address // Provides information for ORIGIN
+	GasPrice   *big.Int       // Provides information for GASPRICE
+	BlobHashes []common.Hash  // Provides information for DATAHASH
 }
 
 // EVM is the Ethereum Virtual Machine base object and provides
@@ -121,6 +127,9 @@ type EVM struct {
 	// available gas is calculated in gasCall* according to the 63/64 rule and later
 	// applied in opCall*.
 	callGasTemp uint64
+	// containers caches the EOF containers parsed from deployed code by code
+	// hash, nil marking code which is not a valid container.
+	containers map[common.Hash]*Container
 }
 
 // NewEVM returns a new EVM. The returned EVM is not thread safe and should
@@ -225,6 +234,7 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 			// The depth-check is already done, and precompiles handled above
 			contract := NewContract(caller, AccountRef(addrCopy), value, gas)
 			contract.SetCallCode(&addrCopy, evm.StateDB.GetCodeHash(addrCopy), code)
+			contract.Container = evm.parseContainer(contract.CodeHash, contract.Code)
 			ret, err = evm.interpreter.Run(contract, input, false)
 			gas = contract.Gas
 		}
@@ -282,6 +292,7 @@ func (evm *EVM) CallCode(caller ContractRef, addr common.Address, input []byte,
 		// The contract is a scoped environment for this execution context only.
 		contract := NewContract(caller, AccountRef(caller.Address()), value, gas)
 		contract.SetCallCode(&addrCopy, evm.StateDB.GetCodeHash(addrCopy), evm.StateDB.GetCode(addrCopy))
+		contract.Container = evm.parseContainer(contract.CodeHash, contract.Code)
 		ret, err = evm.interpreter.Run(contract, input, false)
 		gas = contract.Gas
 	}
@@ -322,6 +333,7 @@ func (evm *EVM) DelegateCall(caller ContractRef, addr common.Address, input []by
 		// Initialise a new contract and make initialise the delegate values
 		contract := NewContract(caller, AccountRef(caller.Address()), nil, gas).AsDelegate()
 		contract.SetCallCode(&addrCopy, evm.StateDB.GetCodeHash(addrCopy), evm.StateDB.GetCode(addrCopy))
+		contract.Container = evm.parseContainer(contract.CodeHash, contract.Code)
 		ret, err = evm.interpreter.Run(contract, input, false)
 		gas = contract.Gas
 	}
@@ -375,6 +387,7 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 		// The contract is a scoped environment for this execution context only.
 		contract := NewContract(caller, AccountRef(addrCopy), new(big.Int), gas)
 		contract.SetCallCode(&addrCopy, evm.StateDB.GetCodeHash(addrCopy), evm.StateDB.GetCode(addrCopy))
+		contract.Container = evm.parseContainer(contract.CodeHash, contract.Code)
 		// When an error was returned by the EVM or when setting the creation code
 		// above we revert to the snapshot and consume any gas remaining. Additionally
 		// when we're in Homestead this also counts for code storage gas errors.
@@ -440,6 +453,15 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 	contract := NewContract(caller, AccountRef(address), value, gas)
 	contract.SetCodeOptionalHash(&address, codeAndHash)
 
+	// Validate the initcode if it is an EOF container, failing the creation if invalid.
+	var err error
+	if evm.chainRules.IsPrague && HasEOFMagic(codeAndHash.code) {
+		contract.Container, err = evm.validateContainer(codeAndHash.code)
+		if err != nil {
+			err = fmt.Errorf("%w: %v", ErrInvalidEOFInitcode, err)
+		}
+	}
+
 	if evm.Config.Debug {
 		if evm.depth == 0 {
 			evm.Config.Tracer.CaptureStart(evm, caller.Address(), address, true, codeAndHash.code, gas, value)
@@ -450,16 +472,28 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 
 	start := time.Now()
 
-	ret, err := evm.interpreter.Run(contract, nil, false)
+	var ret []byte
+	if err == nil {
+		ret, err = evm.interpreter.Run(contract, nil, false)
+	}
 
 	// Check whether the max code size has been exceeded, assign err if the case.
 	if err == nil && evm.chainRules.IsEIP158 && len(ret) > params.MaxCodeSize {
 		err = ErrMaxCodeSizeExceeded
 	}
 
-	// Reject code starting with 0xEF if EIP-3541 is enabled.
+	// Reject code starting with 0xEF if EIP-3541 is enabled, unless it is a
+	// valid EOF container deployed by EOF initcode.
 	if err == nil && len(ret) >= 1 && ret[0] == 0xEF && evm.chainRules.IsLondon {
-		err = ErrInvalidCode
+		if !contract.IsEOF() {
+			err = ErrInvalidCode
+		} else if _, verr := evm.validateContainer(ret); verr != nil {
+			err = fmt.Errorf("%w: %v", ErrInvalidCode, verr)
+		}
+	}
+	// EOF initcode may only deploy EOF code.
+	if err == nil && contract.IsEOF() && !HasEOFMagic(ret) {
+		err = ErrLegacyCode
 	}
 
 	// if the contract creation ran successfully and no errors were returned
@@ -511,5 +545,44 @@ func (evm *EVM) Create2(caller ContractRef, code []byte, gas uint64, endowment *
 	return evm.create(caller, codeAndHash, gas, endowment, contractAddr, CREATE2)
 }
 
+// parseContainer tries to parse an EOF container from the given code if EOF is
+// enabled. It returns nil for legacy code.
+//
+// Deployed EOF code is validated upon creation, but code can also be placed into
+// the state directly (genesis allocations, state overrides), so the container is
+// validated again before execution. Code that fails validation is treated as
+// legacy code, which aborts on its leading 0xEF byte. The result is cached by
+// code hash, so repeated calls into the same code are only validated once.
+func (evm *EVM) parseContainer(hash common.Hash, code []byte) *Container {
+	if !evm.chainRules.IsPrague || !HasEOFMagic(code) {
+		return nil
+	}
+	if c, ok := evm.containers[hash]; ok {
+		return c
+	}
+	c, err := evm.validateContainer(code)
+	if err != nil {
+		c = nil
+	}
+	if evm.containers == nil {
+		evm.containers = make(map[common.Hash]*Container)
+	}
+	evm.containers[hash] = c
+	return c
+}
+
+// validateContainer decodes the given code as an EOF container and validates
+// all of its code sections.
+func (evm *EVM) validateContainer(code []byte) (*Container, error) {
+	var c Container
+	if err := c.UnmarshalBinary(code); err != nil {
+		return nil, err
+	}
+	if err := c.ValidateCode(evm.interpreter.eofTable); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
 // ChainConfig returns the environment's chain configuration
 func (evm *EVM) ChainConfig() *params.ChainConfig { return evm.chainConfig }
@@ -41,12 +41,23 @@ type ScopeContext struct {
 	Memory   *Memory
 	Stack    *Stack
 	Contract *Contract
+
+	CodeSection uint64           // Currently executing code section of an EOF contract
+	ReturnStack []*ReturnContext // Return contexts of the active EOF function calls
+}
+
+// ReturnContext represents a returnable caller frame of an EOF function call.
+type ReturnContext struct {
+	Section     uint64 // Code section of the caller
+	Pc          uint64 // Program counter to resume execution at in the caller
+	StackHeight int    // Stack height of the caller, excluding the callee inputs
 }
 
 // EVMInterpreter represents an EVM interpreter
 type EVMInterpreter struct {
-	evm *EVM
-	cfg Config
+	evm      *EVM
+	cfg      Config
+	eofTable *JumpTable // EVM instruction table for EOF formatted code
 
 	hasher    crypto.KeccakState // Keccak256 hasher instance shared across opcodes
 	hasherBuf common.Hash        // Keccak256 hasher result array shared aross opcodes
@@ -60,6 +71,10 @@ func NewEVMInterpreter(evm *EVM, cfg Config) *EVMInterpreter {
 	// If jump table was not initialised we set the default one.
 	if cfg.JumpTable == nil {
 		switch {
+		case evm.chainRules.IsCancun:
+			cfg.JumpTable = &cancunInstructionSet
+		case evm.chainRules.IsShanghai:
+			cfg.JumpTable = &shanghaiInstructionSet
 		case evm.chainRules.IsMerge:
 			cfg.JumpTable = &mergeInstructionSet
 		case evm.chainRules.IsLondon:
@@ -82,22 +97,36 @@ func NewEVMInterpreter(evm *EVM, cfg Config) *EVMInterpreter {
 			cfg.JumpTable = &frontierInstructionSet
 		}
 		var extraEips []int
+		if len(cfg.ExtraEips) > 0 {
+			// Deep-copy jumptable to prevent modification of opcodes in other tables
+			cfg.JumpTable = copyJumpTable(cfg.JumpTable)
+		}
 		for _, eip := range cfg.ExtraEips {
-			copy := *cfg.JumpTable
-			if err := EnableEIP(eip, &copy); err != nil {
+			if err := EnableEIP(eip, cfg.JumpTable); err != nil {
 				// Disable it, so caller can check if it's activated or not
 				log.Error("EIP activation failed", "eip", eip, "error", err)
 			} else {
 				extraEips = append(extraEips, eip)
 			}
-			cfg.JumpTable = &copy
 		}
 		cfg.ExtraEips = extraEips
 	}
-
+	var eofTable *JumpTable
+	if evm.chainRules.IsPrague {
+		eofTable = &pragueEOFInstructionSet
+		if len(cfg.ExtraEips) > 0 {
+			eofTable = copyJumpTable(eofTable)
+		}
+		for _, eip := range cfg.ExtraEips {
+			if err := EnableEIP(eip, eofTable); err != nil {
+				log.Error("EIP activation failed", "eip", eip, "error", err)
+			}
+		}
+	}
 	return &EVMInterpreter{
-		evm: evm,
-		cfg: cfg,
+		evm:      evm,
+		cfg:      cfg,
+		eofTable: eofTable,
 	}
 }
 
@@ -147,7 +176,12 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 		gasCopy uint64 // for EVMLogger to log gas remaining before execution
 		logged  bool   // deferred EVMLogger should ignore already logged steps
 		res     []byte // result of the opcode execution function
+		jt      = in.cfg.JumpTable
 	)
+	// EOF formatted code is executed with its own instruction set.
+	if contract.IsEOF() {
+		jt = in.eofTable
+	}
 	// Don't move this deferred function, it's placed before the capturestate-deferred method,
 	// so that it get's executed _after_: the capturestate needs the stacks before
 	// they are returned to the pools
@@ -178,8 +212,8 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 		}
 		// Get the operation from the jump table and validate the stack to ensure there are
 		// enough stack items available to perform the operation.
-		op = contract.GetOp(pc)
-		operation := in.cfg.JumpTable[op]
+		op = contract.getOp(callContext.CodeSection, pc)
+		operation := jt[op]
 		cost = operation.constantGas // For tracing
 		// Validate stack
 		if sLen := stack.len(); sLen < operation.minStack {
@@ -30,6 +30,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/bloombits"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/event"
@@ -250,6 +251,18 @@ func (b *GAPIBackend) SendTx(ctx context.Context, signedTx *types.Transaction) e
 	return b.g.txPool.AddLocal(signedTx)
 }
 
+func (b *GAPIBackend) SendPrivateTx(ctx context.Context, signedTx *types.Transaction) error {
+	return b.g.txPool.AddPrivate(signedTx)
+}
+
+func (b *GAPIBackend) IsPrivateTx(hash common.Hash) bool {
+	return b.g.txPool.IsPrivate(hash)
+}
+
+func (b *GAPIBackend) SendBundle(ctx context.Context, bundle *txpool.Bundle) error {
+	return b.g.txPool.AddBundle(bundle, b.g.blockchain.CurrentHeader().Number.Uint64())
+}
+
 func (b *GAPIBackend) GetPoolTransactions() (types.Transactions, error) {
 	pending := b.g.txPool.Pending(false)
 	var txs types.Transactions
@@ -272,6 +285,13 @@ func (b *GAPIBackend) GetPoolNonce(ctx context.Context, addr common.Address) (ui
 	return b.g.txPool.Nonce(addr), nil
 }
 
+func (b *GAPIBackend) TxPoolPriceBump() uint64 {
+	if bump := b.g.config.TxPool.PriceBump; bump >= 1 {
+		return bump
+	}
+	return core.DefaultTxPoolConfig.PriceBump
+}
+
 func (b *GAPIBackend) Stats() (pending int, queued int) {
 	return b.g.txPool.Stats()
 }
@@ -284,14 +304,22 @@ func (b *GAPIBackend) TxPoolContentFrom(addr common.Address) (types.Transactions
 	return b.g.TxPool().ContentFrom(addr)
 }
 
-func (b *GAPIBackend) TxPool() *core.TxPool {
+func (b *GAPIBackend) TxPool() *txpool.TxPool {
 	return b.g.TxPool()
 }
 
+func (b *GAPIBackend) TxPoolHistory(hash common.Hash) []core.TxHistoryEvent {
+	return b.g.TxPool().History(hash)
+}
+
 func (b *GAPIBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
 	return b.g.TxPool().SubscribeNewTxsEvent(ch)
 }
 
+func (b *GAPIBackend) SubscribeDropTxsEvent(ch chan<- core.DropTxsEvent) event.Subscription {
+	return b.g.TxPool().SubscribeDropTxsEvent(ch)
+}
+
 func (b *GAPIBackend) SyncProgress() ethereum.SyncProgress {
 	return b.g.Downloader().Progress()
 }
@@ -18,6 +18,7 @@
 package catalyst
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/binary"
 	"errors"
@@ -34,7 +35,9 @@ import (
 	"github.com/ethereum/go-ethereum/g"
 	"github.com/ethereum/go-ethereum/g/downloader"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/miner"
 	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
@@ -126,6 +129,13 @@ type ConsensusAPI struct {
 // NewConsensusAPI creates a new consensus api for the given backend.
 // The underlying blockchain needs to have a valid terminal total difficulty set.
 func NewConsensusAPI(g *g.Ethereum) *ConsensusAPI {
+	api := newConsensusAPIWithoutHeartbeat(g)
+	go api.heartbeat()
+	return api
+}
+
+// newConsensusAPIWithoutHeartbeat creates a new consensus api for the SimulatedBeacon Node.
+func newConsensusAPIWithoutHeartbeat(g *g.Ethereum) *ConsensusAPI {
 	if g.BlockChain().Config().TerminalTotalDifficulty == nil {
 		log.Warn("Engine API started but chain not configured for merge yet")
 	}
@@ -137,8 +147,6 @@ func NewConsensusAPI(g *g.Ethereum) *ConsensusAPI {
 		invalidTipsets:    make(map[common.Hash]*types.Header),
 	}
 	g.Downloader().SetBadBlockCallback(api.setInvalidAncestor)
-	go api.heartbeat()
-
 	return api
 }
 
@@ -157,6 +165,21 @@ func NewConsensusAPI(g *g.Ethereum) *ConsensusAPI {
 // If there are payloadAttributes: we try to assemble a block with the payloadAttributes
 // and return its payloadID.
 func (api *ConsensusAPI) ForkchoiceUpdatedV1(update beacon.ForkchoiceStateV1, payloadAttributes *beacon.PayloadAttributesV1) (beacon.ForkChoiceResponse, error) {
+	if payloadAttributes != nil && payloadAttributes.Withdrawals != nil {
+		return beacon.STATUS_INVALID, beacon.InvalidParams.With(errors.New("withdrawals not supported in V1"))
+	}
+	return api.forkchoiceUpdated(update, payloadAttributes)
+}
+
+// ForkchoiceUpdatedV2 is equivalent to V1 with the addition of withdrawals in
+// the payload attributes, which must be set once Shanghai is active.
+func (api *ConsensusAPI) ForkchoiceUpdatedV2(update beacon.ForkchoiceStateV1, payloadAttributes *beacon.PayloadAttributesV1) (beacon.ForkChoiceResponse, error) {
+	return api.forkchoiceUpdated(update, payloadAttributes)
+}
+
+// forkchoiceUpdated implements the shared logic of all the forkchoiceUpdated
+// versions.
+func (api *ConsensusAPI) forkchoiceUpdated(update beacon.ForkchoiceStateV1, payloadAttributes *beacon.PayloadAttributesV1) (beacon.ForkChoiceResponse, error) {
 	api.forkchoiceLock.Lock()
 	defer api.forkchoiceLock.Unlock()
 
@@ -281,21 +304,36 @@ func (api *ConsensusAPI) ForkchoiceUpdatedV1(update beacon.ForkchoiceStateV1, pa
 	// sealed by the beacon client. The payload will be requested later, and we
 	// might replace it arbitrarily many times in between.
 	if payloadAttributes != nil {
-		// Create an empty block first which can be used as a fallback
-		empty, err := api.g.Miner().GetSealingBlockSync(update.HeadBlockHash, payloadAttributes.Timestamp, payloadAttributes.SuggestedFeeRecipient, payloadAttributes.Random, true)
-		if err != nil {
-			log.Error("Failed to create empty sealing payload", "err", err)
-			return valid(nil), beacon.InvalidPayloadAttributes.With(err)
+		// Withdrawals must be present exactly from the Shanghai fork onwards
+		next := new(big.Int).Add(block.Number(), common.Big1)
+		if shanghai := api.g.BlockChain().Config().IsShanghai(next); shanghai != (payloadAttributes.Withdrawals != nil) {
+			if shanghai {
+				return valid(nil), beacon.InvalidPayloadAttributes.With(errors.New("missing withdrawals"))
+			}
+			return valid(nil), beacon.InvalidPayloadAttributes.With(errors.New("withdrawals before shanghai"))
+		}
+		// Create an empty block first which can be used as a fallback, whilst the
+		// full block is built (and possibly improved) in the background until
+		// the payload is retrieved.
+		args := &miner.BuildPayloadArgs{
+			Parent:       update.HeadBlockHash,
+			Timestamp:    payloadAttributes.Timestamp,
+			FeeRecipient: payloadAttributes.SuggestedFeeRecipient,
+			Random:       payloadAttributes.Random,
+			Withdrawals:  payloadAttributes.Withdrawals,
 		}
-		// Send a request to generate a full block in the background.
-		// The result can be obtained via the returned channel.
-		resCh, err := api.g.Miner().GetSealingBlockAsync(update.HeadBlockHash, payloadAttributes.Timestamp, payloadAttributes.SuggestedFeeRecipient, payloadAttributes.Random, false)
+		id := computePayloadId(update.HeadBlockHash, payloadAttributes)
+
+		// If the same payload is already being built, don't start another builder
+		if api.localBlocks.has(id) {
+			return valid(&id), nil
+		}
+		payload, err := api.g.Miner().BuildPayload(args, id)
 		if err != nil {
-			log.Error("Failed to create async sealing payload", "err", err)
+			log.Error("Failed to build payload", "err", err)
 			return valid(nil), beacon.InvalidPayloadAttributes.With(err)
 		}
-		id := computePayloadId(update.HeadBlockHash, payloadAttributes)
-		api.localBlocks.put(id, &payload{empty: empty, result: resCh})
+		api.localBlocks.put(id, payload)
 		return valid(&id), nil
 	}
 	return valid(nil), nil
@@ -333,16 +371,156 @@ func (api *ConsensusAPI) ExchangeTransitionConfigurationV1(config beacon.Transit
 
 // GetPayloadV1 returns a cached payload by id.
 func (api *ConsensusAPI) GetPayloadV1(payloadID beacon.PayloadID) (*beacon.ExecutableDataV1, error) {
+	data, err := api.getPayload(payloadID)
+	if err != nil {
+		return nil, err
+	}
+	return data.ExecutionPayload, nil
+}
+
+// GetPayloadV2 returns a cached payload by id, including any withdrawals,
+// together with the value it pays to the fee recipient.
+func (api *ConsensusAPI) GetPayloadV2(payloadID beacon.PayloadID) (*beacon.ExecutionPayloadEnvelope, error) {
+	data, err := api.getPayload(payloadID)
+	if err != nil {
+		return nil, err
+	}
+	return &beacon.ExecutionPayloadEnvelope{
+		ExecutionPayload: data.ExecutionPayload,
+		BlockValue:       data.BlockValue,
+	}, nil
+}
+
+// GetPayloadV3 returns a cached payload by id, together with its value and the
+// blobs bundle of the blob transactions it contains.
+func (api *ConsensusAPI) GetPayloadV3(payloadID beacon.PayloadID) (*beacon.ExecutionPayloadEnvelope, error) {
+	return api.getPayload(payloadID)
+}
+
+func (api *ConsensusAPI) getPayload(payloadID beacon.PayloadID) (*beacon.ExecutionPayloadEnvelope, error) {
 	log.Trace("Engine API request received", "method", "GetPayload", "id", payloadID)
-	data := api.localBlocks.get(payloadID)
+	data := api.localBlocks.get(payloadID, false)
 	if data == nil {
 		return nil, beacon.UnknownPayload
 	}
 	return data, nil
 }
 
+// PayloadRevision is a notification about a payload being built having been
+// replaced by a more valuable one.
+type PayloadRevision struct {
+	PayloadID   beacon.PayloadID `json:"payloadId"`
+	Revision    hexutil.Uint64   `json:"revision"`
+	BlockHash   common.Hash      `json:"blockHash"`
+	BlockNumber hexutil.Uint64   `json:"blockNumber"`
+	BlockValue  *hexutil.Big     `json:"blockValue"`
+	GasUsed     hexutil.Uint64   `json:"gasUsed"`
+	TxCount     hexutil.Uint64   `json:"transactionCount"`
+}
+
+// PayloadRevisions creates a subscription that is triggered every time a payload
+// being built is improved, until it is retrieved via GetPayload.
+func (api *ConsensusAPI) PayloadRevisions(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		revisions := make(chan miner.PayloadRevisionEvent, 16)
+		sub := api.g.Miner().SubscribePayloadRevisions(revisions)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-revisions:
+				notifier.Notify(rpcSub.ID, &PayloadRevision{
+					PayloadID:   ev.ID,
+					Revision:    hexutil.Uint64(ev.Revision),
+					BlockHash:   ev.Block.Hash(),
+					BlockNumber: hexutil.Uint64(ev.Block.NumberU64()),
+					BlockValue:  (*hexutil.Big)(ev.Value),
+					GasUsed:     hexutil.Uint64(ev.Block.GasUsed()),
+					TxCount:     hexutil.Uint64(len(ev.Block.Transactions())),
+				})
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
 // NewPayloadV1 creates an Eth1 block, inserts it in the chain, and returns the status of the chain.
 func (api *ConsensusAPI) NewPayloadV1(params beacon.ExecutableDataV1) (beacon.PayloadStatusV1, error) {
+	if params.Withdrawals != nil {
+		return beacon.PayloadStatusV1{Status: beacon.INVALID}, beacon.InvalidParams.With(errors.New("withdrawals not supported in V1"))
+	}
+	return api.newPayload(params)
+}
+
+// NewPayloadV2 creates an Eth1 block, inserts it in the chain, and returns the
+// status of the chain. Withdrawals must be set once Shanghai is active.
+func (api *ConsensusAPI) NewPayloadV2(params beacon.ExecutableDataV1) (beacon.PayloadStatusV1, error) {
+	shanghai := api.g.BlockChain().Config().IsShanghai(new(big.Int).SetUint64(params.Number))
+	if shanghai && params.Withdrawals == nil {
+		return beacon.PayloadStatusV1{Status: beacon.INVALID}, beacon.InvalidParams.With(errors.New("nil withdrawals post-shanghai"))
+	}
+	if !shanghai && params.Withdrawals != nil {
+		return beacon.PayloadStatusV1{Status: beacon.INVALID}, beacon.InvalidParams.With(errors.New("non-nil withdrawals pre-shanghai"))
+	}
+	return api.newPayload(params)
+}
+
+// NewPayloadV3 creates an Eth1 block, inserts it in the chain, and returns the
+// status of the chain. The data gas fields must be set once Cancun is active
+// and the given versioned hashes must match the blob hashes of the payload's
+// transactions, in order.
+func (api *ConsensusAPI) NewPayloadV3(params beacon.ExecutableDataV1, versionedHashes []common.Hash) (beacon.PayloadStatusV1, error) {
+	if !api.g.BlockChain().Config().IsCancun(new(big.Int).SetUint64(params.Number)) {
+		return beacon.PayloadStatusV1{Status: beacon.INVALID}, beacon.UnsupportedFork.With(errors.New("newPayloadV3 called pre-cancun"))
+	}
+	if params.Withdrawals == nil {
+		return beacon.PayloadStatusV1{Status: beacon.INVALID}, beacon.InvalidParams.With(errors.New("nil withdrawals post-shanghai"))
+	}
+	if params.DataGasUsed == nil || params.ExcessDataGas == nil {
+		return beacon.PayloadStatusV1{Status: beacon.INVALID}, beacon.InvalidParams.With(errors.New("nil data gas fields post-cancun"))
+	}
+	if versionedHashes == nil {
+		return beacon.PayloadStatusV1{Status: beacon.INVALID}, beacon.InvalidParams.With(errors.New("nil versionedHashes post-cancun"))
+	}
+	if err := checkVersionedHashes(params, versionedHashes); err != nil {
+		return api.invalid(err, nil), nil
+	}
+	return api.newPayload(params)
+}
+
+// checkVersionedHashes verifies that the blob hashes of the transactions in the
+// payload match the expected versioned hashes, in order.
+func checkVersionedHashes(params beacon.ExecutableDataV1, versionedHashes []common.Hash) error {
+	var hashes []common.Hash
+	for i, enc := range params.Transactions {
+		var tx types.Transaction
+		if err := tx.UnmarshalBinary(enc); err != nil {
+			return fmt.Errorf("invalid transaction %d: %v", i, err)
+		}
+		hashes = append(hashes, tx.BlobHashes()...)
+	}
+	if len(hashes) != len(versionedHashes) {
+		return fmt.Errorf("invalid number of versionedHashes: have %d, want %d", len(versionedHashes), len(hashes))
+	}
+	for i := range hashes {
+		if hashes[i] != versionedHashes[i] {
+			return fmt.Errorf("invalid versionedHash at %d: have %v, want %v", i, versionedHashes[i], hashes[i])
+		}
+	}
+	return nil
+}
+
+func (api *ConsensusAPI) newPayload(params beacon.ExecutableDataV1) (beacon.PayloadStatusV1, error) {
 	// The locking here is, strictly, not required. Without these locks, this can happen:
 	//
 	// 1. NewPayload( execdata-N ) is invoked from the CL. It goes all the way down to
@@ -452,6 +630,9 @@ func computePayloadId(headBlockHash common.Hash, params *beacon.PayloadAttribute
 	binary.Write(hasher, binary.BigEndian, params.Timestamp)
 	hasher.Write(params.Random[:])
 	hasher.Write(params.SuggestedFeeRecipient[:])
+	if params.Withdrawals != nil {
+		rlp.Encode(hasher, params.Withdrawals)
+	}
 	var out beacon.PayloadID
 	copy(out[:], hasher.Sum(nil)[:8])
 	return out
@@ -163,7 +163,21 @@ func TestEth2PrepareAndGetPayload(t *testing.T) {
 	if err != nil {
 		t.Fatalf("error preparing payload, err=%v", err)
 	}
+	// Repeating the same request must not start building the payload again
+	if _, err := api.ForkchoiceUpdatedV1(fcState, &blockParams); err != nil {
+		t.Fatalf("error repeating payload preparation, err=%v", err)
+	}
 	payloadID := computePayloadId(fcState.HeadBlockHash, &blockParams)
+
+	tracked := 0
+	for _, item := range api.localBlocks.payloads {
+		if item != nil && item.id == payloadID {
+			tracked++
+		}
+	}
+	if tracked != 1 {
+		t.Fatalf("tracked payload count mismatch: have %d, want %d", tracked, 1)
+	}
 	execData, err := api.GetPayloadV1(payloadID)
 	if err != nil {
 		t.Fatalf("error getting payload, err=%v", err)
@@ -171,6 +185,30 @@ func TestEth2PrepareAndGetPayload(t *testing.T) {
 	if len(execData.Transactions) != blocks[9].Transactions().Len() {
 		t.Fatalf("invalid number of transactions %d != 1", len(execData.Transactions))
 	}
+	// The V2 retrieval must deliver the same payload, together with its value
+	envelope, err := api.GetPayloadV2(payloadID)
+	if err != nil {
+		t.Fatalf("error getting payload v2, err=%v", err)
+	}
+	if envelope.ExecutionPayload.BlockHash != execData.BlockHash {
+		t.Fatalf("payload v2 mismatch: have %x, want %x", envelope.ExecutionPayload.BlockHash, execData.BlockHash)
+	}
+	value := new(big.Int)
+	for _, tx := range blocks[9].Transactions() {
+		tip, _ := tx.EffectiveGasTip(execData.BaseFeePerGas)
+		value.Add(value, new(big.Int).Mul(tip, new(big.Int).SetUint64(tx.Gas())))
+
+		// Direct payments to the fee recipient are part of the value too
+		if *tx.To() == execData.FeeRecipient {
+			value.Add(value, tx.Value())
+		}
+	}
+	if envelope.BlockValue == nil || envelope.BlockValue.ToInt().Cmp(value) != 0 {
+		t.Fatalf("block value mismatch: have %v, want %v", envelope.BlockValue, value)
+	}
+	if envelope.BlobsBundle != nil {
+		t.Fatalf("unexpected blobs bundle in payload v2")
+	}
 	// Test invalid payloadID
 	var invPayload beacon.PayloadID
 	copy(invPayload[:], payloadID[:])
@@ -601,7 +639,7 @@ func TestNewPayloadOnInvalidChain(t *testing.T) {
 }
 
 func assembleBlock(api *ConsensusAPI, parentHash common.Hash, params *beacon.PayloadAttributesV1) (*beacon.ExecutableDataV1, error) {
-	block, err := api.g.Miner().GetSealingBlockSync(parentHash, params.Timestamp, params.SuggestedFeeRecipient, params.Random, false)
+	block, err := api.g.Miner().GetSealingBlockSync(parentHash, params.Timestamp, params.SuggestedFeeRecipient, params.Random, params.Withdrawals, false)
 	if err != nil {
 		return nil, err
 	}
@@ -844,7 +882,7 @@ func TestNewPayloadOnInvalidTerminalBlock(t *testing.T) {
 		Random:                crypto.Keccak256Hash([]byte{byte(1)}),
 		SuggestedFeeRecipient: parent.Coinbase(),
 	}
-	empty, err := api.g.Miner().GetSealingBlockSync(parent.Hash(), params.Timestamp, params.SuggestedFeeRecipient, params.Random, true)
+	empty, err := api.g.Miner().GetSealingBlockSync(parent.Hash(), params.Timestamp, params.SuggestedFeeRecipient, params.Random, nil, true)
 	if err != nil {
 		t.Fatalf("error preparing payload, err=%v", err)
 	}
@@ -956,3 +994,95 @@ func TestSimultaneousNewBlock(t *testing.T) {
 		parent = block
 	}
 }
+
+// Tests that withdrawals are accepted exactly from the Shanghai fork onwards by
+// the V2 engine API methods, and credited when the payload is imported.
+func TestWithdrawalsV2(t *testing.T) {
+	genesis, blocks := generatePreMergeChain(10)
+	// The first post-merge block is still pre-Shanghai
+	genesis.Config.ShanghaiBlock = big.NewInt(12)
+	n, ethservice := startEthService(t, genesis, blocks)
+	defer n.Close()
+
+	var (
+		api       = NewConsensusAPI(ethservice)
+		parent    = blocks[len(blocks)-1]
+		recipient = common.Address{0xaa}
+	)
+	// produce builds a payload through the V2 methods on top of the parent
+	produce := func(withdrawals []*types.Withdrawal) (*beacon.ExecutableDataV1, error) {
+		fcState := beacon.ForkchoiceStateV1{HeadBlockHash: parent.Hash()}
+		attrs := &beacon.PayloadAttributesV1{Timestamp: parent.Time() + 5, Withdrawals: withdrawals}
+		resp, err := api.ForkchoiceUpdatedV2(fcState, attrs)
+		if err != nil {
+			return nil, err
+		}
+		envelope, err := api.GetPayloadV2(*resp.PayloadID)
+		if err != nil {
+			return nil, err
+		}
+		return envelope.ExecutionPayload, nil
+	}
+	// insert imports the payload and makes it the chain head
+	insert := func(data *beacon.ExecutableDataV1) {
+		t.Helper()
+		if status, err := api.NewPayloadV2(*data); err != nil || status.Status != beacon.VALID {
+			t.Fatalf("failed to import payload: status %v, err %v", status.Status, err)
+		}
+		fcState := beacon.ForkchoiceStateV1{HeadBlockHash: data.BlockHash}
+		if _, err := api.ForkchoiceUpdatedV2(fcState, nil); err != nil {
+			t.Fatalf("failed to update head: %v", err)
+		}
+		parent = ethservice.BlockChain().CurrentBlock()
+	}
+	// Before Shanghai, withdrawals must not be set
+	if _, err := produce([]*types.Withdrawal{}); err == nil {
+		t.Fatal("withdrawals accepted before shanghai")
+	}
+	data, err := produce(nil)
+	if err != nil {
+		t.Fatalf("failed to produce pre-shanghai payload: %v", err)
+	}
+	if data.Withdrawals != nil {
+		t.Fatalf("pre-shanghai payload has withdrawals")
+	}
+	insert(data)
+
+	// After Shanghai, withdrawals are mandatory, and V1 rejects them
+	if _, err := produce(nil); err == nil {
+		t.Fatal("missing withdrawals accepted after shanghai")
+	}
+	withdrawals := []*types.Withdrawal{
+		{Index: 0, Validator: 1, Address: recipient, Amount: 10},
+		{Index: 1, Validator: 2, Address: recipient, Amount: 5},
+	}
+	fcState := beacon.ForkchoiceStateV1{HeadBlockHash: parent.Hash()}
+	if _, err := api.ForkchoiceUpdatedV1(fcState, &beacon.PayloadAttributesV1{Timestamp: parent.Time() + 5, Withdrawals: withdrawals}); err == nil {
+		t.Fatal("withdrawals accepted by forkchoiceUpdatedV1")
+	}
+	data, err = produce(withdrawals)
+	if err != nil {
+		t.Fatalf("failed to produce shanghai payload: %v", err)
+	}
+	if len(data.Withdrawals) != len(withdrawals) {
+		t.Fatalf("withdrawals mismatch: have %d, want %d", len(data.Withdrawals), len(withdrawals))
+	}
+	if _, err := api.NewPayloadV1(*data); err == nil {
+		t.Fatal("withdrawals accepted by newPayloadV1")
+	}
+	stripped := *data
+	stripped.Withdrawals = nil
+	if _, err := api.NewPayloadV2(stripped); err == nil {
+		t.Fatal("missing withdrawals accepted by newPayloadV2")
+	}
+	insert(data)
+
+	// The withdrawn amounts are credited in Gwei
+	statedb, err := ethservice.BlockChain().State()
+	if err != nil {
+		t.Fatalf("failed to retrieve state: %v", err)
+	}
+	if have, want := statedb.GetBalance(recipient), new(big.Int).Mul(big.NewInt(15), big.NewInt(params.GWei)); have.Cmp(want) != 0 {
+		t.Fatalf("withdrawal balance mismatch: have %v, want %v", have, want)
+	}
+}
@@ -0,0 +1,265 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package catalyst
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/beacon"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/g"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// maxWithdrawalsPerBlock is the maximum number of withdrawals the simulated
+// beacon includes in a single block, matching the mainnet consensus limit.
+const maxWithdrawalsPerBlock = 16
+
+// withdrawalQueue implements a FIFO queue which holds withdrawals that are
+// pending inclusion.
+type withdrawalQueue struct {
+	pending chan *types.Withdrawal
+}
+
+// add queues a withdrawal for future inclusion.
+func (w *withdrawalQueue) add(withdrawal *types.Withdrawal) error {
+	select {
+	case w.pending <- withdrawal:
+		return nil
+	default:
+		return errors.New("withdrawal queue full")
+	}
+}
+
+// gatherPending returns a number of queued withdrawals up to a maximum count.
+// The result is never nil, as blocks must carry withdrawals after Shanghai.
+func (w *withdrawalQueue) gatherPending(maxCount int) []*types.Withdrawal {
+	withdrawals := make([]*types.Withdrawal, 0, maxCount)
+	for len(withdrawals) < maxCount {
+		select {
+		case withdrawal := <-w.pending:
+			withdrawals = append(withdrawals, withdrawal)
+		default:
+			return withdrawals
+		}
+	}
+	return withdrawals
+}
+
+// SimulatedBeacon drives the block production of a post-merge developer chain
+// through the engine API, standing in for a real consensus client. Blocks are
+// either produced periodically, or on demand whenever transactions or
+// withdrawals arrive if the period is zero.
+type SimulatedBeacon struct {
+	shutdownCh chan struct{}
+	wg         sync.WaitGroup
+
+	g           *g.Ethereum
+	period      uint64
+	withdrawals withdrawalQueue
+
+	engineAPI          *ConsensusAPI
+	curForkchoiceState beacon.ForkchoiceStateV1
+	lastBlockTime      uint64
+	timeOffset         uint64 // Number of seconds the clock was warped forward by
+	lock               sync.Mutex
+}
+
+// NewSimulatedBeacon constructs a simulated beacon producing a block every
+// period seconds, or on demand if the period is zero.
+func NewSimulatedBeacon(period uint64, g *g.Ethereum) (*SimulatedBeacon, error) {
+	chainConfig := g.BlockChain().Config()
+	if !chainConfig.TerminalTotalDifficultyPassed {
+		return nil, errors.New("chain must be post-merge to be driven by a simulated beacon")
+	}
+	if !chainConfig.IsShanghai(common.Big0) {
+		return nil, errors.New("chain must have shanghai activated at genesis")
+	}
+	// Start building on top of the current head, considering it final
+	current := g.BlockChain().CurrentBlock()
+	return &SimulatedBeacon{
+		shutdownCh:  make(chan struct{}),
+		g:           g,
+		period:      period,
+		withdrawals: withdrawalQueue{make(chan *types.Withdrawal, 20)},
+		engineAPI:   newConsensusAPIWithoutHeartbeat(g),
+		curForkchoiceState: beacon.ForkchoiceStateV1{
+			HeadBlockHash:      current.Hash(),
+			SafeBlockHash:      current.Hash(),
+			FinalizedBlockHash: current.Hash(),
+		},
+		lastBlockTime: current.Time(),
+	}, nil
+}
+
+// Start implements node.Lifecycle, starting the block production loop.
+func (c *SimulatedBeacon) Start() error {
+	c.wg.Add(1)
+	if c.period == 0 {
+		go c.loopOnDemand()
+	} else {
+		go c.loop()
+	}
+	return nil
+}
+
+// Stop implements node.Lifecycle, halting block production.
+func (c *SimulatedBeacon) Stop() error {
+	close(c.shutdownCh)
+	c.wg.Wait()
+	return nil
+}
+
+// sealBlock builds a new block on top of the current head with the given
+// withdrawals via the engine API and immediately makes it the new, finalized
+// head of the chain.
+func (c *SimulatedBeacon) sealBlock(withdrawals []*types.Withdrawal) (common.Hash, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	timestamp := uint64(time.Now().Unix()) + c.timeOffset
+	if timestamp <= c.lastBlockTime {
+		timestamp = c.lastBlockTime + 1
+	}
+	feeRecipient, _ := c.g.ACbase()
+
+	var random common.Hash
+	if _, err := rand.Read(random[:]); err != nil {
+		return common.Hash{}, err
+	}
+	response, err := c.engineAPI.ForkchoiceUpdatedV2(c.curForkchoiceState, &beacon.PayloadAttributesV1{
+		Timestamp:             timestamp,
+		Random:                random,
+		SuggestedFeeRecipient: feeRecipient,
+		Withdrawals:           withdrawals,
+	})
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if response.PayloadID == nil {
+		return common.Hash{}, fmt.Errorf("payload not built: %s", response.PayloadStatus.Status)
+	}
+	envelope := c.engineAPI.localBlocks.get(*response.PayloadID, true)
+	if envelope == nil {
+		return common.Hash{}, beacon.UnknownPayload
+	}
+	payload := envelope.ExecutionPayload
+
+	status, err := c.engineAPI.NewPayloadV2(*payload)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if status.Status != beacon.VALID {
+		return common.Hash{}, fmt.Errorf("payload rejected: %s", status.Status)
+	}
+	state := beacon.ForkchoiceStateV1{
+		HeadBlockHash:      payload.BlockHash,
+		SafeBlockHash:      payload.BlockHash,
+		FinalizedBlockHash: payload.BlockHash,
+	}
+	if _, err := c.engineAPI.ForkchoiceUpdatedV2(state, nil); err != nil {
+		return common.Hash{}, err
+	}
+	c.curForkchoiceState = state
+	c.lastBlockTime = payload.Timestamp
+
+	return payload.BlockHash, nil
+}
+
+// loopOnDemand runs the block production loop for period=0, producing a new
+// block whenever new transactions or withdrawals arrive.
+func (c *SimulatedBeacon) loopOnDemand() {
+	defer c.wg.Done()
+
+	var (
+		newTxs = make(chan core.NewTxsEvent)
+		sub    = c.g.TxPool().SubscribeNewTxsEvent(newTxs)
+	)
+	defer sub.Unsubscribe()
+
+	for {
+		var withdrawals []*types.Withdrawal
+		select {
+		case <-c.shutdownCh:
+			return
+		case withdrawal := <-c.withdrawals.pending:
+			withdrawals = append(c.withdrawals.gatherPending(maxWithdrawalsPerBlock-1), withdrawal)
+		case <-newTxs:
+			withdrawals = c.withdrawals.gatherPending(maxWithdrawalsPerBlock)
+		}
+		if _, err := c.sealBlock(withdrawals); err != nil {
+			log.Warn("Error performing sealing work", "err", err)
+		}
+	}
+}
+
+// loop runs the block production loop for non-zero periods.
+func (c *SimulatedBeacon) loop() {
+	defer c.wg.Done()
+
+	timer := time.NewTicker(time.Second * time.Duration(c.period))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-c.shutdownCh:
+			return
+		case <-timer.C:
+			if _, err := c.sealBlock(c.withdrawals.gatherPending(maxWithdrawalsPerBlock)); err != nil {
+				log.Warn("Error performing sealing work", "err", err)
+			}
+		}
+	}
+}
+
+// Commit seals a block on demand, including any pending withdrawals, returning
+// the hash of the new head.
+func (c *SimulatedBeacon) Commit() (common.Hash, error) {
+	return c.sealBlock(c.withdrawals.gatherPending(maxWithdrawalsPerBlock))
+}
+
+// AdjustTime warps the clock of the simulated beacon forward, affecting the
+// timestamps of all subsequently produced blocks.
+func (c *SimulatedBeacon) AdjustTime(adjustment time.Duration) error {
+	if adjustment < 0 {
+		return errors.New("cannot move time backwards")
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.timeOffset += uint64(adjustment / time.Second)
+	return nil
+}
+
+// RegisterSimulatedBeaconAPIs registers the dev namespace APIs controlling the
+// simulated beacon.
+func RegisterSimulatedBeaconAPIs(stack *node.Node, sim *SimulatedBeacon) {
+	stack.RegisterAPIs([]rpc.API{
+		{
+			Namespace: "dev",
+			Service:   &simulatedBeaconAPI{sim},
+		},
+	})
+}
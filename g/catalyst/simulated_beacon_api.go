@@ -0,0 +1,47 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package catalyst
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// simulatedBeaconAPI exposes the controls of the simulated beacon under the dev
+// namespace.
+type simulatedBeaconAPI struct {
+	sim *SimulatedBeacon
+}
+
+// Commit seals a new block right away, returning its hash.
+func (api *simulatedBeaconAPI) Commit() (common.Hash, error) {
+	return api.sim.Commit()
+}
+
+// AdjustTime moves the clock used for block timestamps forward by the given
+// number of seconds.
+func (api *simulatedBeaconAPI) AdjustTime(seconds hexutil.Uint64) error {
+	return api.sim.AdjustTime(time.Duration(seconds) * time.Second)
+}
+
+// AddWithdrawal queues a withdrawal for inclusion in an upcoming block.
+func (api *simulatedBeaconAPI) AddWithdrawal(withdrawal *types.Withdrawal) error {
+	return api.sim.withdrawals.add(withdrawal)
+}
@@ -0,0 +1,127 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package catalyst
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the simulated beacon produces blocks on demand for transactions
+// and withdrawals in period=0 mode.
+func TestSimulatedBeaconOnDemand(t *testing.T) {
+	genesis := core.DeveloperGenesisBlock(11_500_000, testAddr)
+	n, ethservice := startEthService(t, genesis, nil)
+	defer n.Close()
+
+	sim, err := NewSimulatedBeacon(0, ethservice)
+	if err != nil {
+		t.Fatalf("failed to create simulated beacon: %v", err)
+	}
+	sim.Start()
+	defer sim.Stop()
+
+	heads := make(chan core.ChainHeadEvent, 100)
+	sub := ethservice.BlockChain().SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	// Queue up some withdrawals and transactions, expecting all to be included
+	var (
+		withdrawals = make(map[uint64]bool)
+		txs         = make(map[common.Hash]bool)
+		signer      = types.LatestSigner(ethservice.BlockChain().Config())
+	)
+	for i := 0; i < 20; i++ {
+		withdrawal := &types.Withdrawal{Index: uint64(i), Address: common.Address{0xaa}, Amount: 10}
+		if err := sim.withdrawals.add(withdrawal); err != nil {
+			t.Fatalf("failed to add withdrawal %d: %v", i, err)
+		}
+		withdrawals[withdrawal.Index] = true
+	}
+	for i := 0; i < 20; i++ {
+		tx, err := types.SignTx(types.NewTransaction(uint64(i), common.Address{0xbb}, big.NewInt(1000), params.TxGas, big.NewInt(params.InitialBaseFee*2), nil), signer, testKey)
+		if err != nil {
+			t.Fatalf("failed to sign transaction %d: %v", i, err)
+		}
+		if err := ethservice.TxPool().AddLocal(tx); err != nil {
+			t.Fatalf("failed to add transaction %d: %v", i, err)
+		}
+		txs[tx.Hash()] = true
+	}
+	timeout := time.NewTimer(10 * time.Second)
+	defer timeout.Stop()
+
+	for len(withdrawals) > 0 || len(txs) > 0 {
+		select {
+		case ev := <-heads:
+			if len(ev.Block.Withdrawals()) > maxWithdrawalsPerBlock {
+				t.Fatalf("too many withdrawals in block %d: %d", ev.Block.NumberU64(), len(ev.Block.Withdrawals()))
+			}
+			for _, withdrawal := range ev.Block.Withdrawals() {
+				delete(withdrawals, withdrawal.Index)
+			}
+			for _, tx := range ev.Block.Transactions() {
+				delete(txs, tx.Hash())
+			}
+		case <-timeout.C:
+			t.Fatalf("missing inclusions: %d withdrawals, %d transactions", len(withdrawals), len(txs))
+		}
+	}
+}
+
+// Tests that blocks can be committed explicitly and that warping the clock is
+// reflected in the timestamps of subsequent blocks.
+func TestSimulatedBeaconCommitAndWarp(t *testing.T) {
+	genesis := core.DeveloperGenesisBlock(11_500_000, testAddr)
+	n, ethservice := startEthService(t, genesis, nil)
+	defer n.Close()
+
+	sim, err := NewSimulatedBeacon(0, ethservice)
+	if err != nil {
+		t.Fatalf("failed to create simulated beacon: %v", err)
+	}
+	hash, err := sim.Commit()
+	if err != nil {
+		t.Fatalf("failed to commit block: %v", err)
+	}
+	head := ethservice.BlockChain().CurrentBlock()
+	if head.Hash() != hash || head.NumberU64() != 1 {
+		t.Fatalf("head mismatch: have %d/%x, want %d/%x", head.NumberU64(), head.Hash(), 1, hash)
+	}
+	if final := ethservice.BlockChain().CurrentFinalizedBlock(); final == nil || final.Hash() != hash {
+		t.Fatalf("committed block not finalized")
+	}
+	if err := sim.AdjustTime(time.Hour); err != nil {
+		t.Fatalf("failed to adjust time: %v", err)
+	}
+	if _, err := sim.Commit(); err != nil {
+		t.Fatalf("failed to commit block: %v", err)
+	}
+	warped := ethservice.BlockChain().CurrentBlock()
+	if min := uint64(time.Now().Add(time.Hour).Unix()) - 5; warped.Time() < min {
+		t.Fatalf("warped timestamp too low: have %d, want at least %d", warped.Time(), min)
+	}
+	if err := sim.AdjustTime(-time.Second); err == nil {
+		t.Fatalf("moving time backwards succeeded")
+	}
+}
@@ -328,8 +328,10 @@ func XTestDelivery(t *testing.T) {
 					uncleset = append(uncleset, emptyList)
 				}
 				var (
-					txsHashes   = make([]common.Hash, len(txset))
-					uncleHashes = make([]common.Hash, len(uncleset))
+					txsHashes        = make([]common.Hash, len(txset))
+					uncleHashes      = make([]common.Hash, len(uncleset))
+					withdrawalset    = make([][]*types.Withdrawal, len(txset))
+					withdrawalHashes = make([]common.Hash, len(txset))
 				)
 				hasher := trie.NewStackTrie(nil)
 				for i, txs := range txset {
@@ -339,7 +341,7 @@ func XTestDelivery(t *testing.T) {
 					uncleHashes[i] = types.CalcUncleHash(uncles)
 				}
 				time.Sleep(100 * time.Millisecond)
-				_, err := q.DeliverBodies(peer.id, txset, txsHashes, uncleset, uncleHashes)
+				_, err := q.DeliverBodies(peer.id, txset, txsHashes, uncleset, uncleHashes, withdrawalset, withdrawalHashes)
 				if err != nil {
 					fmt.Printf("delivered %d bodies %v\n", len(txset), err)
 				}
@@ -20,6 +20,7 @@ import (
 	"errors"
 	"math/big"
 	"math/rand"
+	"sync"
 	"testing"
 	"time"
 
@@ -44,6 +45,8 @@ var (
 type doTxNotify struct {
 	peer   string
 	hashes []common.Hash
+	types  []byte
+	sizes  []uint32
 }
 type doTxEnqueue struct {
 	peer   string
@@ -81,6 +84,7 @@ func TestTransactionFetcherWaiting(t *testing.T) {
 				func(common.Hash) bool { return false },
 				nil,
 				func(string, []common.Hash) error { return nil },
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -171,6 +175,7 @@ func TestTransactionFetcherSkipWaiting(t *testing.T) {
 				func(common.Hash) bool { return false },
 				nil,
 				func(string, []common.Hash) error { return nil },
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -234,6 +239,7 @@ func TestTransactionFetcherSingletonRequesting(t *testing.T) {
 				func(common.Hash) bool { return false },
 				nil,
 				func(string, []common.Hash) error { return nil },
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -313,6 +319,7 @@ func TestTransactionFetcherFailedRescheduling(t *testing.T) {
 					<-proceed
 					return errors.New("peer disconnected")
 				},
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -382,6 +389,7 @@ func TestTransactionFetcherCleanup(t *testing.T) {
 					return make([]error, len(txs))
 				},
 				func(string, []common.Hash) error { return nil },
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -421,6 +429,7 @@ func TestTransactionFetcherCleanupEmpty(t *testing.T) {
 					return make([]error, len(txs))
 				},
 				func(string, []common.Hash) error { return nil },
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -459,6 +468,7 @@ func TestTransactionFetcherMissingRescheduling(t *testing.T) {
 					return make([]error, len(txs))
 				},
 				func(string, []common.Hash) error { return nil },
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -505,6 +515,7 @@ func TestTransactionFetcherMissingCleanup(t *testing.T) {
 					return make([]error, len(txs))
 				},
 				func(string, []common.Hash) error { return nil },
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -543,6 +554,7 @@ func TestTransactionFetcherBroadcasts(t *testing.T) {
 					return make([]error, len(txs))
 				},
 				func(string, []common.Hash) error { return nil },
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -591,6 +603,7 @@ func TestTransactionFetcherWaitTimerResets(t *testing.T) {
 				func(common.Hash) bool { return false },
 				nil,
 				func(string, []common.Hash) error { return nil },
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -648,6 +661,7 @@ func TestTransactionFetcherTimeoutRescheduling(t *testing.T) {
 					return make([]error, len(txs))
 				},
 				func(string, []common.Hash) error { return nil },
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -713,6 +727,7 @@ func TestTransactionFetcherTimeoutTimerResets(t *testing.T) {
 				func(common.Hash) bool { return false },
 				nil,
 				func(string, []common.Hash) error { return nil },
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -772,6 +787,7 @@ func TestTransactionFetcherRateLimiting(t *testing.T) {
 				func(common.Hash) bool { return false },
 				nil,
 				func(string, []common.Hash) error { return nil },
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -810,6 +826,7 @@ func TestTransactionFetcherDoSProtection(t *testing.T) {
 				func(common.Hash) bool { return false },
 				nil,
 				func(string, []common.Hash) error { return nil },
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -877,6 +894,7 @@ func TestTransactionFetcherUnderpricedDedup(t *testing.T) {
 					return errs
 				},
 				func(string, []common.Hash) error { return nil },
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -946,6 +964,7 @@ func TestTransactionFetcherUnderpricedDoSProtection(t *testing.T) {
 					return errs
 				},
 				func(string, []common.Hash) error { return nil },
+				nil,
 			)
 		},
 		steps: append(steps, []interface{}{
@@ -968,6 +987,7 @@ func TestTransactionFetcherOutOfBoundDeliveries(t *testing.T) {
 					return make([]error, len(txs))
 				},
 				func(string, []common.Hash) error { return nil },
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -1021,6 +1041,7 @@ func TestTransactionFetcherDrop(t *testing.T) {
 					return make([]error, len(txs))
 				},
 				func(string, []common.Hash) error { return nil },
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -1087,6 +1108,7 @@ func TestTransactionFetcherDropRescheduling(t *testing.T) {
 					return make([]error, len(txs))
 				},
 				func(string, []common.Hash) error { return nil },
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -1120,6 +1142,123 @@ func TestTransactionFetcherDropRescheduling(t *testing.T) {
 	})
 }
 
+// Tests that g/68 announcements of unknown transaction types or of sizes the
+// pool would reject anyway are not even tracked.
+func TestTransactionFetcherUnwantedAnnounces(t *testing.T) {
+	testTransactionFetcherParallel(t, txFetcherTest{
+		init: func() *TxFetcher {
+			return NewTxFetcher(
+				func(common.Hash) bool { return false },
+				nil,
+				func(string, []common.Hash) error { return nil },
+				nil,
+			)
+		},
+		steps: []interface{}{
+			doTxNotify{peer: "A", hashes: []common.Hash{{0x01}, {0x02}, {0x03}, {0x04}}, types: []byte{0x00, 0x7f, 0x02, 0x03}, sizes: []uint32{100, 100, maxTxAnnounceSize + 1, maxTxAnnounceSize + 1}},
+			isWaiting(map[string][]common.Hash{
+				"A": {{0x01}, {0x04}},
+			}),
+		},
+	})
+}
+
+// Tests that g/68 retrievals are capped by the announced transaction sizes, not
+// only by their count.
+func TestTransactionFetcherBandwidthLimiting(t *testing.T) {
+	var fetcher *TxFetcher
+	testTransactionFetcherParallel(t, txFetcherTest{
+		init: func() *TxFetcher {
+			fetcher = NewTxFetcher(
+				func(common.Hash) bool { return false },
+				nil,
+				func(string, []common.Hash) error { return nil },
+				nil,
+			)
+			return fetcher
+		},
+		steps: []interface{}{
+			doTxNotify{peer: "A", hashes: []common.Hash{{0x01}, {0x02}, {0x03}}, types: []byte{0x00, 0x00, 0x00}, sizes: []uint32{70 * 1024, 70 * 1024, 70 * 1024}},
+			doTxNotify{peer: "B", hashes: []common.Hash{{0x04}, {0x05}, {0x06}}},
+			doWait{time: txArriveTimeout, step: true},
+			doFunc(func() {
+				if have := len(fetcher.requests["A"].hashes); have != 2 {
+					t.Errorf("sized retrieval mismatch: have %d, want %d", have, 2)
+				}
+				if have := len(fetcher.requests["B"].hashes); have != 3 {
+					t.Errorf("unsized retrieval mismatch: have %d, want %d", have, 3)
+				}
+			}),
+		},
+	})
+}
+
+// Tests that peers announcing transactions with a type or size differing from
+// the delivered ones are dropped, tolerating a few bytes of size deviation.
+func TestTransactionFetcherWrongMetadata(t *testing.T) {
+	var (
+		lock    sync.Mutex
+		dropped = make(map[string]struct{})
+	)
+	expectDropped := func(peers ...string) doFunc {
+		return func() {
+			lock.Lock()
+			defer lock.Unlock()
+
+			if len(dropped) != len(peers) {
+				t.Errorf("dropped peer count mismatch: have %v, want %v", dropped, peers)
+			}
+			for _, peer := range peers {
+				if _, ok := dropped[peer]; !ok {
+					t.Errorf("peer %s not dropped", peer)
+				}
+			}
+		}
+	}
+	var (
+		size0 = uint32(testTxs[0].Size())
+		size1 = uint32(testTxs[1].Size())
+		size2 = uint32(testTxs[2].Size())
+	)
+	testTransactionFetcherParallel(t, txFetcherTest{
+		init: func() *TxFetcher {
+			return NewTxFetcher(
+				func(common.Hash) bool { return false },
+				func(txs []*types.Transaction) []error {
+					return make([]error, len(txs))
+				},
+				func(string, []common.Hash) error { return nil },
+				func(peer string) {
+					lock.Lock()
+					defer lock.Unlock()
+					dropped[peer] = struct{}{}
+				},
+			)
+		},
+		steps: []interface{}{
+			// Announce the same transactions with various metadata and deliver them
+			// via broadcast while still waiting
+			doTxNotify{peer: "A", hashes: []common.Hash{testTxsHashes[0]}, types: []byte{types.DynamicFeeTxType}, sizes: []uint32{size0}},
+			doTxNotify{peer: "B", hashes: []common.Hash{testTxsHashes[0]}, types: []byte{types.LegacyTxType}, sizes: []uint32{size0}},
+			doTxNotify{peer: "C", hashes: []common.Hash{testTxsHashes[1]}, types: []byte{types.LegacyTxType}, sizes: []uint32{size1 + 4}},
+			doTxNotify{peer: "D", hashes: []common.Hash{testTxsHashes[1]}, types: []byte{types.LegacyTxType}, sizes: []uint32{size1 + 100}},
+			doTxNotify{peer: "E", hashes: []common.Hash{testTxsHashes[1]}},
+			doTxEnqueue{peer: "F", txs: []*types.Transaction{testTxs[0], testTxs[1]}, direct: false},
+			expectDropped("A", "D"),
+
+			// Announce a transaction with a wrong type and deliver it when fetched
+			doTxNotify{peer: "G", hashes: []common.Hash{testTxsHashes[2]}, types: []byte{types.AccessListTxType}, sizes: []uint32{size2}},
+			doWait{time: txArriveTimeout, step: true},
+			isScheduled{
+				tracking: map[string][]common.Hash{"G": {testTxsHashes[2]}},
+				fetching: map[string][]common.Hash{"G": {testTxsHashes[2]}},
+			},
+			doTxEnqueue{peer: "G", txs: []*types.Transaction{testTxs[2]}, direct: true},
+			expectDropped("A", "D", "G"),
+		},
+	})
+}
+
 // This test reproduces a crash caught by the fuzzer. The root cause was a
 // dangling transaction timing out and clashing on re-add with a concurrently
 // announced one.
@@ -1132,6 +1271,7 @@ func TestTransactionFetcherFuzzCrash01(t *testing.T) {
 					return make([]error, len(txs))
 				},
 				func(string, []common.Hash) error { return nil },
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -1159,6 +1299,7 @@ func TestTransactionFetcherFuzzCrash02(t *testing.T) {
 					return make([]error, len(txs))
 				},
 				func(string, []common.Hash) error { return nil },
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -1188,6 +1329,7 @@ func TestTransactionFetcherFuzzCrash03(t *testing.T) {
 					return make([]error, len(txs))
 				},
 				func(string, []common.Hash) error { return nil },
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -1224,6 +1366,7 @@ func TestTransactionFetcherFuzzCrash04(t *testing.T) {
 					<-proceed
 					return errors.New("peer disconnected")
 				},
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -1276,7 +1419,7 @@ func testTransactionFetcher(t *testing.T, tt txFetcherTest) {
 	for i, step := range tt.steps {
 		switch step := step.(type) {
 		case doTxNotify:
-			if err := fetcher.Notify(step.peer, step.hashes); err != nil {
+			if err := fetcher.Notify(step.peer, step.types, step.sizes, step.hashes); err != nil {
 				t.Errorf("step %d: %v", i, err)
 			}
 			<-wait // Fetcher needs to process this, wait until it's done
@@ -164,7 +164,15 @@ func (oracle *Oracle) resolveBlockRange(ctx context.Context, reqEnd rpc.BlockNum
 		)
 		switch reqEnd {
 		case rpc.PendingBlockNumber:
-			if pendingBlock, pendingReceipts = oracle.backend.PendingBlockAndReceipts(); pendingBlock != nil {
+			// In pending mode, or if the backend isn't building a pending block,
+			// approximate it from the best paying transactions of the pool.
+			if !oracle.pending {
+				pendingBlock, pendingReceipts = oracle.backend.PendingBlockAndReceipts()
+			}
+			if pendingBlock == nil && oracle.pool != nil {
+				pendingBlock, pendingReceipts = oracle.pendingPoolBlock(headBlock)
+			}
+			if pendingBlock != nil {
 				resolved = pendingBlock.Header()
 			} else {
 				// Pending block not supported by backend, process only until latest block.
@@ -34,6 +34,15 @@ import (
 
 const sampleNumber = 3 // Number of transactions sampled in a block
 
+const (
+	// ModeBlocks suggests tips based on the transactions included in recent blocks.
+	ModeBlocks = "blocks"
+
+	// ModePending suggests tips based on the transactions waiting in the pool,
+	// falling back to recent blocks if the pool is empty.
+	ModePending = "pending"
+)
+
 var (
 	DefaultMaxPrice    = big.NewInt(500 * params.GWei)
 	DefaultIgnorePrice = big.NewInt(2 * params.Wei)
@@ -47,6 +56,8 @@ type Config struct {
 	Default          *big.Int `toml:",omitempty"`
 	MaxPrice         *big.Int `toml:",omitempty"`
 	IgnorePrice      *big.Int `toml:",omitempty"`
+	Mode             string   `toml:",omitempty"` // Suggestion strategy, ModeBlocks or ModePending
+	PendingBlocks    int      `toml:",omitempty"` // Number of blocks to target inclusion within in pending mode
 }
 
 // OracleBackend includes all necessary background APIs for oracle.
@@ -67,11 +78,18 @@ type Oracle struct {
 	lastPrice   *big.Int
 	maxPrice    *big.Int
 	ignorePrice *big.Int
+	pool        PoolBackend // Transaction pool of the backend, nil if not available
+	pending     bool        // Whether tips are suggested based on the pool content
 	cacheLock   sync.RWMutex
 	fetchLock   sync.Mutex
 
+	pendingHead  common.Hash // Head the last pending mode suggestion was made on
+	pendingPrice *big.Int    // Last pending mode suggestion, nil if the pool was empty
+	pendingLock  sync.Mutex
+
 	checkBlocks, percentile           int
 	maxHeaderHistory, maxBlockHistory int
+	pendingBlocks                     int
 	historyCache                      *lru.Cache
 }
 
@@ -113,6 +131,24 @@ func NewOracle(backend OracleBackend, params Config) *Oracle {
 		maxBlockHistory = 1
 		log.Warn("Sanitizing invalid gasprice oracle max block history", "provided", params.MaxBlockHistory, "updated", maxBlockHistory)
 	}
+	pool, _ := backend.(PoolBackend)
+	pending := false
+	switch params.Mode {
+	case "", ModeBlocks:
+	case ModePending:
+		if pending = pool != nil; !pending {
+			log.Warn("Gasprice oracle backend has no transaction pool, sampling blocks instead")
+		}
+	default:
+		log.Warn("Sanitizing invalid gasprice oracle mode", "provided", params.Mode, "updated", ModeBlocks)
+	}
+	pendingBlocks := params.PendingBlocks
+	if pendingBlocks < 1 {
+		pendingBlocks = 1
+		if pending {
+			log.Warn("Sanitizing invalid gasprice oracle pending blocks", "provided", params.PendingBlocks, "updated", pendingBlocks)
+		}
+	}
 
 	cache, _ := lru.New(2048)
 	headEvent := make(chan core.ChainHeadEvent, 1)
@@ -132,10 +168,13 @@ func NewOracle(backend OracleBackend, params Config) *Oracle {
 		lastPrice:        params.Default,
 		maxPrice:         maxPrice,
 		ignorePrice:      ignorePrice,
+		pool:             pool,
+		pending:          pending,
 		checkBlocks:      blocks,
 		percentile:       percent,
 		maxHeaderHistory: maxHeaderHistory,
 		maxBlockHistory:  maxBlockHistory,
+		pendingBlocks:    pendingBlocks,
 		historyCache:     cache,
 	}
 }
@@ -150,6 +189,13 @@ func (oracle *Oracle) SuggestTipCap(ctx context.Context) (*big.Int, error) {
 	head, _ := oracle.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
 	headHash := head.Hash()
 
+	// In pending mode, the suggestion tracks the pool content. Walking the pool
+	// is expensive, so the result is only refreshed once per chain head.
+	if oracle.pending {
+		if price := oracle.cachedPendingTipCap(head); price != nil {
+			return price, nil
+		}
+	}
 	// If the latest gasprice is still available, return it.
 	oracle.cacheLock.RLock()
 	lastHead, lastPrice := oracle.lastHead, oracle.lastPrice
@@ -0,0 +1,150 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// PoolBackend is implemented by oracle backends with access to a transaction
+// pool, which is needed by the pending mode of the oracle and to approximate
+// the pending block for the fee history.
+type PoolBackend interface {
+	GetPoolTransactions() (types.Transactions, error)
+}
+
+// pendingTx is an executable pool transaction with its effective tip in the
+// next block.
+type pendingTx struct {
+	tx  *types.Transaction
+	tip *big.Int
+}
+
+// pendingTxs retrieves the executable transactions from the pool that can be
+// included in the block following head, sorted by their effective tip in
+// descending order. The base fee of said block is also returned.
+//
+// Note, nonce dependencies between the transactions are disregarded, so a well
+// paying transaction may be counted ahead of a cheaper one it waits for.
+func (oracle *Oracle) pendingTxs(head *types.Header) ([]pendingTx, *big.Int) {
+	txs, err := oracle.pool.GetPoolTransactions()
+	if err != nil {
+		log.Debug("Failed to retrieve pool transactions", "err", err)
+		return nil, nil
+	}
+	var (
+		config  = oracle.backend.ChainConfig()
+		number  = new(big.Int).Add(head.Number, common.Big1)
+		baseFee *big.Int
+	)
+	if config.IsLondon(number) {
+		baseFee = misc.CalcBaseFee(config, head)
+	}
+	pending := make([]pendingTx, 0, len(txs))
+	for _, tx := range txs {
+		tip, err := tx.EffectiveGasTip(baseFee)
+		if err != nil {
+			continue // fee cap below the base fee, not includable in the next block
+		}
+		if tip.Cmp(oracle.ignorePrice) < 0 {
+			continue
+		}
+		pending = append(pending, pendingTx{tx: tx, tip: tip})
+	}
+	sort.SliceStable(pending, func(i, j int) bool {
+		return pending[i].tip.Cmp(pending[j].tip) > 0
+	})
+	return pending, baseFee
+}
+
+// suggestPendingTipCap predicts the tip needed for a transaction to be included
+// within the configured number of blocks, based on the gas demand of the better
+// paying transactions in the pool. If the pool is empty, false is returned and
+// the suggestion needs to be made based on recent blocks instead.
+func (oracle *Oracle) suggestPendingTipCap(head *types.Header) (*big.Int, bool) {
+	pending, _ := oracle.pendingTxs(head)
+	if len(pending) == 0 {
+		return nil, false
+	}
+	var (
+		capacity = head.GasLimit * uint64(oracle.pendingBlocks)
+		gas      uint64
+		price    = pending[len(pending)-1].tip // demand below capacity, cheapest tip is enough
+	)
+	for _, ptx := range pending {
+		if gas += ptx.tx.Gas(); gas > capacity {
+			// Demand exceeds the targeted blocks, outbid the first left out
+			price = new(big.Int).Add(ptx.tip, common.Big1)
+			break
+		}
+	}
+	if price.Cmp(oracle.maxPrice) > 0 {
+		price = oracle.maxPrice
+	}
+	return new(big.Int).Set(price), true
+}
+
+// cachedPendingTipCap returns the pending mode tip suggestion for head, reusing
+// the previous one if it was made on the same head. Nil is returned if the pool
+// held no usable transactions.
+func (oracle *Oracle) cachedPendingTipCap(head *types.Header) *big.Int {
+	oracle.pendingLock.Lock()
+	defer oracle.pendingLock.Unlock()
+
+	if hash := head.Hash(); hash != oracle.pendingHead {
+		oracle.pendingPrice, _ = oracle.suggestPendingTipCap(head)
+		oracle.pendingHead = hash
+	}
+	if oracle.pendingPrice == nil {
+		return nil
+	}
+	return new(big.Int).Set(oracle.pendingPrice)
+}
+
+// pendingPoolBlock assembles the block that would follow head if it contained the
+// best paying transactions from the pool, together with approximate receipts. It
+// is used for the pending entry of the fee history.
+func (oracle *Oracle) pendingPoolBlock(head *types.Header) (*types.Block, types.Receipts) {
+	pending, baseFee := oracle.pendingTxs(head)
+
+	header := &types.Header{
+		ParentHash: head.Hash(),
+		Number:     new(big.Int).Add(head.Number, common.Big1),
+		GasLimit:   head.GasLimit,
+		BaseFee:    baseFee,
+		Time:       head.Time,
+	}
+	var (
+		txs      types.Transactions
+		receipts types.Receipts
+	)
+	for _, ptx := range pending {
+		if header.GasUsed+ptx.tx.Gas() > header.GasLimit {
+			continue
+		}
+		header.GasUsed += ptx.tx.Gas()
+		txs = append(txs, ptx.tx)
+		receipts = append(receipts, &types.Receipt{GasUsed: ptx.tx.Gas()})
+	}
+	return types.NewBlockWithHeader(header).WithBody(txs, nil), receipts
+}
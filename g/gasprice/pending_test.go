@@ -0,0 +1,160 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// testPoolBackend extends the test backend with a transaction pool.
+type testPoolBackend struct {
+	*testBackend
+	txs types.Transactions
+}
+
+func (b *testPoolBackend) GetPoolTransactions() (types.Transactions, error) {
+	return b.txs, nil
+}
+
+// newTestPoolBackend creates a post-London test backend with a pool holding
+// eight transactions, each using a quarter of the block gas limit and tipping
+// 1 to 8 gwei, along with one transaction below the ignore threshold.
+func newTestPoolBackend(t *testing.T) *testPoolBackend {
+	backend := &testPoolBackend{testBackend: newTestBackend(t, big.NewInt(0), false)}
+
+	gas := backend.chain.CurrentHeader().GasLimit / 4
+	for i := 1; i <= 8; i++ {
+		backend.txs = append(backend.txs, types.NewTx(&types.DynamicFeeTx{
+			Nonce:     uint64(i),
+			To:        &common.Address{},
+			Gas:       gas,
+			GasFeeCap: big.NewInt(100 * params.GWei),
+			GasTipCap: big.NewInt(int64(i) * params.GWei),
+		}))
+	}
+	backend.txs = append(backend.txs, types.NewTx(&types.DynamicFeeTx{
+		To:        &common.Address{},
+		Gas:       params.TxGas,
+		GasFeeCap: big.NewInt(100 * params.GWei),
+		GasTipCap: big.NewInt(1),
+	}))
+	return backend
+}
+
+func TestSuggestPendingTipCap(t *testing.T) {
+	var cases = []struct {
+		blocks int      // Number of blocks to target inclusion within
+		empty  bool     // Whether the pool is empty
+		expect *big.Int // Expected tip suggestion
+	}{
+		{1, false, big.NewInt(4*params.GWei + 1)}, // Outbid the best transaction not fitting
+		{2, false, big.NewInt(params.GWei)},       // All transactions fit, cheapest tip is enough
+		{1, true, big.NewInt(params.GWei * 30)},   // Empty pool, fall back to sampling blocks
+	}
+	for i, c := range cases {
+		backend := newTestPoolBackend(t)
+		if c.empty {
+			backend.txs = nil
+		}
+		oracle := NewOracle(backend, Config{
+			Blocks:        3,
+			Percentile:    60,
+			Default:       big.NewInt(params.GWei),
+			Mode:          ModePending,
+			PendingBlocks: c.blocks,
+		})
+		got, err := oracle.SuggestTipCap(context.Background())
+		backend.chain.Stop()
+		if err != nil {
+			t.Fatalf("test %d: failed to retrieve recommended tip cap: %v", i, err)
+		}
+		if got.Cmp(c.expect) != 0 {
+			t.Fatalf("test %d: tip cap mismatch, want %d, got %d", i, c.expect, got)
+		}
+	}
+}
+
+// Tests that pending mode suggestions are only recomputed when the head changes.
+func TestSuggestPendingTipCapCache(t *testing.T) {
+	backend := newTestPoolBackend(t)
+	defer backend.chain.Stop()
+
+	oracle := NewOracle(backend, Config{
+		Blocks:        3,
+		Percentile:    60,
+		Default:       big.NewInt(params.GWei),
+		Mode:          ModePending,
+		PendingBlocks: 2,
+	})
+	first, err := oracle.SuggestTipCap(context.Background())
+	if err != nil {
+		t.Fatalf("failed to retrieve recommended tip cap: %v", err)
+	}
+	// Drop the cheap transactions, the suggestion should stay put on the same head
+	backend.txs = backend.txs[4:8]
+	second, err := oracle.SuggestTipCap(context.Background())
+	if err != nil {
+		t.Fatalf("failed to retrieve recommended tip cap: %v", err)
+	}
+	if second.Cmp(first) != 0 {
+		t.Fatalf("cached tip cap mismatch: have %d, want %d", second, first)
+	}
+	// Invalidate the cached head and ensure the pool is sampled anew
+	oracle.pendingHead = common.Hash{}
+	third, err := oracle.SuggestTipCap(context.Background())
+	if err != nil {
+		t.Fatalf("failed to retrieve recommended tip cap: %v", err)
+	}
+	if want := big.NewInt(5 * params.GWei); third.Cmp(want) != 0 {
+		t.Fatalf("refreshed tip cap mismatch: have %d, want %d", third, want)
+	}
+}
+
+func TestFeeHistoryPendingPool(t *testing.T) {
+	backend := newTestPoolBackend(t)
+	defer backend.chain.Stop()
+
+	oracle := NewOracle(backend, Config{
+		MaxHeaderHistory: 1000,
+		MaxBlockHistory:  1000,
+		Mode:             ModePending,
+	})
+	first, reward, baseFee, ratio, err := oracle.FeeHistory(context.Background(), 2, rpc.PendingBlockNumber, []float64{0, 100})
+	if err != nil {
+		t.Fatalf("failed to retrieve fee history: %v", err)
+	}
+	if first.Uint64() != testHead || len(reward) != 2 {
+		t.Fatalf("range mismatch: have %d+%d, want %d+%d", first, len(reward), testHead, 2)
+	}
+	// The pending block holds the four best paying transactions filling it
+	if reward[1][0].Cmp(big.NewInt(5*params.GWei)) != 0 || reward[1][1].Cmp(big.NewInt(8*params.GWei)) != 0 {
+		t.Fatalf("pending rewards mismatch: have %v, want [5 gwei, 8 gwei]", reward[1])
+	}
+	if ratio[1] < 0.99 {
+		t.Fatalf("pending gas used ratio too low: %f", ratio[1])
+	}
+	if baseFee[1] == nil || baseFee[1].Sign() <= 0 {
+		t.Fatalf("pending base fee missing")
+	}
+}
@@ -48,6 +48,8 @@ var FullNodeGPO = gasprice.Config{
 	MaxBlockHistory:  1024,
 	MaxPrice:         gasprice.DefaultMaxPrice,
 	IgnorePrice:      gasprice.DefaultIgnorePrice,
+	Mode:             gasprice.ModeBlocks,
+	PendingBlocks:    1,
 }
 
 // LightClientGPO contains default gasprice oracle settings for light client.
@@ -58,6 +60,8 @@ var LightClientGPO = gasprice.Config{
 	MaxBlockHistory:  5,
 	MaxPrice:         gasprice.DefaultMaxPrice,
 	IgnorePrice:      gasprice.DefaultIgnorePrice,
+	Mode:             gasprice.ModeBlocks,
+	PendingBlocks:    1,
 }
 
 // Defaults contains default settings for use on the Ethereum main net.
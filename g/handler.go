@@ -65,6 +65,10 @@ type txPool interface {
 	// AddRemotes should add the given transactions to the pool.
 	AddRemotes([]*types.Transaction) []error
 
+	// IsPrivate returns whether the transaction with the given hash was submitted
+	// privately and must not be propagated to the network.
+	IsPrivate(hash common.Hash) bool
+
 	// Pending should return pending transactions.
 	// The slice should be modifiable by the caller.
 	Pending(enforceTips bool) map[common.Address]types.Transactions
@@ -192,7 +196,7 @@ func newHandler(config *handlerConfig) (*handler, error) {
 		}
 	}
 	// Construct the downloader (long sync)
-	h.downloader = downloader.New(h.checkpointNumber, config.Database, h.eventMux, h.chain, nil, h.removePeer, success)
+	h.downloader = downloader.New(h.checkpointNumber, config.Database, h.eventMux, h.chain, nil, func(id string) { h.removePeer(id, p2p.ScoreUselessResponse) }, success)
 	if ttd := h.chain.Config().TerminalTotalDifficulty; ttd != nil {
 		if h.chain.Config().TerminalTotalDifficultyPassed {
 			log.Info("Chain post-merge, sync via beacon client")
@@ -292,7 +296,7 @@ func newHandler(config *handlerConfig) (*handler, error) {
 		}
 		return n, err
 	}
-	h.blockFetcher = fetcher.NewBlockFetcher(false, nil, h.chain.GetBlockByHash, validator, h.BroadcastBlock, heighter, nil, inserter, h.removePeer)
+	h.blockFetcher = fetcher.NewBlockFetcher(false, nil, h.chain.GetBlockByHash, validator, h.BroadcastBlock, heighter, nil, inserter, func(id string) { h.removePeer(id, p2p.ScoreInvalidBlock) })
 
 	fetchTx := func(peer string, hashes []common.Hash) error {
 		p := h.peers.peer(peer)
@@ -301,7 +305,7 @@ func newHandler(config *handlerConfig) (*handler, error) {
 		}
 		return p.RequestTxs(hashes)
 	}
-	h.txFetcher = fetcher.NewTxFetcher(h.txpool.Has, h.txpool.AddRemotes, fetchTx)
+	h.txFetcher = fetcher.NewTxFetcher(h.txpool.Has, h.txpool.AddRemotes, fetchTx, func(id string) { h.removePeer(id, p2p.ScoreUselessResponse) })
 	h.chainSync = newChainSyncer(h)
 	return h, nil
 }
@@ -425,6 +429,7 @@ func (h *handler) runEthPeer(peer *g.Peer, handler g.Handler) error {
 					return
 				}
 				if headers[0].Hash() != h.checkpointHash {
+					peer.Report(p2p.ScoreInvalidBlock)
 					res.Done <- errors.New("checkpoint hash mismatch")
 					return
 				}
@@ -432,7 +437,7 @@ func (h *handler) runEthPeer(peer *g.Peer, handler g.Handler) error {
 
 			case <-timeout.C:
 				peer.Log().Warn("Checkpoint challenge timed out, dropping", "addr", peer.RemoteAddr(), "type", peer.Name())
-				h.removePeer(peer.ID())
+				h.removePeer(peer.ID(), p2p.ScoreTimeout)
 
 			case <-dead:
 				// Peer handler terminated, abort all goroutines
@@ -470,6 +475,7 @@ func (h *handler) runEthPeer(peer *g.Peer, handler g.Handler) error {
 				}
 				if headers[0].Number.Uint64() != number || headers[0].Hash() != hash {
 					peer.Log().Info("Required block mismatch, dropping peer", "number", number, "hash", headers[0].Hash(), "want", hash)
+					peer.Report(p2p.ScoreInvalidBlock)
 					res.Done <- errors.New("required block mismatch")
 					return
 				}
@@ -477,7 +483,7 @@ func (h *handler) runEthPeer(peer *g.Peer, handler g.Handler) error {
 				res.Done <- nil
 			case <-timeout.C:
 				peer.Log().Warn("Required block challenge timed out, dropping", "addr", peer.RemoteAddr(), "type", peer.Name())
-				h.removePeer(peer.ID())
+				h.removePeer(peer.ID(), p2p.ScoreTimeout)
 			}
 		}(number, hash, req)
 	}
@@ -500,10 +506,12 @@ func (h *handler) runSnapExtension(peer *snap.Peer, handler snap.Handler) error
 	return handler(peer)
 }
 
-// removePeer requests disconnection of a peer.
-func (h *handler) removePeer(id string) {
+// removePeer requests disconnection of a misbehaving peer, reporting the reason
+// to its reputation.
+func (h *handler) removePeer(id string, ev p2p.ScoreEvent) {
 	peer := h.peers.peer(id)
 	if peer != nil {
+		peer.Peer.Report(ev)
 		peer.Peer.Disconnect(p2p.DiscUselessPeer)
 	}
 }
@@ -638,9 +646,17 @@ func (h *handler) BroadcastTransactions(txs types.Transactions) {
 	)
 	// Broadcast transactions to a batch of peers not knowing about it
 	for _, tx := range txs {
+		// Private transactions are only for our own miner, never propagate them
+		if h.txpool.IsPrivate(tx.Hash()) {
+			continue
+		}
 		peers := h.peers.peersWithoutTransaction(tx.Hash())
-		// Send the tx unconditionally to a subset of our peers
-		numDirect := int(math.Sqrt(float64(len(peers))))
+		// Send the tx unconditionally to a subset of our peers. Blob transactions
+		// are too heavy to push around, they are only ever announced.
+		numDirect := 0
+		if tx.Type() != types.BlobTxType {
+			numDirect = int(math.Sqrt(float64(len(peers))))
+		}
 		for _, peer := range peers[:numDirect] {
 			txset[peer] = append(txset[peer], tx.Hash())
 		}
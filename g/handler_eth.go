@@ -34,7 +34,22 @@ import (
 type gHandler handler
 
 func (h *gHandler) Chain() *core.BlockChain { return h.chain }
-func (h *gHandler) TxPool() g.TxPool        { return h.txpool }
+func (h *gHandler) TxPool() g.TxPool        { return &publicTxPool{h.txpool} }
+
+// publicTxPool is a view of the transaction pool hiding all the private
+// transactions, so they are never served to remote peers.
+type publicTxPool struct {
+	pool txPool
+}
+
+// Get retrieves the transaction from the local txpool with the given hash, if
+// it's not a private one.
+func (p *publicTxPool) Get(hash common.Hash) *types.Transaction {
+	if p.pool.IsPrivate(hash) {
+		return nil
+	}
+	return p.pool.Get(hash)
+}
 
 // RunPeer is invoked when a peer joins on the `g` protocol.
 func (h *gHandler) RunPeer(peer *g.Peer, hand g.Handler) error {
@@ -67,8 +82,11 @@ func (h *gHandler) Handle(peer *g.Peer, packet g.Packet) error {
 	case *g.NewBlockPacket:
 		return h.handleBlockBroadcast(peer, packet.Block, packet.TD)
 
-	case *g.NewPooledTransactionHashesPacket:
-		return h.txFetcher.Notify(peer.ID(), *packet)
+	case *g.NewPooledTransactionHashesPacket66:
+		return h.txFetcher.Notify(peer.ID(), nil, nil, *packet)
+
+	case *g.NewPooledTransactionHashesPacket68:
+		return h.txFetcher.Notify(peer.ID(), packet.Types, packet.Sizes, packet.Hashes)
 
 	case *g.TransactionsPacket:
 		return h.txFetcher.Enqueue(peer.ID(), *packet, false)
@@ -61,10 +61,14 @@ func (h *testEthHandler) Handle(peer *g.Peer, packet g.Packet) error {
 		h.blockBroadcasts.Send(packet.Block)
 		return nil
 
-	case *g.NewPooledTransactionHashesPacket:
+	case *g.NewPooledTransactionHashesPacket66:
 		h.txAnnounces.Send(([]common.Hash)(*packet))
 		return nil
 
+	case *g.NewPooledTransactionHashesPacket68:
+		h.txAnnounces.Send(packet.Hashes)
+		return nil
+
 	case *g.TransactionsPacket:
 		h.txBroadcasts.Send(([]*types.Transaction)(*packet))
 		return nil
@@ -235,6 +239,8 @@ func testForkIDSplit(t *testing.T, protocol uint) {
 
 // Tests that received transactions are added to the local pool.
 func TestRecvTransactions66(t *testing.T) { testRecvTransactions(t, g.G66) }
+func TestRecvTransactions67(t *testing.T) { testRecvTransactions(t, g.G67) }
+func TestRecvTransactions68(t *testing.T) { testRecvTransactions(t, g.G68) }
 
 func testRecvTransactions(t *testing.T, protocol uint) {
 	t.Parallel()
@@ -292,6 +298,8 @@ func testRecvTransactions(t *testing.T, protocol uint) {
 
 // This test checks that pending transactions are sent.
 func TestSendTransactions66(t *testing.T) { testSendTransactions(t, g.G66) }
+func TestSendTransactions67(t *testing.T) { testSendTransactions(t, g.G67) }
+func TestSendTransactions68(t *testing.T) { testSendTransactions(t, g.G68) }
 
 func testSendTransactions(t *testing.T, protocol uint) {
 	t.Parallel()
@@ -350,7 +358,7 @@ func testSendTransactions(t *testing.T, protocol uint) {
 	seen := make(map[common.Hash]struct{})
 	for len(seen) < len(insert) {
 		switch protocol {
-		case 66:
+		case 66, 67, 68:
 			select {
 			case hashes := <-anns:
 				for _, hash := range hashes {
@@ -374,9 +382,125 @@ func testSendTransactions(t *testing.T, protocol uint) {
 	}
 }
 
+// Tests that privately submitted transactions are never propagated: neither in
+// the initial pool sync, nor broadcast or announced afterwards, nor served when
+// explicitly requested by a remote peer.
+func TestPrivateTransactions66(t *testing.T) { testPrivateTransactions(t, g.G66) }
+func TestPrivateTransactions67(t *testing.T) { testPrivateTransactions(t, g.G67) }
+func TestPrivateTransactions68(t *testing.T) { testPrivateTransactions(t, g.G68) }
+
+func testPrivateTransactions(t *testing.T, protocol uint) {
+	t.Parallel()
+
+	// Create a message handler and fill the pool with a public and a private
+	// transaction before any peer connects
+	handler := newTestHandler()
+	defer handler.close()
+
+	txs := make([]*types.Transaction, 4)
+	for nonce := range txs {
+		tx := types.NewTransaction(uint64(nonce), common.Address{}, big.NewInt(0), 100000, big.NewInt(0), nil)
+		tx, _ = types.SignTx(tx, types.HomesteadSigner{}, testKey)
+
+		txs[nonce] = tx
+	}
+	handler.txpool.AddRemotes([]*types.Transaction{txs[0]})
+	handler.txpool.AddPrivate(txs[1])
+
+	private := map[common.Hash]bool{txs[1].Hash(): true, txs[3].Hash(): true}
+
+	// Create a source handler to send messages through and a sink peer to receive them
+	p2pSrc, p2pSink := p2p.MsgPipe()
+	defer p2pSrc.Close()
+	defer p2pSink.Close()
+
+	src := g.NewPeer(protocol, p2p.NewPeerPipe(enode.ID{1}, "", nil, p2pSrc), p2pSrc, handler.txpool)
+	sink := g.NewPeer(protocol, p2p.NewPeerPipe(enode.ID{2}, "", nil, p2pSink), p2pSink, handler.txpool)
+	defer src.Close()
+	defer sink.Close()
+
+	go handler.handler.runEthPeer(src, func(peer *g.Peer) error {
+		return g.Handle((*gHandler)(handler.handler), peer)
+	})
+	// Run the handshake locally to avoid spinning up a source handler
+	var (
+		genesis = handler.chain.Genesis()
+		head    = handler.chain.CurrentBlock()
+		td      = handler.chain.GetTd(head.Hash(), head.NumberU64())
+	)
+	if err := sink.Handshake(1, td, head.Hash(), genesis.Hash(), forkid.NewIDWithChain(handler.chain), forkid.NewFilter(handler.chain)); err != nil {
+		t.Fatalf("failed to run protocol handshake")
+	}
+	backend := new(testEthHandler)
+
+	anns := make(chan []common.Hash)
+	annSub := backend.txAnnounces.Subscribe(anns)
+	defer annSub.Unsubscribe()
+
+	bcasts := make(chan []*types.Transaction)
+	bcastSub := backend.txBroadcasts.Subscribe(bcasts)
+	defer bcastSub.Unsubscribe()
+
+	go g.Handle(backend, sink)
+
+	// waitFor collects the transactions arriving at the sink until the given
+	// public one is seen, failing if any private one arrives
+	waitFor := func(want common.Hash) {
+		for {
+			var hashes []common.Hash
+			select {
+			case hashes = <-anns:
+			case txs := <-bcasts:
+				for _, tx := range txs {
+					hashes = append(hashes, tx.Hash())
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("transaction %x not propagated", want)
+			}
+			found := false
+			for _, hash := range hashes {
+				if private[hash] {
+					t.Fatalf("private transaction %x propagated", hash)
+				}
+				if hash == want {
+					found = true
+				}
+			}
+			if found {
+				return
+			}
+		}
+	}
+	// The initial pool sync must only contain the public transaction
+	waitFor(txs[0].Hash())
+
+	// Transactions added after the peer joined must also be filtered. The private
+	// one is added first, so any leak would arrive before the public one.
+	handler.txpool.AddPrivate(txs[3])
+	handler.txpool.AddRemotes([]*types.Transaction{txs[2]})
+	waitFor(txs[2].Hash())
+
+	// Explicitly requesting the private transactions must not serve them
+	if err := sink.RequestTxs([]common.Hash{txs[1].Hash(), txs[3].Hash(), txs[0].Hash()}); err != nil {
+		t.Fatalf("failed to request transactions: %v", err)
+	}
+	select {
+	case delivery := <-bcasts:
+		if len(delivery) != 1 || delivery[0].Hash() != txs[0].Hash() {
+			t.Fatalf("pooled transactions mismatch: have %d txs, want only %x", len(delivery), txs[0].Hash())
+		}
+	case hashes := <-anns:
+		t.Fatalf("unexpected announcement of %d transactions", len(hashes))
+	case <-time.After(time.Second):
+		t.Fatalf("pooled transactions not delivered")
+	}
+}
+
 // Tests that transactions get propagated to all attached peers, either via direct
 // broadcasts or via announcements/retrievals.
 func TestTransactionPropagation66(t *testing.T) { testTransactionPropagation(t, g.G66) }
+func TestTransactionPropagation67(t *testing.T) { testTransactionPropagation(t, g.G67) }
+func TestTransactionPropagation68(t *testing.T) { testTransactionPropagation(t, g.G68) }
 
 func testTransactionPropagation(t *testing.T, protocol uint) {
 	t.Parallel()
@@ -19,7 +19,9 @@ package g
 import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/forkid"
+	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
@@ -63,3 +65,20 @@ func currentENREntry(chain *core.BlockChain) *enrEntry {
 		ForkID: forkid.NewID(chain.Config(), chain.Genesis().Hash(), chain.CurrentHeader().Number.Uint64()),
 	}
 }
+
+// NewNodeFilter returns a dial filter which rejects nodes advertising a fork ID
+// incompatible with the local chain in their `g` entry. Nodes without the entry
+// are accepted, since their fork can only be learned from the handshake.
+func NewNodeFilter(chain *core.BlockChain) p2p.NodeFilter {
+	filter := forkid.NewFilter(chain)
+	return func(n *enode.Node) error {
+		var entry enrEntry
+		if err := n.Load(&entry); err != nil {
+			if enr.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		return filter(entry.ForkID)
+	}
+}
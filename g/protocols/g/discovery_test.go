@@ -0,0 +1,52 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package g
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/forkid"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+)
+
+// Tests that the dial filter rejects nodes advertising an incompatible fork ID.
+func TestNodeFilter(t *testing.T) {
+	t.Parallel()
+
+	backend := newTestBackend(3)
+	defer backend.close()
+
+	node := func(entries ...enr.Entry) *enode.Node {
+		var r enr.Record
+		for _, e := range entries {
+			r.Set(e)
+		}
+		return enode.SignNull(&r, enode.ID{1})
+	}
+	filter := NewNodeFilter(backend.chain)
+	if err := filter(node()); err != nil {
+		t.Errorf("node without entry rejected: %v", err)
+	}
+	if err := filter(node(currentENREntry(backend.chain))); err != nil {
+		t.Errorf("node with current fork ID rejected: %v", err)
+	}
+	other := &enrEntry{ForkID: forkid.ID{Hash: [4]byte{1, 2, 3, 4}}}
+	if err := filter(node(other)); err == nil {
+		t.Error("node with incompatible fork ID accepted")
+	}
+}
@@ -150,6 +150,7 @@ func (p *Peer) dispatchResponse(res *Response, metadata func() interface{}) erro
 	case p.resDispatch <- resOp:
 		// Ensure the response is accepted by the dispatcher
 		if err := <-resOp.fail; err != nil {
+			p.Report(p2p.ScoreUselessResponse)
 			return nil
 		}
 		// Request was accepted, run any postprocessing step to generate metadata
@@ -171,7 +172,15 @@ func (p *Peer) dispatchResponse(res *Response, metadata func() interface{}) erro
 			// for fresh cancellations too
 			select {
 			case res.Req.sink <- res:
-				return <-res.Done // Response delivered, return any errors
+				// Response delivered, score the peer by the outcome and
+				// return any errors
+				err := <-res.Done
+				if err != nil {
+					p.Report(p2p.ScoreUselessResponse)
+				} else {
+					p.Report(p2p.ScoreGoodDelivery)
+				}
+				return err
 			case <-res.Req.cancel:
 				return nil // Request cancelled, silently discard response
 			}
@@ -19,6 +19,7 @@ package g
 import (
 	"bytes"
 	"math/big"
+	"reflect"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -117,6 +118,32 @@ func TestG66EmptyMessages(t *testing.T) {
 	}
 }
 
+// TestG68Announcements tests the encoding of the typed and sized g68 transaction
+// announcements.
+func TestG68Announcements(t *testing.T) {
+	packet := NewPooledTransactionHashesPacket68{
+		Types:  []byte{types.LegacyTxType, types.DynamicFeeTxType},
+		Sizes:  []uint32{100, 130000},
+		Hashes: []common.Hash{{0x01}, {0x02}},
+	}
+	blob, err := rlp.EncodeToBytes(packet)
+	if err != nil {
+		t.Fatalf("failed to encode announcement: %v", err)
+	}
+	// Types are encoded as a byte string, sizes and hashes as lists
+	want := common.FromHex("f84d820002c5648301fbd0f842a00100000000000000000000000000000000000000000000000000000000000000a00200000000000000000000000000000000000000000000000000000000000000")
+	if !bytes.Equal(blob, want) {
+		t.Fatalf("encoding mismatch:\nhave %x\nwant %x", blob, want)
+	}
+	var decoded NewPooledTransactionHashesPacket68
+	if err := rlp.DecodeBytes(blob, &decoded); err != nil {
+		t.Fatalf("failed to decode announcement: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, packet) {
+		t.Fatalf("decoded announcement mismatch: have %+v, want %+v", decoded, packet)
+	}
+}
+
 // TestG66Messages tests the encoding of all redefined g66 messages
 func TestG66Messages(t *testing.T) {
 	// Some basic structs used during testing
@@ -209,9 +209,10 @@ type blockTraceTask struct {
 // blockTraceResult represents the results of tracing a single block when an entire
 // chain is being traced.
 type blockTraceResult struct {
-	Block  hexutil.Uint64   `json:"block"`  // Block number corresponding to this trace
-	Hash   common.Hash      `json:"hash"`   // Block hash corresponding to this trace
-	Traces []*txTraceResult `json:"traces"` // Trace results produced by the task
+	Block  hexutil.Uint64   `json:"block"`           // Block number corresponding to this trace
+	Hash   common.Hash      `json:"hash"`            // Block hash corresponding to this trace
+	Traces []*txTraceResult `json:"traces"`          // Trace results produced by the task
+	Error  string           `json:"error,omitempty"` // Reason the chain tracing stopped at this block (internal only)
 }
 
 // txTraceTask represents a single transaction trace task when an entire block
@@ -245,6 +246,12 @@ func (api *API) TraceChain(ctx context.Context, start, end rpc.BlockNumber, conf
 	resCh := api.traceChain(from, to, config, notifier.Closed())
 	go func() {
 		for result := range resCh {
+			// Failure markers are only consumed internally (e.g. by TraceFilter),
+			// keep the subscription output unchanged for existing consumers.
+			if result.Error != "" {
+				log.Debug("Chain tracing aborted", "block", uint64(result.Block), "err", result.Error)
+				continue
+			}
 			notifier.Notify(sub.ID, result)
 		}
 	}()
@@ -296,6 +303,7 @@ func (api *API) traceChain(start, end *types.Block, config *TraceConfig, closed
 		taskCh = make(chan *blockTraceTask, threads)
 		resCh  = make(chan *blockTraceTask, threads)
 		reler  = new(releaser)
+		failed error // Set by the feeder before resCh is closed
 	)
 	for th := 0; th < threads; th++ {
 		pend.Add(1)
@@ -345,7 +353,6 @@ func (api *API) traceChain(start, end *types.Block, config *TraceConfig, closed
 			begin   = time.Now()
 			number  uint64
 			traced  uint64
-			failed  error
 			statedb *state.StateDB
 			release StateReleaseFunc
 		)
@@ -455,6 +462,10 @@ func (api *API) traceChain(start, end *types.Block, config *TraceConfig, closed
 				next++
 			}
 		}
+		// Report the block at which tracing failed, if any
+		if failed != nil {
+			retCh <- &blockTraceResult{Block: hexutil.Uint64(next), Error: failed.Error()}
+		}
 	}()
 	return retCh
 }
@@ -0,0 +1,265 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// filterTracer is the tracer used to collect the call frames of the
+	// transactions matched by TraceFilter.
+	filterTracer = "callTracer"
+
+	// maxFilterCount is the maximum number of traces a single TraceFilter
+	// invocation is allowed to return.
+	maxFilterCount = 10000
+)
+
+// TraceFilterArgs represents the arguments for TraceFilter.
+type TraceFilterArgs struct {
+	FromBlock   *rpc.BlockNumber `json:"fromBlock"`   // First block of the range (inclusive), defaults to 1
+	ToBlock     *rpc.BlockNumber `json:"toBlock"`     // Last block of the range (inclusive), defaults to latest
+	FromAddress []common.Address `json:"fromAddress"` // Sender addresses to match, empty matches all
+	ToAddress   []common.Address `json:"toAddress"`   // Recipient addresses to match, empty matches all
+	After       *uint64          `json:"after"`       // Number of matching traces to skip
+	Count       *uint64          `json:"count"`       // Maximum number of matching traces to return
+	Reexec      *uint64          `json:"reexec"`      // Number of blocks to re-execute for missing state
+}
+
+// filterFrame is the subset of the call tracer's frame output which is needed
+// to filter and flatten the call tree of a transaction.
+type filterFrame struct {
+	Type    string         `json:"type"`
+	From    common.Address `json:"from"`
+	To      common.Address `json:"to"`
+	Value   *hexutil.Big   `json:"value"`
+	Gas     hexutil.Uint64 `json:"gas"`
+	GasUsed hexutil.Uint64 `json:"gasUsed"`
+	Input   hexutil.Bytes  `json:"input"`
+	Output  hexutil.Bytes  `json:"output"`
+	Error   string         `json:"error"`
+	Calls   []filterFrame  `json:"calls"`
+}
+
+// filterTraceResult is a single call frame matched by TraceFilter, flattened out
+// of the call tree of the transaction it belongs to.
+type filterTraceResult struct {
+	BlockNumber         hexutil.Uint64 `json:"blockNumber"`
+	BlockHash           common.Hash    `json:"blockHash"`
+	TransactionHash     common.Hash    `json:"transactionHash"`
+	TransactionPosition hexutil.Uint64 `json:"transactionPosition"`
+	TraceAddress        []int          `json:"traceAddress"` // Path of child indices from the top call to this frame
+	Subtraces           int            `json:"subtraces"`    // Number of direct subcalls made by this frame
+
+	Type    string         `json:"type"`
+	From    common.Address `json:"from"`
+	To      common.Address `json:"to"`
+	Value   *hexutil.Big   `json:"value,omitempty"`
+	Gas     hexutil.Uint64 `json:"gas"`
+	GasUsed hexutil.Uint64 `json:"gasUsed"`
+	Input   hexutil.Bytes  `json:"input"`
+	Output  hexutil.Bytes  `json:"output,omitempty"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// traceFilter matches call frames against the address sets of a TraceFilter
+// query and tracks the pagination window.
+type traceFilter struct {
+	from  map[common.Address]struct{}
+	to    map[common.Address]struct{}
+	after uint64 // Number of matches still to be skipped
+	count uint64 // Number of matches still to be returned
+}
+
+// newTraceFilter creates a call frame filter from the given query arguments.
+func newTraceFilter(args *TraceFilterArgs) *traceFilter {
+	filter := &traceFilter{
+		from:  make(map[common.Address]struct{}),
+		to:    make(map[common.Address]struct{}),
+		count: maxFilterCount,
+	}
+	for _, addr := range args.FromAddress {
+		filter.from[addr] = struct{}{}
+	}
+	for _, addr := range args.ToAddress {
+		filter.to[addr] = struct{}{}
+	}
+	if args.After != nil {
+		filter.after = *args.After
+	}
+	if args.Count != nil && *args.Count < filter.count {
+		filter.count = *args.Count
+	}
+	return filter
+}
+
+// match reports whether the given frame satisfies the address constraints.
+func (f *traceFilter) match(frame *filterFrame) bool {
+	if len(f.from) > 0 {
+		if _, ok := f.from[frame.From]; !ok {
+			return false
+		}
+	}
+	if len(f.to) > 0 {
+		if _, ok := f.to[frame.To]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// done reports whether the requested number of traces has been collected.
+func (f *traceFilter) done() bool {
+	return f.count == 0
+}
+
+// collect walks the call tree of a single transaction in depth-first order and
+// appends every matching frame within the pagination window to results.
+func (f *traceFilter) collect(block *types.Block, index int, frame *filterFrame, path []int, results []*filterTraceResult) []*filterTraceResult {
+	if f.done() {
+		return results
+	}
+	if f.match(frame) {
+		if f.after > 0 {
+			f.after--
+		} else {
+			results = append(results, &filterTraceResult{
+				BlockNumber:         hexutil.Uint64(block.NumberU64()),
+				BlockHash:           block.Hash(),
+				TransactionHash:     block.Transactions()[index].Hash(),
+				TransactionPosition: hexutil.Uint64(index),
+				TraceAddress:        append([]int{}, path...),
+				Subtraces:           len(frame.Calls),
+				Type:                frame.Type,
+				From:                frame.From,
+				To:                  frame.To,
+				Value:               frame.Value,
+				Gas:                 frame.Gas,
+				GasUsed:             frame.GasUsed,
+				Input:               frame.Input,
+				Output:              frame.Output,
+				Error:               frame.Error,
+			})
+			f.count--
+		}
+	}
+	for i := range frame.Calls {
+		results = f.collect(block, index, &frame.Calls[i], append(path, i), results)
+	}
+	return results
+}
+
+// TraceFilter traces all the transactions in the requested block range with the
+// call tracer and returns the call frames whose sender and recipient match the
+// requested address sets. Blocks are traced concurrently in the same way as with
+// TraceChain, and results are returned in chain order.
+func (api *API) TraceFilter(ctx context.Context, args TraceFilterArgs) ([]*filterTraceResult, error) {
+	start := uint64(1)
+	if args.FromBlock != nil {
+		// Resolve block tags like "latest" before clamping to the first block
+		block, err := api.blockByNumber(ctx, *args.FromBlock)
+		if err != nil {
+			return nil, err
+		}
+		if block.NumberU64() > start {
+			start = block.NumberU64()
+		}
+	}
+	end := rpc.LatestBlockNumber
+	if args.ToBlock != nil {
+		end = *args.ToBlock
+	}
+	to, err := api.blockByNumber(ctx, end)
+	if err != nil {
+		return nil, err
+	}
+	if start > to.NumberU64() {
+		return nil, fmt.Errorf("end block (#%d) needs to come after start block (#%d)", to.NumberU64(), start)
+	}
+	// The chain tracer excludes the first block of the range, so start from its parent
+	from, err := api.blockByNumber(ctx, rpc.BlockNumber(start-1))
+	if err != nil {
+		return nil, err
+	}
+	var (
+		tracer = filterTracer
+		config = &TraceConfig{Tracer: &tracer, Reexec: args.Reexec}
+		closed = make(chan interface{})
+		resCh  = api.traceChain(from, to, config, closed)
+	)
+	// Abort the chain tracing on any exit path, draining the remaining results
+	// so the internal routines can terminate.
+	defer func() {
+		close(closed)
+		go func() {
+			for range resCh {
+			}
+		}()
+	}()
+	var (
+		filter  = newTraceFilter(&args)
+		results = []*filterTraceResult{}
+	)
+	for !filter.done() {
+		var (
+			res *blockTraceResult
+			ok  bool
+		)
+		select {
+		case res, ok = <-resCh:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if !ok {
+			break
+		}
+		if res.Error != "" {
+			return nil, fmt.Errorf("failed to trace block #%d: %s", res.Block, res.Error)
+		}
+		block, err := api.blockByHash(ctx, res.Hash)
+		if err != nil {
+			return nil, err
+		}
+		for i, trace := range res.Traces {
+			if trace == nil {
+				continue
+			}
+			if trace.Error != "" {
+				return nil, fmt.Errorf("failed to trace transaction %#x: %s", block.Transactions()[i].Hash(), trace.Error)
+			}
+			raw, ok := trace.Result.(json.RawMessage)
+			if !ok {
+				return nil, errors.New("unexpected trace result")
+			}
+			var frame filterFrame
+			if err := json.Unmarshal(raw, &frame); err != nil {
+				return nil, err
+			}
+			results = filter.collect(block, i, &frame, nil, results)
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,243 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func init() {
+	// The native call tracer can't be imported here without an import cycle, so
+	// stand in for it with a tracer reporting the top call frame only.
+	RegisterLookup(false, func(name string, ctx *Context, cfg json.RawMessage) (Tracer, error) {
+		if name != filterTracer {
+			return nil, errors.New("not the filter tracer")
+		}
+		return new(topFrameTracer), nil
+	})
+}
+
+// topFrameTracer is a minimal call tracer which only records the top call frame
+// of a transaction.
+type topFrameTracer struct {
+	frame filterFrame
+}
+
+func (t *topFrameTracer) CaptureTxStart(gasLimit uint64) {}
+func (t *topFrameTracer) CaptureTxEnd(restGas uint64)    {}
+func (t *topFrameTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.frame = filterFrame{Type: "CALL", From: from, To: to, Value: (*hexutil.Big)(value), Gas: hexutil.Uint64(gas), Input: input}
+}
+func (t *topFrameTracer) CaptureEnd(output []byte, gasUsed uint64, _ time.Duration, err error) {
+	t.frame.Output, t.frame.GasUsed = output, hexutil.Uint64(gasUsed)
+}
+func (t *topFrameTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+}
+func (t *topFrameTracer) CaptureExit(output []byte, gasUsed uint64, err error) {}
+func (t *topFrameTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+}
+func (t *topFrameTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+func (t *topFrameTracer) GetResult() (json.RawMessage, error) { return json.Marshal(t.frame) }
+func (t *topFrameTracer) Stop(err error)                      {}
+
+// failingBackend is a test backend which has no state available for a block.
+type failingBackend struct {
+	*testBackend
+	missing uint64
+}
+
+func (b *failingBackend) StateAtBlock(ctx context.Context, block *types.Block, reexec uint64, base *state.StateDB, readOnly bool, preferDisk bool) (*state.StateDB, StateReleaseFunc, error) {
+	if block.NumberU64() == b.missing {
+		return nil, nil, errStateNotFound
+	}
+	return b.testBackend.StateAtBlock(ctx, block, reexec, base, readOnly, preferDisk)
+}
+
+func TestTraceFilterCollect(t *testing.T) {
+	var (
+		a = common.BytesToAddress([]byte{0xa})
+		b = common.BytesToAddress([]byte{0xb})
+		c = common.BytesToAddress([]byte{0xc})
+	)
+	// a -> b -> c
+	//   -> c -> b
+	//   -> b
+	frame := filterFrame{
+		Type: "CALL", From: a, To: b,
+		Calls: []filterFrame{
+			{Type: "CALL", From: b, To: c},
+			{Type: "CALL", From: b, To: c, Calls: []filterFrame{
+				{Type: "STATICCALL", From: c, To: b},
+			}},
+			{Type: "DELEGATECALL", From: b, To: b},
+		},
+	}
+	tx := types.NewTransaction(0, b, big.NewInt(0), 0, big.NewInt(0), nil)
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1)}).WithBody([]*types.Transaction{tx}, nil)
+
+	u64 := func(n uint64) *uint64 { return &n }
+	var tests = []struct {
+		args  TraceFilterArgs
+		paths [][]int
+	}{
+		// No constraints, every frame matches
+		{TraceFilterArgs{}, [][]int{{}, {0}, {1}, {1, 0}, {2}}},
+		// Recipient constraint only
+		{TraceFilterArgs{ToAddress: []common.Address{c}}, [][]int{{0}, {1}}},
+		// Sender and recipient constraints
+		{TraceFilterArgs{FromAddress: []common.Address{b, c}, ToAddress: []common.Address{b}}, [][]int{{1, 0}, {2}}},
+		// Pagination window
+		{TraceFilterArgs{After: u64(1), Count: u64(2)}, [][]int{{0}, {1}}},
+		{TraceFilterArgs{After: u64(4), Count: u64(2)}, [][]int{{2}}},
+		{TraceFilterArgs{After: u64(5)}, nil},
+	}
+	for i, tt := range tests {
+		var (
+			filter  = newTraceFilter(&tt.args)
+			results = filter.collect(block, 0, &frame, nil, nil)
+			paths   [][]int
+		)
+		for _, res := range results {
+			if res.TransactionHash != tx.Hash() || res.BlockNumber != 1 {
+				t.Errorf("test %d: result position mismatch: have %x/%d", i, res.TransactionHash, res.BlockNumber)
+			}
+			paths = append(paths, res.TraceAddress)
+		}
+		if !reflect.DeepEqual(paths, tt.paths) {
+			t.Errorf("test %d: trace address mismatch: have %v, want %v", i, paths, tt.paths)
+		}
+	}
+}
+
+func TestTraceFilter(t *testing.T) {
+	t.Parallel()
+
+	// Every block contains a transfer from account 0, odd blocks also contain
+	// one from account 2.
+	accounts := newAccounts(3)
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			accounts[0].addr: {Balance: big.NewInt(params.AC)},
+			accounts[2].addr: {Balance: big.NewInt(params.AC)},
+		},
+	}
+	var (
+		genBlocks = 10
+		signer    = types.HomesteadSigner{}
+		nonces    [3]uint64
+	)
+	transfer := func(b *core.BlockGen, from int) {
+		tx, _ := types.SignTx(types.NewTransaction(nonces[from], accounts[1].addr, big.NewInt(1000), params.TxGas, b.BaseFee(), nil), signer, accounts[from].key)
+		b.AddTx(tx)
+		nonces[from]++
+	}
+	backend := newTestBackend(t, genBlocks, genesis, func(i int, b *core.BlockGen) {
+		transfer(b, 0)
+		if i%2 == 0 {
+			transfer(b, 2)
+		}
+	})
+	api := NewAPI(backend)
+
+	type position struct {
+		block uint64
+		index uint64
+	}
+	var (
+		num = func(n rpc.BlockNumber) *rpc.BlockNumber { return &n }
+		u64 = func(n uint64) *uint64 { return &n }
+	)
+	var tests = []struct {
+		args TraceFilterArgs
+		want []position
+	}{
+		// Explicit range
+		{
+			args: TraceFilterArgs{FromBlock: num(2), ToBlock: num(3)},
+			want: []position{{2, 0}, {3, 0}, {3, 1}},
+		},
+		// Block tags resolve to the chain head instead of the whole chain
+		{
+			args: TraceFilterArgs{FromBlock: num(rpc.LatestBlockNumber)},
+			want: []position{{10, 0}},
+		},
+		{
+			args: TraceFilterArgs{FromBlock: num(rpc.PendingBlockNumber), ToBlock: num(rpc.LatestBlockNumber)},
+			want: []position{{10, 0}},
+		},
+		// Earliest is clamped to the first block
+		{
+			args: TraceFilterArgs{FromBlock: num(rpc.EarliestBlockNumber), ToBlock: num(2)},
+			want: []position{{1, 0}, {1, 1}, {2, 0}},
+		},
+		// Sender constraint over the default range
+		{
+			args: TraceFilterArgs{FromAddress: []common.Address{accounts[2].addr}},
+			want: []position{{1, 1}, {3, 1}, {5, 1}, {7, 1}, {9, 1}},
+		},
+		// Pagination across blocks
+		{
+			args: TraceFilterArgs{FromAddress: []common.Address{accounts[2].addr}, After: u64(1), Count: u64(3)},
+			want: []position{{3, 1}, {5, 1}, {7, 1}},
+		},
+		{
+			args: TraceFilterArgs{ToAddress: []common.Address{accounts[0].addr}},
+			want: []position{},
+		},
+	}
+	for i, tt := range tests {
+		results, err := api.TraceFilter(context.Background(), tt.args)
+		if err != nil {
+			t.Errorf("test %d: failed to trace: %v", i, err)
+			continue
+		}
+		have := []position{}
+		for _, res := range results {
+			have = append(have, position{uint64(res.BlockNumber), uint64(res.TransactionPosition)})
+		}
+		if !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("test %d: trace position mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+	// Invalid ranges are rejected
+	if _, err := api.TraceFilter(context.Background(), TraceFilterArgs{FromBlock: num(5), ToBlock: num(4)}); err == nil {
+		t.Error("inverted range accepted")
+	}
+	// Missing state in the middle of the range must fail rather than return the
+	// partial results
+	api = NewAPI(&failingBackend{testBackend: backend, missing: 5})
+	if _, err := api.TraceFilter(context.Background(), TraceFilterArgs{}); err == nil {
+		t.Error("missing state not reported")
+	}
+}
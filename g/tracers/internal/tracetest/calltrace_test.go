@@ -17,6 +17,7 @@
 package tracetest
 
 import (
+	"bytes"
 	"encoding/json"
 	"math/big"
 	"os"
@@ -115,6 +116,15 @@ type callTrace struct {
 	Error    string          `json:"error,omitempty"`
 	Revertal string          `json:"revertReason,omitempty"`
 	Calls    []callTrace     `json:"calls,omitempty"`
+	Logs     []callLog       `json:"logs,omitempty"`
+}
+
+// callLog is the result of LOG opCode
+type callLog struct {
+	Address  common.Address `json:"address"`
+	Topics   []common.Hash  `json:"topics"`
+	Data     hexutil.Bytes  `json:"data"`
+	Position hexutil.Uint   `json:"position"`
 }
 
 // callTracerTest defines a single test to check the call tracer against.
@@ -390,3 +400,108 @@ func TestZeroValueToNotExitCall(t *testing.T) {
 		t.Error("have != want")
 	}
 }
+
+// TestCallTracerWithLog tests that the call tracer attributes emitted logs to
+// the emitting frame and drops the logs of reverted frames.
+func TestCallTracerWithLog(t *testing.T) {
+	var (
+		to     = common.HexToAddress("0x00000000000000000000000000000000deadbeef")
+		callee = common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	)
+	privkey, err := crypto.HexToECDSA("0000000000000000deadbeef00000000000000000000000000000000deadbeef")
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+	signer := types.NewEIP155Signer(big.NewInt(1))
+	tx, err := types.SignNewTx(privkey, signer, &types.LegacyTx{
+		GasPrice: big.NewInt(0),
+		Gas:      100000,
+		To:       &to,
+	})
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+	origin, _ := signer.Sender(tx)
+	txContext := vm.TxContext{
+		Origin:   origin,
+		GasPrice: big.NewInt(1),
+	}
+	context := vm.BlockContext{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		Coinbase:    common.Address{},
+		BlockNumber: new(big.Int).SetUint64(8000000),
+		Time:        new(big.Int).SetUint64(5),
+		Difficulty:  big.NewInt(0x30000),
+		GasLimit:    uint64(6000000),
+	}
+	var code = []byte{
+		byte(vm.PUSH1), 0x1, byte(vm.PUSH1), 0x0, byte(vm.PUSH1), 0x0, byte(vm.LOG1), // log1(0, 0, 0x1)
+		byte(vm.PUSH1), 0x0, byte(vm.DUP1), byte(vm.DUP1), byte(vm.DUP1), // in and outs zero
+		byte(vm.DUP1), byte(vm.PUSH1), 0xbb, byte(vm.GAS), // value=0,address=0xbb, gas=GAS
+		byte(vm.CALL), byte(vm.POP),
+		byte(vm.PUSH1), 0x20, byte(vm.PUSH1), 0x0, byte(vm.LOG0), // log0(0, 32), expands memory
+	}
+	var calleeCode = []byte{
+		byte(vm.PUSH1), 0x0, byte(vm.DUP1), byte(vm.LOG0), // log0(0, 0)
+		byte(vm.PUSH1), 0x0, byte(vm.DUP1), byte(vm.REVERT),
+	}
+	var alloc = core.GenesisAlloc{
+		to: core.GenesisAccount{
+			Nonce: 1,
+			Code:  code,
+		},
+		callee: core.GenesisAccount{
+			Nonce: 1,
+			Code:  calleeCode,
+		},
+		origin: core.GenesisAccount{
+			Nonce:   0,
+			Balance: big.NewInt(500000000000000),
+		},
+	}
+	_, statedb := tests.MakePreState(rawdb.NewMemoryDatabase(), alloc, false)
+	// Create the tracer, the EVM environment and run it
+	tracer, err := tracers.New("callTracer", nil, json.RawMessage(`{"withLog": true}`))
+	if err != nil {
+		t.Fatalf("failed to create call tracer: %v", err)
+	}
+	evm := vm.NewEVM(context, txContext, statedb, params.MainnetChainConfig, vm.Config{Debug: true, Tracer: tracer})
+	msg, err := tx.AsMessage(signer, nil)
+	if err != nil {
+		t.Fatalf("failed to prepare transaction for tracing: %v", err)
+	}
+	st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
+	if _, err = st.TransitionDb(); err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	have := new(callTrace)
+	if err := json.Unmarshal(res, have); err != nil {
+		t.Fatalf("failed to unmarshal trace result: %v", err)
+	}
+	want := []callLog{
+		{Address: to, Topics: []common.Hash{common.BigToHash(common.Big1)}, Data: hexutil.Bytes{}, Position: 0},
+		{Address: to, Topics: []common.Hash{}, Data: make(hexutil.Bytes, 32), Position: 1},
+	}
+	if len(have.Logs) != len(want) {
+		t.Fatalf("top frame log count mismatch: have %d, want %d", len(have.Logs), len(want))
+	}
+	for i, log := range have.Logs {
+		if log.Address != want[i].Address || log.Position != want[i].Position {
+			t.Errorf("log %d: emitter/position mismatch: have %x/%d, want %x/%d", i, log.Address, log.Position, want[i].Address, want[i].Position)
+		}
+		if !reflect.DeepEqual(log.Topics, want[i].Topics) || !bytes.Equal(log.Data, want[i].Data) {
+			t.Errorf("log %d: content mismatch: have %v/%x, want %v/%x", i, log.Topics, log.Data, want[i].Topics, want[i].Data)
+		}
+	}
+	if len(have.Calls) != 1 {
+		t.Fatalf("subcall count mismatch: have %d, want 1", len(have.Calls))
+	}
+	if len(have.Calls[0].Logs) != 0 {
+		t.Errorf("reverted frame retained logs: %v", have.Calls[0].Logs)
+	}
+}
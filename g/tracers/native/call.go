@@ -19,6 +19,7 @@ package native
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"math/big"
 	"sync/atomic"
 	"time"
@@ -28,6 +29,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/g/tracers"
+	"github.com/ethereum/go-ethereum/log"
 )
 
 //go:generate go run github.com/fjl/gencodec -type callFrame -field-override callFrameMarshaling -out gen_callframe_json.go
@@ -36,6 +38,19 @@ func init() {
 	register("callTracer", newCallTracer)
 }
 
+// memoryPadLimit is the maximum number of bytes a memory slice may be padded
+// with when reading log data, guarding against unrealistically large sizes.
+const memoryPadLimit = 1024 * 1024
+
+type callLog struct {
+	Address common.Address `json:"address"`
+	Topics  []common.Hash  `json:"topics"`
+	Data    hexutil.Bytes  `json:"data"`
+	// Position of the log relative to the subcalls of the emitting frame,
+	// i.e. the number of subcalls that were completed before the log.
+	Position hexutil.Uint `json:"position"`
+}
+
 type callFrame struct {
 	Type     vm.OpCode      `json:"-"`
 	From     common.Address `json:"from"`
@@ -47,6 +62,7 @@ type callFrame struct {
 	Error    string         `json:"error,omitempty" rlp:"optional"`
 	Revertal string         `json:"revertReason,omitempty"`
 	Calls    []callFrame    `json:"calls,omitempty" rlp:"optional"`
+	Logs     []callLog      `json:"logs,omitempty" rlp:"optional"`
 	// Placed at end on purpose. The RLP will be decoded to 0 instead of
 	// nil if there are non-empty elements after in the struct.
 	Value *big.Int `json:"value,omitempty" rlp:"optional"`
@@ -56,6 +72,10 @@ func (f callFrame) TypeString() string {
 	return f.Type.String()
 }
 
+func (f callFrame) failed() bool {
+	return len(f.Error) > 0
+}
+
 type callFrameMarshaling struct {
 	TypeString string `json:"type"`
 	Gas        hexutil.Uint64
@@ -75,6 +95,7 @@ type callTracer struct {
 
 type callTracerConfig struct {
 	OnlyTopCall bool `json:"onlyTopCall"` // If true, call tracer won't collect any subcalls
+	WithLog     bool `json:"withLog"`     // If true, call tracer will collect event logs
 }
 
 // newCallTracer returns a native go tracer which tracks
@@ -130,6 +151,45 @@ func (t *callTracer) CaptureEnd(output []byte, gasUsed uint64, _ time.Duration,
 
 // CaptureState implements the EVMLogger interface to trace a single step of VM execution.
 func (t *callTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	// Skip if the opcode failed or logs were not requested
+	if err != nil || !t.config.WithLog {
+		return
+	}
+	// Avoid processing nested calls when only caring about the top call
+	if t.config.OnlyTopCall && depth > 1 {
+		return
+	}
+	// Skip if tracing was interrupted
+	if atomic.LoadUint32(&t.interrupt) > 0 {
+		return
+	}
+	switch op {
+	case vm.LOG0, vm.LOG1, vm.LOG2, vm.LOG3, vm.LOG4:
+		var (
+			size      = int(op - vm.LOG0)
+			stackData = scope.Stack.Data()
+			mStart    = stackData[len(stackData)-1]
+			mSize     = stackData[len(stackData)-2]
+			topics    = make([]common.Hash, size)
+		)
+		for i := 0; i < size; i++ {
+			topic := stackData[len(stackData)-2-(i+1)]
+			topics[i] = common.Hash(topic.Bytes32())
+		}
+		data, err := getMemoryCopyPadded(scope.Memory, int64(mStart.Uint64()), int64(mSize.Uint64()))
+		if err != nil {
+			// mSize was unrealistically large
+			log.Warn("Failed to copy log data", "err", err, "tracer", "callTracer", "offset", mStart, "size", mSize)
+			return
+		}
+		frame := &t.callstack[len(t.callstack)-1]
+		frame.Logs = append(frame.Logs, callLog{
+			Address:  scope.Contract.Address(),
+			Topics:   topics,
+			Data:     hexutil.Bytes(data),
+			Position: hexutil.Uint(len(frame.Calls)),
+		})
+	}
 }
 
 // CaptureFault implements the EVMLogger interface to trace an execution fault.
@@ -195,6 +255,9 @@ func (t *callTracer) GetResult() (json.RawMessage, error) {
 	if len(t.callstack) != 1 {
 		return nil, errors.New("incorrect number of top-level calls")
 	}
+	if t.config.WithLog {
+		clearFailedLogs(&t.callstack[0], false)
+	}
 	res, err := json.Marshal(t.callstack[0])
 	if err != nil {
 		return nil, err
@@ -207,3 +270,36 @@ func (t *callTracer) Stop(err error) {
 	t.reason = err
 	atomic.StoreUint32(&t.interrupt, 1)
 }
+
+// clearFailedLogs clears the logs of a callframe and all its children
+// in case of execution failure, since the logs of reverted frames are
+// discarded by the state.
+func clearFailedLogs(cf *callFrame, parentFailed bool) {
+	failed := cf.failed() || parentFailed
+	if failed {
+		cf.Logs = nil
+	}
+	for i := range cf.Calls {
+		clearFailedLogs(&cf.Calls[i], failed)
+	}
+}
+
+// getMemoryCopyPadded returns offset + size as a new slice.
+// It zero-pads the slice if it extends beyond memory bounds.
+func getMemoryCopyPadded(m *vm.Memory, offset, size int64) ([]byte, error) {
+	if offset < 0 || size < 0 {
+		return nil, errors.New("offset or size must not be negative")
+	}
+	if int(offset+size) <= m.Len() { // slice fully inside memory
+		return m.GetCopy(offset, size), nil
+	}
+	paddingNeeded := int(offset+size) - m.Len()
+	if paddingNeeded > memoryPadLimit {
+		return nil, fmt.Errorf("reached limit for padding memory slice: %d", paddingNeeded)
+	}
+	cpy := make([]byte, size)
+	if overlap := int64(m.Len()) - offset; overlap > 0 {
+		copy(cpy, m.Data()[offset:offset+overlap])
+	}
+	return cpy, nil
+}
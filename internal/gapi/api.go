@@ -37,6 +37,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -253,6 +254,79 @@ func (s *TxPoolAPI) Inspect() map[string]map[string]map[string]string {
 	return content
 }
 
+// RPCTxHistoryEvent represents a transaction pool lifecycle event that will
+// serialize to the RPC representation of it.
+type RPCTxHistoryEvent struct {
+	Hash        common.Hash     `json:"hash"`
+	Event       string          `json:"event"`
+	Reason      string          `json:"reason,omitempty"`
+	ReplacedBy  *common.Hash    `json:"replacedBy,omitempty"`
+	BlockNumber *hexutil.Uint64 `json:"blockNumber,omitempty"`
+	Time        hexutil.Uint64  `json:"time"`
+}
+
+// newRPCTxHistoryEvent returns a lifecycle event that will serialize to the RPC
+// representation.
+func newRPCTxHistoryEvent(event core.TxHistoryEvent) *RPCTxHistoryEvent {
+	result := &RPCTxHistoryEvent{
+		Hash:   event.Hash,
+		Event:  string(event.Kind),
+		Reason: event.Reason,
+		Time:   hexutil.Uint64(event.Time.Unix()),
+	}
+	switch event.Kind {
+	case core.TxLifecycleReplaced:
+		result.ReplacedBy = &event.ReplacedBy
+	case core.TxLifecycleIncluded:
+		number := hexutil.Uint64(event.BlockNumber)
+		result.BlockNumber = &number
+	}
+	return result
+}
+
+// GetTransactionHistory returns the lifecycle events of a transaction retained
+// by the transaction pool, oldest first.
+func (s *TxPoolAPI) GetTransactionHistory(hash common.Hash) []*RPCTxHistoryEvent {
+	events := s.b.TxPoolHistory(hash)
+	result := make([]*RPCTxHistoryEvent, len(events))
+	for i, event := range events {
+		result[i] = newRPCTxHistoryEvent(event)
+	}
+	return result
+}
+
+// DroppedTransactions creates a subscription that is triggered each time a
+// transaction is dropped from the transaction pool without being included.
+func (s *TxPoolAPI) DroppedTransactions(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		drops := make(chan core.DropTxsEvent, 128)
+		sub := s.b.SubscribeDropTxsEvent(drops)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case drop := <-drops:
+				for _, event := range drop.Events {
+					notifier.Notify(rpcSub.ID, newRPCTxHistoryEvent(event))
+				}
+			case <-sub.Err():
+				return
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
 // EthereumAccountAPI provides an API to access accounts managed by this node.
 // It offers only methods that can retrieve accounts.
 type EthereumAccountAPI struct {
@@ -1679,6 +1753,12 @@ func (s *TransactionAPI) sign(addr common.Address, tx *types.Transaction) (*type
 
 // SubmitTransaction is a helper function that submits tx to txPool and logs a message.
 func SubmitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (common.Hash, error) {
+	return submitTransaction(ctx, b, tx, false)
+}
+
+// submitTransaction submits tx to the txPool, either publicly or privately, and
+// logs a message.
+func submitTransaction(ctx context.Context, b Backend, tx *types.Transaction, private bool) (common.Hash, error) {
 	// If the transaction fee cap is already specified, ensure the
 	// fee of the given transaction is _reasonable_.
 	if err := checkTxFee(tx.GasPrice(), tx.Gas(), b.RPCTxFeeCap()); err != nil {
@@ -1688,8 +1768,14 @@ func SubmitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (c
 		// Ensure only eip155 signed transactions are submitted if EIP155Required is set.
 		return common.Hash{}, errors.New("only replay-protected (EIP-155) transactions allowed over RPC")
 	}
-	if err := b.SendTx(ctx, tx); err != nil {
-		return common.Hash{}, err
+	if private {
+		if err := b.SendPrivateTx(ctx, tx); err != nil {
+			return common.Hash{}, err
+		}
+	} else {
+		if err := b.SendTx(ctx, tx); err != nil {
+			return common.Hash{}, err
+		}
 	}
 	// Print a log with full tx details for manual investigations and interventions
 	signer := types.MakeSigner(b.ChainConfig(), b.CurrentBlock().Number())
@@ -1700,9 +1786,9 @@ func SubmitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (c
 
 	if tx.To() == nil {
 		addr := crypto.CreateAddress(from, tx.Nonce())
-		log.Info("Submitted contract creation", "hash", tx.Hash().Hex(), "from", from, "nonce", tx.Nonce(), "contract", addr.Hex(), "value", tx.Value())
+		log.Info("Submitted contract creation", "hash", tx.Hash().Hex(), "from", from, "nonce", tx.Nonce(), "contract", addr.Hex(), "value", tx.Value(), "private", private)
 	} else {
-		log.Info("Submitted transaction", "hash", tx.Hash().Hex(), "from", from, "nonce", tx.Nonce(), "recipient", tx.To(), "value", tx.Value())
+		log.Info("Submitted transaction", "hash", tx.Hash().Hex(), "from", from, "nonce", tx.Nonce(), "recipient", tx.To(), "value", tx.Value(), "private", private)
 	}
 	return tx.Hash(), nil
 }
@@ -1766,6 +1852,64 @@ func (s *TransactionAPI) SendRawTransaction(ctx context.Context, input hexutil.B
 	return SubmitTransaction(ctx, s.b, tx)
 }
 
+// SendPrivateRawTransaction will add the signed transaction to the transaction
+// pool without propagating it to the network. The transaction is only included
+// in blocks produced by the local miner, and dropped if not included within the
+// configured number of blocks.
+func (s *TransactionAPI) SendPrivateRawTransaction(ctx context.Context, input hexutil.Bytes) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(input); err != nil {
+		return common.Hash{}, err
+	}
+	return submitTransaction(ctx, s.b, tx, true)
+}
+
+// SendBundleArgs represents the arguments to submit a bundle of transactions to
+// be included atomically in a future block.
+type SendBundleArgs struct {
+	Txs               []hexutil.Bytes `json:"txs"`
+	BlockNumber       hexutil.Uint64  `json:"blockNumber"`
+	MinTimestamp      *hexutil.Uint64 `json:"minTimestamp"`
+	MaxTimestamp      *hexutil.Uint64 `json:"maxTimestamp"`
+	RevertingTxHashes []common.Hash   `json:"revertingTxHashes"`
+}
+
+// SendBundle will add the signed transaction bundle to the bundle pool, to be
+// included back to back in the given block by the local miner, if all of its
+// transactions succeed. Transactions listed as reverting are allowed to fail
+// without invalidating the bundle.
+func (s *TransactionAPI) SendBundle(ctx context.Context, args SendBundleArgs) (common.Hash, error) {
+	if len(args.Txs) == 0 {
+		return common.Hash{}, txpool.ErrBundleEmpty
+	}
+	bundle := &txpool.Bundle{
+		Txs:               make(types.Transactions, len(args.Txs)),
+		BlockNumber:       uint64(args.BlockNumber),
+		RevertingTxHashes: args.RevertingTxHashes,
+	}
+	if args.MinTimestamp != nil {
+		bundle.MinTimestamp = uint64(*args.MinTimestamp)
+	}
+	if args.MaxTimestamp != nil {
+		bundle.MaxTimestamp = uint64(*args.MaxTimestamp)
+	}
+	for i, input := range args.Txs {
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(input); err != nil {
+			return common.Hash{}, fmt.Errorf("transaction %d: %w", i, err)
+		}
+		if !s.b.UnprotectedAllowed() && !tx.Protected() {
+			return common.Hash{}, fmt.Errorf("transaction %d: only replay-protected (EIP-155) transactions allowed over RPC", i)
+		}
+		bundle.Txs[i] = tx
+	}
+	if err := s.b.SendBundle(ctx, bundle); err != nil {
+		return common.Hash{}, err
+	}
+	log.Info("Submitted transaction bundle", "hash", bundle.Hash(), "txs", len(bundle.Txs), "block", bundle.BlockNumber)
+	return bundle.Hash(), nil
+}
+
 // Sign calculates an ECDSA signature for:
 // keccak256("\x19Ethereum Signed Message:\n" + len(message) + message).
 //
@@ -1905,6 +2049,109 @@ func (s *TransactionAPI) Resend(ctx context.Context, sendArgs TransactionArgs, g
 	return common.Hash{}, fmt.Errorf("transaction %#x not found", matchTx.Hash())
 }
 
+// SpeedUpTransaction replaces a pending transaction sent from a local account
+// with an identical one paying the minimum fee bump accepted by the transaction
+// pool, returning the hash of the replacement.
+func (s *TransactionAPI) SpeedUpTransaction(ctx context.Context, hash common.Hash) (common.Hash, error) {
+	return s.replaceTransaction(ctx, hash, false)
+}
+
+// CancelTransaction replaces a pending transaction sent from a local account
+// with an empty transfer to the sender itself, paying the minimum fee bump
+// accepted by the transaction pool. The hash of the replacement is returned.
+func (s *TransactionAPI) CancelTransaction(ctx context.Context, hash common.Hash) (common.Hash, error) {
+	return s.replaceTransaction(ctx, hash, true)
+}
+
+// replaceTransaction constructs, signs and submits the replacement of a pending
+// transaction, either retaining its content or turning it into a no-op.
+func (s *TransactionAPI) replaceTransaction(ctx context.Context, hash common.Hash, cancel bool) (common.Hash, error) {
+	tx := s.b.GetPoolTransaction(hash)
+	if tx == nil {
+		return common.Hash{}, fmt.Errorf("transaction %#x not pending", hash)
+	}
+	from, err := types.Sender(s.signer, tx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	s.nonceLock.LockAddr(from)
+	defer s.nonceLock.UnlockAddr(from)
+
+	// The replacement needs to be executable at the base fee of the next block
+	var (
+		config  = s.b.ChainConfig()
+		head    = s.b.CurrentHeader()
+		baseFee *big.Int
+	)
+	if config.IsLondon(new(big.Int).Add(head.Number, common.Big1)) {
+		baseFee = misc.CalcBaseFee(config, head)
+	}
+	data, err := replacementTx(tx, from, cancel, s.b.TxPoolPriceBump(), baseFee, config.ChainID)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	replacement := types.NewTx(data)
+	if err := checkTxFee(replacement.GasFeeCap(), replacement.Gas(), s.b.RPCTxFeeCap()); err != nil {
+		return common.Hash{}, err
+	}
+	signed, err := s.sign(from, replacement)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	// Keep private transactions private, a public replacement would leak them
+	return submitTransaction(ctx, s.b, signed, s.b.IsPrivateTx(hash))
+}
+
+// replacementTx assembles the transaction replacing tx in the pool, bumping its
+// prices by the given percentage and making sure it can pay the base fee. If
+// cancel is set, the replacement is a plain zero value transfer to the sender.
+func replacementTx(tx *types.Transaction, from common.Address, cancel bool, priceBump uint64, baseFee *big.Int, chainID *big.Int) (types.TxData, error) {
+	var (
+		to         = tx.To()
+		value      = tx.Value()
+		gas        = tx.Gas()
+		input      = tx.Data()
+		accessList = tx.AccessList()
+	)
+	if cancel {
+		to, value, gas, input, accessList = &from, new(big.Int), params.TxGas, nil, nil
+	}
+	switch tx.Type() {
+	case types.LegacyTxType, types.AccessListTxType:
+		price := bumpPrice(tx.GasPrice(), priceBump)
+		if baseFee != nil && price.Cmp(baseFee) < 0 {
+			price = new(big.Int).Set(baseFee)
+		}
+		if tx.Type() == types.LegacyTxType {
+			return &types.LegacyTx{Nonce: tx.Nonce(), GasPrice: price, Gas: gas, To: to, Value: value, Data: input}, nil
+		}
+		return &types.AccessListTx{ChainID: chainID, Nonce: tx.Nonce(), GasPrice: price, Gas: gas, To: to, Value: value, Data: input, AccessList: accessList}, nil
+
+	case types.DynamicFeeTxType:
+		tip, feeCap := bumpPrice(tx.GasTipCap(), priceBump), bumpPrice(tx.GasFeeCap(), priceBump)
+		if baseFee != nil {
+			if min := new(big.Int).Add(baseFee, tip); feeCap.Cmp(min) < 0 {
+				feeCap = min
+			}
+		}
+		return &types.DynamicFeeTx{ChainID: chainID, Nonce: tx.Nonce(), GasTipCap: tip, GasFeeCap: feeCap, Gas: gas, To: to, Value: value, Data: input, AccessList: accessList}, nil
+
+	default:
+		return nil, fmt.Errorf("replacing transactions of type %d not supported", tx.Type())
+	}
+}
+
+// bumpPrice returns the lowest price exceeding the old one by at least the given
+// percentage, matching the replacement rules of the transaction pool.
+func bumpPrice(old *big.Int, percent uint64) *big.Int {
+	price := new(big.Int).Mul(old, new(big.Int).SetUint64(100+percent))
+	price.Div(price, big.NewInt(100))
+	if price.Cmp(old) <= 0 {
+		price.Add(old, common.Big1)
+	}
+	return price
+}
+
 // DebugAPI is the collection of Ethereum APIs exposed over the debugging
 // namespace.
 type DebugAPI struct {
@@ -0,0 +1,286 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gapi
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func TestBumpPrice(t *testing.T) {
+	var cases = []struct {
+		old     int64
+		percent uint64
+		want    int64
+	}{
+		{100, 10, 110},
+		{105, 10, 115}, // 115.5 rounded down, as the pool does
+		{5, 10, 6},     // wei-level prices still need to increase
+		{0, 10, 1},
+	}
+	for i, c := range cases {
+		if have := bumpPrice(big.NewInt(c.old), c.percent); have.Int64() != c.want {
+			t.Errorf("test %d: price mismatch: have %d, want %d", i, have, c.want)
+		}
+	}
+}
+
+func TestReplacementTx(t *testing.T) {
+	var (
+		from    = common.Address{0xaa}
+		to      = common.Address{0xbb}
+		chainID = big.NewInt(1)
+	)
+	dynamic := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     7,
+		GasTipCap: big.NewInt(10),
+		GasFeeCap: big.NewInt(100),
+		Gas:       50000,
+		To:        &to,
+		Value:     big.NewInt(1000),
+		Data:      []byte{0x01},
+	})
+	legacy := types.NewTx(&types.LegacyTx{
+		Nonce:    7,
+		GasPrice: big.NewInt(100),
+		Gas:      50000,
+		To:       &to,
+		Value:    big.NewInt(1000),
+	})
+	var cases = []struct {
+		tx      *types.Transaction
+		cancel  bool
+		baseFee *big.Int
+		tip     int64
+		feeCap  int64
+	}{
+		{dynamic, false, big.NewInt(50), 11, 110},  // plain bump of both prices
+		{dynamic, false, big.NewInt(200), 11, 211}, // fee cap raised to cover the base fee
+		{dynamic, true, nil, 11, 110},
+		{legacy, false, big.NewInt(50), 110, 110},
+		{legacy, false, big.NewInt(150), 150, 150},
+		{legacy, true, nil, 110, 110},
+	}
+	for i, c := range cases {
+		data, err := replacementTx(c.tx, from, c.cancel, 10, c.baseFee, chainID)
+		if err != nil {
+			t.Fatalf("test %d: failed to create replacement: %v", i, err)
+		}
+		tx := types.NewTx(data)
+		if tx.Type() != c.tx.Type() || tx.Nonce() != c.tx.Nonce() {
+			t.Errorf("test %d: type/nonce mismatch: have %d/%d, want %d/%d", i, tx.Type(), tx.Nonce(), c.tx.Type(), c.tx.Nonce())
+		}
+		if tx.GasTipCap().Int64() != c.tip || tx.GasFeeCap().Int64() != c.feeCap {
+			t.Errorf("test %d: price mismatch: have %d/%d, want %d/%d", i, tx.GasTipCap(), tx.GasFeeCap(), c.tip, c.feeCap)
+		}
+		if c.cancel {
+			if *tx.To() != from || tx.Value().Sign() != 0 || len(tx.Data()) != 0 || tx.Gas() != params.TxGas {
+				t.Errorf("test %d: cancellation not a plain self transfer", i)
+			}
+		} else {
+			if *tx.To() != *c.tx.To() || tx.Value().Cmp(c.tx.Value()) != 0 || tx.Gas() != c.tx.Gas() || string(tx.Data()) != string(c.tx.Data()) {
+				t.Errorf("test %d: speed up changed transaction content", i)
+			}
+		}
+	}
+	blob := types.NewTx(&types.BlobTx{})
+	if _, err := replacementTx(blob, from, false, 10, nil, chainID); err == nil {
+		t.Errorf("replacing blob transaction succeeded")
+	}
+}
+
+// replaceBackend is a backend mock holding a single pool transaction and
+// recording the submission path of its replacements.
+type replaceBackend struct {
+	*backendMock
+	am      *accounts.Manager
+	tx      *types.Transaction
+	private bool
+	public  []*types.Transaction
+	hidden  []*types.Transaction
+}
+
+func (b *replaceBackend) AccountManager() *accounts.Manager { return b.am }
+func (b *replaceBackend) CurrentBlock() *types.Block        { return types.NewBlockWithHeader(b.current) }
+func (b *replaceBackend) UnprotectedAllowed() bool          { return true }
+func (b *replaceBackend) IsPrivateTx(hash common.Hash) bool {
+	return b.private && hash == b.tx.Hash()
+}
+func (b *replaceBackend) GetPoolTransaction(hash common.Hash) *types.Transaction {
+	if hash == b.tx.Hash() {
+		return b.tx
+	}
+	return nil
+}
+func (b *replaceBackend) SendTx(ctx context.Context, tx *types.Transaction) error {
+	b.public = append(b.public, tx)
+	return nil
+}
+func (b *replaceBackend) SendPrivateTx(ctx context.Context, tx *types.Transaction) error {
+	b.hidden = append(b.hidden, tx)
+	return nil
+}
+
+// Tests that replacements of private transactions are submitted privately too.
+func TestReplacePrivateTransaction(t *testing.T) {
+	for _, private := range []bool{false, true} {
+		ks := keystore.NewKeyStore(t.TempDir(), keystore.LightScryptN, keystore.LightScryptP)
+		account, err := ks.NewAccount("")
+		if err != nil {
+			t.Fatalf("failed to create account: %v", err)
+		}
+		if err := ks.Unlock(account, ""); err != nil {
+			t.Fatalf("failed to unlock account: %v", err)
+		}
+		backend := &replaceBackend{
+			backendMock: newBackendMock(),
+			am:          accounts.NewManager(&accounts.Config{InsecureUnlockAllowed: true}, ks),
+			private:     private,
+		}
+		backend.tx, err = ks.SignTx(account, types.NewTx(&types.DynamicFeeTx{
+			ChainID:   backend.config.ChainID,
+			Nonce:     1,
+			GasTipCap: big.NewInt(10),
+			GasFeeCap: big.NewInt(100),
+			Gas:       params.TxGas,
+			To:        &common.Address{0xbb},
+		}), backend.config.ChainID)
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		api := NewTransactionAPI(backend, new(AddrLocker))
+		if _, err := api.SpeedUpTransaction(context.Background(), backend.tx.Hash()); err != nil {
+			t.Fatalf("private %v: failed to speed up transaction: %v", private, err)
+		}
+		public, hidden := len(backend.public), len(backend.hidden)
+		if private && (public != 0 || hidden != 1) {
+			t.Errorf("private replacement submitted publicly: public %d, private %d", public, hidden)
+		}
+		if !private && (public != 1 || hidden != 0) {
+			t.Errorf("public replacement submitted privately: public %d, private %d", public, hidden)
+		}
+	}
+}
+
+// historyBackend is a backend mock serving transaction pool lifecycle events.
+type historyBackend struct {
+	*backendMock
+	history map[common.Hash][]core.TxHistoryEvent
+	drops   event.Feed
+}
+
+func (b *historyBackend) TxPoolHistory(hash common.Hash) []core.TxHistoryEvent {
+	return b.history[hash]
+}
+
+func (b *historyBackend) SubscribeDropTxsEvent(ch chan<- core.DropTxsEvent) event.Subscription {
+	return b.drops.Subscribe(ch)
+}
+
+// Tests that the transaction pool lifecycle events are served through the RPC
+// history method and the dropped transactions subscription.
+func TestTransactionHistoryRPC(t *testing.T) {
+	var (
+		original    = common.Hash{0x01}
+		replacement = common.Hash{0x02}
+		now         = time.Unix(1000, 0)
+	)
+	backend := &historyBackend{
+		backendMock: newBackendMock(),
+		history: map[common.Hash][]core.TxHistoryEvent{
+			original: {
+				{Hash: original, Kind: core.TxLifecycleAdded, Time: now},
+				{Hash: original, Kind: core.TxLifecycleReplaced, ReplacedBy: replacement, Time: now},
+			},
+			replacement: {
+				{Hash: replacement, Kind: core.TxLifecycleAdded, Time: now},
+				{Hash: replacement, Kind: core.TxLifecycleIncluded, BlockNumber: 5, Time: now},
+			},
+		},
+	}
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("txpool", NewTxPoolAPI(backend)); err != nil {
+		t.Fatalf("failed to register API: %v", err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	// Retrieve the history of both transactions and check the kind specific fields
+	var events []*RPCTxHistoryEvent
+	if err := client.Call(&events, "txpool_getTransactionHistory", original); err != nil {
+		t.Fatalf("failed to retrieve history: %v", err)
+	}
+	if len(events) != 2 || events[0].Event != "added" || events[1].Event != "replaced" {
+		t.Fatalf("original history mismatch: have %v", events)
+	}
+	if events[1].ReplacedBy == nil || *events[1].ReplacedBy != replacement || events[1].BlockNumber != nil {
+		t.Errorf("replacement event fields mismatch: have %+v", events[1])
+	}
+	if uint64(events[0].Time) != uint64(now.Unix()) {
+		t.Errorf("event time mismatch: have %d, want %d", events[0].Time, now.Unix())
+	}
+	var included []*RPCTxHistoryEvent
+	if err := client.Call(&included, "txpool_getTransactionHistory", replacement); err != nil {
+		t.Fatalf("failed to retrieve history: %v", err)
+	}
+	if len(included) != 2 || included[1].Event != "included" || included[1].BlockNumber == nil || *included[1].BlockNumber != 5 || included[1].ReplacedBy != nil {
+		t.Errorf("inclusion event mismatch: have %+v", included[len(included)-1])
+	}
+	var unknown []*RPCTxHistoryEvent
+	if err := client.Call(&unknown, "txpool_getTransactionHistory", common.Hash{0xff}); err != nil || len(unknown) != 0 {
+		t.Errorf("unknown transaction history mismatch: have %v, %v", unknown, err)
+	}
+	// Subscribe to the dropped transactions and ensure drops are forwarded
+	drops := make(chan *RPCTxHistoryEvent)
+	sub, err := client.Subscribe(context.Background(), "txpool", drops, "droppedTransactions")
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	drop := core.DropTxsEvent{Events: []core.TxHistoryEvent{
+		{Hash: original, Kind: core.TxLifecycleDropped, Reason: core.TxDropUnderpriced, Time: now},
+	}}
+	for deadline := time.Now().Add(time.Second); backend.drops.Send(drop) == 0; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("subscription not registered with the backend")
+		}
+	}
+	select {
+	case event := <-drops:
+		if event.Hash != original || event.Event != "dropped" || event.Reason != core.TxDropUnderpriced {
+			t.Errorf("dropped event mismatch: have %+v", event)
+		}
+	case err := <-sub.Err():
+		t.Fatalf("subscription failed: %v", err)
+	case <-time.After(time.Second):
+		t.Fatalf("dropped event not delivered")
+	}
+}
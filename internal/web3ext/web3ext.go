@@ -22,6 +22,7 @@ var Modules = map[string]string{
 	"clique":   CliqueJs,
 	"gash":     GashJs,
 	"debug":    DebugJs,
+	"dev":      DevJs,
 	"g":        EthJs,
 	"miner":    MinerJs,
 	"net":      NetJs,
@@ -141,6 +142,31 @@ web3._extend({
 			call: 'admin_removeTrustedPeer',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'setPeerGroup',
+			call: 'admin_setPeerGroup',
+			params: 4
+		}),
+		new web3._extend.Method({
+			name: 'removePeerGroup',
+			call: 'admin_removePeerGroup',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'peerReputation',
+			call: 'admin_peerReputation',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'setPeerReputation',
+			call: 'admin_setPeerReputation',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'resetPeerReputation',
+			call: 'admin_resetPeerReputation',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'exportChain',
 			call: 'admin_exportChain',
@@ -199,6 +225,14 @@ web3._extend({
 			name: 'peers',
 			getter: 'admin_peers'
 		}),
+		new web3._extend.Property({
+			name: 'peerGroups',
+			getter: 'admin_peerGroups'
+		}),
+		new web3._extend.Property({
+			name: 'peerReputations',
+			getter: 'admin_peerReputations'
+		}),
 		new web3._extend.Property({
 			name: 'datadir',
 			getter: 'admin_datadir'
@@ -516,6 +550,16 @@ web3._extend({
 			params: 3,
 			inputFormatter: [web3._extend.formatters.inputTransactionFormatter, web3._extend.utils.fromDecimal, web3._extend.utils.fromDecimal]
 		}),
+		new web3._extend.Method({
+			name: 'speedUpTransaction',
+			call: 'g_speedUpTransaction',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'cancelTransaction',
+			call: 'g_cancelTransaction',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'signTransaction',
 			call: 'g_signTransaction',
@@ -535,6 +579,16 @@ web3._extend({
 			params: 1,
 			inputFormatter: [web3._extend.formatters.inputTransactionFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'sendPrivateRawTransaction',
+			call: 'g_sendPrivateRawTransaction',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'sendBundle',
+			call: 'g_sendBundle',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'fillTransaction',
 			call: 'g_fillTransaction',
@@ -784,10 +838,40 @@ web3._extend({
 			call: 'txpool_contentFrom',
 			params: 1,
 		}),
+		new web3._extend.Method({
+			name: 'getTransactionHistory',
+			call: 'txpool_getTransactionHistory',
+			params: 1,
+		}),
 	]
 });
 `
 
+const DevJs = `
+web3._extend({
+	property: 'dev',
+	methods:
+	[
+		new web3._extend.Method({
+			name: 'commit',
+			call: 'dev_commit',
+			params: 0,
+		}),
+		new web3._extend.Method({
+			name: 'adjustTime',
+			call: 'dev_adjustTime',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'addWithdrawal',
+			call: 'dev_addWithdrawal',
+			params: 1,
+		}),
+	],
+});
+`
+
 const LESJs = `
 web3._extend({
 	property: 'les',
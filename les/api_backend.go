@@ -30,6 +30,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/bloombits"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/event"
@@ -193,6 +194,18 @@ func (b *LesApiBackend) SendTx(ctx context.Context, signedTx *types.Transaction)
 	return b.g.txPool.Add(ctx, signedTx)
 }
 
+func (b *LesApiBackend) SendPrivateTx(ctx context.Context, signedTx *types.Transaction) error {
+	return errors.New("private transactions not supported by light client")
+}
+
+func (b *LesApiBackend) IsPrivateTx(hash common.Hash) bool {
+	return false
+}
+
+func (b *LesApiBackend) SendBundle(ctx context.Context, bundle *txpool.Bundle) error {
+	return errors.New("transaction bundles not supported by light client")
+}
+
 func (b *LesApiBackend) RemoveTx(txHash common.Hash) {
 	b.g.txPool.RemoveTx(txHash)
 }
@@ -213,6 +226,12 @@ func (b *LesApiBackend) GetPoolNonce(ctx context.Context, addr common.Address) (
 	return b.g.txPool.GetNonce(ctx, addr)
 }
 
+// TxPoolPriceBump returns the default price bump, as the light client forwards
+// transactions to servers which are assumed to run with default pool settings.
+func (b *LesApiBackend) TxPoolPriceBump() uint64 {
+	return core.DefaultTxPoolConfig.PriceBump
+}
+
 func (b *LesApiBackend) Stats() (pending int, queued int) {
 	return b.g.txPool.Stats(), 0
 }
@@ -225,10 +244,21 @@ func (b *LesApiBackend) TxPoolContentFrom(addr common.Address) (types.Transactio
 	return b.g.txPool.ContentFrom(addr)
 }
 
+func (b *LesApiBackend) TxPoolHistory(hash common.Hash) []core.TxHistoryEvent {
+	return nil
+}
+
 func (b *LesApiBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
 	return b.g.txPool.SubscribeNewTxsEvent(ch)
 }
 
+func (b *LesApiBackend) SubscribeDropTxsEvent(ch chan<- core.DropTxsEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
 func (b *LesApiBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
 	return b.g.blockchain.SubscribeChainEvent(ch)
 }
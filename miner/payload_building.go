@@ -0,0 +1,251 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/beacon"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// payloadResolveTimeout is the maximum time to wait for the first full block
+	// when a payload is retrieved, before falling back to the empty one.
+	payloadResolveTimeout = 500 * time.Millisecond
+
+	// payloadBuildTimeout is the maximum time spent rebuilding a payload that is
+	// never retrieved, roughly matching the slot time of the beacon chain.
+	payloadBuildTimeout = 12 * time.Second
+)
+
+// BuildPayloadArgs contains the provided parameters for building payload.
+type BuildPayloadArgs struct {
+	Parent       common.Hash       // The parent block to build payload on top
+	Timestamp    uint64            // The provided timestamp of generated payload
+	FeeRecipient common.Address    // The provided recipient address for collecting transaction fee
+	Random       common.Hash       // The provided randomness value
+	Withdrawals  types.Withdrawals // The provided withdrawals
+}
+
+// PayloadRevisionEvent is posted every time a payload being built is replaced
+// by a more valuable one.
+type PayloadRevisionEvent struct {
+	ID       beacon.PayloadID // Identifier of the payload being built
+	Revision int              // Sequence number of the revision, starting at 1 for the first full block
+	Block    *types.Block     // Block of the new revision
+	Value    *big.Int         // Balance increase of the fee recipient from the new revision
+}
+
+// Payload wraps the built payload (block waiting for sealing). According to the
+// engine-api specification, the execution layer should build an initial version
+// of the payload right after the payload attributes arrive and may keep on
+// improving it until it is retrieved by the consensus layer.
+type Payload struct {
+	id        beacon.PayloadID
+	empty     *types.Block
+	full      *types.Block
+	fullValue *big.Int
+	revision  int
+	resolved  bool // Whether the payload was delivered, freezing its content
+
+	feed  *event.Feed   // Feed to report the payload revisions on
+	stop  chan struct{} // Channel closed when the payload is retrieved
+	ready chan struct{} // Channel closed once the first full block attempt finished
+	once  sync.Once     // Guards the closing of the ready channel
+	lock  sync.Mutex
+}
+
+// newPayload initializes the payload object.
+func newPayload(id beacon.PayloadID, empty *types.Block, feed *event.Feed) *Payload {
+	payload := &Payload{
+		id:    id,
+		empty: empty,
+		feed:  feed,
+		stop:  make(chan struct{}),
+		ready: make(chan struct{}),
+	}
+	log.Info("Starting work on payload", "id", id)
+	return payload
+}
+
+// update replaces the full block of the payload if the given one pays more to
+// the fee recipient, reporting the new revision to any subscriber.
+func (payload *Payload) update(block *types.Block, value *big.Int, elapsed time.Duration) {
+	payload.lock.Lock()
+
+	// Once delivered, the payload must not change anymore. Between being asked
+	// to stop and the delivery, only the first full block may still arrive.
+	if payload.resolved {
+		payload.lock.Unlock()
+		return
+	}
+	select {
+	case <-payload.stop:
+		if payload.full != nil {
+			payload.lock.Unlock()
+			return
+		}
+	default:
+	}
+	if payload.full != nil && value.Cmp(payload.fullValue) <= 0 {
+		payload.lock.Unlock()
+		return
+	}
+	payload.full, payload.fullValue = block, value
+	payload.revision++
+
+	event := PayloadRevisionEvent{
+		ID:       payload.id,
+		Revision: payload.revision,
+		Block:    block,
+		Value:    value,
+	}
+	payload.lock.Unlock()
+
+	log.Info("Updated payload", "id", payload.id, "number", block.NumberU64(), "hash", block.Hash(),
+		"txs", len(block.Transactions()), "gas", block.GasUsed(), "value", value,
+		"revision", event.Revision, "elapsed", common.PrettyDuration(elapsed))
+
+	// Report the revision without holding the lock, subscribers may be slow
+	payload.feed.Send(event)
+}
+
+// markReady signals that the first attempt at building a full block finished,
+// whether successfully or not.
+func (payload *Payload) markReady() {
+	payload.once.Do(func() { close(payload.ready) })
+}
+
+// Resolve stops any further rebuilding of the payload and returns the best one
+// built so far. If no full block is available yet, it waits a short while for
+// the first one to be produced before falling back to the empty block.
+func (payload *Payload) Resolve() *beacon.ExecutionPayloadEnvelope {
+	payload.Stop()
+
+	timeout := time.NewTimer(payloadResolveTimeout)
+	defer timeout.Stop()
+
+	select {
+	case <-payload.ready:
+	case <-timeout.C:
+	}
+	return payload.envelope()
+}
+
+// ResolveFull is like Resolve, but waits for the first full block attempt to
+// finish, however long it takes. It is meant for simulated environments where
+// a missed slot is no concern, but an unexpectedly empty block is.
+func (payload *Payload) ResolveFull() *beacon.ExecutionPayloadEnvelope {
+	payload.Stop()
+	<-payload.ready
+	return payload.envelope()
+}
+
+// Stop terminates any further rebuilding of the payload. It's used directly for
+// payloads that are discarded without ever being resolved.
+func (payload *Payload) Stop() {
+	payload.lock.Lock()
+	defer payload.lock.Unlock()
+
+	select {
+	case <-payload.stop:
+	default:
+		close(payload.stop)
+	}
+}
+
+// envelope assembles the best payload built so far, falling back to the empty
+// block if no full one is available. The payload is frozen afterwards, so all
+// retrievals deliver the same content.
+func (payload *Payload) envelope() *beacon.ExecutionPayloadEnvelope {
+	payload.lock.Lock()
+	defer payload.lock.Unlock()
+
+	payload.resolved = true
+
+	block, value := payload.empty, new(big.Int)
+	if payload.full != nil {
+		block, value = payload.full, payload.fullValue
+	}
+	return &beacon.ExecutionPayloadEnvelope{
+		ExecutionPayload: beacon.BlockToExecutableData(block),
+		BlockValue:       (*hexutil.Big)(value),
+		BlobsBundle:      beacon.BlockToBlobsBundle(block),
+	}
+}
+
+// buildPayload builds the payload according to the provided parameters. The
+// empty block is built synchronously, whereas the full block is built in the
+// background, optionally rebuilding it every recommit interval until retrieved.
+func (w *worker) buildPayload(args *BuildPayloadArgs, id beacon.PayloadID) (*Payload, error) {
+	// Build the initial version with no transaction included. It should be fast
+	// enough to run. The empty payload can at least make sure there is something
+	// to deliver for not missing slot.
+	empty, _, err := w.getSealingBlock(args.Parent, args.Timestamp, args.FeeRecipient, args.Random, args.Withdrawals, true)
+	if err != nil {
+		return nil, err
+	}
+	payload := newPayload(id, empty, &w.payloadFeed)
+
+	recommit := w.config.Recommit
+	if recommit < minRecommitInterval {
+		recommit = minRecommitInterval
+	}
+	go func() {
+		defer payload.markReady()
+
+		deadline := time.NewTimer(payloadBuildTimeout)
+		defer deadline.Stop()
+
+		// Build the full block right away, then keep improving it if requested
+		// until the payload is retrieved or it is most certainly useless
+		for {
+			start := time.Now()
+			block, value, err := w.getSealingBlock(args.Parent, args.Timestamp, args.FeeRecipient, args.Random, args.Withdrawals, false)
+			if err != nil {
+				log.Warn("Failed to build payload", "id", id, "err", err)
+			} else {
+				payload.update(block, value, time.Since(start))
+			}
+			payload.markReady()
+
+			if !w.config.PayloadRebuild {
+				return
+			}
+			timer := time.NewTimer(recommit)
+			select {
+			case <-timer.C:
+			case <-payload.stop:
+				timer.Stop()
+				log.Info("Stopping work on payload", "id", id, "reason", "delivery")
+				return
+			case <-deadline.C:
+				timer.Stop()
+				log.Info("Stopping work on payload", "id", id, "reason", "timeout")
+				return
+			}
+		}
+	}()
+	return payload, nil
+}
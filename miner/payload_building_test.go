@@ -0,0 +1,203 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/gash"
+	"github.com/ethereum/go-ethereum/core/beacon"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that payloads are rebuilt with newly arriving transactions until they
+// are retrieved, reporting every revision along with the value it pays.
+func TestBuildPayloadRebuild(t *testing.T) {
+	w, b := newTestWorker(t, gashChainConfig, gash.NewFaker(), rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	config := *testConfig
+	config.PayloadRebuild = true
+	w.config = &config
+
+	revisions := make(chan PayloadRevisionEvent, 4)
+	sub := w.payloadFeed.Subscribe(revisions)
+	defer sub.Unsubscribe()
+
+	var (
+		recipient = common.HexToAddress("0xdeadbeef")
+		args      = &BuildPayloadArgs{
+			Parent:       b.chain.CurrentBlock().Hash(),
+			Timestamp:    uint64(time.Now().Unix()),
+			FeeRecipient: recipient,
+			Random:       common.HexToHash("0xcafebabe"),
+		}
+		id = beacon.PayloadID{0x01}
+	)
+	payload, err := w.buildPayload(args, id)
+	if err != nil {
+		t.Fatalf("failed to build payload: %v", err)
+	}
+	waitRevision := func(revision int, txs int) PayloadRevisionEvent {
+		t.Helper()
+		select {
+		case ev := <-revisions:
+			if ev.ID != id || ev.Revision != revision {
+				t.Fatalf("revision mismatch: have %x/%d, want %x/%d", ev.ID, ev.Revision, id, revision)
+			}
+			if have := len(ev.Block.Transactions()); have != txs {
+				t.Fatalf("revision %d: transaction count mismatch: have %d, want %d", revision, have, txs)
+			}
+			if want := blockFees(ev.Block); ev.Value.Cmp(want) != 0 {
+				t.Fatalf("revision %d: value mismatch: have %v, want %v", revision, ev.Value, want)
+			}
+			return ev
+		case <-time.After(5 * time.Second):
+			t.Fatalf("revision %d not reported", revision)
+		}
+		return PayloadRevisionEvent{}
+	}
+	first := waitRevision(1, len(pendingTxs))
+
+	// Feed a more valuable transaction and wait for the payload to be rebuilt
+	b.txPool.AddLocal(b.newRandomTx(false))
+	second := waitRevision(2, len(pendingTxs)+1)
+	if second.Value.Cmp(first.Value) <= 0 {
+		t.Fatalf("revision value not increasing: have %v, previous %v", second.Value, first.Value)
+	}
+	// Retrieve the payload and ensure the best revision is delivered, unchanged
+	// by any subsequent retrieval
+	for i := 0; i < 2; i++ {
+		envelope := payload.Resolve()
+		if envelope.ExecutionPayload.BlockHash != second.Block.Hash() {
+			t.Fatalf("resolved payload mismatch: have %x, want %x", envelope.ExecutionPayload.BlockHash, second.Block.Hash())
+		}
+		if envelope.BlockValue.ToInt().Cmp(second.Value) != 0 {
+			t.Fatalf("resolved value mismatch: have %v, want %v", envelope.BlockValue, second.Value)
+		}
+	}
+}
+
+// Tests that a payload is frozen once delivered: later updates are ignored and
+// all retrievals return the same block. Revisions must be reported without the
+// payload lock held, so a slow subscriber cannot stall the retrieval.
+func TestPayloadResolveFreeze(t *testing.T) {
+	newBlock := func(number int64) *types.Block {
+		return types.NewBlockWithHeader(&types.Header{Number: big.NewInt(number), BaseFee: big.NewInt(1), Difficulty: new(big.Int)})
+	}
+	var (
+		empty = newBlock(1)
+		first = newBlock(2)
+		late  = newBlock(3)
+		feed  = new(event.Feed)
+	)
+	revisions := make(chan PayloadRevisionEvent) // unbuffered, blocks the sender
+	sub := feed.Subscribe(revisions)
+	defer sub.Unsubscribe()
+
+	// Update the payload and retrieve it while the revision is still undelivered
+	payload := newPayload(beacon.PayloadID{0x01}, empty, feed)
+	go payload.update(first, big.NewInt(1), 0)
+
+	done := make(chan *beacon.ExecutionPayloadEnvelope)
+	go func() {
+		for {
+			payload.lock.Lock()
+			revision := payload.revision
+			payload.lock.Unlock()
+
+			if revision > 0 {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		payload.markReady()
+		done <- payload.Resolve()
+	}()
+	select {
+	case envelope := <-done:
+		if envelope.ExecutionPayload.BlockHash != first.Hash() {
+			t.Fatalf("resolved payload mismatch: have %x, want %x", envelope.ExecutionPayload.BlockHash, first.Hash())
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("payload retrieval blocked by revision report")
+	}
+	<-revisions
+
+	// Any further update must be ignored, even if more valuable
+	go payload.update(late, big.NewInt(2), 0)
+	select {
+	case ev := <-revisions:
+		t.Fatalf("revision %d reported after delivery", ev.Revision)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if envelope := payload.Resolve(); envelope.ExecutionPayload.BlockHash != first.Hash() {
+		t.Fatalf("re-resolved payload mismatch: have %x, want %x", envelope.ExecutionPayload.BlockHash, first.Hash())
+	}
+	// A payload delivered empty must also stay empty when the full block arrives late
+	payload = newPayload(beacon.PayloadID{0x02}, empty, feed)
+	payload.markReady()
+	if envelope := payload.Resolve(); envelope.ExecutionPayload.BlockHash != empty.Hash() {
+		t.Fatalf("resolved payload mismatch: have %x, want empty %x", envelope.ExecutionPayload.BlockHash, empty.Hash())
+	}
+	payload.update(first, big.NewInt(1), 0)
+	if envelope := payload.Resolve(); envelope.ExecutionPayload.BlockHash != empty.Hash() || envelope.BlockValue.ToInt().Sign() != 0 {
+		t.Fatalf("late full block delivered: have %x, want empty %x", envelope.ExecutionPayload.BlockHash, empty.Hash())
+	}
+}
+
+// Tests that the value of a payload is the balance increase of the fee recipient,
+// including direct payments next to the priority fees.
+func TestPayloadValueDirectPayment(t *testing.T) {
+	w, b := newTestWorker(t, gashChainConfig, gash.NewFaker(), rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	recipient := common.HexToAddress("0xdeadbeef")
+	payment := big.NewInt(params.GWei)
+
+	tx, _ := types.SignTx(types.NewTransaction(b.txPool.Nonce(testBankAddress), recipient, payment, params.TxGas, big.NewInt(10*params.InitialBaseFee), nil), types.HomesteadSigner{}, testBankKey)
+	if err := b.txPool.AddLocal(tx); err != nil {
+		t.Fatalf("failed to add payment: %v", err)
+	}
+	block, value, err := w.getSealingBlock(b.chain.CurrentBlock().Hash(), uint64(time.Now().Unix()), recipient, common.Hash{}, nil, false)
+	if err != nil {
+		t.Fatalf("failed to build block: %v", err)
+	}
+	if have := len(block.Transactions()); have != len(pendingTxs)+1 {
+		t.Fatalf("transaction count mismatch: have %d, want %d", have, len(pendingTxs)+1)
+	}
+	if want := new(big.Int).Add(blockFees(block), payment); value.Cmp(want) != 0 {
+		t.Fatalf("value mismatch: have %v, want %v", value, want)
+	}
+}
+
+// blockFees calculates the priority fees paid by the transactions of a block of
+// plain value transfers.
+func blockFees(block *types.Block) *big.Int {
+	fees := new(big.Int)
+	for _, tx := range block.Transactions() {
+		tip, _ := tx.EffectiveGasTip(block.BaseFee())
+		fees.Add(fees, new(big.Int).Mul(tip, new(big.Int).SetUint64(tx.Gas())))
+	}
+	return fees
+}
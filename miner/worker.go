@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -30,7 +31,9 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
@@ -75,6 +78,11 @@ const (
 
 	// staleThreshold is the maximum depth of the acceptable stale block.
 	staleThreshold = 7
+
+	// maxSimulatedBundles is the maximum number of bundles simulated when building
+	// a single block. Bundles are simulated for every build, so only the earliest
+	// arrivals are considered instead of everything the pool is willing to hold.
+	maxSimulatedBundles = 256
 )
 
 var (
@@ -127,6 +135,17 @@ func (env *environment) copy() *environment {
 	return cpy
 }
 
+// txsWithoutSidecars returns the contained transactions with any blob sidecars
+// stripped off, as they are not allowed in sealed blocks. The sidecars are only
+// retained in the environment to be handed out along with built payloads.
+func (env *environment) txsWithoutSidecars() []*types.Transaction {
+	txs := make([]*types.Transaction, len(env.txs))
+	for i, tx := range env.txs {
+		txs[i] = tx.WithoutBlobTxSidecar()
+	}
+	return txs
+}
+
 // unclelist returns the contained uncles as the list format.
 func (env *environment) unclelist() []*types.Header {
 	var uncles []*types.Header
@@ -167,11 +186,17 @@ type newWorkReq struct {
 	timestamp int64
 }
 
+// newPayloadResult represents a result struct corresponds to payload generation.
+type newPayloadResult struct {
+	err   error
+	block *types.Block
+	value *big.Int // Balance increase of the fee recipient from the included transactions
+}
+
 // getWorkReq represents a request for getting a new sealing work with provided parameters.
 type getWorkReq struct {
 	params *generateParams
-	result chan *types.Block // non-blocking channel
-	err    chan error
+	result chan *newPayloadResult // non-blocking channel
 }
 
 // intervalAdjust represents a resubmitting interval adjustment.
@@ -191,6 +216,7 @@ type worker struct {
 
 	// Feeds
 	pendingLogsFeed event.Feed
+	payloadFeed     event.Feed // Revisions of the payloads built for the beacon client
 
 	// Subscriptions
 	mux          *event.TypeMux
@@ -534,14 +560,8 @@ func (w *worker) mainLoop() {
 			w.commitWork(req.interrupt, req.noempty, req.timestamp)
 
 		case req := <-w.getWorkCh:
-			block, err := w.generateWork(req.params)
-			if err != nil {
-				req.err <- err
-				req.result <- nil
-			} else {
-				req.err <- nil
-				req.result <- block
-			}
+			req.result <- w.generateWork(req.params)
+
 		case ev := <-w.chainSideCh:
 			// Short circuit for duplicate side blocks
 			if _, exist := w.localUncles[ev.Block.Hash()]; exist {
@@ -832,7 +852,9 @@ func (w *worker) commitTransaction(env *environment, tx *types.Transaction) ([]*
 	}
 	env.txs = append(env.txs, tx)
 	env.receipts = append(env.receipts, receipt)
-
+	if env.header.DataGasUsed != nil {
+		*env.header.DataGasUsed += tx.DataGas()
+	}
 	return receipt.Logs, nil
 }
 
@@ -875,6 +897,14 @@ func (w *worker) commitTransactions(env *environment, txs *types.TransactionsByP
 		if tx == nil {
 			break
 		}
+		// If we don't have enough data gas space left for the blobs, skip the sender
+		if dataGas := tx.DataGas(); dataGas > 0 {
+			if env.header.DataGasUsed == nil || *env.header.DataGasUsed+dataGas > params.MaxDataGasPerBlock {
+				log.Trace("Not enough data gas left for transaction", "hash", tx.Hash(), "need", dataGas)
+				txs.Pop()
+				continue
+			}
+		}
 		// Error may be ignored here. The error has already been checked
 		// during transaction acceptance is the transaction pool.
 		//
@@ -950,6 +980,199 @@ func (w *worker) commitTransactions(env *environment, txs *types.TransactionsByP
 	return nil
 }
 
+// simulatedBundle is a transaction bundle along with the profit it yields to the
+// coinbase when executed on top of the pending state.
+type simulatedBundle struct {
+	bundle *txpool.Bundle
+	profit *big.Int
+}
+
+// simulateBundle executes a bundle on top of the given state, returning the
+// coinbase profit it yields, or an error if any of its transactions fail or a
+// transaction not allowed to revert does so. All changes are reverted before
+// returning, so the same state can be reused to simulate many bundles.
+//
+// Note, the transactions are not finalised one by one as during block processing,
+// since that would discard the snapshot. The profit is thus only an estimate and
+// the bundle is re-executed in full when committed.
+func (w *worker) simulateBundle(env *environment, statedb *state.StateDB, bundle *txpool.Bundle) (*big.Int, error) {
+	snap := statedb.Snapshot()
+	defer statedb.RevertToSnapshot(snap)
+
+	var (
+		gasPool = new(core.GasPool).AddGas(env.gasPool.Gas())
+		signer  = types.MakeSigner(w.chainConfig, env.header.Number)
+		context = core.NewEVMBlockContext(env.header, w.chain, &env.coinbase)
+		evm     = vm.NewEVM(context, vm.TxContext{}, statedb, w.chainConfig, *w.chain.GetVMConfig())
+		before  = statedb.GetBalance(env.coinbase)
+	)
+	for i, tx := range bundle.Txs {
+		msg, err := tx.AsMessage(signer, env.header.BaseFee)
+		if err != nil {
+			return nil, err
+		}
+		// Drop the refund of the previous transaction, done by finalisation otherwise
+		statedb.SubRefund(statedb.GetRefund())
+		statedb.Prepare(tx.Hash(), env.tcount+i)
+		evm.Reset(core.NewEVMTxContext(msg), statedb)
+
+		result, err := core.ApplyMessage(evm, msg, gasPool)
+		if err != nil {
+			return nil, err
+		}
+		if result.Failed() && !bundle.AllowsRevert(tx.Hash()) {
+			return nil, fmt.Errorf("transaction %x reverted", tx.Hash())
+		}
+	}
+	return new(big.Int).Sub(statedb.GetBalance(env.coinbase), before), nil
+}
+
+// commitBundle applies all the transactions of a bundle to the environment,
+// reverting all of them if any fails or reverts without being allowed to.
+//
+// Every transaction is finalised into the state, so snapshots cannot span the
+// bundle. Instead it is applied on a copy of the state, which only replaces the
+// original if the whole bundle succeeds.
+func (w *worker) commitBundle(env *environment, bundle *txpool.Bundle) ([]*types.Log, error) {
+	var (
+		statedb  = env.state
+		gas      = env.gasPool.Gas()
+		gasUsed  = env.header.GasUsed
+		tcount   = env.tcount
+		txcount  = len(env.txs)
+		dataUsed uint64
+		logs     []*types.Log
+	)
+	if env.header.DataGasUsed != nil {
+		dataUsed = *env.header.DataGasUsed
+	}
+	revert := func() {
+		env.state = statedb
+		env.gasPool.SetGas(gas)
+		env.header.GasUsed = gasUsed
+		if env.header.DataGasUsed != nil {
+			*env.header.DataGasUsed = dataUsed
+		}
+		env.tcount = tcount
+		env.txs = env.txs[:txcount]
+		env.receipts = env.receipts[:txcount]
+	}
+	env.state = statedb.Copy()
+
+	for _, tx := range bundle.Txs {
+		if dataGas := tx.DataGas(); dataGas > 0 {
+			if env.header.DataGasUsed == nil || *env.header.DataGasUsed+dataGas > params.MaxDataGasPerBlock {
+				revert()
+				return nil, fmt.Errorf("not enough data gas for transaction %x", tx.Hash())
+			}
+		}
+		env.state.Prepare(tx.Hash(), env.tcount)
+
+		txLogs, err := w.commitTransaction(env, tx)
+		if err != nil {
+			revert()
+			return nil, err
+		}
+		if env.receipts[len(env.receipts)-1].Status == types.ReceiptStatusFailed && !bundle.AllowsRevert(tx.Hash()) {
+			revert()
+			return nil, fmt.Errorf("transaction %x reverted", tx.Hash())
+		}
+		logs = append(logs, txLogs...)
+		env.tcount++
+	}
+	return logs, nil
+}
+
+// commitBundles simulates all the bundles targeting the block being built, and
+// includes them in decreasing order of coinbase profit. Bundles conflicting with
+// already included ones (i.e. failing on top of them) are skipped.
+func (w *worker) commitBundles(env *environment, interrupt *int32) error {
+	bundles := w.g.TxPool().Bundles(env.header.Number.Uint64(), env.header.Time)
+	if len(bundles) == 0 {
+		return nil
+	}
+	if env.gasPool == nil {
+		env.gasPool = new(core.GasPool).AddGas(env.header.GasLimit)
+	}
+	if len(bundles) > maxSimulatedBundles {
+		log.Debug("Limiting bundles to simulate", "bundles", len(bundles), "limit", maxSimulatedBundles)
+		bundles = bundles[:maxSimulatedBundles]
+	}
+	// Simulate all the bundles on top of the pending state and order by profit.
+	// A single copy of the state is shared by all simulations, each of them
+	// reverting its own changes.
+	var (
+		scratch   = env.state.Copy()
+		simulated = make([]*simulatedBundle, 0, len(bundles))
+	)
+	for _, bundle := range bundles {
+		if interrupt != nil && atomic.LoadInt32(interrupt) != commitInterruptNone {
+			if atomic.LoadInt32(interrupt) == commitInterruptResubmit {
+				return errBlockInterruptedByRecommit
+			}
+			return errBlockInterruptedByNewHead
+		}
+		profit, err := w.simulateBundle(env, scratch, bundle)
+		if err != nil {
+			log.Trace("Bundle simulation failed", "hash", bundle.Hash(), "err", err)
+			continue
+		}
+		simulated = append(simulated, &simulatedBundle{bundle: bundle, profit: profit})
+	}
+	sort.SliceStable(simulated, func(i, j int) bool {
+		return simulated[i].profit.Cmp(simulated[j].profit) > 0
+	})
+	// Include the bundles one by one, skipping the ones that became invalid
+	var (
+		included = make(map[common.Hash]struct{})
+		logs     []*types.Log
+	)
+	for _, sim := range simulated {
+		if interrupt != nil && atomic.LoadInt32(interrupt) != commitInterruptNone {
+			if atomic.LoadInt32(interrupt) == commitInterruptResubmit {
+				return errBlockInterruptedByRecommit
+			}
+			return errBlockInterruptedByNewHead
+		}
+		conflict := false
+		for _, tx := range sim.bundle.Txs {
+			if _, ok := included[tx.Hash()]; ok {
+				conflict = true
+				break
+			}
+		}
+		if conflict {
+			log.Trace("Skipping conflicting bundle", "hash", sim.bundle.Hash())
+			continue
+		}
+		// Check the bundle against the state built so far before committing, to
+		// avoid copying the state for bundles that became invalid
+		if _, err := w.simulateBundle(env, env.state, sim.bundle); err != nil {
+			log.Trace("Skipping invalidated bundle", "hash", sim.bundle.Hash(), "err", err)
+			continue
+		}
+		bundleLogs, err := w.commitBundle(env, sim.bundle)
+		if err != nil {
+			log.Trace("Skipping invalidated bundle", "hash", sim.bundle.Hash(), "err", err)
+			continue
+		}
+		for _, tx := range sim.bundle.Txs {
+			included[tx.Hash()] = struct{}{}
+		}
+		logs = append(logs, bundleLogs...)
+		log.Debug("Included transaction bundle", "hash", sim.bundle.Hash(), "txs", len(sim.bundle.Txs), "profit", sim.profit)
+	}
+	if !w.isRunning() && len(logs) > 0 {
+		cpy := make([]*types.Log, len(logs))
+		for i, l := range logs {
+			cpy[i] = new(types.Log)
+			*cpy[i] = *l
+		}
+		w.pendingLogsFeed.Send(cpy)
+	}
+	return nil
+}
+
 // generateParams wraps various of settings for generating sealing task.
 type generateParams struct {
 	timestamp  uint64         // The timstamp for sealing task
@@ -960,6 +1183,8 @@ type generateParams struct {
 	noUncle    bool           // Flag whether the uncle block inclusion is allowed
 	noExtra    bool           // Flag whether the extra field assignment is allowed
 	noTxs      bool           // Flag whether an empty block without any transaction is expected
+
+	withdrawals types.Withdrawals // List of withdrawals to include in block, nil before Shanghai
 }
 
 // prepareWork constructs the sealing task according to the given parameters,
@@ -1010,6 +1235,15 @@ func (w *worker) prepareWork(genParams *generateParams) (*environment, error) {
 			header.GasLimit = core.CalcGasLimit(parentGasLimit, w.config.GasCeil)
 		}
 	}
+	// Set the data gas fields if we are on an EIP-4844 chain
+	if w.chainConfig.IsCancun(header.Number) {
+		var excessDataGas uint64
+		if w.chainConfig.IsCancun(parent.Number()) {
+			excessDataGas = misc.CalcExcessDataGas(*parent.ExcessDataGas(), *parent.DataGasUsed())
+		}
+		header.ExcessDataGas = &excessDataGas
+		header.DataGasUsed = new(uint64)
+	}
 	// Run the consensus preparation with the default or customized consensus engine.
 	if err := w.engine.Prepare(w.chain, header); err != nil {
 		log.Error("Failed to prepare header for sealing", "err", err)
@@ -1048,6 +1282,10 @@ func (w *worker) prepareWork(genParams *generateParams) (*environment, error) {
 // into the given sealing block. The transaction selection and ordering strategy can
 // be customized with the plugin in the future.
 func (w *worker) fillTransactions(interrupt *int32, env *environment) error {
+	// Include the most profitable bundles ahead of any pooled transaction
+	if err := w.commitBundles(env, interrupt); err != nil {
+		return err
+	}
 	// Split the pending transactions into locals and remotes
 	// Fill the block with all available pending transactions.
 	pending := w.g.TxPool().Pending(true)
@@ -1074,17 +1312,27 @@ func (w *worker) fillTransactions(interrupt *int32, env *environment) error {
 }
 
 // generateWork generates a sealing block based on the given parameters.
-func (w *worker) generateWork(params *generateParams) (*types.Block, error) {
+func (w *worker) generateWork(params *generateParams) *newPayloadResult {
 	work, err := w.prepareWork(params)
 	if err != nil {
-		return nil, err
+		return &newPayloadResult{err: err}
 	}
 	defer work.discard()
 
+	// The value of the block is whatever the fee recipient earns from it, which
+	// includes direct payments next to the priority fees. It is measured before
+	// finalisation, which credits the withdrawals and any block reward.
+	before := new(big.Int).Set(work.state.GetBalance(work.coinbase))
 	if !params.noTxs {
 		w.fillTransactions(nil, work)
 	}
-	return w.engine.FinalizeAndAssemble(w.chain, work.header, work.state, work.txs, work.unclelist(), work.receipts)
+	value := new(big.Int).Sub(work.state.GetBalance(work.coinbase), before)
+
+	block, err := w.engine.FinalizeAndAssemble(w.chain, work.header, work.state, work.txs, work.unclelist(), work.receipts, params.withdrawals)
+	if err != nil {
+		return &newPayloadResult{err: err}
+	}
+	return &newPayloadResult{block: block, value: value}
 }
 
 // commitWork generates several new sealing tasks based on the parent block
@@ -1142,7 +1390,7 @@ func (w *worker) commit(env *environment, interval func(), update bool, start ti
 		// Create a local environment copy, avoid the data race with snapshot state.
 		// https://github.com/ethereum/go-ethereum/issues/24299
 		env := env.copy()
-		block, err := w.engine.FinalizeAndAssemble(w.chain, env.header, env.state, env.txs, env.unclelist(), env.receipts)
+		block, err := w.engine.FinalizeAndAssemble(w.chain, env.header, env.state, env.txsWithoutSidecars(), env.unclelist(), env.receipts, nil)
 		if err != nil {
 			return err
 		}
@@ -1167,31 +1415,27 @@ func (w *worker) commit(env *environment, interval func(), update bool, start ti
 	return nil
 }
 
-// getSealingBlock generates the sealing block based on the given parameters.
-// The generation result will be passed back via the given channel no matter
-// the generation itself succeeds or not.
-func (w *worker) getSealingBlock(parent common.Hash, timestamp uint64, coinbase common.Address, random common.Hash, noTxs bool) (chan *types.Block, chan error, error) {
-	var (
-		resCh = make(chan *types.Block, 1)
-		errCh = make(chan error, 1)
-	)
+// getSealingBlock generates the sealing block based on the given parameters,
+// returning it together with the value it pays to the fee recipient.
+func (w *worker) getSealingBlock(parent common.Hash, timestamp uint64, coinbase common.Address, random common.Hash, withdrawals types.Withdrawals, noTxs bool) (*types.Block, *big.Int, error) {
 	req := &getWorkReq{
 		params: &generateParams{
-			timestamp:  timestamp,
-			forceTime:  true,
-			parentHash: parent,
-			coinbase:   coinbase,
-			random:     random,
-			noUncle:    true,
-			noExtra:    true,
-			noTxs:      noTxs,
+			timestamp:   timestamp,
+			forceTime:   true,
+			parentHash:  parent,
+			coinbase:    coinbase,
+			random:      random,
+			withdrawals: withdrawals,
+			noUncle:     true,
+			noExtra:     true,
+			noTxs:       noTxs,
 		},
-		result: resCh,
-		err:    errCh,
+		result: make(chan *newPayloadResult, 1),
 	}
 	select {
 	case w.getWorkCh <- req:
-		return resCh, errCh, nil
+		result := <-req.result
+		return result.block, result.value, result.err
 	case <-w.exitCh:
 		return nil, nil, errors.New("miner closed")
 	}
@@ -1224,10 +1468,17 @@ func (w *worker) postSideBlock(event core.ChainSideEvent) {
 
 // totalFees computes total consumed miner fees in G. Block transactions and receipts have to have the same order.
 func totalFees(block *types.Block, receipts []*types.Receipt) *big.Float {
-	feesWei := new(big.Int)
-	for i, tx := range block.Transactions() {
-		minerFee, _ := tx.EffectiveGasTip(block.BaseFee())
-		feesWei.Add(feesWei, new(big.Int).Mul(new(big.Int).SetUint64(receipts[i].GasUsed), minerFee))
-	}
+	feesWei := txFees(block.Transactions(), receipts, block.BaseFee())
 	return new(big.Float).Quo(new(big.Float).SetInt(feesWei), new(big.Float).SetInt(big.NewInt(params.AC)))
 }
+
+// txFees computes the priority fees paid to the fee recipient by the given
+// transactions, in wei.
+func txFees(txs types.Transactions, receipts []*types.Receipt, baseFee *big.Int) *big.Int {
+	fees := new(big.Int)
+	for i, tx := range txs {
+		minerFee, _ := tx.EffectiveGasTip(baseFee)
+		fees.Add(fees, new(big.Int).Mul(new(big.Int).SetUint64(receipts[i].GasUsed), minerFee))
+	}
+	return fees
+}
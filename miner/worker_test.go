@@ -17,6 +17,7 @@
 package miner
 
 import (
+	"crypto/ecdsa"
 	"errors"
 	"math/big"
 	"math/rand"
@@ -32,6 +33,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -111,7 +113,7 @@ func init() {
 // testWorkerBackend implements worker.Backend interfaces and wraps all information needed during the testing.
 type testWorkerBackend struct {
 	db         gdb.Database
-	txPool     *core.TxPool
+	txPool     *txpool.TxPool
 	chain      *core.BlockChain
 	genesis    *core.Genesis
 	uncleBlock *types.Block
@@ -134,7 +136,7 @@ func newTestWorkerBackend(t *testing.T, chainConfig *params.ChainConfig, engine
 		t.Fatalf("unexpected consensus engine type: %T", engine)
 	}
 	chain, _ := core.NewBlockChain(db, &core.CacheConfig{TrieDirtyDisabled: true}, gspec, nil, engine, vm.Config{}, nil, nil)
-	txpool := core.NewTxPool(testTxPoolConfig, chainConfig, chain)
+	pool := txpool.New(core.NewTxPool(testTxPoolConfig, chainConfig, chain))
 
 	// Generate a small n-block chain and an uncle block for it
 	var uncle *types.Block
@@ -159,14 +161,14 @@ func newTestWorkerBackend(t *testing.T, chainConfig *params.ChainConfig, engine
 	return &testWorkerBackend{
 		db:         db,
 		chain:      chain,
-		txPool:     txpool,
+		txPool:     pool,
 		genesis:    gspec,
 		uncleBlock: uncle,
 	}
 }
 
 func (b *testWorkerBackend) BlockChain() *core.BlockChain { return b.chain }
-func (b *testWorkerBackend) TxPool() *core.TxPool         { return b.txPool }
+func (b *testWorkerBackend) TxPool() *txpool.TxPool       { return b.txPool }
 func (b *testWorkerBackend) StateAtBlock(block *types.Block, reexec uint64, base *state.StateDB, checkLive bool, preferDisk bool) (statedb *state.StateDB, err error) {
 	return nil, errors.New("not supported")
 }
@@ -633,9 +635,7 @@ func testGetSealingWork(t *testing.T, chainConfig *params.ChainConfig, engine co
 
 	// This API should work even when the automatic sealing is not enabled
 	for _, c := range cases {
-		resChan, errChan, _ := w.getSealingBlock(c.parent, timestamp, c.coinbase, c.random, false)
-		block := <-resChan
-		err := <-errChan
+		block, _, err := w.getSealingBlock(c.parent, timestamp, c.coinbase, c.random, nil, false)
 		if c.expectErr {
 			if err == nil {
 				t.Error("Expect error but get nil")
@@ -651,9 +651,7 @@ func testGetSealingWork(t *testing.T, chainConfig *params.ChainConfig, engine co
 	// This API should work even when the automatic sealing is enabled
 	w.start()
 	for _, c := range cases {
-		resChan, errChan, _ := w.getSealingBlock(c.parent, timestamp, c.coinbase, c.random, false)
-		block := <-resChan
-		err := <-errChan
+		block, _, err := w.getSealingBlock(c.parent, timestamp, c.coinbase, c.random, nil, false)
 		if c.expectErr {
 			if err == nil {
 				t.Error("Expect error but get nil")
@@ -666,3 +664,99 @@ func testGetSealingWork(t *testing.T, chainConfig *params.ChainConfig, engine co
 		}
 	}
 }
+
+// Tests that transaction bundles are included atomically ahead of the pooled
+// transactions, in order of coinbase profit, skipping any that conflict.
+func TestBundleInclusion(t *testing.T) {
+	engine := gash.NewFaker()
+	defer engine.Close()
+
+	w, b := newTestWorker(t, gashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	signer := types.LatestSigner(gashChainConfig)
+	transfer := func(key *ecdsa.PrivateKey, nonce uint64, price int64) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.LegacyTx{
+			Nonce:    nonce,
+			To:       &testUserAddress,
+			Value:    big.NewInt(1000),
+			Gas:      params.TxGas,
+			GasPrice: big.NewInt(price * params.InitialBaseFee),
+		})
+	}
+	var (
+		best     = &txpool.Bundle{Txs: types.Transactions{transfer(testBankKey, 0, 3), transfer(testBankKey, 1, 3)}, BlockNumber: 1}
+		worse    = &txpool.Bundle{Txs: types.Transactions{transfer(testBankKey, 0, 2)}, BlockNumber: 1}
+		failing  = &txpool.Bundle{Txs: types.Transactions{transfer(testBankKey, 2, 5), transfer(testUserKey, 0, 5)}, BlockNumber: 1}
+		future   = &txpool.Bundle{Txs: types.Transactions{transfer(testBankKey, 2, 4)}, BlockNumber: 2}
+		outdated = &txpool.Bundle{Txs: types.Transactions{transfer(testBankKey, 2, 4)}, BlockNumber: 1, MaxTimestamp: 1}
+	)
+	for _, bundle := range []*txpool.Bundle{worse, best, failing, future, outdated} {
+		if err := b.txPool.AddBundle(bundle, 0); err != nil {
+			t.Fatalf("failed to add bundle: %v", err)
+		}
+	}
+	result := w.generateWork(&generateParams{
+		timestamp: uint64(time.Now().Unix()),
+		coinbase:  testUserAddress,
+	})
+	if result.err != nil {
+		t.Fatalf("failed to generate block: %v", result.err)
+	}
+	txs := result.block.Transactions()
+	if len(txs) != len(best.Txs) {
+		t.Fatalf("transaction count mismatch: have %d, want %d", len(txs), len(best.Txs))
+	}
+	for i, tx := range best.Txs {
+		if txs[i].Hash() != tx.Hash() {
+			t.Errorf("transaction %d mismatch: have %x, want %x", i, txs[i].Hash(), tx.Hash())
+		}
+	}
+}
+
+// Tests that a bundle failing half way through being committed is rolled back
+// entirely, leaving the environment as it was before.
+func TestBundleCommitRevert(t *testing.T) {
+	engine := gash.NewFaker()
+	defer engine.Close()
+
+	w, _ := newTestWorker(t, gashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	env, err := w.prepareWork(&generateParams{
+		timestamp: uint64(time.Now().Unix()),
+		coinbase:  testUserAddress,
+	})
+	if err != nil {
+		t.Fatalf("failed to prepare work: %v", err)
+	}
+	defer env.discard()
+	env.gasPool = new(core.GasPool).AddGas(env.header.GasLimit)
+
+	signer := types.LatestSigner(gashChainConfig)
+	transfer := func(key *ecdsa.PrivateKey, nonce uint64) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.LegacyTx{
+			Nonce:    nonce,
+			To:       &testBankAddress,
+			Value:    big.NewInt(1000),
+			Gas:      params.TxGas,
+			GasPrice: big.NewInt(params.InitialBaseFee),
+		})
+	}
+	// The second transaction is from an unfunded account, failing after the
+	// first one was already applied
+	bundle := &txpool.Bundle{Txs: types.Transactions{transfer(testBankKey, 0), transfer(testUserKey, 0)}, BlockNumber: 1}
+
+	if _, err := w.commitBundle(env, bundle); err == nil {
+		t.Fatal("invalid bundle committed")
+	}
+	if len(env.txs) != 0 || len(env.receipts) != 0 || env.tcount != 0 {
+		t.Fatalf("transactions left behind: txs %d, receipts %d, count %d", len(env.txs), len(env.receipts), env.tcount)
+	}
+	if env.header.GasUsed != 0 || env.gasPool.Gas() != env.header.GasLimit {
+		t.Fatalf("gas left consumed: used %d, pool %d", env.header.GasUsed, env.gasPool.Gas())
+	}
+	if nonce := env.state.GetNonce(testBankAddress); nonce != 0 {
+		t.Fatalf("state not reverted: bank nonce %d", nonce)
+	}
+}
@@ -116,6 +116,116 @@ func (api *adminAPI) RemoveTrustedPeer(url string) (bool, error) {
 	return true, nil
 }
 
+// PeerGroups retrieves the configured peer groups and their connected members.
+func (api *adminAPI) PeerGroups() ([]*p2p.PeerGroupInfo, error) {
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	return server.PeerGroupsInfo(), nil
+}
+
+// SetPeerGroup creates or replaces a named peer group, reserving minSlots peer
+// slots for its members and connecting at most maxSlots of them (0 means no limit).
+func (api *adminAPI) SetPeerGroup(name string, urls []string, minSlots, maxSlots int) (bool, error) {
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	group := p2p.PeerGroup{Name: name, MinSlots: minSlots, MaxSlots: maxSlots}
+	for _, url := range urls {
+		node, err := enode.Parse(enode.ValidSchemes, url)
+		if err != nil {
+			return false, fmt.Errorf("invalid enode: %v", err)
+		}
+		group.Nodes = append(group.Nodes, node)
+	}
+	if err := server.SetPeerGroup(group); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RemovePeerGroup deletes a named peer group. Its members stay connected.
+func (api *adminAPI) RemovePeerGroup(name string) (bool, error) {
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	return server.RemovePeerGroup(name), nil
+}
+
+// PeerReputations retrieves the reputation scores of all nodes with a non-zero
+// score, keyed by node ID.
+func (api *adminAPI) PeerReputations() (map[string]int64, error) {
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	scores := make(map[string]int64)
+	for id, score := range server.PeerReputations() {
+		scores[id.String()] = score
+	}
+	return scores, nil
+}
+
+// PeerReputation retrieves the reputation score of a node, identified either by
+// its enode URL or its node ID.
+func (api *adminAPI) PeerReputation(node string) (int64, error) {
+	server := api.node.Server()
+	if server == nil {
+		return 0, ErrNodeStopped
+	}
+	id, err := parseNodeID(node)
+	if err != nil {
+		return 0, err
+	}
+	return server.PeerReputation(id), nil
+}
+
+// SetPeerReputation overrides the reputation score of a node, identified either
+// by its enode URL or its node ID. Connected peers are dropped if the new score
+// bans them. The stored score is returned.
+func (api *adminAPI) SetPeerReputation(node string, score int64) (int64, error) {
+	server := api.node.Server()
+	if server == nil {
+		return 0, ErrNodeStopped
+	}
+	id, err := parseNodeID(node)
+	if err != nil {
+		return 0, err
+	}
+	return server.SetPeerReputation(id, score), nil
+}
+
+// ResetPeerReputation forgets the reputation of a node, identified either by its
+// enode URL or its node ID.
+func (api *adminAPI) ResetPeerReputation(node string) (bool, error) {
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	id, err := parseNodeID(node)
+	if err != nil {
+		return false, err
+	}
+	server.ResetPeerReputation(id)
+	return true, nil
+}
+
+// parseNodeID parses a node identifier given either as an enode URL, an ENR or
+// a hex node ID.
+func parseNodeID(node string) (enode.ID, error) {
+	if n, err := enode.Parse(enode.ValidSchemes, node); err == nil {
+		return n.ID(), nil
+	}
+	id, err := enode.ParseID(node)
+	if err != nil {
+		return enode.ID{}, fmt.Errorf("invalid node: %v", err)
+	}
+	return id, nil
+}
+
 // PeerEvents creates an RPC subscription which receives peer events from the
 // node's p2p.Server
 func (api *adminAPI) PeerEvents(ctx context.Context) (*rpc.Subscription, error) {
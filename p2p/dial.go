@@ -79,6 +79,9 @@ var (
 	errRecentlyDialed   = errors.New("recently dialed")
 	errNetRestrict      = errors.New("not contained in netrestrict list")
 	errNoPort           = errors.New("node does not provide TCP port")
+	errLowReputation    = errors.New("reputation too low")
+	errFiltered         = errors.New("rejected by dial filter")
+	errNoDialSlots      = errors.New("no free dial slots")
 )
 
 // dialer creates outbound connections and submits them into Server.
@@ -90,6 +93,7 @@ var (
 //   - dynamic dials are created from node discovery results. The dialer
 //     continuously reads candidate nodes from its input iterator and attempts
 //     to create peer connections to nodes arriving through the iterator.
+//     Nodes which ask to be dialed back are handled the same way.
 type dialScheduler struct {
 	dialConfig
 	setupFunc   dialSetupFunc
@@ -100,6 +104,7 @@ type dialScheduler struct {
 	doneCh      chan *dialTask
 	addStaticCh chan *enode.Node
 	remStaticCh chan *enode.Node
+	dialBackCh  chan *enode.Node
 	addPeerCh   chan *conn
 	remPeerCh   chan *conn
 
@@ -129,10 +134,13 @@ type dialScheduler struct {
 type dialSetupFunc func(net.Conn, connFlag, *enode.Node) error
 
 type dialConfig struct {
-	self           enode.ID         // our own ID
-	maxDialPeers   int              // maximum number of dialed peers
-	maxActiveDials int              // maximum number of active dials
-	netRestrict    *netutil.Netlist // IP netrestrict list, disabled if nil
+	self           enode.ID             // our own ID
+	maxDialPeers   int                  // maximum number of dialed peers
+	maxActiveDials int                  // maximum number of active dials
+	netRestrict    *netutil.Netlist     // IP netrestrict list, disabled if nil
+	reputation     func(enode.ID) int64 // reputation score lookup for dynamic dials, disabled if nil
+	groups         *peerGroups          // peer group quotas, disabled if nil
+	filters        []NodeFilter         // record checks for dynamic dials
 	resolver       nodeResolver
 	dialer         NodeDialer
 	log            log.Logger
@@ -170,6 +178,7 @@ func newDialScheduler(config dialConfig, it enode.Iterator, setupFunc dialSetupF
 		nodesIn:     make(chan *enode.Node),
 		addStaticCh: make(chan *enode.Node),
 		remStaticCh: make(chan *enode.Node),
+		dialBackCh:  make(chan *enode.Node),
 		addPeerCh:   make(chan *conn),
 		remPeerCh:   make(chan *conn),
 	}
@@ -203,6 +212,16 @@ func (d *dialScheduler) removeStatic(n *enode.Node) {
 	}
 }
 
+// dialBack dials a node which asked to be dialed, e.g. after hole punching. The
+// node is treated like a dynamic dial candidate, so it is only dialed if a dial
+// slot is free and it passes all checks and dial filters.
+func (d *dialScheduler) dialBack(n *enode.Node) {
+	select {
+	case d.dialBackCh <- n:
+	case <-d.ctx.Done():
+	}
+}
+
 // peerAdded updates the peer set.
 func (d *dialScheduler) peerAdded(c *conn) {
 	select {
@@ -229,9 +248,8 @@ func (d *dialScheduler) loop(it enode.Iterator) {
 loop:
 	for {
 		// Launch new dials if slots are available.
-		slots := d.freeDialSlots()
-		slots -= d.startStaticDials(slots)
-		if slots > 0 {
+		d.startStaticDials(d.freeDialSlots(0))
+		if d.freeDialSlots(d.reservedSlots()) > 0 {
 			nodesCh = d.nodesIn
 		} else {
 			nodesCh = nil
@@ -241,10 +259,13 @@ loop:
 
 		select {
 		case node := <-nodesCh:
-			if err := d.checkDial(node); err != nil {
-				d.log.Trace("Discarding dial candidate", "id", node.ID(), "ip", node.IP(), "reason", err)
+			d.startDynDial(node)
+
+		case node := <-d.dialBackCh:
+			if nodesCh == nil {
+				d.log.Trace("Discarding dial back", "id", node.ID(), "ip", node.IP(), "reason", errNoDialSlots)
 			} else {
-				d.startDial(newDialTask(node, dynDialedConn))
+				d.startDynDial(node)
 			}
 
 		case task := <-d.doneCh:
@@ -272,6 +293,13 @@ loop:
 			}
 			delete(d.peers, c.node.ID())
 			d.updateStaticPool(c.node.ID())
+			// A slot of the node's peer group became free, its other
+			// members may be dialed again.
+			if d.groups != nil {
+				for _, id := range d.groups.siblings(c.node.ID()) {
+					d.updateStaticPool(id)
+				}
+			}
 
 		case node := <-d.addStaticCh:
 			id := node.ID()
@@ -372,10 +400,11 @@ func (d *dialScheduler) expireHistory() {
 	})
 }
 
-// freeDialSlots returns the number of free dial slots. The result can be negative
-// when peers are connected while their task is still running.
-func (d *dialScheduler) freeDialSlots() int {
-	slots := (d.maxDialPeers - d.dialPeers) * 2
+// freeDialSlots returns the number of free dial slots, keeping the given number
+// of peer slots unused. The result can be negative when peers are connected while
+// their task is still running.
+func (d *dialScheduler) freeDialSlots(keep int) int {
+	slots := (d.maxDialPeers - keep - d.dialPeers) * 2
 	if slots > d.maxActiveDials {
 		slots = d.maxActiveDials
 	}
@@ -383,6 +412,16 @@ func (d *dialScheduler) freeDialSlots() int {
 	return free
 }
 
+// reservedSlots returns the number of peer slots reserved for peer groups which
+// are not filled yet. Group members are dialed as static nodes, so dynamic dials
+// must leave these slots unused.
+func (d *dialScheduler) reservedSlots() int {
+	if d.groups == nil {
+		return 0
+	}
+	return d.groups.unfilled()
+}
+
 // checkDial returns an error if node n should not be dialed.
 func (d *dialScheduler) checkDial(n *enode.Node) error {
 	if n.ID() == d.self {
@@ -406,9 +445,46 @@ func (d *dialScheduler) checkDial(n *enode.Node) error {
 	if d.history.contains(string(n.ID().Bytes())) {
 		return errRecentlyDialed
 	}
+	if _, ok := d.static[n.ID()]; !ok && d.reputation != nil {
+		// Banned nodes are never dialed. Other nodes with a negative score are
+		// skipped with a probability growing towards the ban threshold, so
+		// better behaving candidates are preferred.
+		score := d.reputation(n.ID())
+		if score <= reputationBanThreshold || (score < 0 && d.rand.Int63n(-reputationBanThreshold) < -score) {
+			return errLowReputation
+		}
+	}
+	if d.groups != nil && d.groups.full(n.ID()) {
+		return errGroupFull
+	}
+	return nil
+}
+
+// filterDial returns an error if dynamic dial candidate n is rejected by a dial filter.
+func (d *dialScheduler) filterDial(n *enode.Node) error {
+	for _, f := range d.filters {
+		if err := f(n); err != nil {
+			dialFilteredMeter.Mark(1)
+			return fmt.Errorf("%w: %v", errFiltered, err)
+		}
+	}
 	return nil
 }
 
+// startDynDial starts a dynamic dial task for n if it passes checkDial and the
+// dial filters.
+func (d *dialScheduler) startDynDial(n *enode.Node) {
+	err := d.checkDial(n)
+	if err == nil {
+		err = d.filterDial(n)
+	}
+	if err != nil {
+		d.log.Trace("Discarding dial candidate", "id", n.ID(), "ip", n.IP(), "reason", err)
+		return
+	}
+	d.startDial(newDialTask(n, dynDialedConn))
+}
+
 // startStaticDials starts n static dial tasks.
 func (d *dialScheduler) startStaticDials(n int) (started int) {
 	for started = 0; started < n && len(d.staticPool) > 0; started++ {
@@ -539,8 +615,8 @@ func (t *dialTask) dial(d *dialScheduler, dest *enode.Node) error {
 		d.log.Trace("Dial error", "id", t.dest.ID(), "addr", nodeAddr(t.dest), "conn", t.flags, "err", cleanupDialErr(err))
 		return &dialError{err}
 	}
-	mfd := newMeteredConn(fd, false, &net.TCPAddr{IP: dest.IP(), Port: dest.TCP()})
-	return d.setupFunc(mfd, t.flags, dest)
+	fd = newMeteredConn(fd, false, &net.TCPAddr{IP: dest.IP(), Port: dest.TCP()})
+	return d.setupFunc(fd, t.flags, dest)
 }
 
 func (t *dialTask) String() string {
@@ -34,6 +34,60 @@ import (
 	"github.com/ethereum/go-ethereum/p2p/netutil"
 )
 
+// This test checks that dynamic dials leave the unfilled slots of peer groups unused.
+func TestDialSchedReservedSlots(t *testing.T) {
+	t.Parallel()
+
+	member := newNode(uintID(0x10), "")
+	groups, err := newPeerGroups([]PeerGroup{{Name: "sentry", Nodes: []*enode.Node{member}, MinSlots: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := dialConfig{
+		maxActiveDials: 10,
+		maxDialPeers:   4,
+		groups:         groups,
+	}
+	runDialTest(t, config, []dialTestRound{
+		// One of the four peer slots is reserved for the group, leaving 6 dial
+		// slots for the 8 discovered nodes.
+		{
+			discovered: []*enode.Node{
+				newNode(uintID(0x01), "127.0.0.1:30303"),
+				newNode(uintID(0x02), "127.0.0.1:30303"),
+				newNode(uintID(0x03), "127.0.0.1:30303"),
+				newNode(uintID(0x04), "127.0.0.1:30303"),
+				newNode(uintID(0x05), "127.0.0.1:30303"),
+				newNode(uintID(0x06), "127.0.0.1:30303"),
+				newNode(uintID(0x07), "127.0.0.1:30303"), // not dialed because of the reservation
+				newNode(uintID(0x08), "127.0.0.1:30303"), // ...
+			},
+			wantNewDials: []*enode.Node{
+				newNode(uintID(0x01), "127.0.0.1:30303"),
+				newNode(uintID(0x02), "127.0.0.1:30303"),
+				newNode(uintID(0x03), "127.0.0.1:30303"),
+				newNode(uintID(0x04), "127.0.0.1:30303"),
+				newNode(uintID(0x05), "127.0.0.1:30303"),
+				newNode(uintID(0x06), "127.0.0.1:30303"),
+			},
+		},
+		// The group member connects, releasing the reservation. One dial
+		// fails, so the remaining candidates are dialed.
+		{
+			update: func(*dialScheduler) {
+				groups.connect(member.ID())
+			},
+			failed: []enode.ID{
+				uintID(0x01),
+			},
+			wantNewDials: []*enode.Node{
+				newNode(uintID(0x07), "127.0.0.1:30303"),
+				newNode(uintID(0x08), "127.0.0.1:30303"),
+			},
+		},
+	})
+}
+
 // This test checks that dynamic dials are launched from discovery results.
 func TestDialSchedDynDial(t *testing.T) {
 	t.Parallel()
@@ -152,6 +206,105 @@ func TestDialSchedNetRestrict(t *testing.T) {
 	})
 }
 
+// This test checks that candidates rejected by a dial filter are not dialed,
+// while static nodes are dialed regardless of the filters.
+func TestDialSchedFilter(t *testing.T) {
+	t.Parallel()
+
+	nodes := []*enode.Node{
+		newNode(uintID(0x01), "127.0.0.1:30303"),
+		newNode(uintID(0x02), "127.0.0.2:30303"),
+		newNode(uintID(0x03), "127.0.2.3:30303"),
+		newNode(uintID(0x04), "127.0.2.4:30303"),
+		newNode(uintID(0x05), "127.0.2.5:30303"),
+	}
+	static := newNode(uintID(0x06), "127.0.0.6:30303")
+	config := dialConfig{
+		maxActiveDials: 10,
+		maxDialPeers:   10,
+	}
+	dialRange := new(netutil.Netlist)
+	dialRange.Add("127.0.2.0/24")
+	config.filters = []NodeFilter{
+		IPFilter(dialRange),
+		func(n *enode.Node) error {
+			if n.ID() == uintID(0x04) {
+				return errors.New("rejected")
+			}
+			return nil
+		},
+	}
+	runDialTest(t, config, []dialTestRound{
+		{
+			update: func(d *dialScheduler) {
+				d.addStatic(static)
+			},
+			discovered:   nodes,
+			wantNewDials: []*enode.Node{static, nodes[2], nodes[4]},
+		},
+		{
+			succeeded: []enode.ID{
+				static.ID(),
+				nodes[2].ID(),
+				nodes[4].ID(),
+			},
+		},
+	})
+}
+
+// This test checks that nodes which ask to be dialed back are subject to the
+// checks and dial filters for dynamic dials, and are only dialed if a dial slot
+// is free.
+func TestDialSchedDialBack(t *testing.T) {
+	t.Parallel()
+
+	nodes := []*enode.Node{
+		newNode(uintID(0x01), "127.0.2.1:30303"),
+		newNode(uintID(0x02), "127.0.0.2:30303"),
+		newNode(uintID(0x03), "127.0.2.3:30303"),
+		newNode(uintID(0x04), "127.0.2.4:30303"),
+		newNode(uintID(0x05), "127.0.2.5:30303"),
+	}
+	config := dialConfig{
+		netRestrict:    new(netutil.Netlist),
+		maxActiveDials: 10,
+		maxDialPeers:   1,
+	}
+	config.netRestrict.Add("127.0.2.0/24")
+	config.filters = []NodeFilter{
+		func(n *enode.Node) error {
+			if n.ID() == uintID(0x03) {
+				return errors.New("rejected")
+			}
+			return nil
+		},
+	}
+	runDialTest(t, config, []dialTestRound{
+		{
+			update: func(d *dialScheduler) {
+				d.dialBack(nodes[0])
+				d.dialBack(nodes[1])
+				d.dialBack(nodes[2])
+			},
+			wantNewDials: []*enode.Node{nodes[0]},
+		},
+		{
+			update: func(d *dialScheduler) {
+				d.dialBack(nodes[3])
+			},
+			succeeded:    []enode.ID{nodes[0].ID()},
+			wantNewDials: []*enode.Node{nodes[3]},
+		},
+		// All dial slots are in use, the node isn't dialed.
+		{
+			update: func(d *dialScheduler) {
+				d.dialBack(nodes[4])
+			},
+			succeeded: []enode.ID{nodes[3].ID()},
+		},
+	})
+}
+
 // This test checks that static dials work and obey the limits.
 func TestDialSchedStaticDial(t *testing.T) {
 	t.Parallel()
@@ -0,0 +1,175 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/ethereum/go-ethereum/p2p/netutil"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// NodeFilter checks the record of a node found by discovery before it is dialed. It
+// returns a non-nil error describing the reason if the node should not be dialed.
+//
+// Filters are evaluated in order for every dynamic dial candidate, and the first
+// failing filter rejects it. This avoids spending a dial and the protocol handshake
+// on nodes which are known to be useless from their record alone. Static nodes are
+// never filtered.
+type NodeFilter func(*enode.Node) error
+
+// maxClientEntryLen is the maximum length of the client name and version advertised
+// in the "client" entry of the local node record.
+const maxClientEntryLen = 32
+
+var errNotInDialRange = errors.New("not contained in dial IP ranges")
+
+// IPFilter returns a filter which accepts nodes within the given IP networks.
+func IPFilter(list *netutil.Netlist) NodeFilter {
+	return func(n *enode.Node) error {
+		if !list.Contains(n.IP()) {
+			return errNotInDialRange
+		}
+		return nil
+	}
+}
+
+// EntryFilter returns a filter which accepts nodes whose record contains the given
+// key, e.g. "snap" to dial only nodes that support the snap protocol.
+func EntryFilter(key string) NodeFilter {
+	return func(n *enode.Node) error {
+		var v rlp.RawValue
+		if err := n.Load(enr.WithEntry(key, &v)); err != nil {
+			return fmt.Errorf("no %q entry", key)
+		}
+		return nil
+	}
+}
+
+// ClientFilter returns a filter which accepts nodes running one of the given clients,
+// as advertised in the "client" entry of their record. Client names are matched case
+// insensitively. Nodes which don't advertise their client are accepted.
+func ClientFilter(names ...string) NodeFilter {
+	return func(n *enode.Node) error {
+		var client enr.Client
+		if err := n.Load(&client); err != nil {
+			if enr.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		for _, name := range names {
+			if strings.EqualFold(client.Name, name) {
+				return nil
+			}
+		}
+		return fmt.Errorf("client %q not allowed", client.Name)
+	}
+}
+
+// clientEntry derives the "client" entry of the local node from the node name,
+// which has the form name/[identity/]version/os/go-version. Name and version are
+// truncated to maxClientEntryLen.
+func clientEntry(name string) *enr.Client {
+	parts := strings.Split(name, "/")
+	if parts[0] == "" {
+		return nil
+	}
+	client := &enr.Client{Name: truncate(parts[0], maxClientEntryLen)}
+	for _, p := range parts[1:] {
+		if len(p) > 1 && p[0] == 'v' && p[1] >= '0' && p[1] <= '9' {
+			client.Version = truncate(p, maxClientEntryLen)
+			break
+		}
+	}
+	return client
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// setClientEntry adds the "client" entry to the local node record. The entry is
+// left out if the record could exceed the size limit with it, which would make
+// signing the record fail once the endpoints are set.
+func (srv *Server) setClientEntry() {
+	client := clientEntry(srv.Name)
+	if client == nil {
+		return
+	}
+	if err := checkRecordSize(srv.localnode.Node().Record(), srv.PrivateKey, client); err != nil {
+		srv.log.Warn("Not advertising client in node record", "client", client.Name, "err", err)
+		return
+	}
+	srv.localnode.Set(client)
+}
+
+// checkRecordSize checks that a record can still be signed with the given entry
+// added, with all endpoint entries and the sequence number at their maximum size.
+func checkRecordSize(r *enr.Record, key *ecdsa.PrivateKey, e enr.Entry) error {
+	blob, err := rlp.EncodeToBytes(r)
+	if err != nil {
+		return err
+	}
+	var full enr.Record
+	if err := rlp.DecodeBytes(blob, &full); err != nil {
+		return err
+	}
+	full.Set(e)
+	full.Set(enr.IPv4(net.IP{255, 255, 255, 255}))
+	full.Set(enr.IPv6(net.IP{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}))
+	full.Set(enr.TCP(math.MaxUint16))
+	full.Set(enr.TCP6(math.MaxUint16))
+	full.Set(enr.UDP(math.MaxUint16))
+	full.Set(enr.UDP6(math.MaxUint16))
+	full.Set(enr.QUIC(math.MaxUint16))
+	full.SetSeq(math.MaxUint64)
+	return enode.SignV4(&full, key)
+}
+
+// dialFilters assembles the filter chain of the dial scheduler from the config and
+// the protocols.
+func (srv *Server) dialFilters() []NodeFilter {
+	var filters []NodeFilter
+	if srv.DialNetRestrict != nil {
+		filters = append(filters, IPFilter(srv.DialNetRestrict))
+	}
+	for _, key := range srv.DialRequire {
+		filters = append(filters, EntryFilter(key))
+	}
+	if len(srv.DialClients) > 0 {
+		filters = append(filters, ClientFilter(srv.DialClients...))
+	}
+	added := make(map[string]bool)
+	for _, proto := range srv.Protocols {
+		if proto.DialFilter != nil && !added[proto.Name] {
+			filters = append(filters, proto.DialFilter)
+			added[proto.Name] = true
+		}
+	}
+	return append(filters, srv.DialFilters...)
+}
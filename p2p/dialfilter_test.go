@@ -0,0 +1,136 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/internal/testlog"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+)
+
+func TestDialFilters(t *testing.T) {
+	type snapEntry struct{}
+	var (
+		plain = newNode(uintID(1), "127.0.0.1:30303")
+		snap  = withEntries(uintID(2), enr.WithEntry("snap", &snapEntry{}))
+		geth  = withEntries(uintID(3), &enr.Client{Name: "Geth", Version: "v1.10.26"})
+		other = withEntries(uintID(4), &enr.Client{Name: "Other"})
+	)
+	tests := []struct {
+		name   string
+		filter NodeFilter
+		accept []*enode.Node
+		reject []*enode.Node
+	}{
+		{
+			name:   "entry",
+			filter: EntryFilter("snap"),
+			accept: []*enode.Node{snap},
+			reject: []*enode.Node{plain, geth},
+		},
+		{
+			name:   "client",
+			filter: ClientFilter("geth", "erigon"),
+			accept: []*enode.Node{plain, snap, geth},
+			reject: []*enode.Node{other},
+		},
+	}
+	for _, test := range tests {
+		for _, n := range test.accept {
+			if err := test.filter(n); err != nil {
+				t.Errorf("%s: node %v rejected: %v", test.name, n.ID(), err)
+			}
+		}
+		for _, n := range test.reject {
+			if err := test.filter(n); err == nil {
+				t.Errorf("%s: node %v accepted", test.name, n.ID())
+			}
+		}
+	}
+}
+
+func TestClientEntry(t *testing.T) {
+	tests := []struct {
+		name string
+		want *enr.Client
+	}{
+		{"", nil},
+		{"test", &enr.Client{Name: "test"}},
+		{"Geth/v1.10.26-stable/linux-amd64/go1.19.3", &enr.Client{Name: "Geth", Version: "v1.10.26-stable"}},
+		{"Geth/mynode/v1.10.26-stable/linux-amd64/go1.19.3", &enr.Client{Name: "Geth", Version: "v1.10.26-stable"}},
+		{strings.Repeat("n", 100) + "/v" + strings.Repeat("1", 100), &enr.Client{Name: strings.Repeat("n", maxClientEntryLen), Version: "v" + strings.Repeat("1", maxClientEntryLen-1)}},
+	}
+	for _, test := range tests {
+		if got := clientEntry(test.name); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("clientEntry(%q) = %+v, want %+v", test.name, got, test.want)
+		}
+	}
+}
+
+// This test checks that servers advertising their client start with node names of
+// any length, and leave out the client entry if the record would be too large.
+func TestServerClientEntry(t *testing.T) {
+	type bigEntry struct{ Data []byte }
+	tests := []struct {
+		name      string
+		attrs     []enr.Entry
+		advertise bool
+		want      *enr.Client
+	}{
+		{name: "Geth/v1.10.26-stable/linux-amd64/go1.19.3", want: nil},
+		{name: "Geth/v1.10.26-stable/linux-amd64/go1.19.3", advertise: true, want: &enr.Client{Name: "Geth", Version: "v1.10.26-stable"}},
+		{name: strings.Repeat("x", 400), advertise: true, want: &enr.Client{Name: strings.Repeat("x", maxClientEntryLen)}},
+		{name: "Geth/v1.10.26-stable", attrs: []enr.Entry{enr.WithEntry("big", &bigEntry{make([]byte, 100)})}, advertise: true, want: nil},
+	}
+	for _, test := range tests {
+		srv := &Server{Config: Config{
+			Name:            test.name,
+			PrivateKey:      newkey(),
+			MaxPeers:        10,
+			NoDiscovery:     true,
+			ListenAddr:      "127.0.0.1:0",
+			AdvertiseClient: test.advertise,
+			Protocols:       []Protocol{{Name: "test", Length: 1, Attributes: test.attrs}},
+			Logger:          testlog.Logger(t, log.LvlTrace),
+		}}
+		if err := srv.Start(); err != nil {
+			t.Fatal("could not start server:", err)
+		}
+		var client enr.Client
+		err := srv.Self().Load(&client)
+		srv.Stop()
+		switch {
+		case test.want == nil && !enr.IsNotFound(err):
+			t.Errorf("name %.20q: unexpected client entry %+v (err %v)", test.name, client, err)
+		case test.want != nil && (err != nil || client.Name != test.want.Name || client.Version != test.want.Version):
+			t.Errorf("name %.20q: client entry %+v (err %v), want %+v", test.name, client, err, test.want)
+		}
+	}
+}
+
+func withEntries(id enode.ID, entries ...enr.Entry) *enode.Node {
+	var r enr.Record
+	for _, e := range entries {
+		r.Set(e)
+	}
+	return enode.SignNull(&r, id)
+}
@@ -47,6 +47,10 @@ type Config struct {
 	Log          log.Logger         // if set, log messages go here
 	ValidSchemes enr.IdentityScheme // allowed identity schemes
 	Clock        mclock.Clock
+
+	// PunchHandler is called with the initiator of hole punching requests which
+	// were relayed to the local node (discv5 only).
+	PunchHandler func(initiator *enode.Node)
 }
 
 func (cfg Config) withDefaults() Config {
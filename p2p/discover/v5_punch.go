@@ -0,0 +1,313 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package discover
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/p2p/discover/v5wire"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/netutil"
+)
+
+// Hole punching lets two nodes behind NATs establish direct connectivity. The
+// initiator sends a packet to the target, which opens the mapping of its own NAT
+// for packets from the target but is usually dropped by the NAT of the target.
+// It then asks a relay, a node which knows both of them, to introduce it to the
+// target using RELAYINIT. The relay acknowledges the request and forwards the
+// initiator's record to the target in RELAYMSG, upon which the target pings the
+// initiator. The ping passes the NAT
+// of the initiator and opens the mapping of the target's NAT, so the initiator
+// can now reach the target as well.
+//
+// This requires the records of both nodes to contain their external endpoints,
+// which are predicted from the endpoint statements of other nodes. The relay
+// checks that the initiator's record matches the address it sees the request
+// coming from, so it can't be used to direct traffic at arbitrary hosts, and
+// passes that address on to the target, which checks it again. The target only
+// accepts introductions from relays in its table, and keeps a
+// session for every accepted introduction, limiting how often it can be made to
+// send packets to initiators.
+
+const (
+	punchRelays      = 3  // number of relays tried by HolePunch
+	punchAttempts    = 3  // pings sent to the target after the introduction
+	maxActiveRelays  = 16 // introductions served concurrently as a relay
+	relayTargetIDLen = len(enode.ID{})
+
+	maxRelaySessions      = 16               // introductions accepted concurrently as a target
+	maxRelaySessionsRelay = 2                // introductions accepted concurrently from a single relay
+	relaySessionTimeout   = 30 * time.Second // time until an initiator can be introduced again
+)
+
+var (
+	errNoEndpoint       = errors.New("local node has no external endpoint")
+	errNoRelay          = errors.New("no relay available")
+	errRelayRejected    = errors.New("introduction rejected")
+	errRelayBusy        = errors.New("too many active introductions")
+	errMissingRecord    = errors.New("missing initiator record")
+	errEndpointMismatch = errors.New("record endpoint does not match sender")
+	errUnknownTarget    = errors.New("unknown target node")
+	errUnknownRelay     = errors.New("unknown relay node")
+	errRelaySession     = errors.New("introduction already accepted")
+	errReachable        = errors.New("node is reachable without hole punching")
+)
+
+// relaySession is an introduction accepted by the local node as the target.
+type relaySession struct {
+	relay   enode.ID
+	expires mclock.AbsTime
+}
+
+// HolePunch tries to establish direct connectivity with a node which doesn't
+// respond to packets, assuming it is behind a NAT. The node is pinged first, and
+// hole punching is only attempted if the ping times out. Nodes close to the
+// target are then asked to introduce the local node to the target until one
+// succeeds.
+func (t *UDPv5) HolePunch(ctx context.Context, n *enode.Node) error {
+	self := t.Self()
+	if self.IP() == nil || self.UDP() == 0 {
+		return errNoEndpoint
+	}
+	// The ping also opens the local NAT for packets from the target.
+	switch _, err := t.ping(n); {
+	case err == nil:
+		return errReachable
+	case !errors.Is(err, errTimeout):
+		return err
+	}
+	err := errNoRelay
+	tried := 0
+	for _, relay := range t.tab.findnodeByID(n.ID(), punchRelays+1, true).entries {
+		if relay.ID() == n.ID() {
+			continue
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err = t.holePunchVia(ctx, unwrapNode(relay), n); err == nil {
+			return nil
+		}
+		t.log.Debug("Hole punching failed", "id", n.ID(), "relay", relay.ID(), "err", err)
+		if tried++; tried >= punchRelays {
+			break
+		}
+	}
+	return err
+}
+
+// holePunchVia performs hole punching with the given relay.
+func (t *UDPv5) holePunchVia(ctx context.Context, relay, n *enode.Node) error {
+	if err := t.relayInit(ctx, relay, n.ID()); err != nil {
+		return err
+	}
+	var err error
+	for i := 0; i < punchAttempts && ctx.Err() == nil; i++ {
+		if _, err = t.ping(n); err == nil {
+			return nil
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// relayInit asks the relay to introduce the local node to the target.
+func (t *UDPv5) relayInit(ctx context.Context, relay *enode.Node, target enode.ID) error {
+	req := &v5wire.RelayInit{Initiator: t.Self().Record(), Target: target[:]}
+	resp := t.call(relay, v5wire.RelayResponseMsg, req)
+	defer t.callDone(resp)
+
+	select {
+	case r := <-resp.ch:
+		if !r.(*v5wire.RelayResponse).Relayed {
+			return errRelayRejected
+		}
+		return nil
+	case err := <-resp.err:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// relayMsg forwards the initiator record and the address it was received from
+// to the target.
+func (t *UDPv5) relayMsg(target *enode.Node, initiator *v5wire.RelayInit, fromAddr *net.UDPAddr) error {
+	ip := fromAddr.IP
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	req := &v5wire.RelayMsg{Initiator: initiator.Initiator, FromIP: ip, FromPort: uint16(fromAddr.Port)}
+	resp := t.call(target, v5wire.RelayResponseMsg, req)
+	defer t.callDone(resp)
+
+	select {
+	case r := <-resp.ch:
+		if !r.(*v5wire.RelayResponse).Relayed {
+			return errRelayRejected
+		}
+		return nil
+	case err := <-resp.err:
+		return err
+	}
+}
+
+// handleRelayInit forwards an introduction to the target. The initiator is
+// answered as soon as the introduction is accepted, because waiting for the
+// target could exceed the response timeout of the initiator. Whether the target
+// accepts the introduction is up to the initiator to find out by pinging it.
+func (t *UDPv5) handleRelayInit(p *v5wire.RelayInit, fromID enode.ID, fromAddr *net.UDPAddr) {
+	resp := newRelayResponse(p.ReqID, fromAddr)
+	target, err := t.checkRelayInit(p, fromID, fromAddr)
+	if err == nil {
+		select {
+		case t.relaySlots <- struct{}{}:
+		default:
+			err = errRelayBusy
+		}
+	}
+	if err != nil {
+		t.log.Debug("Rejected "+p.Name(), "id", fromID, "addr", fromAddr, "err", err)
+		t.sendResponse(fromID, fromAddr, resp)
+		return
+	}
+	resp.Relayed = true
+	t.sendResponse(fromID, fromAddr, resp)
+
+	go func() {
+		defer func() { <-t.relaySlots }()
+		if err := t.relayMsg(target, p, fromAddr); err != nil {
+			t.log.Debug("Introduction failed", "id", fromID, "target", target.ID(), "err", err)
+		}
+	}()
+}
+
+func (t *UDPv5) checkRelayInit(p *v5wire.RelayInit, fromID enode.ID, fromAddr *net.UDPAddr) (*enode.Node, error) {
+	if p.Initiator == nil {
+		return nil, errMissingRecord
+	}
+	n, err := enode.New(t.validSchemes, p.Initiator)
+	if err != nil {
+		return nil, err
+	}
+	if n.ID() != fromID || !n.IP().Equal(fromAddr.IP) || n.UDP() != fromAddr.Port {
+		return nil, errEndpointMismatch
+	}
+	if len(p.Target) != relayTargetIDLen {
+		return nil, errUnknownTarget
+	}
+	var id enode.ID
+	copy(id[:], p.Target)
+	target := t.tab.getNode(id)
+	if target == nil || target.ID() == fromID {
+		return nil, errUnknownTarget
+	}
+	return target, nil
+}
+
+// handleRelayMsg accepts an introduction and pings the initiator.
+func (t *UDPv5) handleRelayMsg(p *v5wire.RelayMsg, fromID enode.ID, fromAddr *net.UDPAddr) {
+	resp := newRelayResponse(p.ReqID, fromAddr)
+	n, err := t.checkRelayMsg(p, fromID, fromAddr)
+	if err == nil {
+		err = t.addRelaySession(n.ID(), fromID)
+	}
+	if err != nil {
+		t.log.Debug("Rejected "+p.Name(), "id", fromID, "addr", fromAddr, "err", err)
+		t.sendResponse(fromID, fromAddr, resp)
+		return
+	}
+	resp.Relayed = true
+	t.sendResponse(fromID, fromAddr, resp)
+
+	// The ping opens the local NAT for packets from the initiator.
+	go t.ping(n)
+	if t.punchHandler != nil {
+		go t.punchHandler(n)
+	}
+}
+
+func (t *UDPv5) checkRelayMsg(p *v5wire.RelayMsg, fromID enode.ID, fromAddr *net.UDPAddr) (*enode.Node, error) {
+	relay := t.tab.getNode(fromID)
+	if relay == nil || !relay.IP().Equal(fromAddr.IP) || relay.UDP() != fromAddr.Port {
+		return nil, errUnknownRelay
+	}
+	if p.Initiator == nil {
+		return nil, errMissingRecord
+	}
+	n, err := enode.New(t.validSchemes, p.Initiator)
+	if err != nil {
+		return nil, err
+	}
+	if n.ID() == t.Self().ID() {
+		return nil, errors.New("introduction to self")
+	}
+	if n.IP() == nil || n.UDP() == 0 {
+		return nil, errNoEndpoint
+	}
+	if !n.IP().Equal(p.FromIP) || n.UDP() != int(p.FromPort) {
+		return nil, errEndpointMismatch
+	}
+	if err := netutil.CheckRelayIP(fromAddr.IP, n.IP()); err != nil {
+		return nil, err
+	}
+	if t.netrestrict != nil && !t.netrestrict.Contains(n.IP()) {
+		return nil, errors.New("not contained in netrestrict list")
+	}
+	return n, nil
+}
+
+// addRelaySession records an introduction of the initiator by the relay. It fails
+// if the initiator was introduced recently, or if too many introductions are
+// active, in total or from the relay.
+//
+// This is called from the dispatch loop.
+func (t *UDPv5) addRelaySession(initiator, relay enode.ID) error {
+	now := t.clock.Now()
+	fromRelay := 0
+	for id, s := range t.relaySessions {
+		switch {
+		case s.expires <= now:
+			delete(t.relaySessions, id)
+		case s.relay == relay:
+			fromRelay++
+		}
+	}
+	if _, ok := t.relaySessions[initiator]; ok {
+		return errRelaySession
+	}
+	if len(t.relaySessions) >= maxRelaySessions || fromRelay >= maxRelaySessionsRelay {
+		return errRelayBusy
+	}
+	t.relaySessions[initiator] = relaySession{relay: relay, expires: now.Add(relaySessionTimeout)}
+	return nil
+}
+
+// newRelayResponse creates a RELAYRESP reflecting the address of the requester.
+func newRelayResponse(reqID []byte, toAddr *net.UDPAddr) *v5wire.RelayResponse {
+	ip := toAddr.IP
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	return &v5wire.RelayResponse{ReqID: reqID, ToIP: ip, ToPort: uint16(toAddr.Port)}
+}
@@ -0,0 +1,365 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package discover
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/p2p/discover/v5wire"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// This test checks that introductions are relayed to known target nodes.
+func TestUDPv5_relayInit(t *testing.T) {
+	t.Parallel()
+	test := newUDPV5Test(t)
+	defer test.close()
+
+	var (
+		initiator  = test.getNode(test.remotekey, test.remoteaddr).Node()
+		targetkey  = newkey()
+		targetaddr = &net.UDPAddr{IP: net.IP{10, 0, 2, 2}, Port: 30303}
+		target     = test.getNode(targetkey, targetaddr).Node()
+	)
+	test.table.addSeenNode(wrapNode(target))
+
+	// Introductions to unknown nodes are rejected.
+	unknown := enode.ID{1}
+	test.packetIn(&v5wire.RelayInit{ReqID: []byte{1}, Initiator: initiator.Record(), Target: unknown[:]})
+	test.waitPacketOut(func(p *v5wire.RelayResponse, addr *net.UDPAddr, _ v5wire.Nonce) {
+		if p.Relayed {
+			t.Error("relayed to unknown node")
+		}
+		if !p.ToIP.Equal(test.remoteaddr.IP) || int(p.ToPort) != test.remoteaddr.Port {
+			t.Errorf("wrong endpoint in response: %v:%d", p.ToIP, p.ToPort)
+		}
+	})
+
+	// Introductions with a record not matching the sender endpoint are rejected.
+	otheraddr := &net.UDPAddr{IP: net.IP{10, 0, 1, 100}, Port: 30303}
+	test.packetInFrom(test.remotekey, otheraddr, &v5wire.RelayInit{ReqID: []byte{2}, Initiator: initiator.Record(), Target: target.ID().Bytes()})
+	test.waitPacketOut(func(p *v5wire.RelayResponse, addr *net.UDPAddr, _ v5wire.Nonce) {
+		if p.Relayed {
+			t.Error("relayed with mismatching record")
+		}
+	})
+
+	// Valid introductions are acknowledged right away and forwarded to the
+	// target. The target doesn't answer here, the initiator must not depend on it.
+	test.packetIn(&v5wire.RelayInit{ReqID: []byte{3}, Initiator: initiator.Record(), Target: target.ID().Bytes()})
+	test.waitPacketOut(func(p *v5wire.RelayResponse, addr *net.UDPAddr, _ v5wire.Nonce) {
+		if !addr.IP.Equal(test.remoteaddr.IP) {
+			t.Errorf("RELAYRESP sent to wrong address %v", addr)
+		}
+		if string(p.ReqID) != string([]byte{3}) || !p.Relayed {
+			t.Errorf("wrong response %v", p)
+		}
+	})
+	test.waitPacketOut(func(p *v5wire.RelayMsg, addr *net.UDPAddr, _ v5wire.Nonce) {
+		if !addr.IP.Equal(targetaddr.IP) {
+			t.Errorf("RELAYMSG sent to wrong address %v", addr)
+		}
+		if n, err := enode.New(enode.ValidSchemesForTesting, p.Initiator); err != nil || n.ID() != initiator.ID() {
+			t.Errorf("wrong initiator record in RELAYMSG")
+		}
+		if !p.FromIP.Equal(test.remoteaddr.IP) || int(p.FromPort) != test.remoteaddr.Port {
+			t.Errorf("wrong initiator endpoint in RELAYMSG: %v:%d", p.FromIP, p.FromPort)
+		}
+	})
+}
+
+// This test checks that the target of an introduction pings the initiator.
+func TestUDPv5_relayMsg(t *testing.T) {
+	t.Parallel()
+	test := newUDPV5Test(t)
+	defer test.close()
+
+	punched := make(chan *enode.Node, 1)
+	test.udp.punchHandler = func(n *enode.Node) { punched <- n }
+
+	var (
+		relay         = test.getNode(test.remotekey, test.remoteaddr).Node()
+		initiatorkey  = newkey()
+		initiatoraddr = &net.UDPAddr{IP: net.IP{10, 0, 3, 3}, Port: 30303}
+		initiator     = test.getNode(initiatorkey, initiatoraddr).Node()
+	)
+
+	// Introductions by relays which aren't in the table are rejected.
+	test.packetIn(&v5wire.RelayMsg{ReqID: []byte{1}, Initiator: initiator.Record(), FromIP: initiatoraddr.IP, FromPort: 30303})
+	test.waitPacketOut(func(p *v5wire.RelayResponse, addr *net.UDPAddr, _ v5wire.Nonce) {
+		if p.Relayed {
+			t.Error("introduction by unknown relay accepted")
+		}
+	})
+
+	test.table.addSeenNode(wrapNode(relay))
+
+	// Introductions with a record not matching the endpoint seen by the relay
+	// are rejected.
+	test.packetIn(&v5wire.RelayMsg{ReqID: []byte{4}, Initiator: initiator.Record(), FromIP: net.IP{10, 0, 3, 4}, FromPort: 30303})
+	test.waitPacketOut(func(p *v5wire.RelayResponse, addr *net.UDPAddr, _ v5wire.Nonce) {
+		if p.Relayed {
+			t.Error("introduction with mismatching record accepted")
+		}
+	})
+
+	test.packetIn(&v5wire.RelayMsg{ReqID: []byte{2}, Initiator: initiator.Record(), FromIP: initiatoraddr.IP, FromPort: 30303})
+	test.waitPacketOut(func(p *v5wire.RelayResponse, addr *net.UDPAddr, _ v5wire.Nonce) {
+		if !p.Relayed {
+			t.Error("introduction not accepted")
+		}
+	})
+	test.waitPacketOut(func(p *v5wire.Ping, addr *net.UDPAddr, _ v5wire.Nonce) {
+		if !addr.IP.Equal(initiatoraddr.IP) || addr.Port != initiatoraddr.Port {
+			t.Errorf("PING sent to wrong address %v", addr)
+		}
+	})
+	select {
+	case n := <-punched:
+		if n.ID() != initiator.ID() {
+			t.Errorf("punch handler called with wrong node %v", n.ID())
+		}
+	case <-time.After(time.Second):
+		t.Error("punch handler not called")
+	}
+
+	// Repeated introductions of the initiator are rejected.
+	test.packetIn(&v5wire.RelayMsg{ReqID: []byte{3}, Initiator: initiator.Record(), FromIP: initiatoraddr.IP, FromPort: 30303})
+	test.waitPacketOut(func(p *v5wire.RelayResponse, addr *net.UDPAddr, _ v5wire.Nonce) {
+		if p.Relayed {
+			t.Error("repeated introduction accepted")
+		}
+	})
+}
+
+// This test checks the limits of concurrent introductions.
+func TestUDPv5_relaySessions(t *testing.T) {
+	t.Parallel()
+	clock := new(mclock.Simulated)
+	udp := &UDPv5{clock: clock, relaySessions: make(map[enode.ID]relaySession)}
+
+	relay := enode.ID{1}
+	for i := 0; i < maxRelaySessionsRelay; i++ {
+		if err := udp.addRelaySession(enode.ID{2, byte(i)}, relay); err != nil {
+			t.Fatalf("introduction %d rejected: %v", i, err)
+		}
+	}
+	if err := udp.addRelaySession(enode.ID{3}, relay); err != errRelayBusy {
+		t.Fatalf("wrong error for introduction over relay limit: %v", err)
+	}
+	for i := maxRelaySessionsRelay; i < maxRelaySessions; i++ {
+		if err := udp.addRelaySession(enode.ID{2, byte(i)}, enode.ID{4, byte(i)}); err != nil {
+			t.Fatalf("introduction %d rejected: %v", i, err)
+		}
+	}
+	if err := udp.addRelaySession(enode.ID{3}, enode.ID{5}); err != errRelayBusy {
+		t.Fatalf("wrong error for introduction over total limit: %v", err)
+	}
+
+	// Sessions expire after the timeout.
+	clock.Run(relaySessionTimeout)
+	if err := udp.addRelaySession(enode.ID{2, 0}, relay); err != nil {
+		t.Fatalf("introduction rejected after timeout: %v", err)
+	}
+	if len(udp.relaySessions) != 1 {
+		t.Fatalf("wrong number of sessions after timeout: %d", len(udp.relaySessions))
+	}
+}
+
+// natConn simulates a NAT in front of a socket. It drops packets from endpoints
+// which the socket hasn't sent packets to.
+type natConn struct {
+	*net.UDPConn
+	mu     sync.Mutex
+	opened map[string]bool
+}
+
+func (c *natConn) WriteToUDP(b []byte, addr *net.UDPAddr) (int, error) {
+	c.mu.Lock()
+	c.opened[addr.String()] = true
+	c.mu.Unlock()
+	return c.UDPConn.WriteToUDP(b, addr)
+}
+
+func (c *natConn) ReadFromUDP(b []byte) (int, *net.UDPAddr, error) {
+	for {
+		n, addr, err := c.UDPConn.ReadFromUDP(b)
+		if err != nil {
+			return n, addr, err
+		}
+		c.mu.Lock()
+		open := c.opened[addr.String()]
+		c.mu.Unlock()
+		if open {
+			return n, addr, nil
+		}
+	}
+}
+
+// delayConn delays the delivery of packets from an endpoint once it is armed.
+// Only packets large enough to carry a node record are delayed, so that PING
+// and PONG exchanges with the endpoint keep working.
+type delayConn struct {
+	UDPConn
+	from    string
+	delay   time.Duration
+	armed   int32 // atomic
+	packets chan delayedPacket
+	errc    chan error
+	closing chan struct{}
+}
+
+// delayMinSize is the size of the smallest packet delayed by delayConn.
+const delayMinSize = 200
+
+type delayedPacket struct {
+	data []byte
+	addr *net.UDPAddr
+}
+
+func newDelayConn(c UDPConn, from *net.UDPAddr, delay time.Duration) *delayConn {
+	dc := &delayConn{
+		UDPConn: c,
+		from:    from.String(),
+		delay:   delay,
+		packets: make(chan delayedPacket, 16),
+		errc:    make(chan error, 1),
+		closing: make(chan struct{}),
+	}
+	go dc.readLoop()
+	return dc
+}
+
+func (c *delayConn) arm() {
+	atomic.StoreInt32(&c.armed, 1)
+}
+
+func (c *delayConn) readLoop() {
+	for {
+		buf := make([]byte, maxPacketSize)
+		n, addr, err := c.UDPConn.ReadFromUDP(buf)
+		if err != nil {
+			c.errc <- err
+			return
+		}
+		p := delayedPacket{buf[:n], addr}
+		if atomic.LoadInt32(&c.armed) == 1 && addr.String() == c.from && n >= delayMinSize {
+			time.AfterFunc(c.delay, func() { c.deliver(p) })
+		} else {
+			c.deliver(p)
+		}
+	}
+}
+
+func (c *delayConn) deliver(p delayedPacket) {
+	select {
+	case c.packets <- p:
+	case <-c.closing:
+	}
+}
+
+func (c *delayConn) ReadFromUDP(b []byte) (int, *net.UDPAddr, error) {
+	select {
+	case p := <-c.packets:
+		return copy(b, p.data), p.addr, nil
+	case err := <-c.errc:
+		c.errc <- err
+		return 0, nil, err
+	}
+}
+
+func (c *delayConn) Close() error {
+	close(c.closing)
+	return c.UDPConn.Close()
+}
+
+// Real sockets, real crypto: this test checks that a node can reach another one
+// behind a NAT through an introduction by a relay.
+func TestUDPv5_holePunchE2E(t *testing.T) {
+	t.Parallel()
+	testHolePunchE2E(t, 0)
+}
+
+// This test checks that hole punching works when the relay takes longer than
+// the response timeout to reach the target.
+func TestUDPv5_holePunchDelayedTarget(t *testing.T) {
+	t.Parallel()
+	testHolePunchE2E(t, respTimeoutV5+respTimeoutV5/2)
+}
+
+func testHolePunchE2E(t *testing.T, relayDelay time.Duration) {
+	punched := make(chan *enode.Node, 1)
+	relay := startLocalhostV5(t, Config{})
+	defer relay.Close()
+
+	var delayed *delayConn
+	target := startLocalhostV5Conn(t, Config{
+		Bootnodes:    []*enode.Node{relay.Self()},
+		PunchHandler: func(n *enode.Node) { punched <- n },
+	}, func(c *net.UDPConn) UDPConn {
+		nat := &natConn{UDPConn: c, opened: make(map[string]bool)}
+		if relayDelay == 0 {
+			return nat
+		}
+		delayed = newDelayConn(nat, &net.UDPAddr{IP: relay.Self().IP(), Port: relay.Self().UDP()}, relayDelay)
+		return delayed
+	})
+	defer target.Close()
+
+	// Wait for the relay and the target to learn about each other. The initiator
+	// is started afterwards, so the target doesn't contact it during its initial
+	// lookup, which would open the NAT.
+	<-target.tab.initDone
+	for deadline := time.Now().Add(5 * time.Second); relay.tab.getNode(target.Self().ID()) == nil || target.tab.getNode(relay.Self().ID()) == nil; {
+		if time.Now().After(deadline) {
+			t.Fatal("relay and target don't know each other")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	initiator := startLocalhostV5(t, Config{Bootnodes: []*enode.Node{relay.Self()}})
+	defer initiator.Close()
+	<-initiator.tab.initDone
+
+	if _, err := initiator.ping(target.Self()); err != errTimeout {
+		t.Fatal("target reachable without hole punching:", err)
+	}
+	if delayed != nil {
+		delayed.arm()
+	}
+	if err := initiator.HolePunch(context.Background(), target.Self()); err != nil {
+		t.Fatal("hole punching failed:", err)
+	}
+	select {
+	case n := <-punched:
+		if n.ID() != initiator.Self().ID() {
+			t.Errorf("punch handler called with wrong node %v", n.ID())
+		}
+	case <-time.After(time.Second):
+		t.Error("punch handler not called")
+	}
+
+	// Nodes which are reachable directly aren't punched.
+	if err := initiator.HolePunch(context.Background(), relay.Self()); err != errReachable {
+		t.Errorf("wrong error for reachable node: %v", err)
+	}
+}
@@ -0,0 +1,572 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package discover
+
+import (
+	"context"
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/discover/v5wire"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/netutil"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	topicAdLifetime     = 15 * time.Minute
+	topicQueueCapacity  = 50   // max ads per topic
+	topicTableCapacity  = 5000 // max ads across all topics
+	topicRegisterWindow = 10 * time.Second
+
+	topicIPLimit, topicIPSubnet   = 2, 128 // at most 2 ads per topic from the same address
+	topicSubnetLimit, topicSubnet = 5, 24  // at most 5 ads per topic from the same /24
+
+	topicRegistrarCount = 8               // number of nodes an ad is placed on
+	topicTicketAttempts = 3               // max tickets requested from a registrar per round
+	topicMaxTicketWait  = 5 * time.Minute // registrars demanding longer waits are skipped
+	topicRenewInterval  = topicAdLifetime - topicMaxTicketWait
+	topicRetryInterval  = time.Minute
+	topicSearchInterval = 10 * time.Second
+)
+
+var (
+	errInvalidTopic       = errors.New("invalid topic")
+	errInvalidTicket      = errors.New("invalid ticket")
+	errTicketEarly        = errors.New("ticket used before waiting time")
+	errTicketExpired      = errors.New("ticket expired")
+	errTicketWaitTooLong  = errors.New("ticket waiting time too long")
+	errTopicNotRegistered = errors.New("topic registration not confirmed")
+)
+
+// Topic identifies a topic which nodes can advertise themselves for. Ads are placed on
+// the nodes closest to the topic in the DHT.
+type Topic common.Hash
+
+// NewTopic creates the topic identifier of the given topic name.
+func NewTopic(name string) Topic {
+	return Topic(crypto.Keccak256Hash([]byte(name)))
+}
+
+func (t Topic) String() string {
+	return common.Hash(t).String()
+}
+
+// topicAd is an advertisement stored in the topic table.
+type topicAd struct {
+	node    *enode.Node
+	expires mclock.AbsTime
+}
+
+// topicTicket is the content of a ticket issued by the local node. Tickets are opaque
+// to the requester and can only be used by the node they were issued to.
+type topicTicket struct {
+	Topic  Topic
+	ID     enode.ID
+	IP     net.IP
+	Issued uint64 // local clock time
+	Wait   uint64 // in nanoseconds
+}
+
+// topicTable stores the ads registered with the local node. Registrants obtain a ticket
+// first, which tells them how long to wait until a slot frees up in the topic queue.
+//
+// The table is accessed by the UDPv5 dispatch loop only.
+type topicTable struct {
+	clock  mclock.Clock
+	secret [32]byte // authenticates tickets
+	queues map[Topic][]*topicAd
+	nets   map[Topic]*topicNets
+	total  int
+}
+
+// topicNets tracks the addresses of the ads registered for a topic, so a single
+// host or network can't occupy the whole topic queue.
+type topicNets struct {
+	ips     netutil.DistinctNetSet
+	subnets netutil.DistinctNetSet
+}
+
+func newTopicTable(clock mclock.Clock) *topicTable {
+	tt := &topicTable{
+		clock:  clock,
+		queues: make(map[Topic][]*topicAd),
+		nets:   make(map[Topic]*topicNets),
+	}
+	crand.Read(tt.secret[:])
+	return tt
+}
+
+// addIP reserves space for an ad from the given address, returning false if the
+// address or its network exceeds the limits of the topic.
+func (tt *topicTable) addIP(topic Topic, ip net.IP) bool {
+	if netutil.IsLAN(ip) {
+		return true
+	}
+	nets := tt.nets[topic]
+	if nets == nil {
+		nets = &topicNets{
+			ips:     netutil.DistinctNetSet{Subnet: topicIPSubnet, Limit: topicIPLimit},
+			subnets: netutil.DistinctNetSet{Subnet: topicSubnet, Limit: topicSubnetLimit},
+		}
+		tt.nets[topic] = nets
+	}
+	if !nets.ips.Add(ip) {
+		return false
+	}
+	if !nets.subnets.Add(ip) {
+		nets.ips.Remove(ip)
+		return false
+	}
+	return true
+}
+
+// removeIP releases the space of an ad from the given address.
+func (tt *topicTable) removeIP(topic Topic, ip net.IP) {
+	if netutil.IsLAN(ip) {
+		return
+	}
+	if nets := tt.nets[topic]; nets != nil {
+		nets.ips.Remove(ip)
+		nets.subnets.Remove(ip)
+		if nets.ips.Len() == 0 {
+			delete(tt.nets, topic)
+		}
+	}
+}
+
+// expire removes expired ads. Queues are ordered by expiry time.
+func (tt *topicTable) expire() {
+	now := tt.clock.Now()
+	for topic, q := range tt.queues {
+		n := 0
+		for n < len(q) && q[n].expires <= now {
+			tt.removeIP(topic, q[n].node.IP())
+			n++
+		}
+		tt.total -= n
+		if n == len(q) {
+			delete(tt.queues, topic)
+		} else {
+			tt.queues[topic] = q[n:]
+		}
+	}
+}
+
+// indexOf returns the queue position of the node's ad, or -1 if it has none.
+func (tt *topicTable) indexOf(topic Topic, id enode.ID) int {
+	for i, ad := range tt.queues[topic] {
+		if ad.node.ID() == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// waitTime returns how long the node must wait until it can register for the topic.
+func (tt *topicTable) waitTime(topic Topic, id enode.ID) time.Duration {
+	tt.expire()
+	if tt.indexOf(topic, id) >= 0 {
+		return 0 // renewal
+	}
+	var (
+		now  = tt.clock.Now()
+		wait time.Duration
+	)
+	if q := tt.queues[topic]; len(q) >= topicQueueCapacity {
+		wait = time.Duration(q[0].expires - now)
+	}
+	if tt.total >= topicTableCapacity {
+		oldest := mclock.AbsTime(-1)
+		for _, q := range tt.queues {
+			if oldest < 0 || q[0].expires < oldest {
+				oldest = q[0].expires
+			}
+		}
+		if w := time.Duration(oldest - now); w > wait {
+			wait = w
+		}
+	}
+	return wait
+}
+
+// register adds an ad for the node. Ads of nodes which are already registered are
+// renewed. It returns false if there is no space left, or if too many ads of the
+// topic come from the node's address or network.
+func (tt *topicTable) register(topic Topic, n *enode.Node) bool {
+	if tt.waitTime(topic, n.ID()) > 0 {
+		return false
+	}
+	q := tt.queues[topic]
+	i := tt.indexOf(topic, n.ID())
+	if i >= 0 {
+		// Release the old address first, the node may have moved.
+		tt.removeIP(topic, q[i].node.IP())
+	}
+	if !tt.addIP(topic, n.IP()) {
+		if i >= 0 {
+			tt.addIP(topic, q[i].node.IP())
+		}
+		return false
+	}
+	if i >= 0 {
+		q = append(q[:i], q[i+1:]...)
+		tt.total--
+	}
+	tt.queues[topic] = append(q, &topicAd{node: n, expires: tt.clock.Now().Add(topicAdLifetime)})
+	tt.total++
+	return true
+}
+
+// nodes returns the most recently registered nodes of a topic.
+func (tt *topicTable) nodes(topic Topic, limit int) []*enode.Node {
+	tt.expire()
+	q := tt.queues[topic]
+	nodes := make([]*enode.Node, 0, min(limit, len(q)))
+	for i := len(q) - 1; i >= 0 && len(nodes) < limit; i-- {
+		nodes = append(nodes, q[i].node)
+	}
+	return nodes
+}
+
+// issueTicket creates a ticket for the given registrant.
+func (tt *topicTable) issueTicket(topic Topic, id enode.ID, ip net.IP) ([]byte, time.Duration) {
+	wait := tt.waitTime(topic, id)
+	enc, _ := rlp.EncodeToBytes(&topicTicket{
+		Topic:  topic,
+		ID:     id,
+		IP:     ip,
+		Issued: uint64(tt.clock.Now()),
+		Wait:   uint64(wait),
+	})
+	return append(enc, tt.ticketMAC(enc)...), wait
+}
+
+// checkTicket verifies a ticket presented by a registrant and returns its topic.
+func (tt *topicTable) checkTicket(ticket []byte, id enode.ID, ip net.IP) (Topic, error) {
+	if len(ticket) <= sha256.Size {
+		return Topic{}, errInvalidTicket
+	}
+	enc, mac := ticket[:len(ticket)-sha256.Size], ticket[len(ticket)-sha256.Size:]
+	if !hmac.Equal(mac, tt.ticketMAC(enc)) {
+		return Topic{}, errInvalidTicket
+	}
+	var tk topicTicket
+	if err := rlp.DecodeBytes(enc, &tk); err != nil {
+		return Topic{}, err
+	}
+	if tk.ID != id || !tk.IP.Equal(ip) {
+		return Topic{}, errInvalidTicket
+	}
+	var (
+		now   = tt.clock.Now()
+		start = mclock.AbsTime(tk.Issued).Add(time.Duration(tk.Wait))
+	)
+	switch {
+	case now < start:
+		return Topic{}, errTicketEarly
+	case now > start.Add(topicRegisterWindow):
+		return Topic{}, errTicketExpired
+	}
+	return tk.Topic, nil
+}
+
+func (tt *topicTable) ticketMAC(enc []byte) []byte {
+	mac := hmac.New(sha256.New, tt.secret[:])
+	mac.Write(enc)
+	return mac.Sum(nil)
+}
+
+// RegisterTopic advertises the local node for the given topic until the stop channel is
+// closed or the transport is shut down. Ads are placed on the nodes closest to the topic
+// and renewed before they expire.
+func (t *UDPv5) RegisterTopic(topic Topic, stop <-chan struct{}) {
+	ctx, cancel := context.WithCancel(t.closeCtx)
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		registrars := t.newLookup(ctx, enode.ID(topic)).run()
+		if len(registrars) > topicRegistrarCount {
+			registrars = registrars[:topicRegistrarCount]
+		}
+		var (
+			wg         sync.WaitGroup
+			registered int32
+		)
+		for _, n := range registrars {
+			wg.Add(1)
+			go func(n *enode.Node) {
+				defer wg.Done()
+				if err := t.registerTopicAt(ctx, n, topic); err != nil {
+					t.log.Debug("Topic registration failed", "topic", topic, "id", n.ID(), "err", err)
+					return
+				}
+				t.log.Debug("Registered topic", "topic", topic, "id", n.ID())
+				atomic.AddInt32(&registered, 1)
+			}(n)
+		}
+		wg.Wait()
+
+		next := topicRenewInterval
+		if registered == 0 {
+			next = topicRetryInterval
+		}
+		timer := t.clock.NewTimer(next)
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// registerTopicAt places an ad for the local node on registrar n.
+func (t *UDPv5) registerTopicAt(ctx context.Context, n *enode.Node, topic Topic) error {
+	for i := 0; i < topicTicketAttempts; i++ {
+		ticket, wait, err := t.requestTicket(n, topic)
+		if err != nil {
+			return err
+		}
+		if wait > topicMaxTicketWait {
+			return errTicketWaitTooLong
+		}
+		timer := t.clock.NewTimer(wait)
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+		registered, err := t.regtopic(n, ticket)
+		if err != nil {
+			return err
+		}
+		if registered {
+			return nil
+		}
+	}
+	return errTopicNotRegistered
+}
+
+// requestTicket calls REQUESTTICKET on a node and waits for a TICKET response.
+func (t *UDPv5) requestTicket(n *enode.Node, topic Topic) ([]byte, time.Duration, error) {
+	resp := t.call(n, v5wire.TicketMsg, &v5wire.RequestTicket{Topic: topic[:]})
+	defer t.callDone(resp)
+
+	select {
+	case p := <-resp.ch:
+		ticket := p.(*v5wire.Ticket)
+		return ticket.Ticket, time.Duration(ticket.WaitTime) * time.Millisecond, nil
+	case err := <-resp.err:
+		return nil, 0, err
+	}
+}
+
+// regtopic calls REGTOPIC on a node and waits for a REGCONFIRMATION response.
+func (t *UDPv5) regtopic(n *enode.Node, ticket []byte) (bool, error) {
+	req := &v5wire.Regtopic{Ticket: ticket, ENR: t.localNode.Node().Record()}
+	resp := t.call(n, v5wire.RegconfirmationMsg, req)
+	defer t.callDone(resp)
+
+	select {
+	case p := <-resp.ch:
+		return p.(*v5wire.Regconfirmation).Registered, nil
+	case err := <-resp.err:
+		return false, err
+	}
+}
+
+// TopicQuery asks a node for the nodes registered with it for the given topic.
+func (t *UDPv5) TopicQuery(n *enode.Node, topic Topic) ([]*enode.Node, error) {
+	resp := t.call(n, v5wire.NodesMsg, &v5wire.TopicQuery{Topic: topic[:]})
+	return t.waitForNodes(resp, nil)
+}
+
+// TopicNodes returns an iterator over nodes advertising the given topic. It looks up
+// the nodes closest to the topic and asks each of them for registered nodes.
+func (t *UDPv5) TopicNodes(topic Topic) enode.Iterator {
+	ctx, cancel := context.WithCancel(t.closeCtx)
+	return &topicIterator{t: t, topic: topic, ctx: ctx, cancel: cancel}
+}
+
+// handleRequestTicket issues a ticket for the requested topic.
+func (t *UDPv5) handleRequestTicket(p *v5wire.RequestTicket, fromID enode.ID, fromAddr *net.UDPAddr) {
+	if len(p.Topic) != len(Topic{}) {
+		t.log.Debug("Invalid "+p.Name(), "id", fromID, "addr", fromAddr, "err", errInvalidTopic)
+		return
+	}
+	ticket, wait := t.topics.issueTicket(Topic(common.BytesToHash(p.Topic)), fromID, fromAddr.IP)
+	t.sendResponse(fromID, fromAddr, &v5wire.Ticket{
+		ReqID:    p.ReqID,
+		Ticket:   ticket,
+		WaitTime: uint64(wait / time.Millisecond),
+	})
+}
+
+// handleRegtopic registers the sender if it presents a valid ticket.
+func (t *UDPv5) handleRegtopic(p *v5wire.Regtopic, fromID enode.ID, fromAddr *net.UDPAddr) {
+	registered, err := t.registerAd(p, fromID, fromAddr)
+	if err != nil {
+		t.log.Debug("Rejected "+p.Name(), "id", fromID, "addr", fromAddr, "err", err)
+	}
+	t.sendResponse(fromID, fromAddr, &v5wire.Regconfirmation{ReqID: p.ReqID, Registered: registered})
+}
+
+func (t *UDPv5) registerAd(p *v5wire.Regtopic, fromID enode.ID, fromAddr *net.UDPAddr) (bool, error) {
+	if p.ENR == nil {
+		return false, errors.New("missing record")
+	}
+	n, err := enode.New(t.validSchemes, p.ENR)
+	if err != nil {
+		return false, err
+	}
+	if n.ID() != fromID || !n.IP().Equal(fromAddr.IP) {
+		return false, errors.New("record does not match sender")
+	}
+	topic, err := t.topics.checkTicket(p.Ticket, fromID, fromAddr.IP)
+	if err != nil {
+		return false, err
+	}
+	return t.topics.register(topic, n), nil
+}
+
+// handleTopicQuery returns the nodes registered for a topic.
+func (t *UDPv5) handleTopicQuery(p *v5wire.TopicQuery, fromID enode.ID, fromAddr *net.UDPAddr) {
+	if len(p.Topic) != len(Topic{}) {
+		t.log.Debug("Invalid "+p.Name(), "id", fromID, "addr", fromAddr, "err", errInvalidTopic)
+		return
+	}
+	var nodes []*enode.Node
+	for _, n := range t.topics.nodes(Topic(common.BytesToHash(p.Topic)), topicQueueCapacity) {
+		if n.ID() == fromID || netutil.CheckRelayIP(fromAddr.IP, n.IP()) != nil {
+			continue
+		}
+		if nodes = append(nodes, n); len(nodes) >= findnodeResultLimit {
+			break
+		}
+	}
+	for _, resp := range packNodes(p.ReqID, nodes) {
+		t.sendResponse(fromID, fromAddr, resp)
+	}
+}
+
+// topicIterator performs lookups for a topic and queries the nodes found during each
+// lookup for registered nodes. Nodes are returned once per lookup.
+type topicIterator struct {
+	t      *UDPv5
+	topic  Topic
+	ctx    context.Context
+	cancel func()
+	lookup *lookup
+	rounds int
+	asked  map[enode.ID]bool // registrars queried in the current lookup
+	seen   map[enode.ID]bool // nodes returned in the current lookup
+	buffer []*enode.Node
+}
+
+// Node returns the current node.
+func (it *topicIterator) Node() *enode.Node {
+	if len(it.buffer) == 0 {
+		return nil
+	}
+	return it.buffer[0]
+}
+
+// Next moves to the next node.
+func (it *topicIterator) Next() bool {
+	if len(it.buffer) > 0 {
+		it.buffer = it.buffer[1:]
+	}
+	for len(it.buffer) == 0 {
+		if it.ctx.Err() != nil {
+			it.lookup = nil
+			it.buffer = nil
+			return false
+		}
+		if it.lookup == nil {
+			// Wait between lookups, the ads don't change quickly.
+			if it.rounds > 0 && !it.sleep(topicSearchInterval) {
+				continue
+			}
+			it.rounds++
+			it.lookup = it.t.newLookup(it.ctx, enode.ID(it.topic))
+			it.asked = make(map[enode.ID]bool)
+			it.seen = make(map[enode.ID]bool)
+			continue
+		}
+		if !it.lookup.advance() {
+			it.lookup = nil
+			continue
+		}
+		for _, n := range it.lookup.replyBuffer {
+			it.query(unwrapNode(n))
+		}
+	}
+	return true
+}
+
+// query asks a registrar for nodes and adds them to the buffer.
+func (it *topicIterator) query(registrar *enode.Node) {
+	if it.asked[registrar.ID()] {
+		return
+	}
+	it.asked[registrar.ID()] = true
+	nodes, err := it.t.TopicQuery(registrar, it.topic)
+	if err != nil {
+		it.t.log.Trace("TOPICQUERY failed", "id", registrar.ID(), "err", err)
+	}
+	for _, n := range nodes {
+		if n.ID() != it.t.Self().ID() && !it.seen[n.ID()] {
+			it.seen[n.ID()] = true
+			it.buffer = append(it.buffer, n)
+		}
+	}
+}
+
+// sleep waits for the given duration. It returns false if the iterator was closed.
+func (it *topicIterator) sleep(d time.Duration) bool {
+	timer := it.t.clock.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return true
+	case <-it.ctx.Done():
+		return false
+	}
+}
+
+// Close ends the iterator.
+func (it *topicIterator) Close() {
+	it.cancel()
+}
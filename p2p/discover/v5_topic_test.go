@@ -0,0 +1,265 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package discover
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/p2p/discover/v5wire"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+func TestTopicTable(t *testing.T) {
+	var (
+		clock = new(mclock.Simulated)
+		tt    = newTopicTable(clock)
+		topic = NewTopic("test")
+		ip    = net.IP{10, 0, 0, 1}
+		nodes = nodesAtDistance(enode.ID{}, 256, topicQueueCapacity+1)
+	)
+	// Fill the topic queue, registering one node per second.
+	for _, n := range nodes[:topicQueueCapacity] {
+		ticket, wait := tt.issueTicket(topic, n.ID(), ip)
+		if wait != 0 {
+			t.Fatalf("wrong waiting time %v for free queue", wait)
+		}
+		got, err := tt.checkTicket(ticket, n.ID(), ip)
+		if err != nil || got != topic {
+			t.Fatalf("ticket check failed: %v", err)
+		}
+		if !tt.register(topic, n) {
+			t.Fatal("registration failed")
+		}
+		clock.Run(time.Second)
+	}
+	if got := tt.nodes(topic, 2); len(got) != 2 || got[0] != nodes[topicQueueCapacity-1] {
+		t.Fatalf("wrong nodes returned: %v", got)
+	}
+
+	// The next registrant must wait until the first ad expires.
+	last := nodes[topicQueueCapacity]
+	ticket, wait := tt.issueTicket(topic, last.ID(), ip)
+	if want := topicAdLifetime - topicQueueCapacity*time.Second; wait != want {
+		t.Fatalf("wrong waiting time %v, want %v", wait, want)
+	}
+	if _, err := tt.checkTicket(ticket, last.ID(), ip); err != errTicketEarly {
+		t.Fatalf("wrong error for early ticket: %v", err)
+	}
+	if _, err := tt.checkTicket(ticket, nodes[0].ID(), ip); err != errInvalidTicket {
+		t.Fatalf("wrong error for ticket of other node: %v", err)
+	}
+	ticket[0]++
+	if _, err := tt.checkTicket(ticket, last.ID(), ip); err != errInvalidTicket {
+		t.Fatalf("wrong error for modified ticket: %v", err)
+	}
+	ticket[0]--
+
+	// Registered nodes can renew their ad without waiting.
+	if _, wait := tt.issueTicket(topic, nodes[1].ID(), ip); wait != 0 {
+		t.Fatalf("wrong waiting time %v for renewal", wait)
+	}
+	if !tt.register(topic, nodes[1]) {
+		t.Fatal("renewal failed")
+	}
+
+	clock.Run(wait)
+	if _, err := tt.checkTicket(ticket, last.ID(), ip); err != nil {
+		t.Fatalf("ticket check failed after waiting: %v", err)
+	}
+	if !tt.register(topic, last) {
+		t.Fatal("registration failed after waiting")
+	}
+	clock.Run(topicRegisterWindow + time.Second)
+	if _, err := tt.checkTicket(ticket, last.ID(), ip); err != errTicketExpired {
+		t.Fatalf("wrong error for expired ticket: %v", err)
+	}
+}
+
+// This test checks that the ads of a topic are limited per address and network.
+func TestTopicTableIPLimits(t *testing.T) {
+	var (
+		clock = new(mclock.Simulated)
+		tt    = newTopicTable(clock)
+		topic = NewTopic("test")
+		other = NewTopic("other")
+	)
+	newNode := func(ip net.IP) *enode.Node {
+		return unwrapNode(nodeAtDistance(enode.ID{}, 256, ip))
+	}
+	// Only two ads per topic may come from the same address.
+	var sameIP []*enode.Node
+	for i := 0; i < topicIPLimit+1; i++ {
+		sameIP = append(sameIP, newNode(net.IP{1, 2, 3, 4}))
+	}
+	for _, n := range sameIP[:topicIPLimit] {
+		if !tt.register(topic, n) {
+			t.Fatal("registration failed below the address limit")
+		}
+	}
+	if tt.register(topic, sameIP[topicIPLimit]) {
+		t.Fatal("registration succeeded above the address limit")
+	}
+	if !tt.register(other, sameIP[topicIPLimit]) {
+		t.Fatal("address limit applied across topics")
+	}
+	// Registered nodes can still renew their ads.
+	if !tt.register(topic, sameIP[0]) {
+		t.Fatal("renewal failed at the address limit")
+	}
+	// The /24 of the address is limited too.
+	for i := topicIPLimit; i < topicSubnetLimit; i++ {
+		if !tt.register(topic, newNode(net.IP{1, 2, 3, byte(10 + i)})) {
+			t.Fatalf("registration %d failed below the subnet limit", i)
+		}
+	}
+	if tt.register(topic, newNode(net.IP{1, 2, 3, 100})) {
+		t.Fatal("registration succeeded above the subnet limit")
+	}
+	if !tt.register(topic, newNode(net.IP{1, 2, 4, 100})) {
+		t.Fatal("registration from other subnet failed")
+	}
+	// Local addresses are not limited.
+	for i := 0; i < topicSubnetLimit+1; i++ {
+		if !tt.register(topic, newNode(net.IP{10, 0, 0, 1})) {
+			t.Fatal("registration from LAN address failed")
+		}
+	}
+	// Expired ads free up their slots.
+	clock.Run(topicAdLifetime)
+	if !tt.register(topic, sameIP[topicIPLimit]) {
+		t.Fatal("registration failed after the ads expired")
+	}
+}
+
+// This test checks that topic registration and queries are served.
+func TestUDPv5_topicHandling(t *testing.T) {
+	t.Parallel()
+	test := newUDPV5Test(t)
+	defer test.close()
+
+	topic := NewTopic("test")
+	test.packetIn(&v5wire.RequestTicket{ReqID: []byte{0}, Topic: topic[:]})
+	var ticket []byte
+	test.waitPacketOut(func(p *v5wire.Ticket, addr *net.UDPAddr, _ v5wire.Nonce) {
+		if p.WaitTime != 0 {
+			t.Errorf("wrong waiting time %d", p.WaitTime)
+		}
+		ticket = p.Ticket
+	})
+
+	// Registration with a modified ticket is rejected.
+	remote := test.getNode(test.remotekey, test.remoteaddr).Node()
+	invalid := append([]byte{}, ticket...)
+	invalid[len(invalid)-1]++
+	test.packetIn(&v5wire.Regtopic{ReqID: []byte{1}, Ticket: invalid, ENR: remote.Record()})
+	test.waitPacketOut(func(p *v5wire.Regconfirmation, addr *net.UDPAddr, _ v5wire.Nonce) {
+		if p.Registered {
+			t.Error("registered with invalid ticket")
+		}
+	})
+	test.packetIn(&v5wire.Regtopic{ReqID: []byte{2}, Ticket: ticket, ENR: remote.Record()})
+	test.waitPacketOut(func(p *v5wire.Regconfirmation, addr *net.UDPAddr, _ v5wire.Nonce) {
+		if !p.Registered {
+			t.Error("not registered with valid ticket")
+		}
+	})
+
+	// Another node asks for the topic.
+	otherkey, otheraddr := newkey(), &net.UDPAddr{IP: net.IP{10, 0, 1, 100}, Port: 30303}
+	test.getNode(otherkey, otheraddr)
+	test.packetInFrom(otherkey, otheraddr, &v5wire.TopicQuery{ReqID: []byte{3}, Topic: topic[:]})
+	test.waitPacketOut(func(p *v5wire.Nodes, addr *net.UDPAddr, _ v5wire.Nonce) {
+		if len(p.Nodes) != 1 {
+			t.Fatalf("wrong number of nodes in response: %d", len(p.Nodes))
+		}
+		if n, err := enode.New(enode.ValidSchemes, p.Nodes[0]); err != nil || n.ID() != remote.ID() {
+			t.Errorf("wrong node in response: %v", p.Nodes[0])
+		}
+	})
+	other := NewTopic("other")
+	test.packetInFrom(otherkey, otheraddr, &v5wire.TopicQuery{ReqID: []byte{4}, Topic: other[:]})
+	test.waitPacketOut(func(p *v5wire.Nodes, addr *net.UDPAddr, _ v5wire.Nonce) {
+		if len(p.Nodes) != 0 {
+			t.Errorf("wrong nodes in response for unregistered topic: %v", p.Nodes)
+		}
+	})
+}
+
+// Real sockets, real crypto: this test checks that nodes advertising a topic are
+// found by topic search.
+func TestUDPv5_topicE2E(t *testing.T) {
+	t.Parallel()
+
+	const N = 5
+	var nodes []*UDPv5
+	for i := 0; i < N; i++ {
+		var cfg Config
+		if len(nodes) > 0 {
+			cfg.Bootnodes = []*enode.Node{nodes[0].Self()}
+		}
+		node := startLocalhostV5(t, cfg)
+		nodes = append(nodes, node)
+		defer node.Close()
+	}
+	var (
+		topic      = NewTopic("test")
+		registrant = nodes[1]
+		searcher   = nodes[N-1]
+		stop       = make(chan struct{})
+	)
+	go registrant.RegisterTopic(topic, stop)
+	defer close(stop)
+
+	// Wait for the ads to be placed.
+	for deadline := time.Now().Add(10 * time.Second); ; {
+		if registered(searcher, nodes, registrant, topic) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("topic not registered")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	it := searcher.TopicNodes(topic)
+	defer it.Close()
+	if !it.Next() {
+		t.Fatal("topic search ended")
+	}
+	if it.Node().ID() != registrant.Self().ID() {
+		t.Fatalf("wrong node found: %v", it.Node().ID())
+	}
+}
+
+// registered reports whether any node has an ad of the registrant.
+func registered(searcher *UDPv5, nodes []*UDPv5, registrant *UDPv5, topic Topic) bool {
+	for _, n := range nodes {
+		if n == searcher || n == registrant {
+			continue
+		}
+		found, _ := searcher.TopicQuery(n.Self(), topic)
+		for _, f := range found {
+			if f.ID() == registrant.Self().ID() {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -76,18 +76,25 @@ type UDPv5 struct {
 	trlock     sync.Mutex
 	trhandlers map[string]TalkRequestHandler
 
+	// hole punching
+	punchHandler func(*enode.Node)
+	relaySlots   chan struct{}
+
 	// channels into dispatch
 	packetInCh    chan ReadPacket
 	readNextCh    chan struct{}
 	callCh        chan *callV5
 	callDoneCh    chan *callV5
 	respTimeoutCh chan *callTimeout
+	sendCh        chan sendRequest
 
 	// state of dispatch
 	codec            codecV5
 	activeCallByNode map[enode.ID]*callV5
 	activeCallByAuth map[v5wire.Nonce]*callV5
 	callQueue        map[enode.ID][]*callV5
+	topics           *topicTable
+	relaySessions    map[enode.ID]relaySession
 
 	// shutdown stuff
 	closeOnce      sync.Once
@@ -115,6 +122,13 @@ type callV5 struct {
 	timeout        mclock.Timer
 }
 
+// sendRequest is a packet sent by a goroutine other than dispatch.
+type sendRequest struct {
+	destID   enode.ID
+	destAddr *net.UDPAddr
+	msg      v5wire.Packet
+}
+
 // callTimeout is the response timeout event of a call.
 type callTimeout struct {
 	c     *callV5
@@ -149,17 +163,22 @@ func newUDPv5(conn UDPConn, ln *enode.LocalNode, cfg Config) (*UDPv5, error) {
 		validSchemes: cfg.ValidSchemes,
 		clock:        cfg.Clock,
 		trhandlers:   make(map[string]TalkRequestHandler),
+		punchHandler: cfg.PunchHandler,
+		relaySlots:   make(chan struct{}, maxActiveRelays),
 		// channels into dispatch
 		packetInCh:    make(chan ReadPacket, 1),
 		readNextCh:    make(chan struct{}, 1),
 		callCh:        make(chan *callV5),
 		callDoneCh:    make(chan *callV5),
 		respTimeoutCh: make(chan *callTimeout),
+		sendCh:        make(chan sendRequest),
 		// state of dispatch
 		codec:            v5wire.NewCodec(ln, cfg.PrivateKey, cfg.Clock),
 		activeCallByNode: make(map[enode.ID]*callV5),
 		activeCallByAuth: make(map[v5wire.Nonce]*callV5),
 		callQueue:        make(map[enode.ID][]*callV5),
+		topics:           newTopicTable(cfg.Clock),
+		relaySessions:    make(map[enode.ID]relaySession),
 		// shutdown
 		closeCtx:       closeCtx,
 		cancelCloseCtx: cancelCloseCtx,
@@ -518,6 +537,9 @@ func (t *UDPv5) dispatch() {
 			delete(t.activeCallByNode, id)
 			t.sendNextCall(id)
 
+		case r := <-t.sendCh:
+			t.send(r.destID, r.destAddr, r.msg, nil)
+
 		case p := <-t.packetInCh:
 			t.handlePacket(p.Data, p.Addr)
 			// Arm next read.
@@ -593,6 +615,15 @@ func (t *UDPv5) sendCall(c *callV5) {
 	t.startResponseTimeout(c)
 }
 
+// sendFromAnotherThread sends a packet from a goroutine other than dispatch.
+// Like sendResponse, this doesn't trigger a handshake.
+func (t *UDPv5) sendFromAnotherThread(toID enode.ID, toAddr *net.UDPAddr, packet v5wire.Packet) {
+	select {
+	case t.sendCh <- sendRequest{toID, toAddr, packet}:
+	case <-t.closeCtx.Done():
+	}
+}
+
 // sendResponse sends a response packet to the given node.
 // This doesn't trigger a handshake even if no keys are available.
 func (t *UDPv5) sendResponse(toID enode.ID, toAddr *net.UDPAddr, packet v5wire.Packet) error {
@@ -717,6 +748,24 @@ func (t *UDPv5) handle(p v5wire.Packet, fromID enode.ID, fromAddr *net.UDPAddr)
 		t.handleTalkRequest(p, fromID, fromAddr)
 	case *v5wire.TalkResponse:
 		t.handleCallResponse(fromID, fromAddr, p)
+	case *v5wire.RequestTicket:
+		t.handleRequestTicket(p, fromID, fromAddr)
+	case *v5wire.Ticket:
+		t.handleCallResponse(fromID, fromAddr, p)
+	case *v5wire.Regtopic:
+		t.handleRegtopic(p, fromID, fromAddr)
+	case *v5wire.Regconfirmation:
+		t.handleCallResponse(fromID, fromAddr, p)
+	case *v5wire.TopicQuery:
+		t.handleTopicQuery(p, fromID, fromAddr)
+	case *v5wire.RelayInit:
+		t.handleRelayInit(p, fromID, fromAddr)
+	case *v5wire.RelayMsg:
+		t.handleRelayMsg(p, fromID, fromAddr)
+	case *v5wire.RelayResponse:
+		if t.handleCallResponse(fromID, fromAddr, p) && p.ToIP != nil {
+			t.localNode.UDPEndpointStatement(fromAddr, &net.UDPAddr{IP: p.ToIP, Port: int(p.ToPort)})
+		}
 	}
 }
 
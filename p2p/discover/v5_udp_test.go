@@ -72,6 +72,11 @@ func TestUDPv5_lookupE2E(t *testing.T) {
 }
 
 func startLocalhostV5(t *testing.T, cfg Config) *UDPv5 {
+	return startLocalhostV5Conn(t, cfg, func(c *net.UDPConn) UDPConn { return c })
+}
+
+// startLocalhostV5Conn starts a node on a localhost socket wrapped by wrap.
+func startLocalhostV5Conn(t *testing.T, cfg Config, wrap func(*net.UDPConn) UDPConn) *UDPv5 {
 	cfg.PrivateKey = newkey()
 	db, _ := enode.OpenDB("")
 	ln := enode.NewLocalNode(db, cfg.PrivateKey)
@@ -92,8 +97,8 @@ func startLocalhostV5(t *testing.T, cfg Config) *UDPv5 {
 	}
 	realaddr := socket.LocalAddr().(*net.UDPAddr)
 	ln.SetStaticIP(realaddr.IP)
-	ln.Set(enr.UDP(realaddr.Port))
-	udp, err := ListenV5(socket, ln, cfg)
+	ln.SetFallbackUDP(realaddr.Port)
+	udp, err := ListenV5(wrap(socket), ln, cfg)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -47,6 +47,9 @@ const (
 	RegtopicMsg
 	RegconfirmationMsg
 	TopicQueryMsg
+	RelayInitMsg
+	RelayMsgMsg
+	RelayResponseMsg
 
 	UnknownPacket   = byte(255) // any non-decryptable packet
 	WhoareyouPacket = byte(254) // the WHOAREYOU packet
@@ -121,8 +124,9 @@ type (
 
 	// TICKET is the response to REQUESTTICKET.
 	Ticket struct {
-		ReqID  []byte
-		Ticket []byte
+		ReqID    []byte
+		Ticket   []byte
+		WaitTime uint64 // in milliseconds
 	}
 
 	// REGTOPIC registers the sender in a topic queue using a ticket.
@@ -143,6 +147,30 @@ type (
 		ReqID []byte
 		Topic []byte
 	}
+
+	// RELAYINIT asks the recipient to introduce the initiator to the target node
+	// for hole punching.
+	RelayInit struct {
+		ReqID     []byte
+		Initiator *enr.Record
+		Target    []byte // node ID
+	}
+
+	// RELAYMSG is sent by a relay to introduce the initiator to the target node.
+	RelayMsg struct {
+		ReqID     []byte
+		Initiator *enr.Record
+		FromIP    net.IP // These fields mirror the UDP envelope address of the
+		FromPort  uint16 // RELAYINIT, which the initiator record must match.
+	}
+
+	// RELAYRESP is the reply to RELAYINIT and RELAYMSG.
+	RelayResponse struct {
+		ReqID   []byte
+		Relayed bool
+		ToIP    net.IP // These fields mirror the UDP envelope address of the request,
+		ToPort  uint16 // like in PONG.
+	}
 )
 
 // DecodeMessage decodes the message body of a packet.
@@ -171,6 +199,12 @@ func DecodeMessage(ptype byte, body []byte) (Packet, error) {
 		dec = new(Regconfirmation)
 	case TopicQueryMsg:
 		dec = new(TopicQuery)
+	case RelayInitMsg:
+		dec = new(RelayInit)
+	case RelayMsgMsg:
+		dec = new(RelayMsg)
+	case RelayResponseMsg:
+		dec = new(RelayResponse)
 	default:
 		return nil, fmt.Errorf("unknown packet type %d", ptype)
 	}
@@ -247,3 +281,18 @@ func (*TopicQuery) Name() string             { return "TOPICQUERY/v5" }
 func (*TopicQuery) Kind() byte               { return TopicQueryMsg }
 func (p *TopicQuery) RequestID() []byte      { return p.ReqID }
 func (p *TopicQuery) SetRequestID(id []byte) { p.ReqID = id }
+
+func (*RelayInit) Name() string             { return "RELAYINIT/v5" }
+func (*RelayInit) Kind() byte               { return RelayInitMsg }
+func (p *RelayInit) RequestID() []byte      { return p.ReqID }
+func (p *RelayInit) SetRequestID(id []byte) { p.ReqID = id }
+
+func (*RelayMsg) Name() string             { return "RELAYMSG/v5" }
+func (*RelayMsg) Kind() byte               { return RelayMsgMsg }
+func (p *RelayMsg) RequestID() []byte      { return p.ReqID }
+func (p *RelayMsg) SetRequestID(id []byte) { p.ReqID = id }
+
+func (*RelayResponse) Name() string             { return "RELAYRESP/v5" }
+func (*RelayResponse) Kind() byte               { return RelayResponseMsg }
+func (p *RelayResponse) RequestID() []byte      { return p.ReqID }
+func (p *RelayResponse) SetRequestID(id []byte) { p.ReqID = id }
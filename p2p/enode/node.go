@@ -128,6 +128,14 @@ func (n *Node) TCP() int {
 	return int(port)
 }
 
+// QUIC returns the devp2p QUIC port of the node, or zero if the node doesn't
+// support the QUIC transport.
+func (n *Node) QUIC() int {
+	var port enr.QUIC
+	n.Load(&port)
+	return int(port)
+}
+
 // Pubkey returns the secp256k1 public key of the node, if present.
 func (n *Node) Pubkey() *ecdsa.PublicKey {
 	var key ecdsa.PublicKey
@@ -40,6 +40,7 @@ const (
 	dbVersionKey   = "version" // Version of the database to flush if changes
 	dbNodePrefix   = "n:"      // Identifier to prefix node entries with
 	dbLocalPrefix  = "local:"
+	dbRepPrefix    = "rep:" // Identifier to prefix node reputations with
 	dbDiscoverRoot = "v4"
 	dbDiscv5Root   = "v5"
 
@@ -56,9 +57,10 @@ const (
 )
 
 const (
-	dbNodeExpiration = 24 * time.Hour // Time after which an unseen node should be dropped.
-	dbCleanupCycle   = time.Hour      // Time period for running the expiration task.
-	dbVersion        = 9
+	dbNodeExpiration       = 24 * time.Hour     // Time after which an unseen node should be dropped.
+	dbReputationExpiration = 7 * 24 * time.Hour // Time after which an unchanged reputation should be dropped.
+	dbCleanupCycle         = time.Hour          // Time period for running the expiration task.
+	dbVersion              = 9
 )
 
 var (
@@ -315,6 +317,7 @@ func (db *DB) expirer() {
 		select {
 		case <-tick.C:
 			db.expireNodes()
+			db.expireReputations()
 		case <-db.quit:
 			return
 		}
@@ -360,6 +363,21 @@ func (db *DB) expireNodes() {
 	}
 }
 
+// expireReputations deletes all node reputations that have not been updated for
+// some time. Scores decay towards zero long before the expiration, so this only
+// drops entries of nodes which were never seen again.
+func (db *DB) expireReputations() {
+	it := db.lvl.NewIterator(util.BytesPrefix([]byte(dbRepPrefix)), nil)
+	defer it.Release()
+
+	threshold := time.Now().Add(-dbReputationExpiration)
+	for it.Next() {
+		if rep := decodeReputation(it.Value()); rep.Score == 0 || rep.Updated.Before(threshold) {
+			db.lvl.Delete(it.Key(), nil)
+		}
+	}
+}
+
 // LastPingReceived retrieves the time of the last ping packet received from
 // a remote node.
 func (db *DB) LastPingReceived(id ID, ip net.IP) time.Time {
@@ -442,6 +460,76 @@ func (db *DB) storeLocalSeq(id ID, n uint64) {
 	db.storeUint64(localItemKey(id, dbLocalSeq), n)
 }
 
+// Reputation is the accumulated score of a remote node, as tracked by the p2p
+// server. Reputations are kept apart from the discovery data, so they are not
+// flushed by the node expiration, but expire on their own once unchanged for
+// dbReputationExpiration.
+type Reputation struct {
+	Score   int64     // Accumulated score, positive for well behaving nodes
+	Updated time.Time // Time of the last score change
+}
+
+// reputationKey returns the database key for a node reputation.
+func reputationKey(id ID) []byte {
+	return append([]byte(dbRepPrefix), id[:]...)
+}
+
+// Reputation retrieves the stored reputation of a node. The zero value is
+// returned for unknown nodes.
+func (db *DB) Reputation(id ID) Reputation {
+	blob, err := db.lvl.Get(reputationKey(id), nil)
+	if err != nil {
+		return Reputation{}
+	}
+	return decodeReputation(blob)
+}
+
+// UpdateReputation stores the reputation of a node.
+func (db *DB) UpdateReputation(id ID, rep Reputation) error {
+	// Launch expirer
+	db.ensureExpirer()
+
+	blob := make([]byte, 2*binary.MaxVarintLen64)
+	n := binary.PutVarint(blob, rep.Score)
+	n += binary.PutVarint(blob[n:], rep.Updated.Unix())
+	return db.lvl.Put(reputationKey(id), blob[:n], nil)
+}
+
+// DeleteReputation removes the stored reputation of a node.
+func (db *DB) DeleteReputation(id ID) {
+	db.lvl.Delete(reputationKey(id), nil)
+}
+
+// Reputations retrieves all stored node reputations.
+func (db *DB) Reputations() map[ID]Reputation {
+	it := db.lvl.NewIterator(util.BytesPrefix([]byte(dbRepPrefix)), nil)
+	defer it.Release()
+
+	reps := make(map[ID]Reputation)
+	for it.Next() {
+		var id ID
+		if len(it.Key()) != len(dbRepPrefix)+len(id) {
+			continue
+		}
+		copy(id[:], it.Key()[len(dbRepPrefix):])
+		reps[id] = decodeReputation(it.Value())
+	}
+	return reps
+}
+
+// decodeReputation parses a reputation created by UpdateReputation.
+func decodeReputation(blob []byte) Reputation {
+	score, n := binary.Varint(blob)
+	if n <= 0 {
+		return Reputation{}
+	}
+	updated, m := binary.Varint(blob[n:])
+	if m <= 0 {
+		return Reputation{}
+	}
+	return Reputation{Score: score, Updated: time.Unix(updated, 0)}
+}
+
 // QuerySeeds retrieves random nodes to be used as potential seed nodes
 // for bootstrapping.
 func (db *DB) QuerySeeds(n int, maxAge time.Duration) []*Node {
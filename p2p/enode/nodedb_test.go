@@ -467,3 +467,49 @@ func TestDBExpireV5(t *testing.T) {
 	db.UpdateFindFailsV5(ID{}, ip, 4)
 	db.expireNodes()
 }
+
+// This test checks that node reputations can be stored, listed and deleted,
+// and that they survive the expiration of the node's discovery data.
+func TestDBReputation(t *testing.T) {
+	db, _ := OpenDB("")
+	defer db.Close()
+
+	var (
+		id1     = ID{0x01}
+		id2     = ID{0x02}
+		updated = time.Unix(1666000000, 0)
+		ip      = net.IP{127, 0, 0, 1}
+	)
+	if rep := db.Reputation(id1); rep != (Reputation{}) {
+		t.Fatalf("unknown node has reputation %v", rep)
+	}
+	if err := db.UpdateReputation(id1, Reputation{Score: -250, Updated: updated}); err != nil {
+		t.Fatalf("failed to store reputation: %v", err)
+	}
+	if err := db.UpdateReputation(id2, Reputation{Score: 42, Updated: updated}); err != nil {
+		t.Fatalf("failed to store reputation: %v", err)
+	}
+	if rep := db.Reputation(id1); rep.Score != -250 || !rep.Updated.Equal(updated) {
+		t.Fatalf("reputation mismatch: have %v, want score -250 at %v", rep, updated)
+	}
+	// Expire the discovery data of the node, the reputation must be retained.
+	db.UpdateLastPongReceived(id1, ip, updated)
+	db.expireNodes()
+	if rep := db.Reputation(id1); rep.Score != -250 {
+		t.Fatalf("reputation dropped by expiration: %v", rep)
+	}
+	reps := db.Reputations()
+	if len(reps) != 2 || reps[id1].Score != -250 || reps[id2].Score != 42 {
+		t.Fatalf("reputation listing mismatch: %v", reps)
+	}
+	db.DeleteReputation(id1)
+	if reps := db.Reputations(); len(reps) != 1 || reps[id2].Score != 42 {
+		t.Fatalf("reputation listing mismatch after delete: %v", reps)
+	}
+	// Reputations unchanged for longer than the expiration must be dropped.
+	db.UpdateReputation(id1, Reputation{Score: 7, Updated: time.Now()})
+	db.expireReputations()
+	if reps := db.Reputations(); len(reps) != 1 || reps[id1].Score != 7 {
+		t.Fatalf("reputation listing mismatch after expiration: %v", reps)
+	}
+}
@@ -76,6 +76,24 @@ type UDP6 uint16
 
 func (v UDP6) ENRKey() string { return "udp6" }
 
+// QUIC is the "quic" key, which holds the UDP port of the node's devp2p QUIC
+// transport.
+type QUIC uint16
+
+func (v QUIC) ENRKey() string { return "quic" }
+
+// Client is the "client" key, which holds the name and version of the software
+// running the node (EIP-7636).
+type Client struct {
+	Name    string
+	Version string
+
+	// Ignore additional fields (for forward compatibility).
+	Rest []rlp.RawValue `rlp:"tail"`
+}
+
+func (v Client) ENRKey() string { return "client" }
+
 // ID is the "id" key, which holds the name of the identity scheme.
 type ID string
 
@@ -0,0 +1,141 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/netutil"
+)
+
+const (
+	// This is the time allowed for hole punching before a failed dial is retried.
+	punchTimeout = 10 * time.Second
+
+	// These limit the dials back to nodes which requested hole punching. Dials
+	// back to the same IP are limited to one per punchBackThrottleTime.
+	maxPunchBacks         = 32 // per punchBackThrottleTime
+	punchBackThrottleTime = 30 * time.Second
+)
+
+// holePuncher is implemented by discover.UDPv5.
+type holePuncher interface {
+	HolePunch(ctx context.Context, n *enode.Node) error
+}
+
+// punchDialer retries failed dials after hole punching through discovery v5.
+//
+// Hole punching opens the NATs of both nodes for UDP traffic between their
+// discovery endpoints. It is only attempted for nodes which don't respond on
+// their discovery endpoint either. A packet is sent from the local QUIC endpoint
+// to the QUIC endpoint of the target, opening the local NAT for QUIC connections
+// from the target. The target of the hole punching does the same and dials back
+// the initiator (see Server.punchBack). Either the dial back or the retried dial
+// of the initiator then succeeds.
+//
+// TCP connections can't pass NATs this way without a simultaneous open, which is
+// not implemented, so punching requires QUIC on both nodes. It is also skipped if
+// the dial failed in a way which shows that the node is down, rather than behind
+// a NAT or firewall dropping the dial.
+type punchDialer struct {
+	NodeDialer
+	disc holePuncher
+	quic *quicEndpoint // nil if QUIC is disabled
+}
+
+func (d punchDialer) Dial(ctx context.Context, n *enode.Node) (net.Conn, error) {
+	fd, err := d.NodeDialer.Dial(ctx, n)
+	if err == nil || ctx.Err() != nil || d.quic == nil || !punchable(n) || !dialFiltered(err) {
+		return fd, err
+	}
+	if perr := d.punch(ctx, n); perr != nil {
+		return nil, err
+	}
+	return d.NodeDialer.Dial(ctx, n)
+}
+
+// punch performs hole punching in the background, returning early when ctx is
+// canceled.
+func (d punchDialer) punch(ctx context.Context, n *enode.Node) error {
+	ctx, cancel := context.WithTimeout(ctx, punchTimeout)
+	defer cancel()
+
+	d.quic.punch(n)
+	errc := make(chan error, 1)
+	go func() { errc <- d.disc.HolePunch(ctx, n) }()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// punchable reports whether n may be behind a NAT which hole punching can
+// traverse.
+func punchable(n *enode.Node) bool {
+	return n.IP() != nil && n.UDP() != 0 && n.QUIC() != 0 && !netutil.IsLAN(n.IP())
+}
+
+// dialFiltered reports whether a dial error indicates that the dial was dropped
+// on its way, as done by NATs and firewalls. Dials to nodes which are down are
+// usually refused by the host, or reported unreachable by a router.
+func dialFiltered(err error) bool {
+	var nerr net.Error
+	return errors.As(err, &nerr) && nerr.Timeout()
+}
+
+// punchBack dials a node which requested hole punching to the local node. The
+// dial is left to the dial scheduler, which applies the same checks and dial
+// filters as for dynamic dial candidates.
+func (srv *Server) punchBack(n *enode.Node) {
+	if srv.NoDial || srv.maxDialedConns() == 0 {
+		return
+	}
+	if !srv.allowPunchBack(n.IP()) {
+		srv.log.Trace("Throttled dial back after hole punching", "id", n.ID(), "addr", nodeAddr(n))
+		return
+	}
+	// Introductions may arrive while Start is still running.
+	srv.lock.Lock()
+	dialsched := srv.dialsched
+	srv.lock.Unlock()
+	if dialsched == nil {
+		return
+	}
+	srv.quic.punch(n)
+	dialsched.dialBack(n)
+}
+
+// allowPunchBack reports whether a dial back to ip is within the rate limits,
+// and records it if so.
+func (srv *Server) allowPunchBack(ip net.IP) bool {
+	srv.punchLock.Lock()
+	defer srv.punchLock.Unlock()
+
+	now := srv.clock.Now()
+	srv.punchHistory.expire(now, nil)
+	if len(srv.punchHistory) >= maxPunchBacks || srv.punchHistory.contains(ip.String()) {
+		return false
+	}
+	srv.punchHistory.add(ip.String(), now.Add(punchBackThrottleTime))
+	return true
+}
@@ -0,0 +1,290 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/internal/testlog"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+)
+
+type failingDialer struct{ err error }
+
+func (d failingDialer) Dial(context.Context, *enode.Node) (net.Conn, error) {
+	return nil, d.err
+}
+
+func TestPunchable(t *testing.T) {
+	tests := []struct {
+		ip        net.IP
+		udp, quic int
+		want      bool
+	}{
+		{ip: net.IP{8, 8, 8, 8}, udp: 30303, quic: 30304, want: true},
+		{ip: net.IP{8, 8, 8, 8}, udp: 30303, quic: 0, want: false},
+		{ip: net.IP{8, 8, 8, 8}, udp: 0, quic: 30304, want: false},
+		{ip: net.IP{192, 168, 0, 1}, udp: 30303, quic: 30304, want: false},
+		{ip: net.IP{127, 0, 0, 1}, udp: 30303, quic: 30304, want: false},
+	}
+	for _, test := range tests {
+		n := punchTestNode(test.ip, test.udp, test.quic)
+		if got := punchable(n); got != test.want {
+			t.Errorf("punchable(%v:%d/%d) = %t, want %t", test.ip, test.udp, test.quic, got, test.want)
+		}
+	}
+}
+
+func punchTestNode(ip net.IP, udp, quic int) *enode.Node {
+	var r enr.Record
+	r.Set(enr.IP(ip))
+	if udp != 0 {
+		r.Set(enr.UDP(udp))
+	}
+	if quic != 0 {
+		r.Set(enr.QUIC(quic))
+	}
+	return enode.SignNull(&r, enode.ID{1})
+}
+
+func TestDialFiltered(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{err: &net.OpError{Op: "dial", Net: "tcp", Err: os.ErrDeadlineExceeded}, want: true},
+		{err: &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, want: false},
+		{err: &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.EHOSTUNREACH)}, want: false},
+		{err: errors.New("dial failed"), want: false},
+	}
+	for _, test := range tests {
+		if got := dialFiltered(test.err); got != test.want {
+			t.Errorf("dialFiltered(%v) = %t, want %t", test.err, got, test.want)
+		}
+	}
+}
+
+// This test checks that dials to nodes in the local network fail without hole
+// punching.
+func TestPunchDialerLAN(t *testing.T) {
+	dialErr := errors.New("dial failed")
+	d := punchDialer{NodeDialer: failingDialer{dialErr}} // no discovery, punching would crash
+	n := newNode(uintID(1), "127.0.0.1:30303")
+	if _, err := d.Dial(context.Background(), n); err != dialErr {
+		t.Fatalf("wrong error %v", err)
+	}
+}
+
+// blockingPuncher blocks hole punching until unblocked, ignoring the context.
+type blockingPuncher chan struct{}
+
+func (p blockingPuncher) HolePunch(ctx context.Context, n *enode.Node) error {
+	<-p
+	return nil
+}
+
+// This test checks that dials to nodes which refuse the connection, or don't
+// support QUIC, fail without hole punching.
+func TestPunchDialerNotPunched(t *testing.T) {
+	endpoint, err := listenQUIC("127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer endpoint.close()
+
+	var (
+		refused  = &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+		timeout  = &net.OpError{Op: "dial", Net: "tcp", Err: os.ErrDeadlineExceeded}
+		quicNode = punchTestNode(net.IP{8, 8, 8, 8}, 30303, 30304)
+		tcpNode  = punchTestNode(net.IP{8, 8, 8, 8}, 30303, 0)
+	)
+	// No discovery, punching would crash.
+	d := punchDialer{NodeDialer: failingDialer{refused}, quic: endpoint}
+	if _, err := d.Dial(context.Background(), quicNode); err != refused {
+		t.Fatalf("wrong error %v", err)
+	}
+	d = punchDialer{NodeDialer: failingDialer{timeout}, quic: endpoint}
+	if _, err := d.Dial(context.Background(), tcpNode); err != timeout {
+		t.Fatalf("wrong error %v", err)
+	}
+	d = punchDialer{NodeDialer: failingDialer{timeout}}
+	if _, err := d.Dial(context.Background(), quicNode); err != timeout {
+		t.Fatalf("wrong error %v", err)
+	}
+}
+
+// This test checks that dials give up hole punching when their context is
+// canceled.
+func TestPunchDialerCancel(t *testing.T) {
+	endpoint, err := listenQUIC("127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer endpoint.close()
+
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: os.ErrDeadlineExceeded}
+	puncher := make(blockingPuncher)
+	defer close(puncher)
+	d := punchDialer{NodeDialer: failingDialer{dialErr}, disc: puncher, quic: endpoint}
+	n := punchTestNode(net.IP{8, 8, 8, 8}, 30303, 30304)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := d.Dial(ctx, n); err != dialErr {
+		t.Fatalf("wrong error %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > punchTimeout/2 {
+		t.Fatalf("dial returned after %v", elapsed)
+	}
+}
+
+// This test checks that dials back to nodes which requested hole punching are
+// limited per IP and in total.
+func TestServerPunchBackThrottle(t *testing.T) {
+	clock := new(mclock.Simulated)
+	srv := &Server{Config: Config{clock: clock}}
+
+	if !srv.allowPunchBack(net.IP{1, 1, 1, 1}) {
+		t.Fatal("first dial back not allowed")
+	}
+	if srv.allowPunchBack(net.IP{1, 1, 1, 1}) {
+		t.Fatal("second dial back to same IP allowed")
+	}
+	for i := 1; i < maxPunchBacks; i++ {
+		if !srv.allowPunchBack(net.IP{2, 2, 2, byte(i)}) {
+			t.Fatalf("dial back %d not allowed", i)
+		}
+	}
+	if srv.allowPunchBack(net.IP{3, 3, 3, 3}) {
+		t.Fatal("dial back over total limit allowed")
+	}
+
+	clock.Run(punchBackThrottleTime + 1)
+	if !srv.allowPunchBack(net.IP{1, 1, 1, 1}) {
+		t.Fatal("dial back not allowed after throttle time")
+	}
+}
+
+// This test checks that dials back to the initiator of hole punching establish
+// a connection, over QUIC if both nodes have a QUIC endpoint and over TCP
+// otherwise.
+func TestServerPunchBack(t *testing.T) {
+	newServer := func(quic bool) *Server {
+		srv := &Server{Config: Config{
+			PrivateKey:  newkey(),
+			MaxPeers:    10,
+			NoDiscovery: true,
+			ListenAddr:  "127.0.0.1:0",
+			Protocols:   []Protocol{discard},
+			Logger:      testlog.Logger(t, log.LvlTrace),
+		}}
+		if quic {
+			srv.QUICAddr = "127.0.0.1:0"
+		}
+		if err := srv.Start(); err != nil {
+			t.Fatal("could not start server:", err)
+		}
+		return srv
+	}
+	for _, quic := range []bool{false, true} {
+		if quic && !quicSupported {
+			continue
+		}
+		target, initiator := newServer(quic), newServer(quic)
+
+		events := make(chan *PeerEvent, 10)
+		sub := target.SubscribeEvents(events)
+		target.punchBack(initiator.Self())
+
+		timeout := time.After(10 * time.Second)
+	wait:
+		for {
+			select {
+			case ev := <-events:
+				if ev.Type == PeerEventTypeAdd && ev.Peer == initiator.Self().ID() {
+					break wait
+				}
+			case <-timeout:
+				t.Fatalf("peer not connected (quic: %t)", quic)
+			}
+		}
+		sub.Unsubscribe()
+
+		p := target.Peers()[0]
+		if p.Inbound() {
+			t.Errorf("dial back not outbound (quic: %t)", quic)
+		}
+		switch addr := p.RemoteAddr().(type) {
+		case *net.UDPAddr:
+			if !quic {
+				t.Errorf("connected over QUIC: %v", addr)
+			}
+		case *net.TCPAddr:
+			if quic {
+				t.Errorf("connected over TCP: %v", addr)
+			}
+		}
+		target.Stop()
+		initiator.Stop()
+	}
+}
+
+// This test checks that dials back are subject to the dial filters.
+func TestServerPunchBackFiltered(t *testing.T) {
+	newServer := func(filters []NodeFilter) *Server {
+		srv := &Server{Config: Config{
+			PrivateKey:  newkey(),
+			MaxPeers:    10,
+			NoDiscovery: true,
+			ListenAddr:  "127.0.0.1:0",
+			Protocols:   []Protocol{discard},
+			DialFilters: filters,
+			Logger:      testlog.Logger(t, log.LvlTrace),
+		}}
+		if err := srv.Start(); err != nil {
+			t.Fatal("could not start server:", err)
+		}
+		return srv
+	}
+	reject := func(n *enode.Node) error { return errors.New("rejected") }
+	target, initiator := newServer([]NodeFilter{reject}), newServer(nil)
+	defer target.Stop()
+	defer initiator.Stop()
+
+	events := make(chan *PeerEvent, 10)
+	sub := target.SubscribeEvents(events)
+	defer sub.Unsubscribe()
+	target.punchBack(initiator.Self())
+
+	select {
+	case ev := <-events:
+		if ev.Type == PeerEventTypeAdd {
+			t.Fatal("filtered node dialed back")
+		}
+	case <-time.After(500 * time.Millisecond):
+	}
+}
@@ -40,6 +40,7 @@ var (
 	ingressTrafficMeter = metrics.NewRegisteredMeter(ingressMeterName, nil)
 	egressConnectMeter  = metrics.NewRegisteredMeter("p2p/dials", nil)
 	egressTrafficMeter  = metrics.NewRegisteredMeter(egressMeterName, nil)
+	dialFilteredMeter   = metrics.NewRegisteredMeter("p2p/dials/filtered", nil)
 	activePeerGauge     = metrics.NewRegisteredGauge("p2p/peers", nil)
 )
 
@@ -64,6 +65,13 @@ func newMeteredConn(conn net.Conn, ingress bool, addr *net.TCPAddr) net.Conn {
 		egressConnectMeter.Mark(1)
 	}
 	activePeerGauge.Inc(1)
+
+	// QUIC connections carry their traffic on many streams and are told apart
+	// from stream connections by type, so the transport meters them itself.
+	if qc, ok := conn.(*quicConn); ok {
+		qc.metered = true
+		return qc
+	}
 	return &meteredConn{Conn: conn}
 }
 
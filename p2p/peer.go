@@ -117,6 +117,12 @@ type Peer struct {
 	// events receives message send / receive events if set
 	events   *event.Feed
 	testPipe *MsgPipeRW // for testing
+
+	// reputation tracks the score of the remote node if set
+	reputation *reputationTracker
+
+	// recorder writes the traffic of the peer to disk if set
+	recorder *peerRecorder
 }
 
 // NewPeer returns a peer for testing purposes.
@@ -409,6 +415,9 @@ func (p *Peer) startProtocols(writeStart <-chan struct{}, writeErr chan<- error)
 		if p.events != nil {
 			rw = newMsgEventer(rw, p.events, p.ID(), proto.Name, p.Info().Network.RemoteAddress, p.Info().Network.LocalAddress)
 		}
+		if p.recorder != nil {
+			rw = newMsgRecorder(rw, p.recorder, proto)
+		}
 		p.log.Trace(fmt.Sprintf("Starting protocol %s/%d", proto.Name, proto.Version))
 		go func() {
 			defer p.wg.Done()
@@ -494,7 +503,8 @@ type PeerInfo struct {
 		Trusted       bool   `json:"trusted"`
 		Static        bool   `json:"static"`
 	} `json:"network"`
-	Protocols map[string]interface{} `json:"protocols"` // Sub-protocol specific metadata fields
+	Reputation int64                  `json:"reputation"` // Reputation score of the remote node
+	Protocols  map[string]interface{} `json:"protocols"`  // Sub-protocol specific metadata fields
 }
 
 // Info gathers and returns a collection of metadata known about a peer.
@@ -520,6 +530,7 @@ func (p *Peer) Info() *PeerInfo {
 	info.Network.Inbound = p.rw.is(inboundConn)
 	info.Network.Trusted = p.rw.is(trustedConn)
 	info.Network.Static = p.rw.is(staticDialedConn)
+	info.Reputation = p.reputation.score(p.ID())
 
 	// Gather all the running protocol infos
 	for _, proto := range p.running {
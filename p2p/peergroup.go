@@ -0,0 +1,353 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+var errGroupFull = errors.New("peer group is full")
+
+// PeerGroup is a named set of nodes with connection quotas. MinSlots peer slots
+// are reserved for members of the group, which are not handed out to other peers
+// even if the members are not connected yet. Members of a group with reserved
+// slots are dialed like static nodes. At most MaxSlots members of the group are
+// connected at the same time, zero means no limit.
+type PeerGroup struct {
+	Name     string
+	Nodes    []*enode.Node
+	MinSlots int `toml:",omitempty"`
+	MaxSlots int `toml:",omitempty"`
+}
+
+// validate checks the group quotas for consistency.
+func (g *PeerGroup) validate() error {
+	switch {
+	case g.Name == "":
+		return errors.New("peer group has no name")
+	case g.MinSlots < 0 || g.MaxSlots < 0:
+		return fmt.Errorf("peer group %q has negative slot quota", g.Name)
+	case g.MaxSlots > 0 && g.MinSlots > g.MaxSlots:
+		return fmt.Errorf("peer group %q reserves more slots (%d) than allowed (%d)", g.Name, g.MinSlots, g.MaxSlots)
+	}
+	return nil
+}
+
+// PeerGroupInfo represents a short summary of a peer group and its connected
+// members.
+type PeerGroupInfo struct {
+	Name      string   `json:"name"`
+	Nodes     []string `json:"nodes"`     // Enode URLs of the group members
+	MinSlots  int      `json:"minSlots"`  // Peer slots reserved for the group
+	MaxSlots  int      `json:"maxSlots"`  // Maximum number of connected members, 0 if unlimited
+	Connected []string `json:"connected"` // Node IDs of the connected members
+}
+
+// peerGroup is a configured group along with its connected members.
+type peerGroup struct {
+	PeerGroup
+	connected map[enode.ID]struct{}
+}
+
+// full reports whether the group has reached its connection limit.
+func (g *peerGroup) full() bool {
+	return g.MaxSlots > 0 && len(g.connected) >= g.MaxSlots
+}
+
+// unfilled returns the number of reserved slots not taken by group members.
+func (g *peerGroup) unfilled() int {
+	if n := g.MinSlots - len(g.connected); n > 0 {
+		return n
+	}
+	return 0
+}
+
+// peerGroups tracks the connection quotas of all peer groups. It is shared by the
+// server run loop, which maintains the connected members, and the dial scheduler.
+type peerGroups struct {
+	mu      sync.Mutex
+	groups  map[string]*peerGroup
+	members map[enode.ID]*peerGroup
+}
+
+func newPeerGroups(groups []PeerGroup) (*peerGroups, error) {
+	pg := &peerGroups{
+		groups:  make(map[string]*peerGroup),
+		members: make(map[enode.ID]*peerGroup),
+	}
+	for _, g := range groups {
+		if _, ok := pg.groups[g.Name]; ok {
+			return nil, fmt.Errorf("duplicate peer group %q", g.Name)
+		}
+		if _, err := pg.set(g, nil); err != nil {
+			return nil, err
+		}
+	}
+	return pg, nil
+}
+
+// set creates or replaces a group. The connected members are derived from the
+// given peer set. The nodes which left the group are returned.
+func (pg *peerGroups) set(g PeerGroup, peers map[enode.ID]*Peer) (removed []*enode.Node, err error) {
+	if err := g.validate(); err != nil {
+		return nil, err
+	}
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+
+	old := pg.groups[g.Name]
+	for _, n := range g.Nodes {
+		if other := pg.members[n.ID()]; other != nil && other != old {
+			return nil, fmt.Errorf("node %v is already in peer group %q", n.ID(), other.Name)
+		}
+	}
+	group := &peerGroup{PeerGroup: g, connected: make(map[enode.ID]struct{})}
+	for _, n := range g.Nodes {
+		pg.members[n.ID()] = group
+		if peers[n.ID()] != nil {
+			group.connected[n.ID()] = struct{}{}
+		}
+	}
+	if old != nil {
+		for _, n := range old.Nodes {
+			if pg.members[n.ID()] == old {
+				delete(pg.members, n.ID())
+				removed = append(removed, n)
+			}
+		}
+	}
+	pg.groups[g.Name] = group
+	return removed, nil
+}
+
+// remove deletes a group, returning its members.
+func (pg *peerGroups) remove(name string) ([]*enode.Node, bool) {
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+
+	group := pg.groups[name]
+	if group == nil {
+		return nil, false
+	}
+	for _, n := range group.Nodes {
+		delete(pg.members, n.ID())
+	}
+	delete(pg.groups, name)
+	return group.Nodes, true
+}
+
+// connect marks a node as connected if it is a member of a group.
+func (pg *peerGroups) connect(id enode.ID) {
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+
+	if group := pg.members[id]; group != nil {
+		group.connected[id] = struct{}{}
+	}
+}
+
+// disconnect marks a node as disconnected if it is a member of a group.
+func (pg *peerGroups) disconnect(id enode.ID) {
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+
+	if group := pg.members[id]; group != nil {
+		delete(group.connected, id)
+	}
+}
+
+// full reports whether the node is a member of a group which reached its
+// connection limit.
+func (pg *peerGroups) full(id enode.ID) bool {
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+
+	group := pg.members[id]
+	return group != nil && group.full()
+}
+
+// excess returns connected members of the group beyond its connection limit.
+func (pg *peerGroups) excess(name string) []enode.ID {
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+
+	group := pg.groups[name]
+	if group == nil || group.MaxSlots == 0 {
+		return nil
+	}
+	var ids []enode.ID
+	for id := range group.connected {
+		if len(group.connected)-len(ids) <= group.MaxSlots {
+			break
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// reserved returns the number of peer slots which are reserved for groups the
+// given node is not a member of.
+func (pg *peerGroups) reserved(id enode.ID) int {
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+
+	var slots int
+	for _, group := range pg.groups {
+		if pg.members[id] != group {
+			slots += group.unfilled()
+		}
+	}
+	return slots
+}
+
+// unfilled returns the number of reserved slots of all groups which are not
+// taken by group members.
+func (pg *peerGroups) unfilled() int {
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+
+	var slots int
+	for _, group := range pg.groups {
+		slots += group.unfilled()
+	}
+	return slots
+}
+
+// siblings returns the members of the group the node belongs to.
+func (pg *peerGroups) siblings(id enode.ID) []enode.ID {
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+
+	group := pg.members[id]
+	if group == nil {
+		return nil
+	}
+	ids := make([]enode.ID, 0, len(group.Nodes))
+	for _, n := range group.Nodes {
+		ids = append(ids, n.ID())
+	}
+	return ids
+}
+
+// dialed returns the members of all groups with reserved slots.
+func (pg *peerGroups) dialed() []*enode.Node {
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+
+	var nodes []*enode.Node
+	for _, group := range pg.groups {
+		if group.MinSlots > 0 {
+			nodes = append(nodes, group.Nodes...)
+		}
+	}
+	return nodes
+}
+
+// info returns a summary of all groups, sorted by name.
+func (pg *peerGroups) info() []*PeerGroupInfo {
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+
+	infos := make([]*PeerGroupInfo, 0, len(pg.groups))
+	for _, group := range pg.groups {
+		info := &PeerGroupInfo{
+			Name:      group.Name,
+			Nodes:     make([]string, 0, len(group.Nodes)),
+			MinSlots:  group.MinSlots,
+			MaxSlots:  group.MaxSlots,
+			Connected: make([]string, 0, len(group.connected)),
+		}
+		for _, n := range group.Nodes {
+			info.Nodes = append(info.Nodes, n.URLv4())
+		}
+		for id := range group.connected {
+			info.Connected = append(info.Connected, id.String())
+		}
+		sort.Strings(info.Connected)
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// PeerGroupsInfo returns a summary of the configured peer groups.
+func (srv *Server) PeerGroupsInfo() []*PeerGroupInfo {
+	return srv.groups.info()
+}
+
+// SetPeerGroup creates or replaces a peer group. Members exceeding the connection
+// limit of the group are disconnected. Nodes joining a group with reserved slots
+// are dialed, nodes leaving it are not dialed anymore unless configured as static
+// nodes.
+func (srv *Server) SetPeerGroup(group PeerGroup) error {
+	var (
+		removed []*enode.Node
+		err     error
+	)
+	srv.doPeerOp(func(peers map[enode.ID]*Peer) {
+		if removed, err = srv.groups.set(group, peers); err != nil {
+			return
+		}
+		for _, id := range srv.groups.excess(group.Name) {
+			peers[id].Disconnect(DiscTooManyPeers)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if group.MinSlots > 0 {
+		for _, n := range group.Nodes {
+			srv.dialsched.addStatic(n)
+		}
+	} else {
+		removed = append(removed, group.Nodes...)
+	}
+	srv.removeGroupDials(removed)
+	return nil
+}
+
+// RemovePeerGroup deletes a peer group. Its members are not disconnected, but
+// they are not dialed anymore unless configured as static nodes.
+func (srv *Server) RemovePeerGroup(name string) bool {
+	var (
+		removed []*enode.Node
+		ok      bool
+	)
+	srv.doPeerOp(func(map[enode.ID]*Peer) {
+		removed, ok = srv.groups.remove(name)
+	})
+	srv.removeGroupDials(removed)
+	return ok
+}
+
+// removeGroupDials stops dialing former group members which are not static nodes,
+// either configured or added through AddPeer.
+func (srv *Server) removeGroupDials(nodes []*enode.Node) {
+	srv.staticLock.Lock()
+	defer srv.staticLock.Unlock()
+
+	for _, n := range nodes {
+		if !srv.static[n.ID()] {
+			srv.dialsched.removeStatic(n)
+		}
+	}
+}
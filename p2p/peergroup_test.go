@@ -0,0 +1,167 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"net"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/internal/testlog"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+)
+
+func TestPeerGroupsValidation(t *testing.T) {
+	var (
+		n1 = newNode(randomID(), "")
+		n2 = newNode(randomID(), "")
+	)
+	tests := []struct {
+		groups []PeerGroup
+		ok     bool
+	}{
+		{groups: []PeerGroup{{Name: "a", Nodes: []*enode.Node{n1}, MinSlots: 1, MaxSlots: 2}}, ok: true},
+		{groups: []PeerGroup{{Name: "", Nodes: []*enode.Node{n1}}}},
+		{groups: []PeerGroup{{Name: "a", MinSlots: -1}}},
+		{groups: []PeerGroup{{Name: "a", MinSlots: 3, MaxSlots: 2}}},
+		{groups: []PeerGroup{{Name: "a", Nodes: []*enode.Node{n1}}, {Name: "a", Nodes: []*enode.Node{n2}}}},
+		{groups: []PeerGroup{{Name: "a", Nodes: []*enode.Node{n1}}, {Name: "b", Nodes: []*enode.Node{n1, n2}}}},
+	}
+	for i, test := range tests {
+		_, err := newPeerGroups(test.groups)
+		if test.ok && err != nil {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+		}
+		if !test.ok && err == nil {
+			t.Errorf("test %d: expected error", i)
+		}
+	}
+}
+
+// This test checks that reserved and capped group slots are enforced when adding
+// peers, and that groups can be changed at runtime.
+func TestServerPeerGroups(t *testing.T) {
+	remote := newkey()
+	var (
+		sentries = []*enode.Node{newNode(randomID(), ""), newNode(randomID(), "")}
+		partners = []*enode.Node{newNode(randomID(), ""), newNode(randomID(), "")}
+	)
+	srv := &Server{
+		Config: Config{
+			PrivateKey:  newkey(),
+			MaxPeers:    5,
+			NoDial:      true,
+			NoDiscovery: true,
+			PeerGroups: []PeerGroup{
+				{Name: "sentry", Nodes: sentries, MinSlots: 2},
+				{Name: "partner", Nodes: partners, MaxSlots: 1},
+			},
+			Logger: testlog.Logger(t, log.LvlTrace),
+		},
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("could not start: %v", err)
+	}
+	defer srv.Stop()
+
+	newconn := func(id enode.ID) *conn {
+		fd, _ := net.Pipe()
+		tx := newTestTransport(&remote.PublicKey, fd, nil)
+		node := enode.SignNull(new(enr.Record), id)
+		return &conn{fd: fd, transport: tx, flags: inboundConn, node: node, cont: make(chan error)}
+	}
+	// Fill the public slots, the sentry reservation must not be handed out.
+	for i := 0; i < 2; i++ {
+		if err := srv.checkpoint(newconn(randomID()), srv.checkpointAddPeer); err != nil {
+			t.Fatalf("could not add public conn %d: %v", i, err)
+		}
+	}
+	// The partner group is capped at a single connection.
+	if err := srv.checkpoint(newconn(partners[0].ID()), srv.checkpointAddPeer); err != nil {
+		t.Fatalf("could not add partner conn: %v", err)
+	}
+	if err := srv.checkpoint(newconn(partners[1].ID()), srv.checkpointPostHandshake); err != DiscTooManyPeers {
+		t.Errorf("wrong error for partner conn above cap: %v", err)
+	}
+	if err := srv.checkpoint(newconn(randomID()), srv.checkpointPostHandshake); err != DiscTooManyPeers {
+		t.Errorf("wrong error for public conn in reserved slot: %v", err)
+	}
+	// Sentries may use their reserved slots.
+	for i, n := range sentries {
+		if err := srv.checkpoint(newconn(n.ID()), srv.checkpointAddPeer); err != nil {
+			t.Fatalf("could not add sentry conn %d: %v", i, err)
+		}
+	}
+	infos := srv.PeerGroupsInfo()
+	if len(infos) != 2 || infos[0].Name != "partner" || len(infos[0].Connected) != 1 || len(infos[1].Connected) != 2 {
+		t.Fatalf("wrong group infos: %+v", infos)
+	}
+	// Lift the partner cap at runtime, the quota is reevaluated.
+	if err := srv.SetPeerGroup(PeerGroup{Name: "partner", Nodes: partners, MaxSlots: 2}); err != nil {
+		t.Fatalf("could not update group: %v", err)
+	}
+	if srv.groups.full(partners[1].ID()) {
+		t.Errorf("partner group still full after raising the cap")
+	}
+	if err := srv.SetPeerGroup(PeerGroup{Name: "other", Nodes: partners[:1]}); err == nil {
+		t.Errorf("expected error for node in two groups")
+	}
+	if !srv.RemovePeerGroup("partner") || srv.RemovePeerGroup("partner") {
+		t.Errorf("wrong result for group removal")
+	}
+	if infos := srv.PeerGroupsInfo(); len(infos) != 1 {
+		t.Errorf("wrong group count after removal: %d", len(infos))
+	}
+}
+
+// This test checks that nodes added through AddPeer keep being dialed when they
+// leave a peer group with reserved slots.
+func TestServerPeerGroupStaticDials(t *testing.T) {
+	srv := &Server{
+		Config: Config{
+			PrivateKey:  newkey(),
+			MaxPeers:    5,
+			NoDial:      true,
+			NoDiscovery: true,
+			Logger:      testlog.Logger(t, log.LvlTrace),
+		},
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("could not start: %v", err)
+	}
+	defer srv.Stop()
+
+	var (
+		added  = newNode(randomID(), "127.0.0.1:30303")
+		member = newNode(randomID(), "127.0.0.1:30303")
+	)
+	srv.AddPeer(added)
+	if err := srv.SetPeerGroup(PeerGroup{Name: "sentry", Nodes: []*enode.Node{added, member}, MinSlots: 1}); err != nil {
+		t.Fatalf("could not set group: %v", err)
+	}
+	srv.RemovePeerGroup("sentry")
+
+	// Stop the dialer to inspect its static node set.
+	srv.dialsched.stop()
+	if _, ok := srv.dialsched.static[added.ID()]; !ok {
+		t.Errorf("added peer not dialed after leaving the group")
+	}
+	if _, ok := srv.dialsched.static[member.ID()]; ok {
+		t.Errorf("former group member still dialed")
+	}
+}
@@ -59,6 +59,12 @@ type Protocol struct {
 	// attempts to create connections to them.
 	DialCandidates enode.Iterator
 
+	// DialFilter, if non-nil, is checked on the record of every node found by
+	// discovery before it is dialed. Nodes rejected by the filter are not dialed.
+	// The filter should accept nodes which don't advertise the protocol in their
+	// record, since records obtained through discovery v4 are usually incomplete.
+	DialFilter NodeFilter
+
 	// Attributes contains protocol specific information for the node record.
 	Attributes []enr.Entry
 }
@@ -0,0 +1,700 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build go1.20
+// +build go1.20
+
+package p2p
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	crand "crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/ethereum/go-ethereum/p2p/nat"
+	"github.com/ethereum/go-ethereum/p2p/netutil"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/golang/snappy"
+	"github.com/quic-go/quic-go"
+	"golang.org/x/sync/semaphore"
+)
+
+// The QUIC transport carries devp2p over a QUIC connection. It is an alternative
+// to RLPx over TCP which avoids head-of-line blocking between subprotocols: the
+// handshakes and base protocol messages are exchanged on a bidirectional control
+// stream, while the messages of each subprotocol are sent on a dedicated
+// unidirectional stream. Message order is retained within a subprotocol only.
+//
+// QUIC secures the connection using TLS 1.3 with ephemeral certificates. The
+// node identities are authenticated after the QUIC handshake by signing the
+// exported keying material of the TLS session with the node keys.
+//
+// Every message is sent as a frame consisting of the message code and payload
+// size as uvarints, followed by the snappy compressed payload. Disconnect reasons
+// are conveyed as the application error code of the connection close.
+
+// quicSupported reports whether the QUIC transport is available.
+const quicSupported = true
+
+const (
+	// quicALPN is the application protocol negotiated for devp2p QUIC connections.
+	quicALPN = "devp2p"
+
+	// quicIdentityLabel is the TLS exporter label of the identity handshake.
+	quicIdentityLabel = "EXPORTER-devp2p-identity"
+
+	// Roles of the identity handshake, signed along with the keying material
+	// so an identity proof can't be reflected back to its sender.
+	quicInitiator = 1
+	quicRecipient = 2
+
+	// quicMaxFrameSize is the maximum size of a message payload, matching the
+	// limit of RLPx.
+	quicMaxFrameSize = 0xffffff
+
+	// quicMaxInflight is the maximum memory held by the frames of a connection
+	// which are being read or wait for delivery, counting both the compressed
+	// and the decoded payload. It fits at least one frame of the maximum size.
+	quicMaxInflight = 4 * quicMaxFrameSize
+
+	// quicKeepAlive is the interval of QUIC keep-alive packets, which keep NAT
+	// mappings of idle connections open.
+	quicKeepAlive = 15 * time.Second
+
+	// quicDialTimeout is the timeout of QUIC dials before falling back to TCP.
+	quicDialTimeout = 5 * time.Second
+
+	// quicDiscCode is the application error code of a connection closed without
+	// a reason. Disconnect reasons are added to it.
+	quicDiscCode = 0x100
+)
+
+// quicPunchPacket is sent by quicEndpoint.punch. Packets with the two most
+// significant bits unset are not QUIC packets.
+var quicPunchPacket = []byte{0}
+
+var (
+	errQUICIdentity = errors.New("invalid QUIC identity proof")
+	errQUICClosed   = errors.New("QUIC connection closed")
+)
+
+// quicEndpoint is a UDP socket accepting and dialing devp2p QUIC connections.
+type quicEndpoint struct {
+	tr       *quic.Transport
+	listener *quic.Listener
+	tlsConf  *tls.Config
+	conf     *quic.Config
+}
+
+// listenQUIC creates a QUIC endpoint listening on the given UDP address.
+func listenQUIC(addr string) (*quicEndpoint, error) {
+	tlsConf, err := newQUICTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	laddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", laddr)
+	if err != nil {
+		return nil, err
+	}
+	e := &quicEndpoint{
+		tr:      &quic.Transport{Conn: conn},
+		tlsConf: tlsConf,
+		conf: &quic.Config{
+			HandshakeIdleTimeout: handshakeTimeout,
+			MaxIdleTimeout:       frameReadTimeout,
+			KeepAlivePeriod:      quicKeepAlive,
+		},
+	}
+	if e.listener, err = e.tr.Listen(e.tlsConf, e.conf); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+// newQUICTLSConfig creates a TLS configuration with an ephemeral self-signed
+// certificate. Certificates are not verified, node identities are checked by
+// the devp2p identity handshake instead.
+func newQUICTLSConfig() (*tls.Config, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), crand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(10 * 365 * 24 * time.Hour),
+	}
+	cert, err := x509.CreateCertificate(crand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates:       []tls.Certificate{{Certificate: [][]byte{cert}, PrivateKey: key}},
+		NextProtos:         []string{quicALPN},
+		InsecureSkipVerify: true,
+		MinVersion:         tls.VersionTLS13,
+	}, nil
+}
+
+// addr returns the local address of the endpoint.
+func (e *quicEndpoint) addr() *net.UDPAddr {
+	return e.tr.Conn.LocalAddr().(*net.UDPAddr)
+}
+
+// accept waits for an inbound connection. The returned connection is not usable
+// until its control stream is accepted using acceptControlStream.
+func (e *quicEndpoint) accept(ctx context.Context) (quic.Connection, error) {
+	return e.listener.Accept(ctx)
+}
+
+// dial creates an outbound connection and opens its control stream.
+func (e *quicEndpoint) dial(ctx context.Context, addr *net.UDPAddr) (*quicConn, error) {
+	conn, err := e.tr.Dial(ctx, addr, e.tlsConf, e.conf)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		conn.CloseWithError(0, err.Error())
+		return nil, err
+	}
+	return &quicConn{Stream: stream, conn: conn, initiator: true}, nil
+}
+
+// punch sends a packet to the QUIC endpoint of n, which opens the NAT in front of
+// the local endpoint for connections from n. The packet is not a QUIC packet and
+// is dropped by the receiver. It is a no-op if e is nil.
+func (e *quicEndpoint) punch(n *enode.Node) {
+	if e == nil || n.IP() == nil || n.QUIC() == 0 {
+		return
+	}
+	e.tr.WriteTo(quicPunchPacket, &net.UDPAddr{IP: n.IP(), Port: n.QUIC()})
+}
+
+// close shuts down the listener and all connections of the endpoint.
+func (e *quicEndpoint) close() {
+	e.listener.Close()
+	e.tr.Close()
+	e.tr.Conn.Close()
+}
+
+func (srv *Server) setupQUIC() error {
+	endpoint, err := listenQUIC(srv.QUICAddr)
+	if err != nil {
+		return err
+	}
+	srv.quic = endpoint
+
+	// Update the local node record and map the QUIC port if NAT is configured.
+	addr := endpoint.addr()
+	srv.localnode.Set(enr.QUIC(addr.Port))
+	if !addr.IP.IsLoopback() && srv.NAT != nil {
+		srv.loopWG.Add(1)
+		go func() {
+			nat.Map(srv.NAT, srv.quit, "udp", addr.Port, addr.Port, "ethereum quic")
+			srv.loopWG.Done()
+		}()
+	}
+
+	srv.loopWG.Add(1)
+	go srv.quicListenLoop()
+	return nil
+}
+
+// quicListenLoop runs in its own goroutine and accepts inbound QUIC connections.
+func (srv *Server) quicListenLoop() {
+	srv.log.Debug("QUIC listener up", "addr", srv.quic.addr())
+
+	// The slots channel limits accepts of new connections.
+	tokens := defaultMaxPendingPeers
+	if srv.MaxPendingPeers > 0 {
+		tokens = srv.MaxPendingPeers
+	}
+	slots := make(chan struct{}, tokens)
+	for i := 0; i < tokens; i++ {
+		slots <- struct{}{}
+	}
+	defer srv.loopWG.Done()
+	defer func() {
+		for i := 0; i < cap(slots); i++ {
+			<-slots
+		}
+	}()
+
+	for {
+		<-slots
+		conn, err := srv.quic.accept(context.Background())
+		if err != nil {
+			srv.log.Debug("QUIC accept error", "err", err)
+			slots <- struct{}{}
+			return
+		}
+		remoteIP := netutil.AddrIP(conn.RemoteAddr())
+		if err := srv.checkInboundConn(remoteIP); err != nil {
+			srv.log.Debug("Rejected inbound QUIC connection", "addr", conn.RemoteAddr(), "err", err)
+			conn.CloseWithError(quicDiscCode, err.Error())
+			slots <- struct{}{}
+			continue
+		}
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+			fd, err := acceptControlStream(ctx, conn)
+			cancel()
+			if err == nil {
+				srv.log.Trace("Accepted QUIC connection", "addr", fd.RemoteAddr())
+				srv.SetupConn(newMeteredConn(fd, true, nil), inboundConn, nil)
+			}
+			slots <- struct{}{}
+		}()
+	}
+}
+
+// acceptControlStream waits for the control stream of an inbound connection.
+func acceptControlStream(ctx context.Context, conn quic.Connection) (*quicConn, error) {
+	stream, err := conn.AcceptStream(ctx)
+	if err != nil {
+		conn.CloseWithError(0, err.Error())
+		return nil, err
+	}
+	return &quicConn{Stream: stream, conn: conn}, nil
+}
+
+// quicConn is a QUIC connection presented as a net.Conn. Reads and writes act
+// on the control stream, closing it closes the whole connection.
+type quicConn struct {
+	quic.Stream
+	conn      quic.Connection
+	initiator bool
+	metered   bool // whether the traffic of all streams is metered
+	closeOnce sync.Once
+}
+
+func (c *quicConn) LocalAddr() net.Addr  { return c.conn.LocalAddr() }
+func (c *quicConn) RemoteAddr() net.Addr { return c.conn.RemoteAddr() }
+
+func (c *quicConn) Close() error {
+	return c.closeWithError(quicDiscCode, "")
+}
+
+// closeWithError closes the connection with the given application error,
+// unregistering it from the peer gauge if it was metered.
+func (c *quicConn) closeWithError(code quic.ApplicationErrorCode, reason string) error {
+	c.closeOnce.Do(func() {
+		if c.metered {
+			activePeerGauge.Dec(1)
+		}
+	})
+	return c.conn.CloseWithError(code, reason)
+}
+
+// reader returns a buffered reader of a stream of the connection, metering the
+// ingress traffic if enabled.
+func (c *quicConn) reader(stream io.Reader) *bufio.Reader {
+	if c.metered {
+		stream = quicMeteredReader{stream}
+	}
+	return bufio.NewReader(stream)
+}
+
+// quicMeteredReader bumps the ingress traffic meter by the data read from a stream.
+type quicMeteredReader struct {
+	io.Reader
+}
+
+func (r quicMeteredReader) Read(b []byte) (n int, err error) {
+	n, err = r.Reader.Read(b)
+	ingressTrafficMeter.Mark(int64(n))
+	return n, err
+}
+
+// quicDialer dials nodes advertising a QUIC endpoint over QUIC, falling back to
+// the given dialer if they don't or if the QUIC dial fails.
+type quicDialer struct {
+	endpoint *quicEndpoint
+	fallback NodeDialer
+}
+
+func (d quicDialer) Dial(ctx context.Context, n *enode.Node) (net.Conn, error) {
+	if port := n.QUIC(); port != 0 && n.IP() != nil {
+		qctx, cancel := context.WithTimeout(ctx, quicDialTimeout)
+		conn, err := d.endpoint.dial(qctx, &net.UDPAddr{IP: n.IP(), Port: port})
+		cancel()
+		if err == nil {
+			return conn, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+	return d.fallback.Dial(ctx, n)
+}
+
+// quicIdentity is the identity proof exchanged on the control stream.
+type quicIdentity struct {
+	Pubkey    []byte // Uncompressed secp256k1 public key without prefix byte
+	Signature []byte // Signature over the role and the TLS keying material
+}
+
+// quicFrame is a message read from one of the streams of a connection.
+type quicFrame struct {
+	msg Msg
+	err error
+}
+
+// quicTransport is the transport of devp2p QUIC connections.
+type quicTransport struct {
+	conn      *quicConn
+	dialDest  *ecdsa.PublicKey
+	protocols []Protocol
+
+	rmu      sync.Mutex    // serializes ReadMsg
+	ctrl     *bufio.Reader // buffered reader of the control stream
+	inbox    chan quicFrame
+	inflight *semaphore.Weighted // bounds the memory of frames read from all streams
+	ctx      context.Context     // canceled when the transport is closed
+	cancel   context.CancelFunc
+	once     sync.Once
+
+	wmu     sync.Mutex                   // protects the fields below
+	lanes   map[string]*protoRW          // matched subprotocols, set after the protocol handshake
+	streams map[string]*quicStreamWriter // send streams of the subprotocols
+	control *quicStreamWriter            // writer of the control stream
+}
+
+// quicStreamWriter serializes the frames written to a send stream.
+type quicStreamWriter struct {
+	mu      sync.Mutex
+	stream  quic.SendStream
+	metered bool // whether written frames bump the egress traffic meter
+	buf     []byte
+}
+
+func newQUICTransport(conn *quicConn, dialDest *ecdsa.PublicKey, protocols []Protocol) transport {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &quicTransport{
+		conn:      conn,
+		dialDest:  dialDest,
+		protocols: protocols,
+		ctrl:      conn.reader(conn.Stream),
+		inbox:     make(chan quicFrame),
+		inflight:  semaphore.NewWeighted(quicMaxInflight),
+		ctx:       ctx,
+		cancel:    cancel,
+		streams:   make(map[string]*quicStreamWriter),
+		control:   &quicStreamWriter{stream: conn.Stream, metered: conn.metered},
+	}
+}
+
+func (t *quicTransport) doEncHandshake(prv *ecdsa.PrivateKey) (*ecdsa.PublicKey, error) {
+	t.conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	defer t.conn.SetDeadline(time.Time{})
+
+	state := t.conn.conn.ConnectionState().TLS
+	material, err := state.ExportKeyingMaterial(quicIdentityLabel, nil, 32)
+	if err != nil {
+		return nil, err
+	}
+	ourRole, theirRole := byte(quicRecipient), byte(quicInitiator)
+	if t.conn.initiator {
+		ourRole, theirRole = theirRole, ourRole
+	}
+	// Send our identity proof and verify the remote one.
+	sig, err := crypto.Sign(quicIdentityHash(ourRole, material), prv)
+	if err != nil {
+		return nil, err
+	}
+	werr := make(chan error, 1)
+	go func() {
+		werr <- rlp.Encode(t.conn, &quicIdentity{Pubkey: crypto.FromECDSAPub(&prv.PublicKey)[1:], Signature: sig})
+	}()
+	var their quicIdentity
+	if err := rlp.NewStream(t.ctrl, 1024).Decode(&their); err != nil {
+		<-werr
+		return nil, err
+	}
+	if err := <-werr; err != nil {
+		return nil, fmt.Errorf("write error: %v", err)
+	}
+	pubkey, err := crypto.SigToPub(quicIdentityHash(theirRole, material), their.Signature)
+	if err != nil {
+		return nil, errQUICIdentity
+	}
+	if !bytes.Equal(crypto.FromECDSAPub(pubkey)[1:], their.Pubkey) {
+		return nil, errQUICIdentity
+	}
+	if t.dialDest != nil && !t.dialDest.Equal(pubkey) {
+		return nil, DiscUnexpectedIdentity
+	}
+	return pubkey, nil
+}
+
+// quicIdentityHash returns the hash signed by the identity proof of a role.
+func quicIdentityHash(role byte, material []byte) []byte {
+	return crypto.Keccak256([]byte(quicIdentityLabel), []byte{role}, material)
+}
+
+func (t *quicTransport) doProtoHandshake(our *protoHandshake) (their *protoHandshake, err error) {
+	t.conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	defer t.conn.SetDeadline(time.Time{})
+
+	werr := make(chan error, 1)
+	go func() { werr <- Send(t, handshakeMsg, our) }()
+	if their, err = readProtocolHandshake(t); err != nil {
+		<-werr // make sure the write terminates too
+		return nil, err
+	}
+	if err := <-werr; err != nil {
+		return nil, fmt.Errorf("write error: %v", err)
+	}
+	// Assign a stream to every subprotocol spoken on the connection and
+	// start delivering messages from all streams.
+	t.wmu.Lock()
+	t.lanes = matchProtocols(t.protocols, their.Caps, nil)
+	t.wmu.Unlock()
+
+	go t.readStream(t.ctrl)
+	go t.acceptStreams()
+	return their, nil
+}
+
+// acceptStreams accepts the subprotocol streams opened by the remote side.
+func (t *quicTransport) acceptStreams() {
+	for {
+		stream, err := t.conn.conn.AcceptUniStream(context.Background())
+		if err != nil {
+			t.deliver(quicFrame{err: err})
+			return
+		}
+		go t.readStream(t.conn.reader(stream))
+	}
+}
+
+// readStream delivers the messages of a stream until it is closed.
+func (t *quicTransport) readStream(r *bufio.Reader) {
+	for {
+		msg, reserved, err := t.readFrame(r)
+		if err == io.EOF {
+			return // remote side is done with the stream
+		}
+		ok := t.deliver(quicFrame{msg: msg, err: err})
+		t.inflight.Release(reserved)
+		if !ok || err != nil {
+			return
+		}
+	}
+}
+
+// deliver hands a frame to ReadMsg, returning false if the transport is closed.
+func (t *quicTransport) deliver(f quicFrame) bool {
+	select {
+	case t.inbox <- f:
+		return true
+	case <-t.ctx.Done():
+		return false
+	}
+}
+
+// readFrame reads a single message frame. The memory of the frame is reserved
+// from the in-flight budget of the connection before its payload is read, and
+// must be released by the caller once the message was handed over.
+func (t *quicTransport) readFrame(r *bufio.Reader) (msg Msg, reserved int64, err error) {
+	code, err := binary.ReadUvarint(r)
+	if err != nil {
+		return Msg{}, 0, err
+	}
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return Msg{}, 0, unexpectedEOF(err)
+	}
+	if size > quicMaxFrameSize {
+		return Msg{}, 0, fmt.Errorf("QUIC frame too large: %d", size)
+	}
+	// The snappy block starts with the decoded length, check it before
+	// reserving memory for the message.
+	peek := binary.MaxVarintLen32
+	if size < uint64(peek) {
+		peek = int(size)
+	}
+	header, err := r.Peek(peek)
+	if err != nil {
+		return Msg{}, 0, unexpectedEOF(err)
+	}
+	n, err := snappy.DecodedLen(header)
+	if err != nil {
+		return Msg{}, 0, err
+	} else if n > quicMaxFrameSize {
+		return Msg{}, 0, fmt.Errorf("QUIC message too large: %d", n)
+	}
+	reserved = int64(size) + int64(n)
+	if err := t.inflight.Acquire(t.ctx, reserved); err != nil {
+		return Msg{}, 0, errQUICClosed
+	}
+	// Read the frame into a buffer growing with the received data, so a peer
+	// announcing a large frame can't make us allocate memory it doesn't send.
+	var frame bytes.Buffer
+	if _, err := io.CopyN(&frame, r, int64(size)); err != nil {
+		t.inflight.Release(reserved)
+		return Msg{}, 0, unexpectedEOF(err)
+	}
+	data, err := snappy.Decode(nil, frame.Bytes())
+	if err != nil {
+		t.inflight.Release(reserved)
+		return Msg{}, 0, err
+	}
+	return Msg{
+		ReceivedAt: time.Now(),
+		Code:       code,
+		Size:       uint32(len(data)),
+		meterSize:  uint32(size),
+		Payload:    bytes.NewReader(data),
+	}, reserved, nil
+}
+
+// unexpectedEOF converts an EOF in the middle of a frame into an error.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// quicError converts the remote close of a connection into the disconnect
+// reason it carries.
+func quicError(err error) error {
+	var appErr *quic.ApplicationError
+	if errors.As(err, &appErr) && appErr.Remote && appErr.ErrorCode > quicDiscCode {
+		return DiscReason(appErr.ErrorCode - quicDiscCode)
+	}
+	return err
+}
+
+func (t *quicTransport) ReadMsg() (Msg, error) {
+	t.rmu.Lock()
+	defer t.rmu.Unlock()
+
+	// Before the protocol handshake completes, messages are read directly
+	// from the control stream.
+	t.wmu.Lock()
+	started := t.lanes != nil
+	t.wmu.Unlock()
+	if !started {
+		msg, reserved, err := t.readFrame(t.ctrl)
+		t.inflight.Release(reserved)
+		return msg, quicError(err)
+	}
+	timer := time.NewTimer(frameReadTimeout)
+	defer timer.Stop()
+
+	select {
+	case f := <-t.inbox:
+		return f.msg, quicError(f.err)
+	case <-timer.C:
+		return Msg{}, errors.New("QUIC read timeout")
+	case <-t.ctx.Done():
+		return Msg{}, errQUICClosed
+	}
+}
+
+func (t *quicTransport) WriteMsg(msg Msg) error {
+	w, err := t.writer(msg.Code)
+	if err != nil {
+		return err
+	}
+	data := make([]byte, msg.Size)
+	if _, err := io.ReadFull(msg.Payload, data); err != nil {
+		return err
+	}
+	return w.write(msg.Code, data, frameWriteTimeout)
+}
+
+// writer returns the stream writer of the protocol the message code belongs to.
+// Base protocol messages and messages of unknown protocols are sent on the
+// control stream.
+func (t *quicTransport) writer(code uint64) (*quicStreamWriter, error) {
+	t.wmu.Lock()
+	defer t.wmu.Unlock()
+
+	for name, proto := range t.lanes {
+		if code < proto.offset || code >= proto.offset+proto.Length {
+			continue
+		}
+		if w := t.streams[name]; w != nil {
+			return w, nil
+		}
+		stream, err := t.conn.conn.OpenUniStream()
+		if err != nil {
+			return nil, err
+		}
+		w := &quicStreamWriter{stream: stream, metered: t.conn.metered}
+		t.streams[name] = w
+		return w, nil
+	}
+	return t.control, nil
+}
+
+// write sends a message frame on the stream.
+func (w *quicStreamWriter) write(code uint64, data []byte, timeout time.Duration) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var (
+		header [2 * binary.MaxVarintLen64]byte
+		frame  = snappy.Encode(nil, data)
+	)
+	n := binary.PutUvarint(header[:], code)
+	n += binary.PutUvarint(header[n:], uint64(len(frame)))
+	w.buf = append(append(w.buf[:0], header[:n]...), frame...)
+
+	w.stream.SetWriteDeadline(time.Now().Add(timeout))
+	n, err := w.stream.Write(w.buf)
+	if w.metered {
+		egressTrafficMeter.Mark(int64(n))
+	}
+	return err
+}
+
+func (t *quicTransport) close(err error) {
+	t.once.Do(func() {
+		// Tell the remote end why we're disconnecting.
+		code, reason := quic.ApplicationErrorCode(quicDiscCode), ""
+		if r, ok := err.(DiscReason); ok && r != DiscNetworkError {
+			code, reason = quic.ApplicationErrorCode(quicDiscCode+uint64(r)), r.String()
+		}
+		t.cancel()
+		t.conn.closeWithError(code, reason)
+	})
+}
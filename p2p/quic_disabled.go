@@ -0,0 +1,68 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build !go1.20
+// +build !go1.20
+
+package p2p
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"net"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// The QUIC transport depends on quic-go, which requires Go 1.20. Older toolchains
+// build the stubs below, and servers configured with a QUIC address fail to start.
+
+// quicSupported reports whether the QUIC transport is available.
+const quicSupported = false
+
+var errQUICUnsupported = errors.New("QUIC transport requires Go 1.20 or newer")
+
+type quicEndpoint struct{}
+
+func listenQUIC(addr string) (*quicEndpoint, error) {
+	return nil, errQUICUnsupported
+}
+
+func (e *quicEndpoint) punch(n *enode.Node) {}
+func (e *quicEndpoint) close()              {}
+
+type quicConn struct {
+	net.Conn
+	metered bool
+}
+
+type quicDialer struct {
+	endpoint *quicEndpoint
+	fallback NodeDialer
+}
+
+func (d quicDialer) Dial(ctx context.Context, n *enode.Node) (net.Conn, error) {
+	return d.fallback.Dial(ctx, n)
+}
+
+func newQUICTransport(conn *quicConn, dialDest *ecdsa.PublicKey, protocols []Protocol) transport {
+	panic("QUIC transport not supported")
+}
+
+func (srv *Server) setupQUIC() error {
+	return errQUICUnsupported
+}
@@ -0,0 +1,356 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build go1.20
+// +build go1.20
+
+package p2p
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/testlog"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/golang/snappy"
+	"golang.org/x/sync/semaphore"
+)
+
+// quicTestProtocols are two subprotocols which are assigned separate streams.
+var quicTestProtocols = []Protocol{
+	{Name: "a", Version: 1, Length: 4},
+	{Name: "b", Version: 1, Length: 4},
+}
+
+// newQUICTestPair creates two QUIC endpoints on the loopback interface and sets
+// up a connection between them.
+func newQUICTestPair(t *testing.T) (dialer, listener *quicEndpoint, dialed, accepted *quicConn) {
+	t.Helper()
+
+	var err error
+	if dialer, err = listenQUIC("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	if listener, err = listenQUIC("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if dialed, err = dialer.dial(ctx, listener.addr()); err != nil {
+		t.Fatal("dial error:", err)
+	}
+	conn, err := listener.accept(ctx)
+	if err != nil {
+		t.Fatal("accept error:", err)
+	}
+	// The control stream is announced to the listener by the first write,
+	// which happens during the identity handshake.
+	accepted = &quicConn{conn: conn}
+	return dialer, listener, dialed, accepted
+}
+
+func TestQUICTransport(t *testing.T) {
+	dialer, listener, dialed, accepted := newQUICTestPair(t)
+	defer dialer.close()
+	defer listener.close()
+
+	var (
+		key1, key2 = newkey(), newkey()
+		hs1        = &protoHandshake{Version: baseProtocolVersion, ID: crypto.FromECDSAPub(&key1.PublicKey)[1:]}
+		hs2        = &protoHandshake{Version: baseProtocolVersion, ID: crypto.FromECDSAPub(&key2.PublicKey)[1:]}
+	)
+	for _, p := range quicTestProtocols {
+		hs1.Caps = append(hs1.Caps, p.cap())
+		hs2.Caps = append(hs2.Caps, p.cap())
+	}
+	// Run the handshakes on the listener side in the background.
+	type result struct {
+		t   transport
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		fd, err := acceptControlStream(ctx, accepted.conn)
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		tr := newQUICTransport(fd, nil, quicTestProtocols)
+		pub, err := tr.doEncHandshake(key2)
+		if err == nil && !pub.Equal(&key1.PublicKey) {
+			err = errors.New("wrong dialer identity")
+		}
+		if err == nil {
+			_, err = tr.doProtoHandshake(hs2)
+		}
+		done <- result{tr, err}
+	}()
+	tr1 := newQUICTransport(dialed, &key2.PublicKey, quicTestProtocols)
+	if _, err := tr1.doEncHandshake(key1); err != nil {
+		t.Fatal("dialer identity handshake failed:", err)
+	}
+	if _, err := tr1.doProtoHandshake(hs1); err != nil {
+		t.Fatal("dialer protocol handshake failed:", err)
+	}
+	res := <-done
+	if res.err != nil {
+		t.Fatal("listener handshake failed:", res.err)
+	}
+	tr2 := res.t
+
+	// Send messages of the base protocol and both subprotocols. Their order
+	// is only retained per stream.
+	codes := []uint64{pingMsg, baseProtocolLength, baseProtocolLength + 4, baseProtocolLength + 1}
+	for _, code := range codes {
+		if err := Send(tr1, code, []uint{uint(code)}); err != nil {
+			t.Fatalf("send %d failed: %v", code, err)
+		}
+	}
+	var (
+		received = make(map[uint64]bool)
+		last     = make(map[uint64]uint64) // stream lane -> last code
+	)
+	for range codes {
+		msg, err := tr2.ReadMsg()
+		if err != nil {
+			t.Fatal("read failed:", err)
+		}
+		var content []uint
+		if err := msg.Decode(&content); err != nil || len(content) != 1 || uint64(content[0]) != msg.Code {
+			t.Fatalf("message %d has wrong content %v (err %v)", msg.Code, content, err)
+		}
+		lane := msg.Code / 4
+		if prev, ok := last[lane]; ok && prev > msg.Code {
+			t.Errorf("message %d received after %d on the same stream", msg.Code, prev)
+		}
+		last[lane] = msg.Code
+		received[msg.Code] = true
+	}
+	if len(received) != len(codes) {
+		t.Fatalf("wrong messages received: %v", received)
+	}
+	// Closing with a reason must deliver it to the remote side.
+	tr1.close(DiscTooManyPeers)
+	if _, err := tr2.ReadMsg(); err != DiscTooManyPeers {
+		t.Fatalf("wrong error after remote close: %v", err)
+	}
+	tr2.close(nil)
+}
+
+// This test checks that frames are only read within the in-flight budget of the
+// connection, and that announced sizes are checked before the payload is read.
+func TestQUICReadFrame(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	tr := &quicTransport{inflight: semaphore.NewWeighted(quicMaxInflight), ctx: ctx, cancel: cancel}
+
+	frame := func(code uint64, payload []byte) *bufio.Reader {
+		var buf []byte
+		buf = binary.AppendUvarint(buf, code)
+		buf = binary.AppendUvarint(buf, uint64(len(payload)))
+		return bufio.NewReader(bytes.NewReader(append(buf, payload...)))
+	}
+	// A valid frame reserves its compressed and decoded size.
+	data := bytes.Repeat([]byte{1}, 1000)
+	compressed := snappy.Encode(nil, data)
+	msg, reserved, err := tr.readFrame(frame(7, compressed))
+	if err != nil {
+		t.Fatal("read failed:", err)
+	}
+	if msg.Code != 7 || msg.Size != uint32(len(data)) {
+		t.Fatalf("wrong message: code %d, size %d", msg.Code, msg.Size)
+	}
+	if want := int64(len(compressed) + len(data)); reserved != want {
+		t.Fatalf("wrong reservation: have %d, want %d", reserved, want)
+	}
+	tr.inflight.Release(reserved)
+
+	// Oversized messages are rejected by their header, without the payload.
+	header := binary.AppendUvarint(nil, quicMaxFrameSize+1)
+	r := frame(7, header)
+	if _, _, err := tr.readFrame(r); err == nil || err == io.ErrUnexpectedEOF {
+		t.Fatalf("oversized message not rejected: %v", err)
+	}
+	// Frames exceeding the remaining budget wait until it is released, and
+	// fail when the transport is closed.
+	if !tr.inflight.TryAcquire(quicMaxInflight) {
+		t.Fatal("budget not available")
+	}
+	errc := make(chan error, 1)
+	go func() {
+		_, _, err := tr.readFrame(frame(7, compressed))
+		errc <- err
+	}()
+	select {
+	case err := <-errc:
+		t.Fatalf("frame read beyond the budget: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	tr.cancel()
+	if err := <-errc; err != errQUICClosed {
+		t.Fatalf("wrong error after close: %v", err)
+	}
+}
+
+func TestQUICTransportWrongIdentity(t *testing.T) {
+	dialer, listener, dialed, accepted := newQUICTestPair(t)
+	defer dialer.close()
+	defer listener.close()
+
+	key1, key2 := newkey(), newkey()
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if fd, err := acceptControlStream(ctx, accepted.conn); err == nil {
+			newQUICTransport(fd, nil, nil).doEncHandshake(key2)
+		}
+	}()
+	// Expect a different key than the listener's.
+	tr := newQUICTransport(dialed, &newkey().PublicKey, nil)
+	if _, err := tr.doEncHandshake(key1); err != DiscUnexpectedIdentity {
+		t.Fatalf("wrong error for unexpected identity: %v", err)
+	}
+	tr.close(nil)
+}
+
+// This test checks that QUIC connections keep their type when metered, so the
+// server still selects the QUIC transport for them.
+func TestQUICMeteredConn(t *testing.T) {
+	dialer, listener, dialed, _ := newQUICTestPair(t)
+	defer dialer.close()
+	defer listener.close()
+
+	enabled := metrics.Enabled
+	metrics.Enabled = true
+	defer func() { metrics.Enabled = enabled }()
+
+	fd := newMeteredConn(dialed, false, nil)
+	if qc, ok := fd.(*quicConn); !ok || qc != dialed || !qc.metered {
+		t.Fatalf("metered QUIC connection mismatch: have %T", fd)
+	}
+	tr := newQUICTransport(dialed, nil, nil).(*quicTransport)
+	if !tr.control.metered {
+		t.Errorf("control stream writer not metered")
+	}
+	tr.close(nil)
+	dialed.Close() // closing twice must not unregister the peer twice
+}
+
+// This test checks that servers connect over QUIC if the dialed node advertises
+// it, and fall back to RLPx over TCP if the QUIC endpoint isn't reachable.
+func TestServerQUIC(t *testing.T) {
+	newServer := func(quic bool) *Server {
+		srv := &Server{Config: Config{
+			PrivateKey:  newkey(),
+			MaxPeers:    10,
+			NoDiscovery: true,
+			ListenAddr:  "127.0.0.1:0",
+			Protocols: []Protocol{{
+				Name: "test", Version: 1, Length: 1,
+				Run: func(p *Peer, rw MsgReadWriter) error {
+					for {
+						if _, err := rw.ReadMsg(); err != nil {
+							return err
+						}
+					}
+				},
+			}},
+			Logger: testlog.Logger(t, log.LvlTrace),
+		}}
+		if quic {
+			srv.QUICAddr = "127.0.0.1:0"
+		}
+		if err := srv.Start(); err != nil {
+			t.Fatal("could not start server:", err)
+		}
+		return srv
+	}
+	connect := func(srv *Server, node *enode.Node) *Peer {
+		events := make(chan *PeerEvent, 10)
+		sub := srv.SubscribeEvents(events)
+		defer sub.Unsubscribe()
+
+		srv.AddPeer(node)
+		timeout := time.After(10 * time.Second)
+		for {
+			select {
+			case ev := <-events:
+				if ev.Type == PeerEventTypeAdd && ev.Peer == node.ID() {
+					for _, p := range srv.Peers() {
+						if p.ID() == node.ID() {
+							return p
+						}
+					}
+				}
+			case <-timeout:
+				t.Fatal("peer not connected")
+			}
+		}
+	}
+	dialer, remote := newServer(true), newServer(true)
+	defer dialer.Stop()
+	defer remote.Stop()
+
+	if remote.Self().QUIC() == 0 {
+		t.Fatal("QUIC endpoint not advertised")
+	}
+	p := connect(dialer, remote.Self())
+	if _, ok := p.RemoteAddr().(*net.UDPAddr); !ok {
+		t.Fatalf("peer not connected over QUIC: %v", p.RemoteAddr())
+	}
+	dialer.RemovePeer(remote.Self())
+
+	// Advertise a QUIC endpoint nobody listens on, forcing the TCP fallback.
+	fallback := newServer(false)
+	defer fallback.Stop()
+
+	unused, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IP{127, 0, 0, 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unused.Close()
+
+	var r enr.Record
+	r.Set(enr.IPv4(net.IP{127, 0, 0, 1}))
+	r.Set(enr.TCP(fallback.Self().TCP()))
+	r.Set(enr.QUIC(unused.LocalAddr().(*net.UDPAddr).Port))
+	if err := enode.SignV4(&r, fallback.PrivateKey); err != nil {
+		t.Fatal(err)
+	}
+	node, err := enode.New(enode.ValidSchemes, &r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p = connect(dialer, node)
+	if _, ok := p.RemoteAddr().(*net.TCPAddr); !ok {
+		t.Fatalf("peer not connected over TCP: %v", p.RemoteAddr())
+	}
+}
@@ -0,0 +1,347 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+const (
+	// recordFileSize is the size at which a recording file is rotated.
+	recordFileSize = 32 * 1024 * 1024
+
+	// recordFileCount is the number of recording files kept per remote node.
+	// The oldest file is removed when a new one is started.
+	recordFileCount = 8
+
+	// recordPeerCount is the number of peer directories kept in the recording
+	// directory. The least recently updated ones are removed when a recording of
+	// another node starts, bounding the disk usage to recordPeerCount *
+	// recordFileCount * recordFileSize.
+	recordPeerCount = 64
+
+	// recordFileExt is the extension of recording files.
+	recordFileExt = ".rec"
+)
+
+// RecordType is the type of an entry in a traffic recording.
+type RecordType string
+
+const (
+	RecordConnect    RecordType = "connect"    // connection established, starts every file
+	RecordSend       RecordType = "send"       // message sent to the peer
+	RecordRecv       RecordType = "recv"       // message received from the peer
+	RecordDisconnect RecordType = "disconnect" // connection closed
+)
+
+// RecordEntry is an entry in a traffic recording. Recordings are files of
+// JSON-encoded entries, one per line.
+type RecordEntry struct {
+	Type RecordType `json:"type"`
+	Time time.Time  `json:"time"`
+
+	// Connection fields, set for connect entries. Caps are the capabilities
+	// announced by the remote node, Protocols the ones running on the connection.
+	ID        *enode.ID        `json:"id,omitempty"`
+	Name      string           `json:"name,omitempty"`
+	Caps      []Cap            `json:"caps,omitempty"`
+	Protocols []RecordProtocol `json:"protocols,omitempty"`
+	Remote    string           `json:"remote,omitempty"`
+	Inbound   bool             `json:"inbound,omitempty"`
+
+	// Message fields, set for send and recv entries. The message code is
+	// relative to the protocol.
+	Protocol string `json:"proto,omitempty"`
+	Version  uint   `json:"version,omitempty"`
+	Code     uint64 `json:"code,omitempty"`
+	Size     uint32 `json:"size,omitempty"`
+	Payload  []byte `json:"payload,omitempty"` // RLP-encoded message content
+
+	// Error is the reason of a disconnect.
+	Error string `json:"error,omitempty"`
+}
+
+// RecordProtocol describes a protocol running on a recorded connection.
+type RecordProtocol struct {
+	Name    string `json:"name"`
+	Version uint   `json:"version"`
+	Length  uint64 `json:"length"` // number of message codes used by the protocol
+}
+
+// ReadRecording reads all entries of a traffic recording.
+func ReadRecording(r io.Reader) ([]RecordEntry, error) {
+	var (
+		entries []RecordEntry
+		dec     = json.NewDecoder(r)
+	)
+	for {
+		var e RecordEntry
+		if err := dec.Decode(&e); err == io.EOF {
+			return entries, nil
+		} else if err != nil {
+			return entries, err
+		}
+		entries = append(entries, e)
+	}
+}
+
+// peerRecorder writes the traffic of a single peer connection to rotating
+// recording files.
+type peerRecorder struct {
+	dir    string
+	header RecordEntry
+	log    log.Logger
+
+	mu   sync.Mutex
+	file *os.File
+	buf  *bufio.Writer
+	size int
+}
+
+// newPeerRecorder creates a recorder for the given peer. Recordings are stored
+// in a subdirectory of dir named after the remote node ID.
+func newPeerRecorder(dir string, p *Peer) (*peerRecorder, error) {
+	id := p.ID()
+	r := &peerRecorder{
+		dir: filepath.Join(dir, id.String()),
+		header: RecordEntry{
+			Type:    RecordConnect,
+			ID:      &id,
+			Name:    p.rw.name,
+			Caps:    p.rw.caps,
+			Remote:  p.RemoteAddr().String(),
+			Inbound: p.Inbound(),
+		},
+		log: p.log,
+	}
+	for _, proto := range p.running {
+		r.header.Protocols = append(r.header.Protocols, RecordProtocol{proto.Name, proto.Version, proto.Length})
+	}
+	sort.Slice(r.header.Protocols, func(i, j int) bool {
+		return r.header.Protocols[i].Name < r.header.Protocols[j].Name
+	})
+	if err := os.MkdirAll(r.dir, 0700); err != nil {
+		return nil, err
+	}
+	if err := pruneRecordings(dir, r.dir); err != nil {
+		return nil, err
+	}
+	r.header.Time = time.Now()
+	if err := r.rotate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// message records a message sent or received on the given protocol.
+func (r *peerRecorder) message(typ RecordType, proto *protoRW, msg Msg, payload []byte) {
+	r.write(&RecordEntry{
+		Type:     typ,
+		Time:     time.Now(),
+		Protocol: proto.Name,
+		Version:  proto.Version,
+		Code:     msg.Code,
+		Size:     msg.Size,
+		Payload:  payload,
+	})
+}
+
+// close records the end of the connection and closes the current file.
+func (r *peerRecorder) close(err error) {
+	entry := &RecordEntry{Type: RecordDisconnect, Time: time.Now()}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	r.write(entry)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closeFile()
+}
+
+func (r *peerRecorder) write(e *RecordEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return // recording stopped after an error
+	}
+	if r.size >= recordFileSize {
+		if err := r.rotate(); err != nil {
+			r.fail(err)
+			return
+		}
+	}
+	if err := r.encode(e); err != nil {
+		r.fail(err)
+	}
+}
+
+func (r *peerRecorder) encode(e *RecordEntry) error {
+	enc, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	enc = append(enc, '\n')
+	if _, err := r.buf.Write(enc); err != nil {
+		return err
+	}
+	r.size += len(enc)
+	// Flush after every entry so the recording is usable while the
+	// connection is still up.
+	return r.buf.Flush()
+}
+
+// rotate starts a new recording file, removing the oldest files of the node if
+// there are too many. Every file starts with the connection entry, so it can be
+// replayed on its own.
+func (r *peerRecorder) rotate() error {
+	r.closeFile()
+	if err := r.prune(); err != nil {
+		return err
+	}
+	name := filepath.Join(r.dir, time.Now().UTC().Format("20060102-150405.000000000")+recordFileExt)
+	file, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	r.file, r.buf, r.size = file, bufio.NewWriter(file), 0
+	return r.encode(&r.header)
+}
+
+// prune removes the oldest recording files so that a new one can be started.
+func (r *peerRecorder) prune() error {
+	files, err := filepath.Glob(filepath.Join(r.dir, "*"+recordFileExt))
+	if err != nil {
+		return err
+	}
+	// File names are timestamps, so sorting them orders the files by age.
+	sort.Strings(files)
+	for len(files) >= recordFileCount {
+		if err := os.Remove(files[0]); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		files = files[1:]
+	}
+	return nil
+}
+
+// pruneRecordings removes the least recently updated peer directories of the
+// recording directory until at most recordPeerCount are left. The directory of
+// the given recording is retained, entries not named after a node are ignored.
+func pruneRecordings(dir string, keep string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	type peerDir struct {
+		path    string
+		updated time.Time
+	}
+	var dirs []peerDir
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if !entry.IsDir() || path == keep {
+			continue
+		}
+		if _, err := enode.ParseID(entry.Name()); err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue // removed concurrently
+		}
+		dirs = append(dirs, peerDir{path, info.ModTime()})
+	}
+	// Rotating files updates the modification time of the directory, so the
+	// oldest directories belong to the peers recorded least recently.
+	sort.Slice(dirs, func(i, j int) bool { return dirs[i].updated.Before(dirs[j].updated) })
+	for len(dirs) >= recordPeerCount {
+		if err := os.RemoveAll(dirs[0].path); err != nil {
+			return err
+		}
+		dirs = dirs[1:]
+	}
+	return nil
+}
+
+func (r *peerRecorder) fail(err error) {
+	r.log.Warn("Stopping traffic recording", "err", err)
+	r.closeFile()
+}
+
+func (r *peerRecorder) closeFile() {
+	if r.file == nil {
+		return
+	}
+	r.buf.Flush()
+	r.file.Close()
+	r.file, r.buf = nil, nil
+}
+
+// msgRecorder wraps a protocol MsgReadWriter and records all messages sent and
+// received through it.
+type msgRecorder struct {
+	MsgReadWriter
+	rec   *peerRecorder
+	proto *protoRW
+}
+
+func newMsgRecorder(rw MsgReadWriter, rec *peerRecorder, proto *protoRW) *msgRecorder {
+	return &msgRecorder{MsgReadWriter: rw, rec: rec, proto: proto}
+}
+
+// ReadMsg reads a message from the underlying MsgReadWriter and records it.
+func (mr *msgRecorder) ReadMsg() (Msg, error) {
+	msg, err := mr.MsgReadWriter.ReadMsg()
+	if err != nil {
+		return msg, err
+	}
+	payload, err := io.ReadAll(msg.Payload)
+	if err != nil {
+		return msg, err
+	}
+	msg.Payload = bytes.NewReader(payload)
+	mr.rec.message(RecordRecv, mr.proto, msg, payload)
+	return msg, nil
+}
+
+// WriteMsg records a message and writes it to the underlying MsgReadWriter.
+func (mr *msgRecorder) WriteMsg(msg Msg) error {
+	payload, err := io.ReadAll(msg.Payload)
+	if err != nil {
+		return err
+	}
+	msg.Payload = bytes.NewReader(payload)
+	if err := mr.MsgReadWriter.WriteMsg(msg); err != nil {
+		return err
+	}
+	mr.rec.message(RecordSend, mr.proto, msg, payload)
+	return nil
+}
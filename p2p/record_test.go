@@ -0,0 +1,201 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+func TestPeerRecorder(t *testing.T) {
+	proto := Protocol{
+		Name:    "a",
+		Version: 1,
+		Length:  5,
+		Run: func(peer *Peer, rw MsgReadWriter) error {
+			if err := ExpectMsg(rw, 2, []uint{1}); err != nil {
+				return err
+			}
+			if err := SendItems(rw, 3, uint(2)); err != nil {
+				return err
+			}
+			_, err := rw.ReadMsg()
+			return err
+		},
+	}
+	var (
+		dir      = t.TempDir()
+		fd1, fd2 = net.Pipe()
+		key1     = newkey()
+		key2     = newkey()
+		c1       = &conn{fd: fd1, node: newNode(uintID(1), ""), transport: newTestTransport(&key2.PublicKey, fd1, nil)}
+		c2       = &conn{fd: fd2, node: newNode(uintID(2), ""), transport: newTestTransport(&key1.PublicKey, fd2, &key1.PublicKey)}
+	)
+	c1.caps = []Cap{proto.cap(), {"b", 1}}
+	c1.name = "remote"
+	peer := newPeer(log.Root(), c1, []Protocol{proto})
+	rec, err := newPeerRecorder(dir, peer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	peer.recorder = rec
+	errc := make(chan error, 1)
+	go func() {
+		_, err := peer.run()
+		rec.close(err)
+		errc <- err
+	}()
+
+	if err := SendItems(c2, baseProtocolLength+2, uint(1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExpectMsg(c2, baseProtocolLength+3, []uint{2}); err != nil {
+		t.Fatal(err)
+	}
+	c2.close(errors.New("test done"))
+	<-errc
+
+	// Check the recording.
+	files, _ := filepath.Glob(filepath.Join(dir, uintID(1).String(), "*"+recordFileExt))
+	if len(files) != 1 {
+		t.Fatalf("wrong number of recording files: %d", len(files))
+	}
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	entries, err := ReadRecording(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("wrong number of entries: %d", len(entries))
+	}
+	connect := entries[0]
+	if connect.Type != RecordConnect || *connect.ID != uintID(1) || connect.Name != "remote" || len(connect.Caps) != 2 {
+		t.Errorf("wrong connect entry: %+v", connect)
+	}
+	if len(connect.Protocols) != 1 || connect.Protocols[0] != (RecordProtocol{"a", 1, 5}) {
+		t.Errorf("wrong protocols in connect entry: %+v", connect.Protocols)
+	}
+	checkMsg := func(e RecordEntry, typ RecordType, code uint64, value uint) {
+		t.Helper()
+		var v []uint
+		if err := rlp.DecodeBytes(e.Payload, &v); err != nil {
+			t.Fatalf("can't decode payload: %v", err)
+		}
+		if e.Type != typ || e.Protocol != "a" || e.Version != 1 || e.Code != code || int(e.Size) != len(e.Payload) || len(v) != 1 || v[0] != value {
+			t.Errorf("wrong %s entry: %+v", typ, e)
+		}
+	}
+	checkMsg(entries[1], RecordRecv, 2, 1)
+	checkMsg(entries[2], RecordSend, 3, 2)
+	if entries[3].Type != RecordDisconnect || entries[3].Error == "" {
+		t.Errorf("wrong disconnect entry: %+v", entries[3])
+	}
+}
+
+func TestPeerRecorderRotate(t *testing.T) {
+	dir := t.TempDir()
+	_, c, peer, _ := testPeer(nil)
+	defer c.close(errors.New("test done"))
+
+	rec, err := newPeerRecorder(dir, peer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rec.close(nil)
+	for i := 0; i < recordFileCount+2; i++ {
+		if err := rec.rotate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, peer.ID().String(), "*"+recordFileExt))
+	if len(files) != recordFileCount {
+		t.Fatalf("wrong number of recording files: %d", len(files))
+	}
+	// Every file starts with the connection entry.
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries, err := ReadRecording(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) == 0 || entries[0].Type != RecordConnect || *entries[0].ID != peer.ID() {
+			t.Errorf("file %s doesn't start with connect entry", file)
+		}
+	}
+}
+
+// This test checks that the recordings of the least recently recorded nodes are
+// removed when too many nodes were recorded.
+func TestPeerRecorderPrune(t *testing.T) {
+	dir := t.TempDir()
+	_, c, peer, _ := testPeer(nil)
+	defer c.close(errors.New("test done"))
+
+	// Create the directories of earlier recordings, the first ones being the oldest.
+	var (
+		dirs []string
+		now  = time.Now()
+	)
+	for i := 0; i < recordPeerCount+1; i++ {
+		path := filepath.Join(dir, randomID().String())
+		if err := os.Mkdir(path, 0700); err != nil {
+			t.Fatal(err)
+		}
+		updated := now.Add(time.Duration(i-recordPeerCount-1) * time.Minute)
+		if err := os.Chtimes(path, updated, updated); err != nil {
+			t.Fatal(err)
+		}
+		dirs = append(dirs, path)
+	}
+	other := filepath.Join(dir, "other")
+	if err := os.Mkdir(other, 0700); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := newPeerRecorder(dir, peer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rec.close(nil)
+
+	for i, path := range dirs {
+		_, err := os.Stat(path)
+		if removed := os.IsNotExist(err); removed != (i < 2) {
+			t.Errorf("directory %d: removed %v, want %v", i, removed, i < 2)
+		}
+	}
+	if _, err := os.Stat(rec.dir); err != nil {
+		t.Errorf("recording of new peer missing: %v", err)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("unrelated directory removed: %v", err)
+	}
+}
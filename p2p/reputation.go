@@ -0,0 +1,309 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+const (
+	// reputationMax and reputationMin bound the score of a node, so a long
+	// history of good behavior cannot buy unlimited misbehavior later on and
+	// a banned node eventually becomes acceptable again.
+	reputationMax = 1000
+	reputationMin = -1000
+
+	// reputationBanThreshold is the score at or below which dynamic peers are
+	// disconnected, not dialed and not accepted anymore.
+	reputationBanThreshold = -200
+
+	// reputationHalfLife is the time after which the score of a node decays to
+	// half of its value.
+	reputationHalfLife = 6 * time.Hour
+
+	// reputationFlushInterval is the interval at which updated scores are
+	// written to the node database.
+	reputationFlushInterval = time.Minute
+)
+
+// ScoreEvent is a peer behavior reported by a protocol handler, which raises or
+// lowers the reputation of the remote node.
+//
+// Note, ScoreTimeout is only reported where a handler drops a peer solely for not
+// answering, such as the checkpoint and required block challenges. Request
+// timeouts in the downloader and fetchers are folded into their generic drop
+// callbacks and score as ScoreUselessResponse, while timeouts which don't lead
+// to a drop are not scored at all.
+type ScoreEvent int
+
+const (
+	ScoreGoodDelivery    ScoreEvent = iota // Requested data was delivered and accepted
+	ScoreUselessResponse                   // Response was unrequested, junk or rejected
+	ScoreTimeout                           // Request was not answered in time
+	ScoreInvalidBlock                      // Block or header failed validation
+)
+
+// scoreWeights is the reputation change caused by each reported event.
+var scoreWeights = map[ScoreEvent]int64{
+	ScoreGoodDelivery:    1,
+	ScoreUselessResponse: -5,
+	ScoreTimeout:         -10,
+	ScoreInvalidBlock:    -100,
+}
+
+func (ev ScoreEvent) String() string {
+	switch ev {
+	case ScoreGoodDelivery:
+		return "good delivery"
+	case ScoreUselessResponse:
+		return "useless response"
+	case ScoreTimeout:
+		return "timeout"
+	case ScoreInvalidBlock:
+		return "invalid block"
+	default:
+		return "unknown"
+	}
+}
+
+// reputationTracker maintains the scores of remote nodes in the node database.
+// Scores decay towards zero over time, the decay is applied lazily whenever a
+// score is read.
+//
+// Score updates are frequent, so they are kept in memory and only written to the
+// database by flush, which the server calls periodically, when a peer disconnects
+// and on shutdown.
+type reputationTracker struct {
+	db    *enode.DB
+	now   func() time.Time              // wall clock, overridable in tests
+	dirty map[enode.ID]enode.Reputation // scores updated since the last flush, zero if deleted
+	mu    sync.Mutex                    // serializes read-modify-write cycles
+}
+
+func newReputationTracker(db *enode.DB) *reputationTracker {
+	return &reputationTracker{
+		db:    db,
+		now:   time.Now,
+		dirty: make(map[enode.ID]enode.Reputation),
+	}
+}
+
+// decay returns the score of a reputation at the given time.
+func decay(rep enode.Reputation, now time.Time) int64 {
+	elapsed := now.Sub(rep.Updated)
+	if rep.Score == 0 || elapsed <= 0 {
+		return rep.Score
+	}
+	return int64(math.Round(float64(rep.Score) * math.Exp2(-float64(elapsed)/float64(reputationHalfLife))))
+}
+
+// reputation returns the stored reputation of a node, preferring unflushed
+// updates over the database. It must be called with the lock held.
+func (t *reputationTracker) reputation(id enode.ID) enode.Reputation {
+	if rep, ok := t.dirty[id]; ok {
+		return rep
+	}
+	return t.db.Reputation(id)
+}
+
+// current returns the decayed score of a node at the given time, scheduling the
+// stored reputation for deletion once it decayed to zero. It must be called with
+// the lock held.
+func (t *reputationTracker) current(id enode.ID, rep enode.Reputation, now time.Time) int64 {
+	score := decay(rep, now)
+	if score == 0 && rep.Score != 0 {
+		t.dirty[id] = enode.Reputation{}
+	}
+	return score
+}
+
+// score returns the current score of a node.
+func (t *reputationTracker) score(id enode.ID) int64 {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.current(id, t.reputation(id), t.now())
+}
+
+// banned reports whether the score of a node is too low to keep it as a dynamic peer.
+func (t *reputationTracker) banned(id enode.ID) bool {
+	return t.score(id) <= reputationBanThreshold
+}
+
+// report applies the weight of an event to the score of a node and returns the
+// updated score.
+func (t *reputationTracker) report(id enode.ID, ev ScoreEvent) int64 {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	score := decay(t.reputation(id), now) + scoreWeights[ev]
+	return t.store(id, score, now)
+}
+
+// set overrides the score of a node and returns the stored (capped) score.
+func (t *reputationTracker) set(id enode.ID, score int64) int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.store(id, score, t.now())
+}
+
+// reset forgets the score of a node.
+func (t *reputationTracker) reset(id enode.ID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.dirty[id] = enode.Reputation{}
+}
+
+// store caps a score and schedules it for persisting. It must be called with
+// the lock held.
+func (t *reputationTracker) store(id enode.ID, score int64, now time.Time) int64 {
+	if score > reputationMax {
+		score = reputationMax
+	}
+	if score < reputationMin {
+		score = reputationMin
+	}
+	if score == 0 {
+		t.dirty[id] = enode.Reputation{}
+		return 0
+	}
+	t.dirty[id] = enode.Reputation{Score: score, Updated: now}
+	return score
+}
+
+// write persists a single reputation. It must be called with the lock held.
+func (t *reputationTracker) write(id enode.ID, rep enode.Reputation) {
+	if rep.Score == 0 {
+		t.db.DeleteReputation(id)
+	} else {
+		t.db.UpdateReputation(id, rep)
+	}
+}
+
+// flush writes all updated scores to the node database.
+func (t *reputationTracker) flush() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for id, rep := range t.dirty {
+		t.write(id, rep)
+	}
+	t.dirty = make(map[enode.ID]enode.Reputation)
+}
+
+// flushNode writes the updated score of a single node to the node database.
+func (t *reputationTracker) flushNode(id enode.ID) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if rep, ok := t.dirty[id]; ok {
+		t.write(id, rep)
+		delete(t.dirty, id)
+	}
+}
+
+// scores returns the current, non-zero scores of all known nodes.
+func (t *reputationTracker) scores() map[enode.ID]int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var (
+		now    = t.now()
+		scores = make(map[enode.ID]int64)
+	)
+	reps := t.db.Reputations()
+	for id, rep := range t.dirty {
+		reps[id] = rep
+	}
+	for id, rep := range reps {
+		if score := t.current(id, rep, now); score != 0 {
+			scores[id] = score
+		}
+	}
+	return scores
+}
+
+// Report adjusts the reputation of the peer according to the behavior reported
+// by a protocol handler. Dynamic peers whose reputation drops to the ban threshold
+// are disconnected.
+func (p *Peer) Report(ev ScoreEvent) {
+	if p.reputation == nil {
+		return
+	}
+	score := p.reputation.report(p.ID(), ev)
+	if score <= reputationBanThreshold && !p.rw.is(trustedConn|staticDialedConn) {
+		p.log.Debug("Disconnecting peer with low reputation", "event", ev, "score", score)
+		p.Disconnect(DiscUselessPeer)
+	}
+}
+
+// PeerReputation returns the current reputation score of a node.
+func (srv *Server) PeerReputation(id enode.ID) int64 {
+	return srv.reputation.score(id)
+}
+
+// PeerReputations returns the reputation scores of all nodes with a non-zero score.
+func (srv *Server) PeerReputations() map[enode.ID]int64 {
+	if srv.reputation == nil {
+		return nil
+	}
+	return srv.reputation.scores()
+}
+
+// SetPeerReputation overrides the reputation score of a node. If the new score
+// is at or below the ban threshold, the node is disconnected unless it is a
+// trusted or static peer. The stored score is returned.
+func (srv *Server) SetPeerReputation(id enode.ID, score int64) int64 {
+	if srv.reputation == nil {
+		return 0
+	}
+	score = srv.reputation.set(id, score)
+	if score <= reputationBanThreshold {
+		srv.doPeerOp(func(peers map[enode.ID]*Peer) {
+			if p := peers[id]; p != nil && !p.rw.is(trustedConn|staticDialedConn) {
+				p.Disconnect(DiscUselessPeer)
+			}
+		})
+	}
+	return score
+}
+
+// ResetPeerReputation forgets the reputation of a node.
+func (srv *Server) ResetPeerReputation(id enode.ID) {
+	if srv.reputation != nil {
+		srv.reputation.reset(id)
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/internal/testlog"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+)
+
+func TestReputationTracker(t *testing.T) {
+	db, _ := enode.OpenDB("")
+	defer db.Close()
+
+	var (
+		now     = time.Unix(1666000000, 0)
+		tracker = newReputationTracker(db)
+		id      = randomID()
+	)
+	tracker.now = func() time.Time { return now }
+
+	for i := 0; i < 10; i++ {
+		tracker.report(id, ScoreGoodDelivery)
+	}
+	if score := tracker.score(id); score != 10 {
+		t.Fatalf("score mismatch after deliveries: have %d, want 10", score)
+	}
+	tracker.report(id, ScoreTimeout)
+	tracker.report(id, ScoreUselessResponse)
+	if score := tracker.score(id); score != -5 {
+		t.Fatalf("score mismatch after penalties: have %d, want -5", score)
+	}
+	// Invalid blocks push the node past the ban threshold.
+	tracker.report(id, ScoreInvalidBlock)
+	if tracker.banned(id) {
+		t.Fatalf("node banned too early with score %d", tracker.score(id))
+	}
+	tracker.report(id, ScoreInvalidBlock)
+	if !tracker.banned(id) {
+		t.Fatalf("node not banned with score %d", tracker.score(id))
+	}
+	// The score decays by half every half-life, lifting the ban.
+	now = now.Add(reputationHalfLife)
+	if score := tracker.score(id); score != -103 {
+		t.Fatalf("decayed score mismatch: have %d, want -103", score)
+	}
+	if tracker.banned(id) {
+		t.Fatalf("node still banned after decay")
+	}
+	// Overrides are capped and zero scores are dropped.
+	if score := tracker.set(id, 5000); score != reputationMax {
+		t.Fatalf("set score not capped: have %d, want %d", score, reputationMax)
+	}
+	if scores := tracker.scores(); len(scores) != 1 || scores[id] != reputationMax {
+		t.Fatalf("score listing mismatch: %v", scores)
+	}
+	tracker.reset(id)
+	if scores := tracker.scores(); len(scores) != 0 {
+		t.Fatalf("score listing not empty after reset: %v", scores)
+	}
+}
+
+// This test checks that reputations which decayed to zero are deleted from the
+// node database on the next flush.
+func TestReputationDecayDelete(t *testing.T) {
+	db, _ := enode.OpenDB("")
+	defer db.Close()
+
+	var (
+		now     = time.Unix(1666000000, 0)
+		tracker = newReputationTracker(db)
+		id      = randomID()
+	)
+	tracker.now = func() time.Time { return now }
+
+	tracker.set(id, reputationMin)
+	tracker.flush()
+	if rep := db.Reputation(id); rep.Score != reputationMin {
+		t.Fatalf("reputation not persisted: %v", rep)
+	}
+	now = now.Add(12 * reputationHalfLife)
+	if scores := tracker.scores(); len(scores) != 0 {
+		t.Fatalf("decayed score still listed: %v", scores)
+	}
+	tracker.flush()
+	if reps := db.Reputations(); len(reps) != 0 {
+		t.Fatalf("decayed reputation not deleted: %v", reps)
+	}
+}
+
+// This test checks that score updates are kept in memory until flushed to the
+// node database.
+func TestReputationFlush(t *testing.T) {
+	db, _ := enode.OpenDB("")
+	defer db.Close()
+
+	var (
+		tracker = newReputationTracker(db)
+		id1     = randomID()
+		id2     = randomID()
+	)
+	tracker.report(id1, ScoreTimeout)
+	tracker.report(id2, ScoreInvalidBlock)
+	if rep := db.Reputation(id1); rep.Score != 0 {
+		t.Fatalf("score written before flush: %d", rep.Score)
+	}
+	if score := tracker.score(id1); score != -10 {
+		t.Fatalf("unflushed score mismatch: have %d, want -10", score)
+	}
+	if scores := tracker.scores(); len(scores) != 2 {
+		t.Fatalf("unflushed score listing mismatch: %v", scores)
+	}
+	// Flushing a single node only persists its own score.
+	tracker.flushNode(id1)
+	if rep := db.Reputation(id1); rep.Score != -10 {
+		t.Fatalf("flushed node score mismatch: have %d, want -10", rep.Score)
+	}
+	if rep := db.Reputation(id2); rep.Score != 0 {
+		t.Fatalf("unrelated score written: %d", rep.Score)
+	}
+	// Resets are persisted as deletions.
+	tracker.reset(id1)
+	tracker.flush()
+	if rep := db.Reputation(id1); rep.Score != 0 {
+		t.Fatalf("reset score still stored: %d", rep.Score)
+	}
+	if rep := db.Reputation(id2); rep.Score != -100 {
+		t.Fatalf("flushed score mismatch: have %d, want -100", rep.Score)
+	}
+	if score := tracker.score(id2); score != -100 {
+		t.Fatalf("score mismatch after flush: have %d, want -100", score)
+	}
+}
+
+// This test checks that dynamic dial candidates are skipped more often the
+// lower their reputation is, and never dialed when banned.
+func TestDialReputation(t *testing.T) {
+	var (
+		scores = make(map[enode.ID]int64)
+		good   = randomID()
+		poor   = randomID()
+		banned = randomID()
+		static = randomID()
+	)
+	scores[poor] = reputationBanThreshold / 2
+	scores[banned] = reputationBanThreshold
+	scores[static] = reputationBanThreshold
+
+	d := newDialScheduler(dialConfig{
+		reputation: func(id enode.ID) int64 { return scores[id] },
+		dialer:     newDialTestDialer(),
+	}, enode.IterNodes(nil), func(net.Conn, connFlag, *enode.Node) error { return nil })
+	d.stop()
+	d.static[static] = newDialTask(newNode(static, "127.0.0.1:30303"), staticDialedConn)
+
+	skipped := make(map[enode.ID]int)
+	for i := 0; i < 1000; i++ {
+		for _, id := range []enode.ID{good, poor, banned, static} {
+			if err := d.checkDial(newNode(id, "127.0.0.1:30303")); err != nil {
+				skipped[id]++
+			}
+		}
+	}
+	if skipped[good] != 0 || skipped[static] != 0 {
+		t.Errorf("well behaving or static node skipped: %d, %d", skipped[good], skipped[static])
+	}
+	if skipped[banned] != 1000 {
+		t.Errorf("banned node dialed %d times", 1000-skipped[banned])
+	}
+	if skipped[poor] < 350 || skipped[poor] > 650 {
+		t.Errorf("poorly scored node skip rate mismatch: have %d/1000, want ~500", skipped[poor])
+	}
+}
+
+// This test checks that inbound connections from banned nodes are rejected,
+// unless the node is trusted.
+func TestServerRejectLowReputation(t *testing.T) {
+	trustedNode := newkey()
+	trustedID := enode.PubkeyToIDV4(&trustedNode.PublicKey)
+	srv := &Server{
+		Config: Config{
+			PrivateKey:   newkey(),
+			MaxPeers:     10,
+			NoDial:       true,
+			NoDiscovery:  true,
+			TrustedNodes: []*enode.Node{newNode(trustedID, "")},
+			Logger:       testlog.Logger(t, log.LvlTrace),
+		},
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("could not start: %v", err)
+	}
+	defer srv.Stop()
+
+	newconn := func(id enode.ID) *conn {
+		fd, _ := net.Pipe()
+		tx := newTestTransport(&trustedNode.PublicKey, fd, nil)
+		node := enode.SignNull(new(enr.Record), id)
+		return &conn{fd: fd, transport: tx, flags: inboundConn, node: node, cont: make(chan error)}
+	}
+	bannedID := randomID()
+	srv.SetPeerReputation(bannedID, reputationBanThreshold)
+	srv.SetPeerReputation(trustedID, reputationBanThreshold)
+
+	if err := srv.checkpoint(newconn(bannedID), srv.checkpointPostHandshake); err != DiscUselessPeer {
+		t.Errorf("wrong error for banned conn: %v", err)
+	}
+	if err := srv.checkpoint(newconn(trustedID), srv.checkpointPostHandshake); err != nil {
+		t.Errorf("unexpected error for banned trusted conn: %v", err)
+	}
+	// Resetting the reputation lifts the ban.
+	srv.ResetPeerReputation(bannedID)
+	if err := srv.checkpoint(newconn(bannedID), srv.checkpointPostHandshake); err != nil {
+		t.Errorf("unexpected error for reset conn: %v", err)
+	}
+}
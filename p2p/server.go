@@ -114,6 +114,9 @@ type Config struct {
 	// allowed to connect, even above the peer limit.
 	TrustedNodes []*enode.Node
 
+	// PeerGroups are named sets of nodes with reserved and capped peer slots.
+	PeerGroups []PeerGroup `toml:",omitempty"`
+
 	// Connectivity can be restricted to certain IP networks.
 	// If this option is set to a non-nil value, only hosts which match one of the
 	// IP networks contained in the list are considered.
@@ -140,6 +143,12 @@ type Config struct {
 	// for TCP and DiscAddr for the UDP discovery protocol.
 	DiscAddr string
 
+	// If QUICAddr is set to a non-empty value, the server also accepts devp2p
+	// connections over QUIC on this UDP address and advertises it in the local
+	// node record. Nodes advertising a QUIC endpoint are then dialed over QUIC,
+	// falling back to RLPx over TCP if that fails.
+	QUICAddr string `toml:",omitempty"`
+
 	// If set to a non-nil value, the given NAT port mapper
 	// is used to make the listening port available to the
 	// Internet.
@@ -152,10 +161,46 @@ type Config struct {
 	// If NoDial is true, the server will not dial any peers.
 	NoDial bool `toml:",omitempty"`
 
+	// DialNetRestrict restricts dialing nodes found by discovery to the given IP
+	// networks. Unlike NetRestrict, it doesn't apply to inbound connections.
+	DialNetRestrict *netutil.Netlist `toml:",omitempty"`
+
+	// DialRequire lists node record keys which nodes found by discovery must
+	// advertise in order to be dialed, e.g. "snap".
+	DialRequire []string `toml:",omitempty"`
+
+	// DialClients restricts dialing nodes found by discovery to the given client
+	// names, according to the "client" entry of their record. Nodes which don't
+	// advertise their client are still dialed.
+	DialClients []string `toml:",omitempty"`
+
+	// If AdvertiseClient is set, the client name and version are derived from Name
+	// and advertised in the "client" entry of the local node record. The entry is
+	// left out if the record would become too large with it.
+	AdvertiseClient bool `toml:",omitempty"`
+
+	// DialFilters are additional checks on the record of nodes found by discovery,
+	// evaluated before dialing them after the filters of the protocols.
+	DialFilters []NodeFilter `toml:"-"`
+
+	// If HolePunching is set, nodes behind a NAT which can't be dialed directly
+	// are reached by UDP hole punching through discovery v5 relays. Nodes which
+	// reach the local node this way are dialed back, limited per IP and in total.
+	// Dials back are subject to the same checks and filters as dials to nodes
+	// found by discovery. This requires DiscoveryV5, and only reaches nodes over QUIC, so it also
+	// requires QUICAddr.
+	HolePunching bool `toml:",omitempty"`
+
 	// If EnableMsgEvents is set then the server will emit PeerEvents
 	// whenever a message is sent to or received from a peer
 	EnableMsgEvents bool
 
+	// If RecordDir is set, the messages exchanged with each peer are recorded
+	// to rotating files in a subdirectory named after the remote node ID. Only
+	// the recordings of the most recently recorded nodes are kept.
+	// Recordings can be played back against a node using 'devp2p replay'.
+	RecordDir string `toml:",omitempty"`
+
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.Logger `toml:",omitempty"`
 
@@ -177,17 +222,23 @@ type Server struct {
 	running bool
 
 	listener     net.Listener
+	quic         *quicEndpoint
+	dialer       NodeDialer // dialer of outbound connections, without hole punching
 	ourHandshake *protoHandshake
 	loopWG       sync.WaitGroup // loop, listenLoop
 	peerFeed     event.Feed
 	log          log.Logger
 
-	nodedb    *enode.DB
-	localnode *enode.LocalNode
-	ntab      *discover.UDPv4
-	DiscV5    *discover.UDPv5
-	discmix   *enode.FairMix
-	dialsched *dialScheduler
+	nodedb     *enode.DB
+	reputation *reputationTracker
+	groups     *peerGroups
+	localnode  *enode.LocalNode
+	ntab       *discover.UDPv4
+	DiscV5     *discover.UDPv5
+	discmix    *enode.FairMix
+	dialsched  *dialScheduler
+	static     map[enode.ID]bool // static nodes, configured or added through AddPeer
+	staticLock sync.Mutex        // protects static
 
 	// Channels into the run loop.
 	quit                    chan struct{}
@@ -201,6 +252,11 @@ type Server struct {
 
 	// State of run loop and listenLoop.
 	inboundHistory expHeap
+	inboundLock    sync.Mutex // protects inboundHistory
+
+	// State of dials back after hole punching.
+	punchHistory expHeap
+	punchLock    sync.Mutex // protects punchHistory
 }
 
 type peerOpFunc func(map[enode.ID]*Peer)
@@ -324,6 +380,10 @@ func (srv *Server) PeerCount() int {
 // the server will connect to the node. If the connection fails for any reason, the server
 // will attempt to reconnect the peer.
 func (srv *Server) AddPeer(node *enode.Node) {
+	srv.staticLock.Lock()
+	srv.static[node.ID()] = true
+	srv.staticLock.Unlock()
+
 	srv.dialsched.addStatic(node)
 }
 
@@ -338,6 +398,10 @@ func (srv *Server) RemovePeer(node *enode.Node) {
 		sub event.Subscription
 	)
 	// Disconnect the peer on the main loop.
+	srv.staticLock.Lock()
+	delete(srv.static, node.ID())
+	srv.staticLock.Unlock()
+
 	srv.doPeerOp(func(peers map[enode.ID]*Peer) {
 		srv.dialsched.removeStatic(node)
 		if peer := peers[node.ID()]; peer != nil {
@@ -404,6 +468,9 @@ func (srv *Server) Stop() {
 		// this unblocks listener Accept
 		srv.listener.Close()
 	}
+	if srv.quic != nil {
+		srv.quic.close()
+	}
 	close(srv.quit)
 	srv.lock.Unlock()
 	srv.loopWG.Wait()
@@ -474,6 +541,9 @@ func (srv *Server) Start() (err error) {
 	srv.peerOp = make(chan peerOpFunc)
 	srv.peerOpDone = make(chan struct{})
 
+	if srv.groups, err = newPeerGroups(srv.PeerGroups); err != nil {
+		return err
+	}
 	if err := srv.setupLocalNode(); err != nil {
 		return err
 	}
@@ -482,6 +552,12 @@ func (srv *Server) Start() (err error) {
 			return err
 		}
 	}
+	if srv.QUICAddr != "" {
+		if err := srv.setupQUIC(); err != nil {
+			return err
+		}
+	}
+	srv.setupDialer()
 	if err := srv.setupDiscovery(); err != nil {
 		return err
 	}
@@ -507,6 +583,7 @@ func (srv *Server) setupLocalNode() error {
 		return err
 	}
 	srv.nodedb = db
+	srv.reputation = newReputationTracker(db)
 	srv.localnode = enode.NewLocalNode(db, srv.PrivateKey)
 	srv.localnode.SetFallbackIP(net.IP{127, 0, 0, 1})
 	// TODO: check conflicts
@@ -515,6 +592,9 @@ func (srv *Server) setupLocalNode() error {
 			srv.localnode.Set(e)
 		}
 	}
+	if srv.AdvertiseClient {
+		srv.setClientEntry()
+	}
 	switch srv.NAT.(type) {
 	case nil:
 		// No NAT interface, do nothing.
@@ -613,6 +693,9 @@ func (srv *Server) setupDiscovery() error {
 			Bootnodes:   srv.BootstrapNodesV5,
 			Log:         srv.log,
 		}
+		if srv.HolePunching {
+			cfg.PunchHandler = srv.punchBack
+		}
 		var err error
 		if sconn != nil {
 			srv.DiscV5, err = discover.ListenV5(sconn, srv.localnode, cfg)
@@ -626,6 +709,16 @@ func (srv *Server) setupDiscovery() error {
 	return nil
 }
 
+func (srv *Server) setupDialer() {
+	srv.dialer = srv.Dialer
+	if srv.dialer == nil {
+		srv.dialer = tcpDialer{&net.Dialer{Timeout: defaultDialTimeout}}
+	}
+	if srv.quic != nil {
+		srv.dialer = quicDialer{endpoint: srv.quic, fallback: srv.dialer}
+	}
+}
+
 func (srv *Server) setupDialScheduler() {
 	config := dialConfig{
 		self:           srv.localnode.ID(),
@@ -633,17 +726,25 @@ func (srv *Server) setupDialScheduler() {
 		maxActiveDials: srv.MaxPendingPeers,
 		log:            srv.Logger,
 		netRestrict:    srv.NetRestrict,
-		dialer:         srv.Dialer,
+		dialer:         srv.dialer,
 		clock:          srv.clock,
+		reputation:     srv.reputation.score,
+		groups:         srv.groups,
+		filters:        srv.dialFilters(),
 	}
 	if srv.ntab != nil {
 		config.resolver = srv.ntab
 	}
-	if config.dialer == nil {
-		config.dialer = tcpDialer{&net.Dialer{Timeout: defaultDialTimeout}}
+	if srv.HolePunching && srv.DiscV5 != nil {
+		config.dialer = punchDialer{NodeDialer: config.dialer, disc: srv.DiscV5, quic: srv.quic}
 	}
 	srv.dialsched = newDialScheduler(config, srv.discmix, srv.SetupConn)
+	srv.static = make(map[enode.ID]bool, len(srv.StaticNodes))
 	for _, n := range srv.StaticNodes {
+		srv.static[n.ID()] = true
+		srv.dialsched.addStatic(n)
+	}
+	for _, n := range srv.groups.dialed() {
 		srv.dialsched.addStatic(n)
 	}
 }
@@ -707,6 +808,7 @@ func (srv *Server) run() {
 	srv.log.Info("Started P2P networking", "self", srv.localnode.Node().URLv4())
 	defer srv.loopWG.Done()
 	defer srv.nodedb.Close()
+	defer srv.reputation.flush()
 	defer srv.discmix.Close()
 	defer srv.dialsched.stop()
 
@@ -714,7 +816,10 @@ func (srv *Server) run() {
 		peers        = make(map[enode.ID]*Peer)
 		inboundCount = 0
 		trusted      = make(map[enode.ID]bool, len(srv.TrustedNodes))
+		flush        = time.NewTicker(reputationFlushInterval)
 	)
+	defer flush.Stop()
+
 	// Put trusted nodes into a map to speed up checks.
 	// Trusted peers are loaded on startup or added via AddTrustedPeer RPC.
 	for _, n := range srv.TrustedNodes {
@@ -746,6 +851,10 @@ running:
 				p.rw.set(trustedConn, false)
 			}
 
+		case <-flush.C:
+			// Persist the reputation updates of the last interval.
+			srv.reputation.flush()
+
 		case op := <-srv.peerOp:
 			// This channel is used by Peers and PeerCount.
 			op(peers)
@@ -769,6 +878,7 @@ running:
 				// The handshakes are done and it passed all checks.
 				p := srv.launchPeer(c)
 				peers[c.node.ID()] = p
+				srv.groups.connect(c.node.ID())
 				srv.log.Debug("Adding p2p peer", "peercount", len(peers), "id", p.ID(), "conn", c.flags, "addr", p.RemoteAddr(), "name", p.Name())
 				srv.dialsched.peerAdded(c)
 				if p.Inbound() {
@@ -781,6 +891,8 @@ running:
 			// A peer disconnected.
 			d := common.PrettyDuration(mclock.Now() - pd.created)
 			delete(peers, pd.ID())
+			srv.groups.disconnect(pd.ID())
+			srv.reputation.flushNode(pd.ID())
 			srv.log.Debug("Removing p2p peer", "peercount", len(peers), "id", pd.ID(), "duration", d, "req", pd.requested, "err", pd.err)
 			srv.dialsched.peerRemoved(pd.rw)
 			if pd.Inbound() {
@@ -814,7 +926,9 @@ running:
 
 func (srv *Server) postHandshakeChecks(peers map[enode.ID]*Peer, inboundCount int, c *conn) error {
 	switch {
-	case !c.is(trustedConn) && len(peers) >= srv.MaxPeers:
+	case !c.is(trustedConn) && len(peers)+srv.groups.reserved(c.node.ID()) >= srv.MaxPeers:
+		return DiscTooManyPeers
+	case srv.groups.full(c.node.ID()):
 		return DiscTooManyPeers
 	case !c.is(trustedConn) && c.is(inboundConn) && inboundCount >= srv.maxInboundConns():
 		return DiscTooManyPeers
@@ -822,6 +936,8 @@ func (srv *Server) postHandshakeChecks(peers map[enode.ID]*Peer, inboundCount in
 		return DiscAlreadyConnected
 	case c.node.ID() == srv.localnode.ID():
 		return DiscSelf
+	case !c.is(trustedConn|staticDialedConn) && srv.reputation.banned(c.node.ID()):
+		return DiscUselessPeer
 	default:
 		return nil
 	}
@@ -913,6 +1029,10 @@ func (srv *Server) checkInboundConn(remoteIP net.IP) error {
 	if remoteIP == nil {
 		return nil
 	}
+	// Inbound connections are checked by both the TCP and QUIC listeners.
+	srv.inboundLock.Lock()
+	defer srv.inboundLock.Unlock()
+
 	// Reject connections that do not match NetRestrict.
 	if srv.NetRestrict != nil && !srv.NetRestrict.Contains(remoteIP) {
 		return fmt.Errorf("not in netrestrict list")
@@ -932,10 +1052,14 @@ func (srv *Server) checkInboundConn(remoteIP net.IP) error {
 // or the handshakes have failed.
 func (srv *Server) SetupConn(fd net.Conn, flags connFlag, dialDest *enode.Node) error {
 	c := &conn{fd: fd, flags: flags, cont: make(chan error)}
-	if dialDest == nil {
-		c.transport = srv.newTransport(fd, nil)
+	var dialPubkey *ecdsa.PublicKey
+	if dialDest != nil {
+		dialPubkey = dialDest.Pubkey()
+	}
+	if qc, ok := fd.(*quicConn); ok {
+		c.transport = newQUICTransport(qc, dialPubkey, srv.Protocols)
 	} else {
-		c.transport = srv.newTransport(fd, dialDest.Pubkey())
+		c.transport = srv.newTransport(fd, dialPubkey)
 	}
 
 	err := srv.setupConn(c, flags, dialDest)
@@ -1025,11 +1149,20 @@ func (srv *Server) checkpoint(c *conn, stage chan<- *conn) error {
 
 func (srv *Server) launchPeer(c *conn) *Peer {
 	p := newPeer(srv.log, c, srv.Protocols)
+	p.reputation = srv.reputation
 	if srv.EnableMsgEvents {
 		// If message events are enabled, pass the peerFeed
 		// to the peer.
 		p.events = &srv.peerFeed
 	}
+	if srv.RecordDir != "" {
+		rec, err := newPeerRecorder(srv.RecordDir, p)
+		if err != nil {
+			p.log.Warn("Failed to start traffic recording", "err", err)
+		} else {
+			p.recorder = rec
+		}
+	}
 	go srv.runPeer(p)
 	return p
 }
@@ -1048,6 +1181,9 @@ func (srv *Server) runPeer(p *Peer) {
 
 	// Run the per-peer main loop.
 	remoteRequested, err := p.run()
+	if p.recorder != nil {
+		p.recorder.close(err)
+	}
 
 	// Announce disconnect on the main loop to update the peer set.
 	// The main loop waits for existing peers to be sent on srv.delpeer
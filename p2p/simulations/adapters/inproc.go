@@ -40,6 +40,7 @@ type SimAdapter struct {
 	pipe       func() (net.Conn, net.Conn, error)
 	mtx        sync.RWMutex
 	nodes      map[enode.ID]*SimNode
+	links      map[linkKey]*simLink
 	lifecycles LifecycleConstructors
 }
 
@@ -51,6 +52,7 @@ func NewSimAdapter(services LifecycleConstructors) *SimAdapter {
 	return &SimAdapter{
 		pipe:       pipes.NetPipe,
 		nodes:      make(map[enode.ID]*SimNode),
+		links:      make(map[linkKey]*simLink),
 		lifecycles: services,
 	}
 }
@@ -91,17 +93,7 @@ func (s *SimAdapter) NewNode(config *NodeConfig) (Node, error) {
 		return nil, err
 	}
 
-	n, err := node.New(&node.Config{
-		P2P: p2p.Config{
-			PrivateKey:      config.PrivateKey,
-			MaxPeers:        math.MaxInt32,
-			NoDiscovery:     true,
-			Dialer:          s,
-			EnableMsgEvents: config.EnableMsgEvents,
-		},
-		ExternalSigner: config.ExternalSigner,
-		Logger:         log.New("node.id", id.String()),
-	})
+	n, err := s.newStack(config)
 	if err != nil {
 		return nil, err
 	}
@@ -117,27 +109,26 @@ func (s *SimAdapter) NewNode(config *NodeConfig) (Node, error) {
 	return simNode, nil
 }
 
+// newStack creates the devp2p node of a simulation node. Nodes dial each other
+// through the adapter, subject to the conditions of the link between them.
+func (s *SimAdapter) newStack(config *NodeConfig) (*node.Node, error) {
+	return node.New(&node.Config{
+		P2P: p2p.Config{
+			PrivateKey:      config.PrivateKey,
+			MaxPeers:        math.MaxInt32,
+			NoDiscovery:     true,
+			Dialer:          &simDialer{adapter: s, self: config.ID},
+			EnableMsgEvents: config.EnableMsgEvents,
+		},
+		ExternalSigner: config.ExternalSigner,
+		Logger:         log.New("node.id", config.ID.String()),
+	})
+}
+
 // Dial implements the p2p.NodeDialer interface by connecting to the node using
 // an in-memory net.Pipe
 func (s *SimAdapter) Dial(ctx context.Context, dest *enode.Node) (conn net.Conn, err error) {
-	node, ok := s.GetNode(dest.ID())
-	if !ok {
-		return nil, fmt.Errorf("unknown node: %s", dest.ID())
-	}
-	srv := node.Server()
-	if srv == nil {
-		return nil, fmt.Errorf("node not running: %s", dest.ID())
-	}
-	// SimAdapter.pipe is net.Pipe (NewSimAdapter)
-	pipe1, pipe2, err := s.pipe()
-	if err != nil {
-		return nil, err
-	}
-	// this is simulated 'listening'
-	// asynchronously call the dialed destination node's p2p server
-	// to set up connection on the 'listening' side
-	go srv.SetupConn(pipe1, 0, nil)
-	return pipe2, nil
+	return s.dial(enode.ID{}, dest)
 }
 
 // DialRPC implements the RPCDialer interface by creating an in-memory RPC
@@ -147,7 +138,7 @@ func (s *SimAdapter) DialRPC(id enode.ID) (*rpc.Client, error) {
 	if !ok {
 		return nil, fmt.Errorf("unknown node: %s", id)
 	}
-	return node.node.Attach()
+	return node.stack().Attach()
 }
 
 // GetNode returns the node with the given ID if it exists
@@ -170,12 +161,21 @@ type SimNode struct {
 	running      map[string]node.Lifecycle
 	client       *rpc.Client
 	registerOnce sync.Once
+	stopped      bool
+}
+
+// stack returns the underlying node.Node, which is replaced when a stopped
+// node is started again.
+func (sn *SimNode) stack() *node.Node {
+	sn.lock.RLock()
+	defer sn.lock.RUnlock()
+	return sn.node
 }
 
 // Close closes the underlaying node.Node to release
 // acquired resources.
 func (sn *SimNode) Close() error {
-	return sn.node.Close()
+	return sn.stack().Close()
 }
 
 // Addr returns the node's discovery address
@@ -202,7 +202,7 @@ func (sn *SimNode) Client() (*rpc.Client, error) {
 // ServeRPC serves RPC requests over the given connection by creating an
 // in-memory client to the node's RPC server.
 func (sn *SimNode) ServeRPC(conn *websocket.Conn) error {
-	handler, err := sn.node.RPCHandler()
+	handler, err := sn.stack().RPCHandler()
 	if err != nil {
 		return err
 	}
@@ -238,10 +238,27 @@ func (sn *SimNode) Snapshots() (map[string][]byte, error) {
 	return snapshots, nil
 }
 
-// Start registers the services and starts the underlying devp2p node
+// Start registers the services and starts the underlying devp2p node. A node
+// which was stopped is recreated, its services are constructed again from the
+// given snapshots.
 func (sn *SimNode) Start(snapshots map[string][]byte) error {
+	sn.lock.Lock()
+	if sn.stopped {
+		stack, err := sn.adapter.newStack(sn.config)
+		if err != nil {
+			sn.lock.Unlock()
+			return err
+		}
+		sn.node = stack
+		sn.running = make(map[string]node.Lifecycle)
+		sn.registerOnce = sync.Once{}
+		sn.stopped = false
+	}
+	stack := sn.node
+	sn.lock.Unlock()
+
 	// ensure we only register the services once in the case of the node
-	// being stopped and then started again
+	// being started again after a failure
 	var regErr error
 	sn.registerOnce.Do(func() {
 		for _, name := range sn.config.Lifecycles {
@@ -253,7 +270,7 @@ func (sn *SimNode) Start(snapshots map[string][]byte) error {
 				ctx.Snapshot = snapshots[name]
 			}
 			serviceFunc := sn.adapter.lifecycles[name]
-			service, err := serviceFunc(ctx, sn.node)
+			service, err := serviceFunc(ctx, stack)
 			if err != nil {
 				regErr = err
 				break
@@ -269,12 +286,12 @@ func (sn *SimNode) Start(snapshots map[string][]byte) error {
 		return regErr
 	}
 
-	if err := sn.node.Start(); err != nil {
+	if err := stack.Start(); err != nil {
 		return err
 	}
 
 	// create an in-process RPC client
-	client, err := sn.node.Attach()
+	client, err := stack.Attach()
 	if err != nil {
 		return err
 	}
@@ -292,8 +309,10 @@ func (sn *SimNode) Stop() error {
 		sn.client.Close()
 		sn.client = nil
 	}
+	stack := sn.node
+	sn.stopped = true
 	sn.lock.Unlock()
-	return sn.node.Close()
+	return stack.Close()
 }
 
 // Service returns a running service by name
@@ -327,7 +346,7 @@ func (sn *SimNode) ServiceMap() map[string]node.Lifecycle {
 
 // Server returns the underlying p2p.Server
 func (sn *SimNode) Server() *p2p.Server {
-	return sn.node.Server()
+	return sn.stack().Server()
 }
 
 // SubscribeEvents subscribes the given channel to peer events from the
@@ -0,0 +1,224 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+const (
+	// retransmitTimeout is the delay added to a write for each simulated packet loss.
+	retransmitTimeout = 200 * time.Millisecond
+	// maxRetransmits caps the number of losses simulated for a single write.
+	maxRetransmits = 5
+)
+
+var errLinkDown = errors.New("link is down")
+
+// LinkConfig describes the simulated conditions of the link between two nodes.
+//
+// Simulated connections are reliable streams, so packet loss does not corrupt the
+// stream. Instead, each lost packet delays the write by a retransmission timeout,
+// which is how loss manifests itself on TCP connections.
+type LinkConfig struct {
+	Latency  time.Duration `json:"latency,omitempty"`  // delay of each write
+	DropRate float64       `json:"dropRate,omitempty"` // probability of a write being lost
+	Down     bool          `json:"down,omitempty"`     // no traffic passes, e.g. during a partition
+}
+
+// LinkConditioner is implemented by node adapters which can simulate faulty links
+// between nodes.
+type LinkConditioner interface {
+	// SetLink sets the conditions of the link between two nodes. Existing
+	// connections between the nodes are closed when the link goes down.
+	SetLink(one, other enode.ID, config LinkConfig)
+
+	// Link returns the conditions of the link between two nodes.
+	Link(one, other enode.ID) LinkConfig
+
+	// Connect connects two running nodes directly, without going through the
+	// dialer of the initiating node.
+	Connect(one, other enode.ID) error
+}
+
+// linkKey identifies the link between two nodes regardless of direction.
+type linkKey [2]enode.ID
+
+func newLinkKey(one, other enode.ID) linkKey {
+	if bytes.Compare(one[:], other[:]) > 0 {
+		one, other = other, one
+	}
+	return linkKey{one, other}
+}
+
+// simLink is the state of a conditioned link.
+type simLink struct {
+	config LinkConfig
+	conns  map[*linkConn]struct{}
+}
+
+// SetLink implements LinkConditioner.
+func (s *SimAdapter) SetLink(one, other enode.ID, config LinkConfig) {
+	s.mtx.Lock()
+	key := newLinkKey(one, other)
+	link := s.links[key]
+	if link == nil {
+		link = &simLink{conns: make(map[*linkConn]struct{})}
+		s.links[key] = link
+	}
+	link.config = config
+	var closing []*linkConn
+	if config.Down {
+		for c := range link.conns {
+			closing = append(closing, c)
+		}
+	}
+	s.mtx.Unlock()
+
+	for _, c := range closing {
+		c.Close()
+	}
+}
+
+// Link implements LinkConditioner.
+func (s *SimAdapter) Link(one, other enode.ID) LinkConfig {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	if link := s.links[newLinkKey(one, other)]; link != nil {
+		return link.config
+	}
+	return LinkConfig{}
+}
+
+// Connect implements LinkConditioner.
+func (s *SimAdapter) Connect(one, other enode.ID) error {
+	src, ok := s.GetNode(one)
+	if !ok {
+		return fmt.Errorf("unknown node: %s", one)
+	}
+	srv := src.Server()
+	if srv == nil {
+		return fmt.Errorf("node not running: %s", one)
+	}
+	dest, ok := s.GetNode(other)
+	if !ok {
+		return fmt.Errorf("unknown node: %s", other)
+	}
+	conn, err := s.dial(one, dest.Node())
+	if err != nil {
+		return err
+	}
+	go srv.SetupConn(conn, 0, dest.Node())
+	return nil
+}
+
+// dial connects the source node to dest over a conditioned pipe.
+func (s *SimAdapter) dial(src enode.ID, dest *enode.Node) (net.Conn, error) {
+	node, ok := s.GetNode(dest.ID())
+	if !ok {
+		return nil, fmt.Errorf("unknown node: %s", dest.ID())
+	}
+	srv := node.Server()
+	if srv == nil {
+		return nil, fmt.Errorf("node not running: %s", dest.ID())
+	}
+	if s.Link(src, dest.ID()).Down {
+		return nil, errLinkDown
+	}
+	// SimAdapter.pipe is net.Pipe (NewSimAdapter)
+	pipe1, pipe2, err := s.pipe()
+	if err != nil {
+		return nil, err
+	}
+	key := newLinkKey(src, dest.ID())
+	listener, dialer := s.newLinkConn(key, pipe1), s.newLinkConn(key, pipe2)
+
+	// this is simulated 'listening'
+	// asynchronously call the dialed destination node's p2p server
+	// to set up connection on the 'listening' side
+	go srv.SetupConn(listener, 0, nil)
+	return dialer, nil
+}
+
+// linkConn is a connection whose writes are subject to the conditions of a link.
+type linkConn struct {
+	net.Conn
+	adapter *SimAdapter
+	key     linkKey
+}
+
+func (s *SimAdapter) newLinkConn(key linkKey, conn net.Conn) *linkConn {
+	c := &linkConn{Conn: conn, adapter: s, key: key}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	link := s.links[key]
+	if link == nil {
+		link = &simLink{conns: make(map[*linkConn]struct{})}
+		s.links[key] = link
+	}
+	link.conns[c] = struct{}{}
+	return c
+}
+
+// Write delays the write according to the link conditions.
+func (c *linkConn) Write(b []byte) (int, error) {
+	config := c.adapter.Link(c.key[0], c.key[1])
+	if config.Down {
+		c.Close()
+		return 0, errLinkDown
+	}
+	delay := config.Latency
+	for i := 0; i < maxRetransmits && rand.Float64() < config.DropRate; i++ {
+		delay += retransmitTimeout
+	}
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	return c.Conn.Write(b)
+}
+
+// Close closes the connection and removes it from its link.
+func (c *linkConn) Close() error {
+	c.adapter.mtx.Lock()
+	if link := c.adapter.links[c.key]; link != nil {
+		delete(link.conns, c)
+	}
+	c.adapter.mtx.Unlock()
+	return c.Conn.Close()
+}
+
+// simDialer dials other simulation nodes on behalf of a node, applying the
+// conditions of the link between them.
+type simDialer struct {
+	adapter *SimAdapter
+	self    enode.ID
+}
+
+// Dial implements the p2p.NodeDialer interface.
+func (d *simDialer) Dial(ctx context.Context, dest *enode.Node) (net.Conn, error) {
+	return d.adapter.dial(d.self, dest)
+}
@@ -168,6 +168,12 @@ func (c *Client) SubscribeNetwork(events chan *Event, opts SubscribeOpts) (event
 	return event.NewSubscription(producer), nil
 }
 
+// RunScenario runs a scenario in the network and returns its result
+func (c *Client) RunScenario(sc *Scenario) (*ScenarioResult, error) {
+	result := &ScenarioResult{}
+	return result, c.Post("/scenario", sc, result)
+}
+
 // GetNodes returns all nodes which exist in the network
 func (c *Client) GetNodes() ([]*p2p.NodeInfo, error) {
 	var nodes []*p2p.NodeInfo
@@ -289,6 +295,7 @@ func NewServer(network *Network) *Server {
 	s.GET("/events", s.StreamNetworkEvents)
 	s.GET("/snapshot", s.CreateSnapshot)
 	s.POST("/snapshot", s.LoadSnapshot)
+	s.POST("/scenario", s.RunScenario)
 	s.POST("/nodes", s.CreateNode)
 	s.GET("/nodes", s.GetNodes)
 	s.GET("/nodes/:nodeid", s.GetNode)
@@ -555,6 +562,23 @@ func (s *Server) LoadSnapshot(w http.ResponseWriter, req *http.Request) {
 	s.JSON(w, http.StatusOK, s.network)
 }
 
+// RunScenario runs a scenario in the network
+func (s *Server) RunScenario(w http.ResponseWriter, req *http.Request) {
+	sc := &Scenario{}
+	if err := json.NewDecoder(req.Body).Decode(sc); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := s.network.RunScenario(req.Context(), sc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.JSON(w, http.StatusOK, result)
+}
+
 // CreateNode creates a node in the network using the given configuration
 func (s *Server) CreateNode(w http.ResponseWriter, req *http.Request) {
 	config := &adapters.NodeConfig{}
@@ -604,6 +604,29 @@ func (net *Network) GetConn(oneID, otherID enode.ID) *Conn {
 	return net.getConn(oneID, otherID)
 }
 
+// GetNodeConns returns copies of the active connections of the node with the
+// given ID
+func (net *Network) GetNodeConns(id enode.ID) []Conn {
+	net.lock.RLock()
+	defer net.lock.RUnlock()
+	var conns []Conn
+	for _, conn := range net.Conns {
+		if conn.Up && (conn.One == id || conn.Other == id) {
+			conns = append(conns, *conn)
+		}
+	}
+	return conns
+}
+
+// connected returns whether there is an active connection between "one" and
+// "other"
+func (net *Network) connected(oneID, otherID enode.ID) bool {
+	net.lock.RLock()
+	defer net.lock.RUnlock()
+	conn := net.getConn(oneID, otherID)
+	return conn != nil && conn.Up
+}
+
 // GetOrCreateConn is like GetConn but creates the connection if it doesn't
 // already exist
 func (net *Network) GetOrCreateConn(oneID, otherID enode.ID) (*Conn, error) {
@@ -0,0 +1,610 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package simulations
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/simulations/adapters"
+	"gopkg.in/yaml.v3"
+)
+
+// Scenario topologies.
+const (
+	TopologyFull  = "full"
+	TopologyRing  = "ring"
+	TopologyChain = "chain"
+	TopologyStar  = "star" // the first node is the center
+)
+
+// Scenario event types.
+const (
+	EventPartition  = "partition"  // cuts the links between Groups
+	EventHeal       = "heal"       // restores all partitioned links and connections
+	EventKill       = "kill"       // stops Nodes
+	EventRestart    = "restart"    // (re)starts Nodes and restores their connections
+	EventConnect    = "connect"    // connects Nodes to Peers
+	EventDisconnect = "disconnect" // disconnects Nodes from Peers
+	EventLink       = "link"       // sets the latency and drop rate between Nodes and Peers
+)
+
+// Scenario assertion types.
+const (
+	AssertConnected    = "connected"    // Nodes are connected to Peers
+	AssertDisconnected = "disconnected" // Nodes are not connected to Peers
+	AssertUp           = "up"           // Nodes are running
+	AssertDown         = "down"         // Nodes are stopped
+	AssertPeers        = "peers"        // Nodes have at least Min peers
+)
+
+const (
+	defaultAssertionTimeout = 10 * time.Second
+	assertionPollInterval   = 100 * time.Millisecond
+)
+
+// Scenario describes a simulation run: the nodes of the network, the conditions of
+// the links between them and a timeline of events and assertions. Nodes are referred
+// to by name, where the name of a node set refers to all nodes of the set. If Peers
+// is empty in an event or assertion, it applies between all Nodes.
+type Scenario struct {
+	Name       string              `json:"name" yaml:"name"`
+	Nodes      []ScenarioNodeSet   `json:"nodes" yaml:"nodes"`
+	Topology   string              `json:"topology,omitempty" yaml:"topology"`
+	Links      []ScenarioLink      `json:"links,omitempty" yaml:"links"`
+	Events     []ScenarioEvent     `json:"events,omitempty" yaml:"events"`
+	Assertions []ScenarioAssertion `json:"assertions,omitempty" yaml:"assertions"`
+}
+
+// ScenarioNodeSet is a set of nodes running the same services. The nodes of a set
+// with Count > 1 are named <name>-<index>.
+type ScenarioNodeSet struct {
+	Name     string   `json:"name" yaml:"name"`
+	Count    int      `json:"count,omitempty" yaml:"count"` // defaults to 1
+	Services []string `json:"services,omitempty" yaml:"services"`
+}
+
+// ScenarioLink sets the conditions of the links between nodes.
+type ScenarioLink struct {
+	Nodes    []string      `json:"nodes" yaml:"nodes"`
+	Peers    []string      `json:"peers,omitempty" yaml:"peers"`
+	Latency  time.Duration `json:"latency,omitempty" yaml:"latency"`
+	DropRate float64       `json:"dropRate,omitempty" yaml:"dropRate"`
+}
+
+// ScenarioEvent is an action performed at a point of time after the start of the
+// scenario.
+type ScenarioEvent struct {
+	At       time.Duration `json:"at" yaml:"at"`
+	Type     string        `json:"type" yaml:"type"`
+	Nodes    []string      `json:"nodes,omitempty" yaml:"nodes"`
+	Peers    []string      `json:"peers,omitempty" yaml:"peers"`
+	Groups   [][]string    `json:"groups,omitempty" yaml:"groups"`     // for partition
+	Latency  time.Duration `json:"latency,omitempty" yaml:"latency"`   // for link
+	DropRate float64       `json:"dropRate,omitempty" yaml:"dropRate"` // for link
+}
+
+// ScenarioAssertion is a condition checked at a point of time after the start of
+// the scenario. The condition is polled until it holds or the timeout expires.
+// Later events are delayed while an assertion is checked.
+type ScenarioAssertion struct {
+	At      time.Duration `json:"at" yaml:"at"`
+	Type    string        `json:"type" yaml:"type"`
+	Nodes   []string      `json:"nodes" yaml:"nodes"`
+	Peers   []string      `json:"peers,omitempty" yaml:"peers"`
+	Min     int           `json:"min,omitempty" yaml:"min"`         // for peers
+	Timeout time.Duration `json:"timeout,omitempty" yaml:"timeout"` // defaults to 10s
+}
+
+// ScenarioResult is the outcome of a scenario run.
+type ScenarioResult struct {
+	Name       string            `json:"name"`
+	StartedAt  time.Time         `json:"startedAt"`
+	FinishedAt time.Time         `json:"finishedAt"`
+	Assertions []AssertionResult `json:"assertions"`
+}
+
+// AssertionResult is the outcome of a scenario assertion.
+type AssertionResult struct {
+	ScenarioAssertion
+	Passed bool   `json:"passed"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Passed reports whether all assertions of the scenario passed.
+func (r *ScenarioResult) Passed() bool {
+	for _, a := range r.Assertions {
+		if !a.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// LoadScenario parses a scenario in YAML or JSON format.
+func LoadScenario(data []byte) (*Scenario, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	sc := new(Scenario)
+	if err := dec.Decode(sc); err != nil {
+		return nil, err
+	}
+	if err := sc.validate(); err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+// nodeNames returns the names of all nodes, grouped by node set.
+func (sc *Scenario) nodeNames() (map[string][]string, error) {
+	sets := make(map[string][]string)
+	for _, set := range sc.Nodes {
+		if set.Name == "" {
+			return nil, errors.New("node set without name")
+		}
+		if _, ok := sets[set.Name]; ok {
+			return nil, fmt.Errorf("duplicate node set %q", set.Name)
+		}
+		switch {
+		case set.Count < 0:
+			return nil, fmt.Errorf("node set %q has negative count", set.Name)
+		case set.Count <= 1:
+			sets[set.Name] = []string{set.Name}
+		default:
+			for i := 0; i < set.Count; i++ {
+				sets[set.Name] = append(sets[set.Name], fmt.Sprintf("%s-%d", set.Name, i))
+			}
+		}
+	}
+	return sets, nil
+}
+
+// validate checks the scenario for unknown types and node names.
+func (sc *Scenario) validate() error {
+	sets, err := sc.nodeNames()
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool)
+	for set, names := range sets {
+		known[set] = true
+		for _, name := range names {
+			known[name] = true
+		}
+	}
+	check := func(what string, names []string) error {
+		for _, name := range names {
+			if !known[name] {
+				return fmt.Errorf("%s refers to unknown node %q", what, name)
+			}
+		}
+		return nil
+	}
+	switch sc.Topology {
+	case "", TopologyFull, TopologyRing, TopologyChain, TopologyStar:
+	default:
+		return fmt.Errorf("unknown topology %q", sc.Topology)
+	}
+	for _, l := range sc.Links {
+		if err := check("link", append(l.Nodes, l.Peers...)); err != nil {
+			return err
+		}
+	}
+	for _, ev := range sc.Events {
+		switch ev.Type {
+		case EventPartition:
+			if len(ev.Groups) < 2 {
+				return errors.New("partition needs at least two groups")
+			}
+			for _, g := range ev.Groups {
+				if err := check(ev.Type, g); err != nil {
+					return err
+				}
+			}
+		case EventHeal, EventKill, EventRestart, EventConnect, EventDisconnect, EventLink:
+		default:
+			return fmt.Errorf("unknown event type %q", ev.Type)
+		}
+		if err := check(ev.Type, append(ev.Nodes, ev.Peers...)); err != nil {
+			return err
+		}
+	}
+	for _, a := range sc.Assertions {
+		switch a.Type {
+		case AssertConnected, AssertDisconnected, AssertUp, AssertDown, AssertPeers:
+		default:
+			return fmt.Errorf("unknown assertion type %q", a.Type)
+		}
+		if err := check(a.Type, append(a.Nodes, a.Peers...)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scenarioRun is the state of a running scenario.
+type scenarioRun struct {
+	net       *Network
+	links     adapters.LinkConditioner // nil if unsupported by the adapter
+	ids       map[string][]enode.ID    // by node and node set name
+	order     []enode.ID               // in declaration order
+	cut       [][2]enode.ID            // partitioned links
+	cutConns  [][2]enode.ID            // connections closed by the partition
+	killed    map[enode.ID][][2]enode.ID
+	snapshots map[enode.ID]map[string][]byte
+}
+
+// RunScenario creates the nodes of the scenario, connects them and executes the
+// timeline of events and assertions. Failed assertions are reported in the result,
+// an error is returned if the scenario cannot be executed. Simulating link faults
+// requires a node adapter implementing adapters.LinkConditioner.
+func (net *Network) RunScenario(ctx context.Context, sc *Scenario) (*ScenarioResult, error) {
+	if err := sc.validate(); err != nil {
+		return nil, err
+	}
+	r := &scenarioRun{
+		net:       net,
+		ids:       make(map[string][]enode.ID),
+		killed:    make(map[enode.ID][][2]enode.ID),
+		snapshots: make(map[enode.ID]map[string][]byte),
+	}
+	r.links, _ = net.nodeAdapter.(adapters.LinkConditioner)
+	if r.links == nil && sc.needsLinks() {
+		return nil, fmt.Errorf("adapter %s cannot simulate link faults", net.nodeAdapter.Name())
+	}
+	if err := r.setup(sc); err != nil {
+		return nil, err
+	}
+
+	// Execute the timeline.
+	result := &ScenarioResult{Name: sc.Name, StartedAt: time.Now()}
+	for _, item := range sc.timeline() {
+		if err := sleepUntil(ctx, result.StartedAt.Add(item.at)); err != nil {
+			return nil, err
+		}
+		if item.event != nil {
+			log.Info("Executing scenario event", "scenario", sc.Name, "type", item.event.Type, "at", item.at)
+			if err := r.execute(item.event); err != nil {
+				return nil, fmt.Errorf("%s event at %v: %v", item.event.Type, item.at, err)
+			}
+			continue
+		}
+		res := AssertionResult{ScenarioAssertion: *item.assertion, Passed: true}
+		if err := r.check(ctx, item.assertion); err != nil {
+			res.Passed, res.Error = false, err.Error()
+		}
+		result.Assertions = append(result.Assertions, res)
+	}
+	result.FinishedAt = time.Now()
+	return result, nil
+}
+
+// needsLinks reports whether the scenario simulates link conditions.
+func (sc *Scenario) needsLinks() bool {
+	if len(sc.Links) > 0 {
+		return true
+	}
+	for _, ev := range sc.Events {
+		if ev.Type == EventPartition || ev.Type == EventHeal || ev.Type == EventLink {
+			return true
+		}
+	}
+	return false
+}
+
+type timelineItem struct {
+	at        time.Duration
+	event     *ScenarioEvent
+	assertion *ScenarioAssertion
+}
+
+// timeline returns the events and assertions ordered by time. Events happening at
+// the same time as assertions go first.
+func (sc *Scenario) timeline() []timelineItem {
+	var items []timelineItem
+	for i := range sc.Events {
+		items = append(items, timelineItem{at: sc.Events[i].At, event: &sc.Events[i]})
+	}
+	for i := range sc.Assertions {
+		items = append(items, timelineItem{at: sc.Assertions[i].At, assertion: &sc.Assertions[i]})
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].at < items[j].at })
+	return items
+}
+
+// setup creates and starts the nodes, applies link conditions and connects the
+// nodes according to the topology.
+func (r *scenarioRun) setup(sc *Scenario) error {
+	sets, _ := sc.nodeNames()
+	for _, set := range sc.Nodes {
+		for _, name := range sets[set.Name] {
+			conf := adapters.RandomNodeConfig()
+			conf.Name = name
+			conf.Lifecycles = set.Services
+			node, err := r.net.NewNodeWithConfig(conf)
+			if err != nil {
+				return err
+			}
+			r.ids[name] = []enode.ID{node.ID()}
+			r.ids[set.Name] = append(r.ids[set.Name], node.ID())
+			r.order = append(r.order, node.ID())
+		}
+	}
+	for _, id := range r.order {
+		if err := r.net.Start(id); err != nil {
+			return err
+		}
+	}
+	for _, l := range sc.Links {
+		r.setLinks(l.Nodes, l.Peers, l.Latency, l.DropRate)
+	}
+	switch sc.Topology {
+	case TopologyFull:
+		return r.net.ConnectNodesFull(r.order)
+	case TopologyRing:
+		return r.net.ConnectNodesRing(r.order)
+	case TopologyChain:
+		return r.net.ConnectNodesChain(r.order)
+	case TopologyStar:
+		if len(r.order) == 0 {
+			return nil
+		}
+		return r.net.ConnectNodesStar(r.order[1:], r.order[0])
+	}
+	return nil
+}
+
+// resolve returns the IDs of the given nodes and node sets.
+func (r *scenarioRun) resolve(names []string) []enode.ID {
+	var (
+		ids  []enode.ID
+		seen = make(map[enode.ID]bool)
+	)
+	for _, name := range names {
+		for _, id := range r.ids[name] {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// pairs returns the node pairs between nodes and peers. If peers is empty, all
+// pairs of nodes are returned.
+func (r *scenarioRun) pairs(nodes, peers []string) [][2]enode.ID {
+	var (
+		ids   = r.resolve(nodes)
+		pairs [][2]enode.ID
+	)
+	if len(peers) == 0 {
+		for i := range ids {
+			for j := i + 1; j < len(ids); j++ {
+				pairs = append(pairs, [2]enode.ID{ids[i], ids[j]})
+			}
+		}
+		return pairs
+	}
+	for _, a := range ids {
+		for _, b := range r.resolve(peers) {
+			if a != b {
+				pairs = append(pairs, [2]enode.ID{a, b})
+			}
+		}
+	}
+	return pairs
+}
+
+// setLinks sets the latency and drop rate of the links between nodes and peers.
+func (r *scenarioRun) setLinks(nodes, peers []string, latency time.Duration, dropRate float64) {
+	for _, p := range r.pairs(nodes, peers) {
+		config := r.links.Link(p[0], p[1])
+		config.Latency, config.DropRate = latency, dropRate
+		r.links.SetLink(p[0], p[1], config)
+	}
+}
+
+// execute performs a scenario event.
+func (r *scenarioRun) execute(ev *ScenarioEvent) error {
+	switch ev.Type {
+	case EventPartition:
+		for i := range ev.Groups {
+			for j := i + 1; j < len(ev.Groups); j++ {
+				for _, a := range r.resolve(ev.Groups[i]) {
+					for _, b := range r.resolve(ev.Groups[j]) {
+						r.cutLink(a, b)
+					}
+				}
+			}
+		}
+	case EventHeal:
+		for _, p := range r.cut {
+			config := r.links.Link(p[0], p[1])
+			config.Down = false
+			r.links.SetLink(p[0], p[1], config)
+		}
+		r.cut = nil
+		r.reconnect(r.cutConns)
+		r.cutConns = nil
+	case EventKill:
+		for _, id := range r.resolve(ev.Nodes) {
+			if err := r.kill(id); err != nil {
+				return err
+			}
+		}
+	case EventRestart:
+		for _, id := range r.resolve(ev.Nodes) {
+			if node := r.net.GetNode(id); node.Up() {
+				if err := r.kill(id); err != nil {
+					return err
+				}
+			}
+			if err := r.net.startWithSnapshots(id, r.snapshots[id]); err != nil {
+				return err
+			}
+			r.reconnect(r.killed[id])
+			delete(r.killed, id)
+		}
+	case EventConnect:
+		for _, p := range r.pairs(ev.Nodes, ev.Peers) {
+			r.net.lock.Lock()
+			err := r.net.connectNotConnected(p[0], p[1])
+			r.net.lock.Unlock()
+			if err != nil {
+				return err
+			}
+		}
+	case EventDisconnect:
+		for _, p := range r.pairs(ev.Nodes, ev.Peers) {
+			if r.net.connected(p[0], p[1]) {
+				conn := r.net.GetConn(p[0], p[1])
+				if err := r.net.Disconnect(conn.One, conn.Other); err != nil {
+					return err
+				}
+			}
+		}
+	case EventLink:
+		r.setLinks(ev.Nodes, ev.Peers, ev.Latency, ev.DropRate)
+	}
+	return nil
+}
+
+// cutLink takes down the link between two nodes, remembering the connection
+// between them for healing.
+func (r *scenarioRun) cutLink(a, b enode.ID) {
+	if r.net.connected(a, b) {
+		conn := r.net.GetConn(a, b)
+		r.cutConns = append(r.cutConns, [2]enode.ID{conn.One, conn.Other})
+	}
+	config := r.links.Link(a, b)
+	config.Down = true
+	r.links.SetLink(a, b, config)
+	r.cut = append(r.cut, [2]enode.ID{a, b})
+}
+
+// kill stops a node, remembering its connections and service state for restarts.
+func (r *scenarioRun) kill(id enode.ID) error {
+	node := r.net.GetNode(id)
+	if snapshots, err := node.Snapshots(); err == nil {
+		r.snapshots[id] = snapshots
+	}
+	for _, conn := range r.net.GetNodeConns(id) {
+		r.killed[id] = append(r.killed[id], [2]enode.ID{conn.One, conn.Other})
+	}
+	return r.net.Stop(id)
+}
+
+// reconnect restores connections between nodes which are up. The adapter
+// connects the nodes directly if possible, bypassing the dial history of
+// the initiating node.
+func (r *scenarioRun) reconnect(conns [][2]enode.ID) {
+	for _, c := range conns {
+		one, other := r.net.GetNode(c[0]), r.net.GetNode(c[1])
+		if !one.Up() || !other.Up() {
+			continue
+		}
+		if r.net.connected(c[0], c[1]) {
+			continue
+		}
+		var err error
+		if r.links != nil {
+			err = r.links.Connect(c[0], c[1])
+		} else {
+			err = r.net.Connect(c[0], c[1])
+		}
+		if err != nil {
+			log.Warn("Could not restore connection", "one", c[0], "other", c[1], "err", err)
+		}
+	}
+}
+
+// check polls an assertion until it holds or times out.
+func (r *scenarioRun) check(ctx context.Context, a *ScenarioAssertion) error {
+	timeout := a.Timeout
+	if timeout == 0 {
+		timeout = defaultAssertionTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(assertionPollInterval)
+	defer ticker.Stop()
+	for {
+		err := r.holds(a)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// holds checks whether an assertion holds at this moment.
+func (r *scenarioRun) holds(a *ScenarioAssertion) error {
+	switch a.Type {
+	case AssertConnected, AssertDisconnected:
+		for _, p := range r.pairs(a.Nodes, a.Peers) {
+			connected := r.net.connected(p[0], p[1])
+			if a.Type == AssertConnected && !connected {
+				return fmt.Errorf("%s not connected to %s", r.name(p[0]), r.name(p[1]))
+			}
+			if a.Type == AssertDisconnected && connected {
+				return fmt.Errorf("%s connected to %s", r.name(p[0]), r.name(p[1]))
+			}
+		}
+	case AssertUp, AssertDown:
+		for _, id := range r.resolve(a.Nodes) {
+			if r.net.GetNode(id).Up() != (a.Type == AssertUp) {
+				return fmt.Errorf("%s is not %s", r.name(id), a.Type)
+			}
+		}
+	case AssertPeers:
+		for _, id := range r.resolve(a.Nodes) {
+			if n := len(r.net.GetNodeConns(id)); n < a.Min {
+				return fmt.Errorf("%s has %d peers, want at least %d", r.name(id), n, a.Min)
+			}
+		}
+	}
+	return nil
+}
+
+// name returns the scenario name of a node.
+func (r *scenarioRun) name(id enode.ID) string {
+	return r.net.GetNode(id).Config.Name
+}
+
+// sleepUntil waits until the given time or until the context is canceled.
+func sleepUntil(ctx context.Context, t time.Time) error {
+	timer := time.NewTimer(time.Until(t))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
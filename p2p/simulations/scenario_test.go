@@ -0,0 +1,171 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package simulations
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/simulations/adapters"
+)
+
+// idleService runs a protocol which keeps peers connected until they are dropped.
+// Unlike testService, its peers can reconnect after being disconnected.
+type idleService struct{}
+
+func newIdleService(ctx *adapters.ServiceContext, stack *node.Node) (node.Lifecycle, error) {
+	stack.RegisterProtocols([]p2p.Protocol{{
+		Name:    "idle",
+		Version: 1,
+		Length:  1,
+		Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+			for {
+				if _, err := rw.ReadMsg(); err != nil {
+					return err
+				}
+			}
+		},
+	}})
+	return idleService{}, nil
+}
+
+func (idleService) Start() error { return nil }
+func (idleService) Stop() error  { return nil }
+
+const testScenario = `
+name: faults
+nodes:
+  - name: a
+    count: 2
+  - name: b
+    count: 2
+topology: full
+links:
+  - nodes: [a]
+    peers: [b]
+    latency: 10ms
+    dropRate: 0.01
+events:
+  - {at: 500ms, type: partition, groups: [[a], [b]]}
+  - {at: 1500ms, type: heal}
+  - {at: 2500ms, type: kill, nodes: [a-0]}
+  - {at: 3s, type: restart, nodes: [a-0]}
+assertions:
+  - {at: 0s, type: connected, nodes: [a, b]}
+  - {at: 1s, type: disconnected, nodes: [a], peers: [b]}
+  - {at: 1s, type: connected, nodes: [a]}
+  - {at: 2s, type: connected, nodes: [a, b]}
+  - {at: 2600ms, type: down, nodes: [a-0]}
+  - {at: 2600ms, type: peers, nodes: [a-1, b], min: 2}
+  - {at: 3s, type: connected, nodes: [a, b]}
+`
+
+func TestLoadScenario(t *testing.T) {
+	sc, err := LoadScenario([]byte(testScenario))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sc.Nodes) != 2 || sc.Nodes[0].Count != 2 || sc.Topology != TopologyFull {
+		t.Errorf("wrong nodes decoded: %+v", sc.Nodes)
+	}
+	if sc.Links[0].Latency != 10*time.Millisecond || sc.Links[0].DropRate != 0.01 {
+		t.Errorf("wrong link decoded: %+v", sc.Links[0])
+	}
+	if len(sc.Events) != 4 || sc.Events[1].At != 1500*time.Millisecond || len(sc.Events[0].Groups) != 2 {
+		t.Errorf("wrong events decoded: %+v", sc.Events)
+	}
+
+	// JSON is accepted as well.
+	sc, err = LoadScenario([]byte(`{"name": "json", "nodes": [{"name": "a"}], "assertions": [{"at": "1s", "type": "up", "nodes": ["a"]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc.Assertions[0].At != time.Second {
+		t.Errorf("wrong assertion decoded: %+v", sc.Assertions[0])
+	}
+
+	invalid := []string{
+		`{"nodes": [{"name": "a"}, {"name": "a"}]}`,
+		`{"nodes": [{"name": "a"}], "topology": "mesh"}`,
+		`{"nodes": [{"name": "a"}], "events": [{"type": "explode", "nodes": ["a"]}]}`,
+		`{"nodes": [{"name": "a"}], "events": [{"type": "kill", "nodes": ["b"]}]}`,
+		`{"nodes": [{"name": "a"}], "events": [{"type": "partition", "groups": [["a"]]}]}`,
+		`{"nodes": [{"name": "a"}], "assertions": [{"type": "up", "nodes": ["a-0"]}]}`,
+		`{"nodes": [{"name": "a", "size": 2}]}`,
+	}
+	for _, input := range invalid {
+		if _, err := LoadScenario([]byte(input)); err == nil {
+			t.Errorf("expected error for %s", input)
+		}
+	}
+}
+
+func TestRunScenario(t *testing.T) {
+	sc, err := LoadScenario([]byte(testScenario))
+	if err != nil {
+		t.Fatal(err)
+	}
+	services := adapters.LifecycleConstructors{"idle": newIdleService}
+	network := NewNetwork(adapters.NewSimAdapter(services), &NetworkConfig{DefaultService: "idle"})
+	defer network.Shutdown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	result, err := network.RunScenario(ctx, sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range result.Assertions {
+		if !a.Passed {
+			t.Errorf("%s assertion at %v failed: %s", a.Type, a.At, a.Error)
+		}
+	}
+	if len(result.Assertions) != len(sc.Assertions) {
+		t.Errorf("wrong number of assertion results: %d", len(result.Assertions))
+	}
+}
+
+func TestHTTPScenario(t *testing.T) {
+	network, s := testHTTPServer(t)
+	defer s.Close()
+	defer network.Shutdown()
+
+	client := NewClient(s.URL)
+	sc := &Scenario{
+		Name:     "http",
+		Nodes:    []ScenarioNodeSet{{Name: "a", Count: 3}},
+		Topology: TopologyRing,
+		Events:   []ScenarioEvent{{At: 100 * time.Millisecond, Type: EventKill, Nodes: []string{"a-0"}}},
+		Assertions: []ScenarioAssertion{
+			{Type: AssertPeers, Nodes: []string{"a"}, Min: 2},
+			{At: 100 * time.Millisecond, Type: AssertConnected, Nodes: []string{"a"}, Timeout: time.Second},
+		},
+	}
+	result, err := client.RunScenario(sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Assertions[0].Passed {
+		t.Errorf("peers assertion failed: %s", result.Assertions[0].Error)
+	}
+	if result.Assertions[1].Passed || result.Passed() {
+		t.Error("connected assertion passed after killing a node")
+	}
+}
@@ -34,6 +34,18 @@ func ListSize(contentSize uint64) uint64 {
 	return uint64(headsize(contentSize)) + contentSize
 }
 
+// BytesSize returns the encoded size of a byte slice.
+func BytesSize(b []byte) uint64 {
+	switch {
+	case len(b) == 0:
+		return 1
+	case len(b) == 1 && b[0] <= 0x7f:
+		return 1
+	default:
+		return uint64(headsize(uint64(len(b))) + len(b))
+	}
+}
+
 // IntSize returns the encoded size of the integer x.
 func IntSize(x uint64) int {
 	if x < 0x80 {